@@ -93,6 +93,7 @@ require (
 	github.com/getkin/kin-openapi v0.93.0
 	github.com/ghodss/yaml v1.0.0
 	github.com/gin-contrib/gzip v0.0.2
+	github.com/gin-contrib/sse v0.1.0
 	github.com/gin-contrib/static v0.0.0-20181225054800-cf5e10bbd933
 	github.com/gin-gonic/gin v1.7.0
 	github.com/go-acme/lego/v3 v3.2.0
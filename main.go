@@ -281,7 +281,7 @@ func main() {
 	}
 	db.Stats()
 	tx := db.MustBegin()
-	_ = tx.Rollback()
+	_ = resource.RollbackTransaction(tx)
 	log.Printf("Connection acquired from database [%s]", *dbType)
 
 	portValue := *port
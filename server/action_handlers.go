@@ -108,7 +108,7 @@ func GetActionPerformers(initConfig *resource.CmsConfig, configStore *resource.C
 	resource.CheckErr(err, "Failed to create mail send performer")
 	performers = append(performers, mailSendAction)
 
-	restartPerformer, err := resource.NewRestarSystemPerformer(initConfig)
+	restartPerformer, err := resource.NewRestarSystemPerformer(initConfig, configStore)
 	resource.CheckErr(err, "Failed to create restart performer")
 	performers = append(performers, restartPerformer)
 
@@ -132,6 +132,50 @@ func GetActionPerformers(initConfig *resource.CmsConfig, configStore *resource.C
 	resource.CheckErr(err, "Failed to create column rename performer")
 	performers = append(performers, columnRenamePerformer)
 
+	schemaMigratePerformer, err := resource.NewSchemaMigratePerformer(initConfig, cruds)
+	resource.CheckErr(err, "Failed to create schema migrate performer")
+	performers = append(performers, schemaMigratePerformer)
+
+	schemaDiffPerformer, err := resource.NewSchemaDiffPerformer(cruds)
+	resource.CheckErr(err, "Failed to create schema diff performer")
+	performers = append(performers, schemaDiffPerformer)
+
+	schemaRollbackPerformer, err := resource.NewSchemaRollbackPerformer(cruds, configStore)
+	resource.CheckErr(err, "Failed to create schema rollback performer")
+	performers = append(performers, schemaRollbackPerformer)
+
+	apiKeyCreatePerformer, err := resource.NewApiKeyCreatePerformer(cruds)
+	resource.CheckErr(err, "Failed to create api key create performer")
+	performers = append(performers, apiKeyCreatePerformer)
+
+	refreshTokenPerformer, err := resource.NewRefreshTokenPerformer(configStore)
+	resource.CheckErr(err, "Failed to create refresh token performer")
+	performers = append(performers, refreshTokenPerformer)
+
+	signOutPerformer, err := resource.NewSignOutPerformer(configStore)
+	resource.CheckErr(err, "Failed to create sign out performer")
+	performers = append(performers, signOutPerformer)
+
+	revokeSessionPerformer, err := resource.NewRevokeSessionPerformer(configStore)
+	resource.CheckErr(err, "Failed to create revoke session performer")
+	performers = append(performers, revokeSessionPerformer)
+
+	revokeUserSessionsPerformer, err := resource.NewRevokeUserSessionsPerformer(cruds, configStore)
+	resource.CheckErr(err, "Failed to create revoke user sessions performer")
+	performers = append(performers, revokeUserSessionsPerformer)
+
+	restoreRowPerformer, err := resource.NewRestoreRowPerformer(cruds)
+	resource.CheckErr(err, "Failed to create restore row performer")
+	performers = append(performers, restoreRowPerformer)
+
+	purgeRowPerformer, err := resource.NewPurgeRowPerformer(cruds)
+	resource.CheckErr(err, "Failed to create purge row performer")
+	performers = append(performers, purgeRowPerformer)
+
+	purgeExpiredRowsPerformer, err := resource.NewPurgeExpiredRowsPerformer(cruds, configStore)
+	resource.CheckErr(err, "Failed to create purge expired rows performer")
+	performers = append(performers, purgeExpiredRowsPerformer)
+
 	randomValueGeneratePerformer, err := resource.NewRandomValueGeneratePerformer()
 	resource.CheckErr(err, "Failed to create random value generate performer")
 	performers = append(performers, randomValueGeneratePerformer)
@@ -168,6 +212,42 @@ func GetActionPerformers(initConfig *resource.CmsConfig, configStore *resource.C
 	resource.CheckErr(err, "Failed to create self tls certificate generator")
 	performers = append(performers, selfTlsCertificateGenerateActionPerformer)
 
+	passwordCheckPerformer, err := resource.NewPasswordCheckPerformer(configStore)
+	resource.CheckErr(err, "Failed to create password check performer")
+	performers = append(performers, passwordCheckPerformer)
+
+	totpEnrollPerformer, err := resource.NewTotpEnrollPerformer(cruds, configStore)
+	resource.CheckErr(err, "Failed to create totp enroll performer")
+	performers = append(performers, totpEnrollPerformer)
+
+	totpEnablePerformer, err := resource.NewTotpEnablePerformer(configStore)
+	resource.CheckErr(err, "Failed to create totp enable performer")
+	performers = append(performers, totpEnablePerformer)
+
+	totpDisablePerformer, err := resource.NewTotpDisablePerformer(configStore)
+	resource.CheckErr(err, "Failed to create totp disable performer")
+	performers = append(performers, totpDisablePerformer)
+
+	totpResetPerformer, err := resource.NewTotpResetPerformer()
+	resource.CheckErr(err, "Failed to create totp reset performer")
+	performers = append(performers, totpResetPerformer)
+
+	impersonateUserPerformer, err := resource.NewImpersonateUserPerformer(cruds, configStore)
+	resource.CheckErr(err, "Failed to create impersonate user performer")
+	performers = append(performers, impersonateUserPerformer)
+
+	revokeImpersonationPerformer, err := resource.NewRevokeImpersonationPerformer(cruds, configStore)
+	resource.CheckErr(err, "Failed to create revoke impersonation performer")
+	performers = append(performers, revokeImpersonationPerformer)
+
+	oauthClientRegisterPerformer, err := resource.NewOAuthClientRegisterPerformer(cruds)
+	resource.CheckErr(err, "Failed to create oauth client register performer")
+	performers = append(performers, oauthClientRegisterPerformer)
+
+	rotateJwtSigningKeyPerformer, err := resource.NewRotateJwtSigningKeyPerformer(certificateManager)
+	resource.CheckErr(err, "Failed to create rotate jwt signing key performer")
+	performers = append(performers, rotateJwtSigningKeyPerformer)
+
 	integrationInstallationPerformer, err := resource.NewIntegrationInstallationPerformer(initConfig, cruds, configStore)
 	resource.CheckErr(err, "Failed to create integration installation performer")
 	performers = append(performers, integrationInstallationPerformer)
@@ -3,12 +3,17 @@ package apiblueprint
 import (
 	"bytes"
 	"github.com/artpar/api2go"
+	"github.com/artpar/api2go/jsonapi"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/fakerservice"
 	"github.com/daptin/daptin/server/resource"
 	"github.com/iancoleman/strcase"
+	"sort"
+	"strconv"
 
 	"fmt"
 	"strings"
-	//"github.com/daptin/daptin/server/fakerservice"
+
 	"github.com/advance512/yaml"
 	log "github.com/sirupsen/logrus"
 )
@@ -58,7 +63,58 @@ var skipColumns = map[string]bool{
 	"status":     true,
 }
 
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"$ref": "#/components/schemas/" + name,
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{
+			"schema": schema,
+		},
+	}
+}
+
+func referenceIdParameter(tableName string) map[string]interface{} {
+	return map[string]interface{}{
+		"name": "referenceId",
+		"schema": map[string]interface{}{
+			"type": "string",
+		},
+		"required":    true,
+		"in":          "path",
+		"description": "Reference Id of the " + tableName,
+	}
+}
+
+// errorResponse is the default response of all operations
+var errorResponse = map[string]interface{}{
+	"$ref": "#/components/responses/Error",
+}
+
+func isFileColumn(colInfo api2go.ColumnInfo) bool {
+	return strings.Split(colInfo.ColumnType, ".")[0] == "file"
+}
+
+// isApiColumn is false for the columns which are not returned as attributes, foreign keys to other
+// tables are relationships. File columns are foreign keys to a cloud store and are attributes
+func isApiColumn(colInfo api2go.ColumnInfo) bool {
+	if colInfo.ExcludeFromApi || skipColumns[colInfo.ColumnName] {
+		return false
+	}
+	return !colInfo.IsForeignKey || colInfo.ForeignKeyData.DataSource != "self"
+}
+
 func CreateColumnLine(colInfo api2go.ColumnInfo) map[string]interface{} {
+	if isFileColumn(colInfo) {
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaRef("FileValue"),
+		}
+	}
+
 	columnType := colInfo.ColumnType
 	typ := resource.ColumnManager.GetBlueprintType(columnType)
 
@@ -69,42 +125,128 @@ func CreateColumnLine(colInfo api2go.ColumnInfo) map[string]interface{} {
 	m := map[string]interface{}{
 		"type": typ,
 	}
-	//if !colInfo.IsNullable {
-	//	m["required"] = true
-	//}
+	if colInfo.IsNullable {
+		m["nullable"] = true
+	}
+	if colInfo.ColumnDescription != "" {
+		m["description"] = colInfo.ColumnDescription
+	}
 	return m
 }
 
-func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.DbResource) string {
+// CreateExample generates an example of the columns with fakerservice, the fake values are converted
+// to the type of the column in the schema
+func CreateExample(columns []api2go.ColumnInfo) map[string]interface{} {
+	example := make(map[string]interface{})
+	exampleColumns := make([]api2go.ColumnInfo, 0)
+	for _, colInfo := range columns {
+		if isFileColumn(colInfo) {
+			example[colInfo.ColumnName] = []map[string]interface{}{
+				{
+					"name": "example.txt",
+					"file": "ZXhhbXBsZQ==",
+					"type": "text/plain",
+				},
+			}
+		} else if _, ok := resource.ColumnManager.ColumnMap[colInfo.ColumnType]; ok {
+			exampleColumns = append(exampleColumns, colInfo)
+		} else {
+			example[colInfo.ColumnName] = colInfo.ColumnName
+		}
+	}
+
+	fakeObject := fakerservice.NewFakeInstance(exampleColumns)
+	for _, colInfo := range exampleColumns {
+		fakeValue, ok := fakeObject[colInfo.ColumnName].(string)
+		if !ok {
+			continue
+		}
+		switch resource.ColumnManager.GetBlueprintType(colInfo.ColumnType) {
+		case "number":
+			value, err := strconv.ParseFloat(fakeValue, 64)
+			if err == nil {
+				example[colInfo.ColumnName] = value
+			}
+		case "boolean":
+			value, err := strconv.ParseBool(fakeValue)
+			if err == nil {
+				example[colInfo.ColumnName] = value
+			}
+		default:
+			example[colInfo.ColumnName] = fakeValue
+		}
+	}
+	return example
+}
+
+// configValue reads an optional openapi setting from the _config table
+func configValue(configStore *resource.ConfigStore, key string) string {
+	if configStore == nil {
+		return ""
+	}
+	value, err := configStore.GetConfigValueFor(key, "backend")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.DbResource, configStore *resource.ConfigStore) string {
 
 	tableMap := map[string]resource.TableInfo{}
+	tableNames := make([]string, 0)
 	for _, table := range config.Tables {
+		if _, ok := tableMap[table.TableName]; ok || strings.Index(table.TableName, "_has_") > -1 {
+			continue
+		}
 		tableMap[table.TableName] = table
+		tableNames = append(tableNames, table.TableName)
 	}
+	sort.Strings(tableNames)
 
 	apiDefinition := make(map[string]interface{})
 
 	apiDefinition["openapi"] = "3.0.0"
-	apiDefinition["info"] = map[string]interface{}{
+	info := map[string]interface{}{
 		"version": "1.0.0",
 		"title":   "Daptin API endpoint",
 		"license": map[string]interface{}{
 			"name": "MIT",
 		},
-		"contact": map[string]interface{}{
-			"name":  "Parth",
-			"url":   "https://dapt.in",
-			"email": "artpar@gmail.com",
-		},
 		"description": "Daptin API server",
 	}
+	if title := configValue(configStore, "openapi.title"); title != "" {
+		info["title"] = title
+	}
+	contact := make(map[string]interface{})
+	for _, key := range []string{"name", "url", "email"} {
+		if value := configValue(configStore, "openapi.contact."+key); value != "" {
+			contact[key] = value
+		}
+	}
+	if len(contact) > 0 {
+		info["contact"] = contact
+	}
+	apiDefinition["info"] = info
 
-	apiDefinition["servers"] = []map[string]interface{}{
-		{
+	servers := make([]map[string]interface{}, 0)
+	for _, server := range strings.Split(configValue(configStore, "openapi.servers"), ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		servers = append(servers, map[string]interface{}{
+			"url": server,
+		})
+	}
+	if len(servers) == 0 {
+		servers = append(servers, map[string]interface{}{
 			"url":         fmt.Sprintf("http://%v", config.Hostname),
 			"description": "Server " + config.Hostname,
-		},
+		})
 	}
+	apiDefinition["servers"] = servers
+
 	typeMap := make(map[string]map[string]interface{})
 	typeMap["RelatedStructure"] = map[string]interface{}{
 		"type": "object",
@@ -142,12 +284,21 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 		"type": "object",
 		"properties": map[string]interface{}{
 			"ResponseType": map[string]interface{}{
-				"type": "string",
+				"type":        "string",
+				"description": "client.notify, client.redirect, client.file.download, client.store.set, client.cookie.set or the type of the returned object",
 			},
 			"Attributes": map[string]interface{}{
 				"type": "object",
 			},
 		},
+		"example": map[string]interface{}{
+			"ResponseType": "client.notify",
+			"Attributes": map[string]interface{}{
+				"type":    "success",
+				"title":   "Success",
+				"message": "Action completed",
+			},
+		},
 	}
 
 	paginationStatus := make(map[string]interface{})
@@ -181,44 +332,176 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 	typeMap["PaginationStatus"] = paginationStatus
 	typeMap["ActionResponse"] = actionResponse
 
+	relationshipLinks := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"related": map[string]interface{}{
+				"type":        "string",
+				"description": "link to related objects",
+			},
+			"self": map[string]interface{}{
+				"type":        "string",
+				"description": "link to self",
+			},
+		},
+	}
+
 	IncludedRelationship := make(map[string]interface{})
 	IncludedRelationship["type"] = "object"
 	IncludedRelationship["properties"] = map[string]interface{}{
-		"data": map[string]interface{}{
-			"$ref": "#/components/schemas/RelatedStructure",
+		"data":  schemaRef("RelatedStructure"),
+		"links": relationshipLinks,
+	}
+	typeMap["IncludedRelationship"] = IncludedRelationship
+
+	typeMap["IncludedRelationships"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data": map[string]interface{}{
+				"type":  "array",
+				"items": schemaRef("RelatedStructure"),
+			},
+			"links": relationshipLinks,
 		},
-		"links": map[string]interface{}{
-			"type":        "object",
-			"description": "From page",
-			"properties": map[string]interface{}{
-				"related": map[string]interface{}{
-					"type":        "string",
-					"description": "link to related objects",
-				},
-				"self": map[string]interface{}{
-					"type":        "string",
-					"description": "link to self",
+	}
+
+	typeMap["RelationshipIdentifiers"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data": map[string]interface{}{
+				"type":  "array",
+				"items": schemaRef("RelatedStructure"),
+			},
+		},
+		"required": []string{"data"},
+	}
+
+	operators := make([]string, 0)
+	for operator := range resource.OperatorMap {
+		operators = append(operators, operator)
+	}
+	sort.Strings(operators)
+	typeMap["Query"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"column": map[string]interface{}{
+				"type": "string",
+			},
+			"operator": map[string]interface{}{
+				"type": "string",
+				"enum": operators,
+			},
+			"value": map[string]interface{}{
+				"description": "value to compare with, an array for in, any of and none of",
+			},
+		},
+		"required": []string{"column", "operator"},
+		"example": map[string]interface{}{
+			"column":   "reference_id",
+			"operator": "is",
+			"value":    "4bd4a1ba-8f80-4a44-8f2e-f3a2b6e1e46c",
+		},
+	}
+
+	typeMap["Group"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"column": map[string]interface{}{
+				"type": "string",
+			},
+			"order": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"asc", "desc"},
+			},
+		},
+		"required": []string{"column"},
+	}
+
+	typeMap["FileValue"] = map[string]interface{}{
+		"type":        "object",
+		"description": "A file in a file column, to upload a file send the base64 encoded contents in file",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type": "string",
+			},
+			"file": map[string]interface{}{
+				"type":        "string",
+				"description": "base64 encoded contents, only in requests",
+			},
+			"type": map[string]interface{}{
+				"type": "string",
+			},
+			"path": map[string]interface{}{
+				"type": "string",
+			},
+			"size": map[string]interface{}{
+				"type": "number",
+			},
+			"md5": map[string]interface{}{
+				"type": "string",
+			},
+		},
+	}
+
+	typeMap["AggregateRow"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"type": map[string]interface{}{
+				"type": "string",
+			},
+			"id": map[string]interface{}{
+				"type": "string",
+			},
+			"attributes": map[string]interface{}{
+				"type":        "object",
+				"description": "the grouped columns and the projected columns",
+			},
+		},
+	}
+
+	typeMap["AggregateResponse"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data": map[string]interface{}{
+				"type":  "array",
+				"items": schemaRef("AggregateRow"),
+			},
+		},
+	}
+
+	typeMap["Error"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"errors": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"status": map[string]interface{}{
+							"type": "string",
+						},
+						"title": map[string]interface{}{
+							"type": "string",
+						},
+						"detail": map[string]interface{}{
+							"type": "string",
+						},
+					},
 				},
 			},
 		},
 	}
-	typeMap["IncludedRelationship"] = IncludedRelationship
 
-	for _, tableInfo := range config.Tables {
+	for _, tableName := range tableNames {
+		tableInfo := tableMap[tableName]
 		ramlType := make(map[string]interface{})
-		// skip join tables
-		if strings.Index(tableInfo.TableName, "_has_") > -1 {
-			continue
-		}
 
 		properties := make(map[string]interface{})
 		requiredCols := make([]string, 0)
+		exampleColumns := make([]api2go.ColumnInfo, 0)
 		ramlType["type"] = "object"
 		for _, colInfo := range tableInfo.Columns {
-			if colInfo.IsForeignKey {
-				continue
-			}
-			if skipColumns[colInfo.ColumnName] {
+			if !isApiColumn(colInfo) {
 				continue
 			}
 
@@ -227,34 +510,36 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 			}
 
 			properties[colInfo.ColumnName] = CreateColumnLine(colInfo)
+			exampleColumns = append(exampleColumns, colInfo)
+		}
+		for _, virtualColumn := range tableInfo.VirtualColumns {
+			colInfo := virtualColumn.ColumnInfo()
+			property := CreateColumnLine(colInfo)
+			property["readOnly"] = true
+			properties[colInfo.ColumnName] = property
+			exampleColumns = append(exampleColumns, colInfo)
 		}
 
 		ramlType["properties"] = properties
-		ramlType["required"] = requiredCols
+		if len(requiredCols) > 0 {
+			ramlType["required"] = requiredCols
+		}
+		ramlType["example"] = CreateExample(exampleColumns)
 
 		typeMap[strcase.ToCamel(tableInfo.TableName)] = ramlType
-
-		//worldActions, err := cruds["action"].GetActionsByType(tableInfo.TableName)
-		//if InfoError(err, "Failed to list world actions for raml") {
-		//	continue
-		//}
+		typeMap[strcase.ToCamel(tableInfo.TableName)+"Resource"] = CreateDataInResponse(tableInfo, tableMap)
 
 	}
-	for _, tableInfo := range config.Tables {
+	for _, tableName := range tableNames {
+		tableInfo := tableMap[tableName]
 		ramlType := make(map[string]interface{})
-		// skip join tables
-		if strings.Index(tableInfo.TableName, "_has_") > -1 {
-			continue
-		}
 
 		properties := make(map[string]interface{})
 		requiredCols := make([]string, 0)
+		exampleColumns := make([]api2go.ColumnInfo, 0)
 		ramlType["type"] = "object"
 		for _, colInfo := range tableInfo.Columns {
-			if colInfo.IsForeignKey {
-				continue
-			}
-			if skipColumns[colInfo.ColumnName] {
+			if !isApiColumn(colInfo) {
 				continue
 			}
 			if resource.IsStandardColumn(colInfo.ColumnName) {
@@ -266,10 +551,14 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 			}
 
 			properties[colInfo.ColumnName] = CreateColumnLine(colInfo)
+			exampleColumns = append(exampleColumns, colInfo)
 		}
 
 		ramlType["properties"] = properties
-		ramlType["required"] = requiredCols
+		if len(requiredCols) > 0 {
+			ramlType["required"] = requiredCols
+		}
+		ramlType["example"] = CreateExample(exampleColumns)
 
 		typeMap["New"+strcase.ToCamel(tableInfo.TableName)] = ramlType
 
@@ -280,64 +569,63 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 		ramlActionType["type"] = "object"
 
 		actionProperties := make(map[string]interface{})
+		requiredFields := make([]string, 0)
+		exampleColumns := make([]api2go.ColumnInfo, 0)
 		for _, colInfo := range action.InFields {
-			if colInfo.IsForeignKey {
-				continue
-			}
 			if skipColumns[colInfo.ColumnName] {
 				continue
 			}
 
+			// the same as GetValidatedInFields, fields with a default value are optional
+			if !colInfo.IsNullable && colInfo.DefaultValue == "" {
+				requiredFields = append(requiredFields, colInfo.ColumnName)
+			}
 			actionProperties[colInfo.ColumnName] = CreateColumnLine(colInfo)
+			exampleColumns = append(exampleColumns, colInfo)
 		}
+		example := CreateExample(exampleColumns)
 		if !action.InstanceOptional {
 			actionProperties[action.OnType+"_id"] = map[string]interface{}{
 				"type":        "string",
 				"description": "reference id of a " + action.OnType,
 			}
+			requiredFields = append(requiredFields, action.OnType+"_id")
+			example[action.OnType+"_id"] = "4bd4a1ba-8f80-4a44-8f2e-f3a2b6e1e46c"
 		}
 		ramlActionType["description"] = action.Label
 
 		ramlActionType["properties"] = actionProperties
+		if len(requiredFields) > 0 {
+			ramlActionType["required"] = requiredFields
+		}
+		ramlActionType["example"] = example
 		typeMap[fmt.Sprintf("%sOn%sRequestObject", strcase.ToCamel(action.Name), strcase.ToCamel(action.OnType))] = ramlActionType
 
 	}
 
 	resourcesMap := map[string]map[string]interface{}{}
-	tableInfoMap := make(map[string]resource.TableInfo)
-	for _, tableInfo := range config.Tables {
-		tableInfoMap[tableInfo.TableName] = tableInfo
-	}
-
-	for _, tableInfo := range config.Tables {
 
-		// skip join tables
-		if strings.Index(tableInfo.TableName, "_has_") > -1 {
-			continue
-		}
+	for _, tableName := range tableNames {
+		tableInfo := tableMap[tableName]
 
 		resourceInstance := make(map[string]interface{})
 
-		dataInResponse := CreateDataInResponse(tableInfo)
-
 		// BEGIN: POST request
-		postMethod := CreatePostMethod(tableInfo, dataInResponse)
+		postMethod := CreatePostMethod(tableInfo)
 		resourceInstance["post"] = &postMethod
 		//  END: POST Request
 
 		//  BEGIN: GET Request
-		getAllMethod := CreateGetAllMethod(tableInfo, dataInResponse)
+		getAllMethod := CreateGetAllMethod(tableInfo)
 		resourceInstance["get"] = &getAllMethod
 		//  END: GET Request
 
-		//fakeObject := fakerservice.NewFakeInstance(tableInfo)
-
 		nestedMap := make(map[string]map[string]interface{})
 
 		byIdResource := make(map[string]interface{})
 
 		//  BEGIN: GET ById Request
-		getByIdMethod := CreateGetMethod(tableInfo, dataInResponse)
+		getByIdMethod := CreateGetMethod(tableInfo)
 		byIdResource["get"] = getByIdMethod
 		//  END: GET ById Request
 
@@ -353,96 +641,44 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 
 		nestedMap["/api/"+tableInfo.TableName+"/{referenceId}"] = byIdResource
 
-		for _, rel := range tableInfo.Relations {
+		for _, reference := range tableReferences(tableInfo, tableMap) {
 
 			// BEGIN: Get Relations Method
 
-			relationsById := make(map[string]interface{})
+			relatedTable := tableMap[reference.Type]
+			relationName := strcase.ToCamel(reference.Name)
 
-			if tableInfo.TableName == rel.Subject {
-				relatedTable := tableInfoMap[rel.Object]
-				getMethod := CreateGetAllMethod(relatedTable, CreateDataInResponse(relatedTable))
-				getMethod["description"] = fmt.Sprintf("Returns a list of all %v", ProperCase(relatedTable.TableName)+" related to a "+tableInfo.TableName)
-				getMethod["operationId"] = fmt.Sprintf("Get" + strcase.ToCamel(rel.ObjectName) + "Of" + strcase.ToCamel(rel.SubjectName))
-				getMethod["summary"] = fmt.Sprintf("Fetch related %s of %v", rel.ObjectName, tableInfo.TableName)
-
-				getMethod["parameters"] = []map[string]interface{}{
-					{
-						"name": "referenceId",
-						"schema": map[string]interface{}{
-							"type": "string",
-						},
-						"required":    true,
-						"in":          "path",
-						"description": "Reference Id of the " + tableInfo.TableName,
-					},
-				}
-
-				deleteMethod := CreateDeleteRelationMethod(relatedTable)
-				deleteMethod["description"] = fmt.Sprintf("Remove a related %v from the %v", tableInfo.TableName, rel.ObjectName)
-				deleteMethod["tags"] = []string{rel.ObjectName, rel.Subject, rel.SubjectName, rel.Object, rel.Relation, "delete"}
-
-				deleteMethod["summary"] = fmt.Sprintf("Delete related %s of %v", rel.ObjectName, tableInfo.TableName)
-				deleteMethod["operationId"] = "Delete" + strcase.ToCamel(rel.ObjectName) + "Of" + strcase.ToCamel(tableInfo.TableName)
-
-				relationsById["get"] = getMethod
-				relationsById["delete"] = deleteMethod
-
-				deleteMethod["parameters"] = []map[string]interface{}{
-					{
-						"name": "referenceId",
-						"schema": map[string]interface{}{
-							"type": "string",
-						},
-						"required":    true,
-						"in":          "path",
-						"description": "Reference Id of the " + tableInfo.TableName,
-					},
-				}
-
-				nestedMap[fmt.Sprintf("/api/%s/{referenceId}/%s", tableInfo.TableName, rel.Object)] = relationsById
+			var getMethod map[string]interface{}
+			if reference.Relationship == jsonapi.ToManyRelationship {
+				getMethod = CreateGetAllMethod(relatedTable)
+				getMethod["description"] = fmt.Sprintf("Returns a list of %v related to a %v", ProperCase(reference.Name), tableInfo.TableName)
+				getMethod["parameters"] = append([]map[string]interface{}{referenceIdParameter(tableInfo.TableName)}, listParameterRefs(relatedTable)...)
 			} else {
-				relatedTable := tableInfoMap[rel.Subject]
-				getMethod := CreateGetAllMethod(relatedTable, CreateDataInResponse(relatedTable))
-				getMethod["summary"] = "Related " + strcase.ToCamel(rel.SubjectName) + " of a " + strcase.ToCamel(tableInfo.TableName)
-				getMethod["operationId"] = "Related" + strcase.ToCamel(rel.SubjectName) + "Of" + strcase.ToCamel(tableInfo.TableName)
-				patchMethod["summary"] = fmt.Sprintf("Fetch related %s of %v", rel.SubjectName, tableInfo.TableName)
-				patchMethod["tags"] = []string{rel.ObjectName, rel.Subject, rel.SubjectName, rel.Object, rel.Relation, "get"}
-
-				deleteMethod := CreateDeleteRelationMethod(relatedTable)
-				deleteMethod["description"] = fmt.Sprintf("Remove a related %v from the %v", rel.SubjectName, rel.ObjectName)
-				deleteMethod["operationId"] = "Delete" + strcase.ToCamel(relatedTable.TableName) + "Of" + strcase.ToCamel(tableInfo.TableName)
-				patchMethod["summary"] = fmt.Sprintf("Delete related %s of %v", rel.SubjectName, tableInfo.TableName)
-				patchMethod["tags"] = []string{rel.ObjectName, rel.Subject, rel.SubjectName, rel.Object, rel.Relation, "delete"}
-				relationsById["get"] = getMethod
-
+				getMethod = CreateGetMethod(relatedTable)
+				getMethod["description"] = fmt.Sprintf("Returns the %v of a %v", ProperCase(reference.Name), tableInfo.TableName)
 				getMethod["parameters"] = []map[string]interface{}{
+					referenceIdParameter(tableInfo.TableName),
 					{
-						"name": "referenceId",
-						"schema": map[string]interface{}{
-							"type": "string",
-						},
-						"required":    true,
-						"in":          "path",
-						"description": "Reference Id of the " + tableInfo.TableName,
+						"$ref": "#/components/parameters/includedRelations",
 					},
 				}
+			}
+			getMethod["operationId"] = "Get" + relationName + "Of" + strcase.ToCamel(tableInfo.TableName)
+			getMethod["summary"] = fmt.Sprintf("Fetch related %s of %v", reference.Name, tableInfo.TableName)
 
-				deleteMethod["parameters"] = []map[string]interface{}{
-					{
-						"name": "referenceId",
-						"schema": map[string]interface{}{
-							"type": "string",
-						},
-						"required":    true,
-						"in":          "path",
-						"description": "Reference Id of the " + tableInfo.TableName,
-					},
-				}
+			nestedMap[fmt.Sprintf("/api/%s/{referenceId}/%s", tableInfo.TableName, reference.Name)] = map[string]interface{}{
+				"get": getMethod,
+			}
 
-				relationsById["delete"] = deleteMethod
-				nestedMap[fmt.Sprintf("/api/%s/{referenceId}/%s", tableInfo.TableName, rel.Subject)] = relationsById
+			relationshipsById := map[string]interface{}{
+				"patch": CreateRelationshipMethod(tableInfo, reference, "Replace"),
+			}
+			// the same condition as api2go for the routes to add and remove to-many relations
+			if reference.Relationship == jsonapi.ToManyRelationship && reference.Name == jsonapi.Pluralize(reference.Name) {
+				relationshipsById["post"] = CreateRelationshipMethod(tableInfo, reference, "Add")
+				relationshipsById["delete"] = CreateDeleteRelationMethod(tableInfo, reference)
 			}
+			nestedMap[fmt.Sprintf("/api/%s/{referenceId}/relationships/%s", tableInfo.TableName, reference.Name)] = relationshipsById
 			// END: Get relations method
 
 		}
@@ -451,15 +687,25 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 			resourcesMap[k] = v
 		}
 
-		if tableInfo.IsStateTrackingEnabled {
-
-			//tableInfo.StateMachines
-
-		}
-
 		resourcesMap["/api/"+tableInfo.TableName] = resourceInstance
 	}
 
+	resourcesMap["/aggregate/{typename}"] = map[string]interface{}{
+		"get": CreateAggregateMethod(tableNames),
+	}
+	resourcesMap["/track/start/{stateMachineId}"] = map[string]interface{}{
+		"post": CreateTrackStartMethod(tableNames),
+	}
+	resourcesMap["/track/event/{typename}/{objectStateId}/{eventName}"] = map[string]interface{}{
+		"post": CreateTrackEventMethod(tableNames),
+	}
+	resourcesMap["/asset/{typename}/{resourceId}/{columnName}"] = map[string]interface{}{
+		"get": CreateAssetMethod(tableNames),
+	}
+	resourcesMap["/.well-known/jwks.json"] = map[string]interface{}{
+		"get": CreateJwksMethod(),
+	}
+
 	for _, action := range config.Actions {
 
 		resourcesMap[fmt.Sprintf("/action/%s/%s", action.OnType, action.Name)] = map[string]interface{}{
@@ -468,49 +714,58 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 				"operationId": "Execute" + strcase.ToCamel(action.Name) + "ActionOn" + strcase.ToCamel(action.OnType),
 				"summary":     action.Label,
 				"requestBody": map[string]interface{}{
-					"content": map[string]interface{}{
-						"application/json": map[string]interface{}{
-							"schema": map[string]interface{}{
-								"$ref": "#/components/schemas/" + fmt.Sprintf("%sOn%sRequestObject", strcase.ToCamel(action.Name), strcase.ToCamel(action.OnType)),
-							},
+					"required": true,
+					"content": jsonContent(map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"attributes": schemaRef(fmt.Sprintf("%sOn%sRequestObject", strcase.ToCamel(action.Name), strcase.ToCamel(action.OnType))),
 						},
-					},
+						"required": []string{"attributes"},
+					}),
 				},
 				"responses": map[string]interface{}{
 					"200": map[string]interface{}{
-
 						"description": "action response of " + action.Name,
-						"content": map[string]interface{}{
-							"application/json": map[string]interface{}{
-								"schema": map[string]interface{}{
-									"type": "array",
-									"items": map[string]interface{}{
-										"$ref": "#/components/schemas/ActionResponse",
-									},
-								},
-							},
-						},
+						"content": jsonContent(map[string]interface{}{
+							"type":  "array",
+							"items": schemaRef("ActionResponse"),
+						}),
 					},
+					"default": errorResponse,
 				},
 			},
 		}
 
 	}
 
-	actionResource := make(map[string]interface{})
-
 	apiDefinition["paths"] = resourcesMap
-	for n, v := range actionResource {
-		apiDefinition[n] = v
-	}
 
 	apiDefinition["components"] = map[string]interface{}{
-		"schemas": typeMap,
+		"schemas":    typeMap,
+		"parameters": CreateListParameters(),
+		"responses": map[string]interface{}{
+			"Error": map[string]interface{}{
+				"description": "the request failed",
+				"content":     jsonContent(schemaRef("Error")),
+			},
+		},
 		"securitySchemes": map[string]map[string]string{
 			"bearerAuth": {
 				"type":         "http",
 				"scheme":       "bearer",
 				"bearerFormat": "JWT",
+				"description":  "token returned by the signin action",
+			},
+			"basicAuth": {
+				"type":        "http",
+				"scheme":      "basic",
+				"description": "email and password of the user account",
+			},
+			"apiKeyAuth": {
+				"type":        "apiKey",
+				"in":          "header",
+				"name":        auth.ApiKeyHeader,
+				"description": "key returned by the create_api_key action",
 			},
 		},
 	}
@@ -518,36 +773,59 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 		{
 			"bearerAuth": []string{},
 		},
+		{
+			"basicAuth": []string{},
+		},
+		{
+			"apiKeyAuth": []string{},
+		},
 	}
 
-	ym, _ := yaml.Marshal(apiDefinition)
+	ym, err := yaml.Marshal(apiDefinition)
+	InfoError(err, "Failed to marshal openapi definition")
 	return string(ym)
 
 }
 
-func CreateDataInResponse(tableInfo resource.TableInfo) map[string]interface{} {
+// tableReferences are the relations of the table with the names and types api2go uses for the routes
+func tableReferences(tableInfo resource.TableInfo, tableMap map[string]resource.TableInfo) []jsonapi.Reference {
+	model := api2go.NewApi2GoModel(tableInfo.TableName, nil, 0, tableInfo.Relations)
+	references := make([]jsonapi.Reference, 0)
+	done := make(map[string]bool)
+	for _, reference := range model.GetReferences() {
+		if _, ok := tableMap[reference.Type]; !ok || reference.Name == "" || done[reference.Name] {
+			continue
+		}
+		done[reference.Name] = true
+		references = append(references, reference)
+	}
+	sort.Slice(references, func(i, j int) bool {
+		return references[i].Name < references[j].Name
+	})
+	return references
+}
+
+// CreateDataInResponse is the json api resource object of the table
+func CreateDataInResponse(tableInfo resource.TableInfo, tableMap map[string]resource.TableInfo) map[string]interface{} {
 	relationshipMap := make(map[string]interface{}, 0)
-	for _, relation := range tableInfo.Relations {
-		if relation.Object == tableInfo.TableName {
-			relationshipMap[relation.SubjectName] = "IncludedRelationship"
+	for _, reference := range tableReferences(tableInfo, tableMap) {
+		if reference.Relationship == jsonapi.ToManyRelationship {
+			relationshipMap[reference.Name] = schemaRef("IncludedRelationships")
 		} else {
-			relationshipMap[relation.ObjectName] = "IncludedRelationship"
+			relationshipMap[reference.Name] = schemaRef("IncludedRelationship")
 		}
 	}
 
 	var dataInResponse = map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
-			"attributes": map[string]interface{}{
-				"schema": map[string]interface{}{
-					"$ref": "#/components/schemas/New" + strcase.ToCamel(tableInfo.TableName),
-				},
-			},
+			"attributes": schemaRef(strcase.ToCamel(tableInfo.TableName)),
 			"id": map[string]interface{}{
 				"type": "string",
 			},
 			"type": map[string]interface{}{
 				"type": "string",
+				"enum": []string{tableInfo.TableName},
 			},
 			"relationships": map[string]interface{}{
 				"type":       "object",
@@ -557,124 +835,377 @@ func CreateDataInResponse(tableInfo resource.TableInfo) map[string]interface{} {
 	}
 	return dataInResponse
 }
-func CreatePostMethod(tableInfo resource.TableInfo, dataInResponse map[string]interface{}) map[string]interface{} {
-	postMethod := make(map[string]interface{})
-	postMethod["operationId"] = fmt.Sprintf("Create%s", strcase.ToCamel(tableInfo.TableName))
-	postMethod["summary"] = fmt.Sprintf("Create a new %v", tableInfo.TableName)
-	postMethod["tags"] = []string{tableInfo.TableName, "create"}
-	postBody := make(map[string]interface{})
 
-	postBody["description"] = tableInfo.TableName + " to create"
-	postBody["required"] = true
-	postBody["content"] = map[string]interface{}{
-		"application/json": map[string]interface{}{
+// CreateListParameters are the paging, sorting and filtering parameters of the list requests
+func CreateListParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"sort": map[string]interface{}{
+			"name": "sort",
 			"schema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"data": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"type": map[string]interface{}{
-								"type": "string",
-								"value": tableInfo.TableName,
-							},
-							"attributes": map[string]interface{}{
-								"$ref": "#/components/schemas/New" + strcase.ToCamel(tableInfo.TableName),
-							},
-						},
-					},
-				},
+				"type": "string",
 			},
+			"required":    false,
+			"in":          "query",
+			"description": "Comma separated column names to sort by, prefix with - for descending order",
+			"example":     "-created_at",
 		},
-	}
-	postMethod["requestBody"] = postBody
-	postResponseMap := make(map[string]interface{})
-	postResponseBody := make(map[string]interface{})
-	postResponseBody["type"] = "object"
-
-	postResponseBody = map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"data": map[string]interface{}{
-				"$ref": "#/components/schemas/" + strcase.ToCamel(tableInfo.TableName),
-			},
-			"links": map[string]interface{}{
-				"$ref": "#/components/schemas/PaginationStatus",
+		"pageNumber": map[string]interface{}{
+			"name": "page[number]",
+			"in":   "query",
+			"schema": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 1,
 			},
+			"required":    false,
+			"description": "Page number for the query set, starts with 1",
 		},
-	}
-	postOkResponse := make(map[string]interface{})
-	postOkResponse["description"] = tableInfo.TableName + " response"
-
-	postOkResponse["content"] = map[string]interface{}{
-		"application/json": map[string]interface{}{
-			"schema": postResponseBody,
+		"pageSize": map[string]interface{}{
+			"schema": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 1,
+			},
+			"name":        "page[size]",
+			"required":    false,
+			"in":          "query",
+			"description": "Size of one page, defaults to 10",
 		},
-	}
-
-	postResponseMap["200"] = postOkResponse
-	postMethod["responses"] = postResponseMap
-	return postMethod
-}
-func CreateGetAllMethod(tableInfo resource.TableInfo, dataInResponse map[string]interface{}) map[string]interface{} {
-	getAllMethod := make(map[string]interface{})
-	getAllMethod["description"] = fmt.Sprintf("Returns a list of %v", ProperCase(tableInfo.TableName))
-	getAllMethod["operationId"] = fmt.Sprintf("Get" + strcase.ToCamel(tableInfo.TableName))
-	getAllMethod["summary"] = fmt.Sprintf("List all %v", tableInfo.TableName)
-	getAllMethod["tags"] = []string{tableInfo.TableName, "find", "get"}
-	getAllMethod["parameters"] = []map[string]interface{}{
-		{
-			"name": "sort",
+		"pageAfter": map[string]interface{}{
 			"schema": map[string]interface{}{
 				"type": "string",
 			},
+			"name":        "page[after]",
 			"required":    false,
 			"in":          "query",
-			"description": "Field name to sort by",
+			"description": "Reference id of the row after which the page starts",
 		},
-		{
-			"name": "page[number]",
-			"in":   "query",
+		"query": map[string]interface{}{
+			"in":          "query",
+			"name":        "query",
+			"required":    false,
+			"description": "Json array of conditions on the columns, all of them have to match",
+			"content": jsonContent(map[string]interface{}{
+				"type":  "array",
+				"items": schemaRef("Query"),
+			}),
+		},
+		"filter": map[string]interface{}{
 			"schema": map[string]interface{}{
 				"type": "string",
 			},
+			"in":          "query",
+			"name":        "filter",
 			"required":    false,
-			"description": "Page number for the query set, starts with 1",
+			"description": "Search text in the indexed columns, ignored when query is set",
 		},
-		{
+		"group": map[string]interface{}{
 			"schema": map[string]interface{}{
-				"type": "string",
+				"type":   "string",
+				"format": "byte",
 			},
-			"name":        "page[size]",
+			"in":          "query",
+			"name":        "group",
 			"required":    false,
+			"description": "Base64 encoded json array of Group, the columns to group the rows by",
+		},
+		"includedRelations": map[string]interface{}{
+			"schema": map[string]interface{}{
+				"type": "string",
+			},
 			"in":          "query",
-			"description": "Size of one page, try 10",
+			"name":        "included_relations",
+			"required":    false,
+			"description": "Comma separated names of the relations to include, * for all",
 		},
-		{
+		"fields": map[string]interface{}{
 			"schema": map[string]interface{}{
 				"type": "string",
 			},
 			"in":          "query",
-			"name":        "query",
+			"name":        "fields",
+			"required":    false,
+			"description": "Comma separated names of the columns to return",
+		},
+		"includeDeleted": map[string]interface{}{
+			"schema": map[string]interface{}{
+				"type": "boolean",
+			},
+			"in":          "query",
+			"name":        "include_deleted",
 			"required":    false,
-			"description": "search text in indexed columns",
+			"description": "Include the soft deleted rows, only for administrators",
+		},
+	}
+}
+
+// listParameterRefs are the parameters of the list of rows of a table, include_deleted is only on the tables
+// with soft delete enabled
+func listParameterRefs(tableInfo resource.TableInfo) []map[string]interface{} {
+	refs := make([]map[string]interface{}, 0)
+	names := []string{"sort", "pageNumber", "pageSize", "pageAfter", "query", "filter", "group", "includedRelations", "fields"}
+	if tableInfo.IsSoftDeleteEnabled {
+		names = append(names, "includeDeleted")
+	}
+	for _, name := range names {
+		refs = append(refs, map[string]interface{}{
+			"$ref": "#/components/parameters/" + name,
+		})
+	}
+	return refs
+}
+
+func typenameParameter(tableNames []string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     "typename",
+		"in":       "path",
+		"required": true,
+		"schema": map[string]interface{}{
+			"type": "string",
+			"enum": tableNames,
+		},
+		"description": "Name of the table",
+	}
+}
+
+func stringParameter(name string, in string, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     name,
+		"in":       in,
+		"required": in == "path",
+		"schema": map[string]interface{}{
+			"type": "string",
+		},
+		"description": description,
+	}
+}
+
+func arrayParameter(name string, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     name,
+		"in":       "query",
+		"required": false,
+		"style":    "form",
+		"explode":  true,
+		"schema": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "string",
+			},
+		},
+		"description": description,
+	}
+}
+
+func CreateAggregateMethod(tableNames []string) map[string]interface{} {
+	return map[string]interface{}{
+		"operationId": "Aggregate",
+		"summary":     "Aggregate the rows of a table",
+		"description": "Group, filter and project the rows of a table, needs execute permission on the table",
+		"tags":        []string{"aggregate"},
+		"parameters": []map[string]interface{}{
+			typenameParameter(tableNames),
+			arrayParameter("group", "Columns to group by"),
+			arrayParameter("join", "Joins with other tables, eg user_account@eq(todo.user_account_id,user_account.id)"),
+			arrayParameter("column", "Columns and functions to project, eg count, sum(amount)"),
+			arrayParameter("filter", "Conditions on the rows, eg eq(status,done)"),
+			arrayParameter("having", "Conditions on the groups, eg gt(count,10)"),
+			arrayParameter("order", "Columns to order by, prefix with - for descending order"),
+			stringParameter("timesample", "query", "Sample the rows by a time unit"),
+			stringParameter("timefrom", "query", "Start of the time range"),
+			stringParameter("timeto", "query", "End of the time range"),
+		},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "aggregated rows",
+				"content":     jsonContent(schemaRef("AggregateResponse")),
+			},
+			"default": errorResponse,
+		},
+	}
+}
+
+func CreateTrackStartMethod(tableNames []string) map[string]interface{} {
+	return map[string]interface{}{
+		"operationId": "StartStateMachine",
+		"summary":     "Start tracking the state of a row with a state machine",
+		"tags":        []string{"state machine"},
+		"parameters": []map[string]interface{}{
+			stringParameter("stateMachineId", "path", "Reference id of the state machine description"),
+		},
+		"requestBody": map[string]interface{}{
+			"required": true,
+			"content": jsonContent(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"typeName": map[string]interface{}{
+						"type": "string",
+						"enum": tableNames,
+					},
+					"referenceId": map[string]interface{}{
+						"type":        "string",
+						"description": "Reference id of the row to track",
+					},
+				},
+				"required": []string{"typeName", "referenceId"},
+			}),
+		},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "the created state of the row",
+				"content": jsonContent(map[string]interface{}{
+					"type": "object",
+				}),
+			},
+			"default": errorResponse,
+		},
+	}
+}
+
+func CreateTrackEventMethod(tableNames []string) map[string]interface{} {
+	return map[string]interface{}{
+		"operationId": "ApplyStateMachineEvent",
+		"summary":     "Apply an event to the state of a row",
+		"tags":        []string{"state machine"},
+		"parameters": []map[string]interface{}{
+			typenameParameter(tableNames),
+			stringParameter("objectStateId", "path", "Reference id of the state of the row"),
+			stringParameter("eventName", "path", "Name of the event"),
+		},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "the event was applied",
+			},
+			"default": errorResponse,
 		},
 	}
+}
+
+func CreateAssetMethod(tableNames []string) map[string]interface{} {
+	return map[string]interface{}{
+		"operationId": "GetAsset",
+		"summary":     "Download a file from a file or markdown column",
+		"tags":        []string{"asset"},
+		"parameters": []map[string]interface{}{
+			typenameParameter(tableNames),
+			stringParameter("resourceId", "path", "Reference id of the row"),
+			stringParameter("columnName", "path", "Name of the column, with an optional extension"),
+			stringParameter("file", "query", "Name of the file when the column has more than one file"),
+		},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "contents of the file",
+				"content": map[string]interface{}{
+					"application/octet-stream": map[string]interface{}{
+						"schema": map[string]interface{}{
+							"type":   "string",
+							"format": "binary",
+						},
+					},
+				},
+			},
+			"default": errorResponse,
+		},
+	}
+}
+
+func CreateJwksMethod() map[string]interface{} {
+	return map[string]interface{}{
+		"operationId": "GetJwks",
+		"summary":     "Public keys which verify the RS256 and ES256 tokens, by kid",
+		"tags":        []string{"auth"},
+		"security":    []map[string][]string{},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "json web key set",
+				"content": jsonContent(map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"keys": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+							},
+						},
+					},
+				}),
+			},
+		},
+	}
+}
+
+func CreatePostMethod(tableInfo resource.TableInfo) map[string]interface{} {
+	postMethod := make(map[string]interface{})
+	postMethod["operationId"] = fmt.Sprintf("Create%s", strcase.ToCamel(tableInfo.TableName))
+	postMethod["summary"] = fmt.Sprintf("Create a new %v", tableInfo.TableName)
+	postMethod["tags"] = []string{tableInfo.TableName}
+	postBody := make(map[string]interface{})
+
+	postBody["description"] = tableInfo.TableName + " to create"
+	postBody["required"] = true
+	postBody["content"] = jsonContent(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"type": map[string]interface{}{
+						"type": "string",
+						"enum": []string{tableInfo.TableName},
+					},
+					"attributes": schemaRef("New" + strcase.ToCamel(tableInfo.TableName)),
+				},
+				"required": []string{"type", "attributes"},
+			},
+		},
+		"required": []string{"data"},
+	})
+	postMethod["requestBody"] = postBody
+	postResponseMap := make(map[string]interface{})
+
+	postOkResponse := make(map[string]interface{})
+	postOkResponse["description"] = "created " + tableInfo.TableName
+	postOkResponse["content"] = jsonContent(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data": schemaRef(strcase.ToCamel(tableInfo.TableName) + "Resource"),
+		},
+	})
+
+	postResponseMap["201"] = postOkResponse
+	postResponseMap["default"] = errorResponse
+	postMethod["responses"] = postResponseMap
+	return postMethod
+}
+
+func CreateGetAllMethod(tableInfo resource.TableInfo) map[string]interface{} {
+	getAllMethod := make(map[string]interface{})
+	getAllMethod["description"] = fmt.Sprintf("Returns a list of %v", ProperCase(tableInfo.TableName))
+	getAllMethod["operationId"] = fmt.Sprintf("Get" + strcase.ToCamel(tableInfo.TableName))
+	getAllMethod["summary"] = fmt.Sprintf("List all %v", tableInfo.TableName)
+	getAllMethod["tags"] = []string{tableInfo.TableName}
+	getAllMethod["parameters"] = listParameterRefs(tableInfo)
 	getResponseMap := make(map[string]interface{})
 	get200Response := make(map[string]interface{})
-	get200Response["description"] = "list of all " + tableInfo.TableName
-	get200Response["content"] = map[string]interface{}{
-		"application/json": map[string]interface{}{
-			"schema": map[string]interface{}{
+	get200Response["description"] = "list of " + tableInfo.TableName
+	get200Response["content"] = jsonContent(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data": map[string]interface{}{
+				"type":  "array",
+				"items": schemaRef(strcase.ToCamel(tableInfo.TableName) + "Resource"),
+			},
+			"links": schemaRef("PaginationStatus"),
+			"included": map[string]interface{}{
 				"type": "array",
 				"items": map[string]interface{}{
-					"$ref": "#/components/schemas/" + strcase.ToCamel(tableInfo.TableName),
+					"type": "object",
 				},
+				"description": "rows of the included relations",
 			},
 		},
-	}
+	})
 
-	getResponseMap["default"] = get200Response
+	getResponseMap["200"] = get200Response
+	getResponseMap["default"] = errorResponse
 	getAllMethod["responses"] = getResponseMap
 	return getAllMethod
 }
@@ -691,81 +1222,108 @@ func CreateDeleteMethod(tableInfo resource.TableInfo) map[string]interface{} {
 	deleteByIdResponseMap := make(map[string]interface{})
 	deleteByIdMethod200Response["description"] = "delete " + tableInfo.TableName + " by reference id"
 	deleteByIdResponseMap["200"] = deleteByIdMethod200Response
+	deleteByIdResponseMap["default"] = errorResponse
 	deleteByIdMethod["responses"] = deleteByIdResponseMap
 
 	deleteByIdMethod["description"] = fmt.Sprintf("Delete a %v", tableInfo.TableName)
+	if tableInfo.IsSoftDeleteEnabled {
+		deleteByIdMethod["description"] = fmt.Sprintf("Mark a %v as deleted, it can be restored until it is purged", tableInfo.TableName)
+	}
 
 	deleteByIdMethod["summary"] = fmt.Sprintf("Delete %v", tableInfo.TableName)
-	deleteByIdMethod["tags"] = []string{tableInfo.TableName, "delete"}
+	deleteByIdMethod["tags"] = []string{tableInfo.TableName}
 	deleteByIdMethod["parameters"] = []map[string]interface{}{
-		{
-			"name": "referenceId",
-			"schema": map[string]interface{}{
-				"type": "string",
-			},
-			"required":    true,
-			"in":          "path",
-			"description": "Reference Id of the " + tableInfo.TableName,
-		},
+		referenceIdParameter(tableInfo.TableName),
 	}
 	deleteByIdMethod["operationId"] = fmt.Sprintf("Delete%s", strcase.ToCamel(tableInfo.TableName))
 	return deleteByIdMethod
 }
 
-func CreateDeleteRelationMethod(tableInfo resource.TableInfo) map[string]interface{} {
+// CreateRelationshipMethod replaces or adds to the related rows of a relation
+func CreateRelationshipMethod(tableInfo resource.TableInfo, reference jsonapi.Reference, operation string) map[string]interface{} {
+	method := make(map[string]interface{})
+	method["operationId"] = operation + strcase.ToCamel(reference.Name) + "Of" + strcase.ToCamel(tableInfo.TableName)
+	method["summary"] = fmt.Sprintf("%s related %s of %v", operation, reference.Name, tableInfo.TableName)
+	method["tags"] = []string{tableInfo.TableName}
+	method["parameters"] = []map[string]interface{}{
+		referenceIdParameter(tableInfo.TableName),
+	}
+
+	body := schemaRef("RelationshipIdentifiers")
+	if reference.Relationship != jsonapi.ToManyRelationship {
+		body = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"data": schemaRef("RelatedStructure"),
+			},
+			"required": []string{"data"},
+		}
+	}
+	method["requestBody"] = map[string]interface{}{
+		"required": true,
+		"content":  jsonContent(body),
+	}
+	method["responses"] = map[string]interface{}{
+		"204": map[string]interface{}{
+			"description": "updated the related " + reference.Type,
+		},
+		"default": errorResponse,
+	}
+	return method
+}
+
+func CreateDeleteRelationMethod(tableInfo resource.TableInfo, reference jsonapi.Reference) map[string]interface{} {
 	deleteByIdMethod := make(map[string]interface{})
 	deleteByIdMethod200Response := make(map[string]interface{})
-	deleteByIdMethod200Response["description"] = "Successful deletion of " + tableInfo.TableName
-	deleteBody := make(map[string]interface{})
-	deleteBody["type"] = tableInfo.TableName
-	deleteByIdMethod["description"] = "Delete a " + tableInfo.TableName
+	deleteByIdMethod200Response["description"] = "Removed the related " + reference.Type
 
 	deleteByIdResponseMap := make(map[string]interface{})
-	deleteByIdResponseMap["200"] = deleteByIdMethod200Response
+	deleteByIdResponseMap["204"] = deleteByIdMethod200Response
+	deleteByIdResponseMap["default"] = errorResponse
 	deleteByIdMethod["responses"] = deleteByIdResponseMap
-	deleteByIdMethod["description"] = fmt.Sprintf("Remove a related %v ", tableInfo.TableName)
+	deleteByIdMethod["description"] = fmt.Sprintf("Remove related %v from the %v", reference.Name, tableInfo.TableName)
+	deleteByIdMethod["summary"] = fmt.Sprintf("Remove related %s of %v", reference.Name, tableInfo.TableName)
+	deleteByIdMethod["operationId"] = "Remove" + strcase.ToCamel(reference.Name) + "Of" + strcase.ToCamel(tableInfo.TableName)
 	deleteByIdMethod["tags"] = []string{tableInfo.TableName}
 	deleteByIdMethod["parameters"] = []map[string]interface{}{
-		{
-			"name": "referenceId",
-			"schema": map[string]interface{}{
-				"type": "string",
-			},
-			"required":    true,
-			"in":          "path",
-			"description": "Reference Id of the " + tableInfo.TableName,
-		},
+		referenceIdParameter(tableInfo.TableName),
+	}
+	deleteByIdMethod["requestBody"] = map[string]interface{}{
+		"required": true,
+		"content":  jsonContent(schemaRef("RelationshipIdentifiers")),
 	}
 
 	return deleteByIdMethod
 }
 
-func CreateGetMethod(tableInfo resource.TableInfo, dataInResponse map[string]interface{}) map[string]interface{} {
+func CreateGetMethod(tableInfo resource.TableInfo) map[string]interface{} {
 	getByIdMethod := make(map[string]interface{})
 	getByIdMethod200Response := make(map[string]interface{})
 	getByIdMethod["tags"] = []string{tableInfo.TableName}
 	getByIdMethod200Response["description"] = "get " + tableInfo.TableName + " by reference id"
-	getByIdMethod200Response["content"] = map[string]interface{}{
-		"application/json": map[string]interface{}{
-			"schema": map[string]interface{}{
-				"$ref": "#/components/schemas/" + strcase.ToCamel(tableInfo.TableName),
+	getByIdMethod200Response["content"] = jsonContent(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data": schemaRef(strcase.ToCamel(tableInfo.TableName) + "Resource"),
+			"included": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+				},
+				"description": "rows of the included relations",
 			},
 		},
-	}
+	})
 
 	getByIdResponseMap := make(map[string]interface{})
 	getByIdResponseMap["200"] = getByIdMethod200Response
+	getByIdResponseMap["default"] = errorResponse
 	getByIdMethod["responses"] = getByIdResponseMap
 
 	getByIdMethod["parameters"] = []map[string]interface{}{
+		referenceIdParameter(tableInfo.TableName),
 		{
-			"name": "referenceId",
-			"schema": map[string]interface{}{
-				"type": "string",
-			},
-			"required":    true,
-			"in":          "path",
-			"description": "Reference Id of the " + tableInfo.TableName,
+			"$ref": "#/components/parameters/includedRelations",
 		},
 	}
 
@@ -779,94 +1337,49 @@ func CreatePatchMethod(tableInfo resource.TableInfo) map[string]interface{} {
 	patchMethod := make(map[string]interface{})
 	patchMethod["operationId"] = fmt.Sprintf("Update%s", strcase.ToCamel(tableInfo.TableName))
 	patchMethod["summary"] = fmt.Sprintf("Update existing %v", tableInfo.TableName)
-	patchMethod["description"] = fmt.Sprintf("Edit an existing %s", tableInfo.TableName)
+	patchMethod["description"] = fmt.Sprintf("Edit an existing %s, only the attributes in the request are changed", tableInfo.TableName)
 	patchMethod["tags"] = []string{tableInfo.TableName}
-	patchBody := make(map[string]interface{})
-	patchBody["type"] = tableInfo.TableName
 	patchResponseMap := make(map[string]interface{})
 	patchOkResponse := make(map[string]interface{})
-	patchResponseBody := make(map[string]interface{})
-	patchResponseBody["type"] = "object"
-	patchRelationshipMap := make(map[string]interface{}, 0)
 
 	patchMethod["requestBody"] = map[string]interface{}{
-		"content": map[string]interface{}{
-			"application/json": map[string]interface{}{
-				"schema": map[string]interface{}{
+		"required": true,
+		"content": jsonContent(map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"data": map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
-						"data": map[string]interface{}{
-							"type": "object",
-							"properties": map[string]interface{}{
-								"attributes": map[string]interface{}{
-									"$ref": "#/components/schemas/" + strcase.ToCamel(tableInfo.TableName),
-								},
-								"id": map[string]interface{}{
-									"type": "string",
-								},
-							},
+						"type": map[string]interface{}{
+							"type": "string",
+							"enum": []string{tableInfo.TableName},
+						},
+						"attributes": map[string]interface{}{
+							"type":        "object",
+							"description": "attributes to change, the same as " + "New" + strcase.ToCamel(tableInfo.TableName),
+						},
+						"id": map[string]interface{}{
+							"type": "string",
 						},
 					},
+					"required": []string{"attributes"},
 				},
 			},
-		},
+			"required": []string{"data"},
+		}),
 	}
 
-	for _, relation := range tableInfo.Relations {
-		if relation.Object == tableInfo.TableName {
-			patchRelationshipMap[relation.SubjectName] = map[string]interface{}{
-				"$ref": "#/components/schemas/IncludedRelationship",
-			}
-		} else {
-			patchRelationshipMap[relation.ObjectName] = map[string]interface{}{
-				"$ref": "#/components/schemas/IncludedRelationship",
-			}
-		}
-	}
-	var patchDataInResponse = map[string]interface{}{
+	patchOkResponse["content"] = jsonContent(map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
-			"attributes": map[string]interface{}{
-				"$ref": "#/components/schemas/" + strcase.ToCamel(tableInfo.TableName),
-			},
-			"id": map[string]interface{}{
-				"type": "string",
-			},
-			"type": map[string]interface{}{
-				"type": "string",
-			},
-			"relationships": map[string]interface{}{
-				"type":       "object",
-				"properties": patchRelationshipMap,
-			},
-		},
-	}
-	patchResponseBody = map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"data": patchDataInResponse,
-			"links": map[string]interface{}{
-				"$ref": "#/components/schemas/PaginationStatus",
-			},
+			"data": schemaRef(strcase.ToCamel(tableInfo.TableName) + "Resource"),
 		},
-	}
-	patchOkResponse["content"] = map[string]interface{}{
-		"application/json": map[string]interface{}{
-			"schema": patchResponseBody,
-		},
-	}
+	})
 	patchOkResponse["description"] = "updated " + tableInfo.TableName
 	patchResponseMap["200"] = patchOkResponse
+	patchResponseMap["default"] = errorResponse
 	patchMethod["parameters"] = []map[string]interface{}{
-		{
-			"name": "referenceId",
-			"schema": map[string]interface{}{
-				"type": "string",
-			},
-			"required":    true,
-			"in":          "path",
-			"description": "Reference Id of the " + tableInfo.TableName,
-		},
+		referenceIdParameter(tableInfo.TableName),
 	}
 	patchMethod["responses"] = patchResponseMap
 	return patchMethod
@@ -0,0 +1,106 @@
+package apiblueprint
+
+import (
+	"context"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/getkin/kin-openapi/openapi3"
+	"testing"
+)
+
+func TestBuildApiBlueprint(t *testing.T) {
+
+	resource.InitialiseColumnManager()
+
+	tables := []resource.TableInfo{
+		{
+			TableName: "todo",
+			Columns: []api2go.ColumnInfo{
+				{ColumnName: "title", ColumnType: "label"},
+				{ColumnName: "priority", ColumnType: "measurement", IsNullable: true},
+				{ColumnName: "completed", ColumnType: "truefalse", DefaultValue: "false"},
+				{ColumnName: "attachment", ColumnType: "file.*", IsNullable: true, IsForeignKey: true,
+					ForeignKeyData: api2go.ForeignKeyData{DataSource: "cloud_store", Namespace: "local", KeyName: "todo"}},
+			},
+			VirtualColumns: []resource.VirtualColumn{
+				{ColumnName: "urgency", ColumnType: "measurement", Expression: "priority * 10"},
+			},
+			Relations: []api2go.TableRelation{
+				api2go.NewTableRelation("todo", "belongs_to", "project"),
+			},
+		},
+		{
+			TableName: "project",
+			Columns: []api2go.ColumnInfo{
+				{ColumnName: "name", ColumnType: "label"},
+			},
+			Relations: []api2go.TableRelation{
+				api2go.NewTableRelation("todo", "belongs_to", "project"),
+			},
+		},
+	}
+	for _, table := range resource.StandardTables {
+		tables = append(tables, table)
+	}
+
+	config := &resource.CmsConfig{
+		Hostname: "localhost:6336",
+		Tables:   tables,
+		Actions:  resource.SystemActions,
+	}
+
+	spec := BuildApiBlueprint(config, nil, nil)
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatalf("failed to load the generated spec: %v", err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		t.Fatalf("generated spec is not valid: %v", err)
+	}
+
+	for _, path := range []string{"/api/todo", "/api/todo/{referenceId}", "/api/todo/{referenceId}/project_id",
+		"/api/project/{referenceId}/todo_id", "/aggregate/{typename}", "/track/start/{stateMachineId}",
+		"/asset/{typename}/{resourceId}/{columnName}", "/action/user_account/signin", "/.well-known/jwks.json"} {
+		if doc.Paths.Find(path) == nil {
+			t.Errorf("expected path [%v] in the spec", path)
+		}
+	}
+
+	operationIds := make(map[string]string)
+	for path, pathItem := range doc.Paths {
+		for method, operation := range pathItem.Operations() {
+			if other, ok := operationIds[operation.OperationID]; ok {
+				t.Errorf("operation id [%v] of %v %v is also used by %v", operation.OperationID, method, path, other)
+			}
+			operationIds[operation.OperationID] = method + " " + path
+		}
+	}
+
+	for name, schema := range doc.Components.Schemas {
+		if schema.Value.Example == nil {
+			continue
+		}
+		if err = schema.Value.VisitJSON(schema.Value.Example); err != nil {
+			t.Errorf("example of [%v] does not match the schema: %v", name, err)
+		}
+	}
+
+	todo := doc.Components.Schemas["Todo"].Value
+	if todo.Properties["attachment"] == nil || todo.Properties["attachment"].Value.Type != "array" {
+		t.Errorf("expected the file column as an array of files")
+	}
+	if urgency := todo.Properties["urgency"]; urgency == nil || !urgency.Value.ReadOnly {
+		t.Errorf("expected the virtual column as a read only property")
+	}
+	if doc.Components.Schemas["NewTodo"].Value.Properties["urgency"] != nil {
+		t.Errorf("expected no virtual column in the create schema")
+	}
+	if title, ok := todo.Example.(map[string]interface{})["title"].(string); !ok || title == "" {
+		t.Errorf("expected a generated example of todo, got %v", todo.Example)
+	}
+	if doc.Components.SecuritySchemes["basicAuth"] == nil || doc.Components.SecuritySchemes["bearerAuth"] == nil ||
+		doc.Components.SecuritySchemes["apiKeyAuth"] == nil {
+		t.Errorf("expected the basic, bearer and api key security schemes")
+	}
+}
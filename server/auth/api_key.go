@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/golang-jwt/jwt/v4"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// ApiKeyHeader is the request header carrying an api key
+const ApiKeyHeader = "X-Api-Key"
+
+// ApiKeyPrefix starts every generated api key so that leaked keys are easy to recognise
+const ApiKeyPrefix = "dak_"
+
+// last_used_at is updated at most once in this interval
+const apiKeyLastUsedInterval = time.Minute
+
+// looked up keys are kept in the auth cache this long, changed and deleted keys are dropped from it
+const apiKeyCacheDuration = time.Minute
+
+// CachedApiKey is a looked up api key with the owner, kept in the auth cache by the hash of the key
+type CachedApiKey struct {
+	Id         int64
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	Scopes     string
+	Email      string
+	Name       string
+}
+
+func init() {
+	// other nodes of the cluster decode cached keys they never encoded
+	gob.Register(CachedApiKey{})
+}
+
+func apiKeyCacheKey(keyHash string) string {
+	return "api_key." + keyHash
+}
+
+// InvalidateCachedApiKey drops a changed or deleted key from the cache, so it is looked up again
+func InvalidateCachedApiKey(keyHash string) {
+	if olricCache == nil || keyHash == "" {
+		return
+	}
+	err := olricCache.Delete(apiKeyCacheKey(keyHash))
+	CheckErr(err, "Failed to invalidate cached api key")
+}
+
+// ApiKeyScope restricts what a request authenticated by an api key can do, on top of the permissions of
+// the user owning the key. Tables and Operations (read, create, update, delete) limit the table api and
+// Actions limit the actions, as action name or as type.action. An empty list allows nothing, "*" allows all.
+type ApiKeyScope struct {
+	Tables     []string `json:"tables"`
+	Operations []string `json:"operations"`
+	Actions    []string `json:"actions"`
+}
+
+// ParseApiKeyScope reads the json scopes of a key, a key without scopes is not restricted
+func ParseApiKeyScope(value string) (*ApiKeyScope, error) {
+	if strings.TrimSpace(value) == "" || strings.TrimSpace(value) == "null" {
+		return nil, nil
+	}
+	var scope ApiKeyScope
+	err := json.Unmarshal([]byte(value), &scope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api key scopes: %v", err)
+	}
+	for _, operation := range scope.Operations {
+		if operation != "*" && operation != "read" && operation != "create" && operation != "update" && operation != "delete" {
+			return nil, fmt.Errorf("unknown operation [%v] in api key scopes", operation)
+		}
+	}
+	return &scope, nil
+}
+
+// OperationForMethod is the scope operation of a request method
+func OperationForMethod(method string) string {
+	switch method {
+	case "GET":
+		return "read"
+	case "POST":
+		return "create"
+	case "PUT", "PATCH":
+		return "update"
+	case "DELETE":
+		return "delete"
+	}
+	return strings.ToLower(method)
+}
+
+func scopeListContains(list []string, values ...string) bool {
+	for _, item := range list {
+		if item == "*" {
+			return true
+		}
+		for _, value := range values {
+			if item == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowsTable is true when the scope allows the request method on the table, a nil scope allows everything
+func (s *ApiKeyScope) AllowsTable(tableName string, method string) bool {
+	if s == nil {
+		return true
+	}
+	return scopeListContains(s.Tables, tableName) && scopeListContains(s.Operations, OperationForMethod(method))
+}
+
+// AllowsAction is true when the scope allows the action on the type, a nil scope allows everything
+func (s *ApiKeyScope) AllowsAction(onType string, actionName string) bool {
+	if s == nil {
+		return true
+	}
+	return scopeListContains(s.Actions, actionName, onType+"."+actionName)
+}
+
+// GenerateApiKey returns a new random api key, only its hash is stored
+func GenerateApiKey() (string, error) {
+	bytes := make([]byte, 24)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return ApiKeyPrefix + hex.EncodeToString(bytes), nil
+}
+
+// HashApiKey is the stored form of an api key, keys are random so a plain sha256 is enough
+func HashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// ParseStoredTime reads a timestamp column, drivers return either a time or its text
+func ParseStoredTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case []byte:
+		value = string(v)
+	}
+	text, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05"} {
+		t, err := time.Parse(layout, text)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// lookupApiKey reads the key with the hash and its owner, from the cache when it was looked up recently
+func (a *AuthMiddleware) lookupApiKey(keyHash string) (*CachedApiKey, error) {
+
+	if olricCache != nil {
+		if cached, err := olricCache.Get(apiKeyCacheKey(keyHash)); err == nil && cached != nil {
+			if apiKey, ok := cached.(CachedApiKey); ok {
+				return &apiKey, nil
+			}
+		}
+	}
+
+	query, args, err := statementbuilder.Squirrel.Select(
+		goqu.I("k.id"), goqu.I("k.expires_at"), goqu.I("k.last_used_at"), goqu.I("k.scopes"),
+		goqu.I("u.email"), goqu.I("u.name")).
+		From(goqu.T("api_key").As("k")).
+		Join(goqu.T("user_account").As("u"), goqu.On(goqu.Ex{
+			"u.id": goqu.I("k.user_account_id"),
+		})).
+		Where(goqu.Ex{"k.key_hash": keyHash}).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var apiKey CachedApiKey
+	var expiresAt, lastUsedAt interface{}
+	var scopes sql.NullString
+	err = a.db.QueryRowx(query, args...).Scan(&apiKey.Id, &expiresAt, &lastUsedAt, &scopes, &apiKey.Email, &apiKey.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown api key")
+	}
+	apiKey.ExpiresAt, _ = ParseStoredTime(expiresAt)
+	apiKey.LastUsedAt, _ = ParseStoredTime(lastUsedAt)
+	apiKey.Scopes = scopes.String
+
+	if olricCache != nil {
+		err = olricCache.PutEx(apiKeyCacheKey(keyHash), apiKey, apiKeyCacheDuration)
+		CheckErr(err, "Failed to cache api key [%v]", apiKey.Id)
+	}
+	return &apiKey, nil
+}
+
+// ApiKeyCheck finds the owner of an api key, the returned token has the claims of the owner as if they had
+// signed in. Expired and unknown keys are an error.
+func (a *AuthMiddleware) ApiKeyCheck(key string) (*jwt.Token, *ApiKeyScope, error) {
+
+	keyHash := HashApiKey(key)
+	apiKey, err := a.lookupApiKey(keyHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if !apiKey.ExpiresAt.IsZero() && now.After(apiKey.ExpiresAt) {
+		return nil, nil, fmt.Errorf("api key has expired")
+	}
+
+	scope, err := ParseApiKeyScope(apiKey.Scopes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if now.Sub(apiKey.LastUsedAt) > apiKeyLastUsedInterval {
+		updateQuery, updateArgs, err := statementbuilder.Squirrel.Update("api_key").
+			Set(goqu.Record{"last_used_at": now}).
+			Where(goqu.Ex{"id": apiKey.Id}).ToSQL()
+		if err == nil {
+			_, err = a.db.Exec(updateQuery, updateArgs...)
+		}
+		if err != nil {
+			log.Errorf("Failed to update last use of api key [%v]: %v", apiKey.Id, err)
+		}
+		// the cached key still has the previous use
+		InvalidateCachedApiKey(keyHash)
+	}
+
+	return &jwt.Token{
+		Claims: jwt.MapClaims{
+			"name":  apiKey.Name,
+			"email": apiKey.Email,
+			"sub":   apiKey.Email,
+		},
+	}, scope, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiKeyScope(t *testing.T) {
+
+	scope, err := ParseApiKeyScope(`{"tables": ["todo"], "operations": ["read", "create"], "actions": ["todo.mark_done", "export_data"]}`)
+	if err != nil {
+		t.Fatalf("failed to parse scope: %v", err)
+	}
+
+	if !scope.AllowsTable("todo", "GET") || !scope.AllowsTable("todo", "POST") {
+		t.Errorf("expected read and create on todo")
+	}
+	if scope.AllowsTable("todo", "DELETE") || scope.AllowsTable("project", "GET") {
+		t.Errorf("expected no delete on todo and no access to project")
+	}
+	if !scope.AllowsAction("todo", "mark_done") || !scope.AllowsAction("world", "export_data") || scope.AllowsAction("world", "restart_daptin") {
+		t.Errorf("unexpected action scope")
+	}
+
+	var unrestricted *ApiKeyScope
+	if !unrestricted.AllowsTable("project", "DELETE") || !unrestricted.AllowsAction("world", "restart_daptin") {
+		t.Errorf("expected a key without scopes to be unrestricted")
+	}
+
+	all, _ := ParseApiKeyScope(`{"tables": ["*"], "operations": ["*"]}`)
+	if !all.AllowsTable("project", "PATCH") || all.AllowsAction("world", "restart_daptin") {
+		t.Errorf("expected all tables and no actions")
+	}
+
+	if _, err = ParseApiKeyScope(`{"operations": ["truncate"]}`); err == nil {
+		t.Errorf("expected an error for an unknown operation")
+	}
+	if scope, err = ParseApiKeyScope(""); scope != nil || err != nil {
+		t.Errorf("expected no scope for an empty value")
+	}
+}
+
+func TestApiKeyCheck(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.MustExec("create table user_account (id integer primary key, email varchar(100), name varchar(100))")
+	db.MustExec("create table api_key (id integer primary key, user_account_id int, key_hash varchar(64), scopes text, expires_at timestamp, last_used_at timestamp)")
+	db.MustExec("insert into user_account (email, name) values ('ci@example.com', 'ci')")
+
+	key, err := GenerateApiKey()
+	if err != nil || !strings.HasPrefix(key, ApiKeyPrefix) {
+		t.Fatalf("failed to generate key: %v %v", key, err)
+	}
+	expiredKey, _ := GenerateApiKey()
+	db.MustExec("insert into api_key (user_account_id, key_hash, scopes) values (1, ?, ?)", HashApiKey(key), `{"tables": ["todo"], "operations": ["read"]}`)
+	db.MustExec("insert into api_key (user_account_id, key_hash, expires_at) values (1, ?, ?)", HashApiKey(expiredKey), time.Now().Add(-time.Hour))
+
+	a := &AuthMiddleware{db: db}
+
+	token, scope, err := a.ApiKeyCheck(key)
+	if err != nil {
+		t.Fatalf("failed to check key: %v", err)
+	}
+	if token.Claims.(jwt.MapClaims)["email"] != "ci@example.com" || scope == nil || !scope.AllowsTable("todo", "GET") {
+		t.Errorf("unexpected token %v and scope %v", token.Claims, scope)
+	}
+	var lastUsed int
+	if err = db.Get(&lastUsed, "select count(*) from api_key where last_used_at is not null"); err != nil || lastUsed != 1 {
+		t.Errorf("expected the last use to be recorded, got %v %v", lastUsed, err)
+	}
+
+	if _, _, err = a.ApiKeyCheck(expiredKey); err == nil {
+		t.Errorf("expected an error for an expired key")
+	}
+	if _, _, err = a.ApiKeyCheck(ApiKeyPrefix + "unknown"); err == nil {
+		t.Errorf("expected an error for an unknown key")
+	}
+}
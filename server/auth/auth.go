@@ -66,6 +66,7 @@ func (a AuthPermission) String() string {
 type ResourceAdapter interface {
 	api2go.CRUD
 	GetUserPassword(email string) (string, error)
+	IsTwoFactorEnabled(email string) (bool, error)
 }
 
 type AuthMiddleware struct {
@@ -103,20 +104,20 @@ func InitJwtMiddleware(secret []byte, issuer string, db *olric.Olric) {
 	if jwtmiddleware.TokenCache == nil {
 		jwtmiddleware.TokenCache, _ = db.NewDMap("token-cache")
 	}
+	if jwtmiddleware.RevokedTokens == nil {
+		jwtmiddleware.RevokedTokens, _ = db.NewDMap("revoked-tokens")
+	}
 	jwtMiddleware = jwtmiddleware.New(jwtmiddleware.Options{
-		ValidationKeyGetter: func(token *jwt.Token) (interface{}, error) {
-			return secret, nil
-		},
-		Issuer: issuer,
+		// HS256 tokens are verified with the secret while it is accepted, RS256 and ES256 tokens with the signing key of their kid
+		ValidationKeyGetter: jwtmiddleware.SigningKeys.KeyFunc(secret),
+		Issuer:              issuer,
 		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err string) {
 			//log.Printf("Guest request [%v]: %v", err, r.Header)
 		},
 		Debug: false,
-		// When set, the middleware verifies that tokens are signed with the specific signing algorithm
-		// If the signing method is not constant the ValidationKeyGetter callback can be used to implement additional checks
+		// The signing method is not constant, ValidationKeyGetter only returns a key for the algorithm of the key
 		// Important to avoid security issues described here: https://auth0.com/blog/2015/03/31/critical-vulnerabilities-in-json-web-token-libraries/
-		SigningMethod: jwt.SigningMethodHS256,
-		UserProperty:  "user",
+		UserProperty: "user",
 		Extractor: jwtmiddleware.FromFirst(
 			jwtmiddleware.FromAuthHeader,
 			jwtmiddleware.FromParameter("token"),
@@ -169,6 +170,11 @@ func (a *AuthMiddleware) BasicAuthCheckMiddlewareWithHttp(req *http.Request, wri
 	}
 
 	if BcryptCheckStringHash(password, existingPasswordHash) {
+		// basic auth cannot carry the second factor, users who enabled it sign in for a token instead
+		twoFactorEnabled, err := a.userCrud.IsTwoFactorEnabled(username)
+		if err != nil || twoFactorEnabled {
+			return nil, fmt.Errorf("basic auth is not allowed for [%v] with two factor authentication", username)
+		}
 		token = &jwt.Token{
 			Claims: jwt.MapClaims{
 				"name":  strings.Split(username, "@")[0],
@@ -249,9 +255,18 @@ func (a *AuthMiddleware) AuthCheckMiddlewareWithHttp(req *http.Request, writer h
 
 	hasUser := false
 
-	userJwtToken, err := jwtMiddleware.CheckJWT(writer, req)
+	var userJwtToken *jwt.Token
+	var apiKeyScope *ApiKeyScope
+	var err error
 
-	if err != nil {
+	if apiKey := req.Header.Get(ApiKeyHeader); apiKey != "" {
+		userJwtToken, apiKeyScope, err = a.ApiKeyCheck(apiKey)
+		if err != nil {
+			log.Warnf("api key auth check failed: %v", err)
+			return false, false, req
+		}
+		hasUser = true
+	} else if userJwtToken, err = jwtMiddleware.CheckJWT(writer, req); err != nil {
 		//log.Warnf("failed to identify user in auth middleware: %v", err)
 		if doBasicAuthCheck {
 			userJwtToken, err = a.BasicAuthCheckMiddlewareWithHttp(req, writer)
@@ -423,6 +438,7 @@ func (a *AuthMiddleware) AuthCheckMiddlewareWithHttp(req *http.Request, writer h
 					}
 
 					//log.Printf("Group permissions :%v", userGroups)
+					userGroups = ExpandUserGroups(userGroups, a.userGroupParents())
 
 					sessionUser = &SessionUser{
 						UserId:          userId,
@@ -464,6 +480,22 @@ func (a *AuthMiddleware) AuthCheckMiddlewareWithHttp(req *http.Request, writer h
 
 			//log.Tracef("User cache map size: %v", len(LocalUserCacheMap))
 
+			sessionId, _ := userToken.Claims.(jwt.MapClaims)["sid"].(string)
+			impersonator := ImpersonatorFromClaims(userToken.Claims.(jwt.MapClaims))
+			if apiKeyScope != nil || sessionId != "" || impersonator != nil {
+				scopedUser := *sessionUser
+				scopedUser.ApiKeyScope = apiKeyScope
+				scopedUser.SessionReferenceId = sessionId
+				scopedUser.Impersonator = impersonator
+				sessionUser = &scopedUser
+			}
+			if impersonator != nil {
+				log.WithFields(log.Fields{
+					"impersonator": impersonator.Email,
+					"user":         email,
+				}).Infof("[impersonation] %v acting as %v: %v %v", impersonator.Email, email, req.Method, req.RequestURI)
+			}
+
 			ct := req.Context()
 			ct = context.WithValue(ct, "user", sessionUser)
 			newRequest := req.WithContext(ct)
@@ -493,6 +525,12 @@ type SessionUser struct {
 	UserId          int64
 	UserReferenceId string
 	Groups          []GroupPermission
+	// set when the request is authenticated by an api key with scopes
+	ApiKeyScope *ApiKeyScope
+	// reference id of the user_session the access token was issued for
+	SessionReferenceId string
+	// set when an administrator is acting as the user with an impersonation token
+	Impersonator *Impersonator
 }
 
 type GroupPermission struct {
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ImpersonatorClaim is the claim of an impersonation token naming the administrator acting as the user,
+// following the actor claim of rfc 8693
+const ImpersonatorClaim = "act"
+
+// Impersonator is the administrator who is acting as the user of the request
+type Impersonator struct {
+	UserReferenceId string
+	Email           string
+}
+
+// ImpersonatorFromClaims returns the administrator of an impersonation token, nil for other tokens
+func ImpersonatorFromClaims(claims jwt.MapClaims) *Impersonator {
+	actor, ok := claims[ImpersonatorClaim].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	referenceId, _ := actor["sub"].(string)
+	email, _ := actor["email"].(string)
+	if referenceId == "" {
+		return nil
+	}
+	return &Impersonator{
+		UserReferenceId: referenceId,
+		Email:           email,
+	}
+}
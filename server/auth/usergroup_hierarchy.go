@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// ParentUserGroupColumn is the column of usergroup referring to its parent group
+const ParentUserGroupColumn = "parent_usergroup_id"
+
+const userGroupParentsCacheKey = "usergroup-parents"
+const userGroupParentsCacheTtl = 5 * time.Minute
+
+// LoadUserGroupParents returns the reference id of the parent of every usergroup which has one
+func LoadUserGroupParents(db sqlx.Queryer) (map[string]string, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select(goqu.I("g.reference_id"), goqu.I("p.reference_id")).
+		From(goqu.T("usergroup").As("g")).
+		Join(goqu.T("usergroup").As("p"), goqu.On(goqu.Ex{"g." + ParentUserGroupColumn: goqu.I("p.id")})).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parents := make(map[string]string)
+	for rows.Next() {
+		var groupReferenceId, parentReferenceId string
+		if err = rows.Scan(&groupReferenceId, &parentReferenceId); err != nil {
+			return nil, err
+		}
+		parents[groupReferenceId] = parentReferenceId
+	}
+	return parents, nil
+}
+
+// userGroupParents are the parents of the usergroups, cached in the auth cache
+func (a *AuthMiddleware) userGroupParents() map[string]string {
+	if olricCache != nil {
+		cached, err := olricCache.Get(userGroupParentsCacheKey)
+		if err == nil && cached != nil {
+			if parents, ok := cached.(map[string]string); ok {
+				return parents
+			}
+		}
+	}
+
+	parents, err := LoadUserGroupParents(a.db)
+	if err != nil {
+		log.Errorf("Failed to load usergroup parents: %v", err)
+		return map[string]string{}
+	}
+	if olricCache != nil {
+		err = olricCache.PutIfEx(userGroupParentsCacheKey, parents, userGroupParentsCacheTtl, olric.IfNotFound)
+		CheckErr(err, "Failed to cache usergroup parents")
+	}
+	return parents
+}
+
+// InvalidateUserGroupParents drops the cached usergroup parents after a group is moved
+func InvalidateUserGroupParents() {
+	if olricCache == nil {
+		return
+	}
+	err := olricCache.Delete(userGroupParentsCacheKey)
+	CheckErr(err, "Failed to invalidate usergroup parents")
+}
+
+// UserGroupDescendants returns the groups below the group, whose members inherit its permissions
+func UserGroupDescendants(groupReferenceId string, parents map[string]string) []string {
+	descendants := make([]string, 0)
+	for group := range parents {
+		for _, ancestor := range UserGroupAncestors(group, parents) {
+			if ancestor == groupReferenceId {
+				descendants = append(descendants, group)
+				break
+			}
+		}
+	}
+	return descendants
+}
+
+// UserGroupMemberEmails returns the emails of the users who are members of any of the groups
+func UserGroupMemberEmails(db sqlx.Queryer, groupReferenceIds []string) ([]string, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select(goqu.I("u.email")).Distinct().
+		From(goqu.T("user_account").As("u")).
+		Join(goqu.T("user_account_user_account_id_has_usergroup_usergroup_id").As("uug"), goqu.On(goqu.Ex{"uug.user_account_id": goqu.I("u.id")})).
+		Join(goqu.T("usergroup").As("g"), goqu.On(goqu.Ex{"uug.usergroup_id": goqu.I("g.id")})).
+		Where(goqu.Ex{"g.reference_id": groupReferenceIds}).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := make([]string, 0)
+	for rows.Next() {
+		var email string
+		if err = rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// InvalidateCachedUsers drops the cached users of the emails, so that their groups are loaded again on their
+// next request
+func InvalidateCachedUsers(emails []string) {
+	if olricCache == nil {
+		return
+	}
+	for _, email := range emails {
+		err := olricCache.Delete(email)
+		CheckErr(err, "Failed to invalidate cached user [%v]", email)
+	}
+}
+
+// UserGroupAncestors returns the ancestors of the group, nearest first. A cycle in the parents stops the walk
+// where it would visit a group again.
+func UserGroupAncestors(groupReferenceId string, parents map[string]string) []string {
+	ancestors := make([]string, 0)
+	visited := map[string]bool{groupReferenceId: true}
+	for parent, ok := parents[groupReferenceId]; ok; parent, ok = parents[parent] {
+		if visited[parent] {
+			log.Warnf("Usergroup [%v] has a cycle in its parents at [%v]", groupReferenceId, parent)
+			break
+		}
+		visited[parent] = true
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// ExpandUserGroups adds the ancestors of the groups of a user, so that permissions granted to a parent group
+// apply to the members of its descendant groups. An inherited group carries the membership it was inherited
+// through.
+func ExpandUserGroups(groups []GroupPermission, parents map[string]string) []GroupPermission {
+	if len(parents) == 0 {
+		return groups
+	}
+
+	included := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		included[group.GroupReferenceId] = true
+	}
+
+	expanded := groups
+	for _, group := range groups {
+		for _, ancestor := range UserGroupAncestors(group.GroupReferenceId, parents) {
+			if included[ancestor] {
+				continue
+			}
+			included[ancestor] = true
+			inherited := group
+			inherited.GroupReferenceId = ancestor
+			expanded = append(expanded, inherited)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,38 @@
+package auth
+
+import "testing"
+
+func TestExpandUserGroups(t *testing.T) {
+
+	parents := map[string]string{
+		"team":       "department",
+		"department": "company",
+	}
+	groups := []GroupPermission{{GroupReferenceId: "team", ObjectReferenceId: "membership", Permission: UserRead}}
+
+	expanded := ExpandUserGroups(groups, parents)
+	if len(expanded) != 3 || expanded[1].GroupReferenceId != "department" || expanded[2].GroupReferenceId != "company" {
+		t.Fatalf("expected the team, department and company groups, got %v", expanded)
+	}
+	if expanded[2].ObjectReferenceId != "membership" || expanded[2].Permission != UserRead {
+		t.Errorf("expected an inherited group to carry the membership it was inherited through, got %v", expanded[2])
+	}
+
+	// a direct membership is not duplicated by an inherited one
+	expanded = ExpandUserGroups(append(groups, GroupPermission{GroupReferenceId: "company"}), parents)
+	if len(expanded) != 3 {
+		t.Errorf("expected three groups, got %v", expanded)
+	}
+
+	if descendants := UserGroupDescendants("department", parents); len(descendants) != 1 || descendants[0] != "team" {
+		t.Errorf("expected the team below the department, got %v", descendants)
+	}
+
+	cycle := map[string]string{"a": "b", "b": "c", "c": "a"}
+	if ancestors := UserGroupAncestors("a", cycle); len(ancestors) != 2 || ancestors[0] != "b" || ancestors[1] != "c" {
+		t.Errorf("expected the walk to stop at the cycle, got %v", ancestors)
+	}
+	if expanded = ExpandUserGroups([]GroupPermission{{GroupReferenceId: "a"}}, cycle); len(expanded) != 3 {
+		t.Errorf("expected a, b and c, got %v", expanded)
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+)
+
+const maxChangeLogPageSize = 1000
+
+// CreateChangeLogHandler serves the change log to administrators
+// offset is the last sequence the consumer has processed, entries after it are returned in order
+// the returned next_offset is to be sent as the offset of the next request
+func CreateChangeLogHandler(cruds map[string]*resource.DbResource) func(*gin.Context) {
+
+	return func(c *gin.Context) {
+
+		user := c.Request.Context().Value("user")
+		if user == nil {
+			c.AbortWithStatus(401)
+			return
+		}
+		sessionUser := user.(*auth.SessionUser)
+		if !cruds["world"].IsAdmin(sessionUser.UserReferenceId) {
+			c.AbortWithStatus(403)
+			return
+		}
+
+		offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+		if err != nil || offset < 0 {
+			c.AbortWithStatusJSON(400, gin.H{"error": "invalid offset"})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit < 1 {
+			c.AbortWithStatusJSON(400, gin.H{"error": "invalid limit"})
+			return
+		}
+		if limit > maxChangeLogPageSize {
+			limit = maxChangeLogPageSize
+		}
+
+		tableNames := make([]string, 0)
+		tableParam := c.Query("table")
+		if len(tableParam) > 0 {
+			tableNames = strings.Split(tableParam, ",")
+		}
+
+		// keys with scopes only read the changes of the tables they can read, and have to name them
+		if sessionUser.ApiKeyScope != nil {
+			if len(tableNames) == 0 {
+				c.AbortWithStatusJSON(403, gin.H{"error": "api keys with scopes have to name the tables"})
+				return
+			}
+			for _, tableName := range tableNames {
+				if !sessionUser.ApiKeyScope.AllowsTable(tableName, "GET") {
+					c.AbortWithStatusJSON(403, gin.H{"error": "api key cannot read [" + tableName + "]"})
+					return
+				}
+			}
+		}
+
+		entries, err := cruds[resource.ChangeLogTableName].GetChangeLogEntries(offset, limit, tableNames)
+		if err != nil {
+			resource.CheckErr(err, "Failed to read change log")
+			c.AbortWithStatusJSON(500, gin.H{"error": "failed to read change log"})
+			return
+		}
+
+		nextOffset := offset
+		if len(entries) > 0 {
+			nextOffset = entries[len(entries)-1].Sequence
+		}
+
+		c.JSON(200, gin.H{
+			"data":        entries,
+			"next_offset": nextOffset,
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/graphql-go/graphql"
+	"github.com/iancoleman/strcase"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// kinds of fields, each generator maps them to its own types
+const (
+	KindString   = "string"
+	KindInt      = "int"
+	KindFloat    = "float"
+	KindBoolean  = "boolean"
+	KindDateTime = "datetime"
+	KindFile     = "file"
+)
+
+// Field is a column of a table or an in field of an action
+type Field struct {
+	Name        string
+	ColumnType  string
+	Kind        string
+	Nullable    bool
+	Required    bool
+	Description string
+	// virtual columns are returned but cannot be written
+	ReadOnly bool
+}
+
+// inputFields leaves out the read only fields
+func inputFields(fields []Field) []Field {
+	inputs := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		if !field.ReadOnly {
+			inputs = append(inputs, field)
+		}
+	}
+	return inputs
+}
+
+// Relation is a relationship of a table, the name is the path of the related endpoint. IsList is the
+// same as the to-many references of api2go
+type Relation struct {
+	Name   string
+	Target string
+	IsList bool
+}
+
+type Type struct {
+	TableName string
+	TypeName  string
+	Fields    []Field
+	Relations []Relation
+}
+
+// ResponseTypes are the client.* responses the outcomes of the action return, the other
+// responses are returned as generic action data
+type ActionType struct {
+	Name             string
+	Label            string
+	OnType           string
+	TypeName         string
+	InstanceOptional bool
+	Fields           []Field
+	ResponseTypes    []string
+}
+
+// Schema is the language independent description of the tables, relations and actions
+type Schema struct {
+	Hostname string
+	Types    []Type
+	Actions  []ActionType
+}
+
+// ActionResponseTypeNames names the typed action responses, the same names as the graphql ActionResult union
+var ActionResponseTypeNames = map[string]string{
+	"client.notify":        "ClientNotify",
+	"client.redirect":      "ClientRedirect",
+	"client.file.download": "ClientFileDownload",
+	"client.store.set":     "ClientStoreSet",
+	"client.cookie.set":    "ClientCookieSet",
+}
+
+// ActionResponseFields are the attributes of each typed action response
+var ActionResponseFields = map[string][]Field{
+	"client.notify": {
+		{Name: "type", Kind: KindString},
+		{Name: "title", Kind: KindString},
+		{Name: "message", Kind: KindString},
+	},
+	"client.redirect": {
+		{Name: "location", Kind: KindString},
+		{Name: "window", Kind: KindString},
+		{Name: "delay", Kind: KindInt},
+	},
+	"client.file.download": {
+		{Name: "name", Kind: KindString},
+		{Name: "content", Kind: KindString, Description: "base64 encoded contents"},
+		{Name: "contentType", Kind: KindString},
+		{Name: "message", Kind: KindString},
+	},
+	"client.store.set": {
+		{Name: "key", Kind: KindString},
+		{Name: "value", Kind: KindString},
+	},
+	"client.cookie.set": {
+		{Name: "key", Kind: KindString},
+		{Name: "value", Kind: KindString},
+	},
+}
+
+// reservedTypeNames are the names used by the generated clients, tables with these names get a Row suffix
+var reservedTypeNames = map[string]bool{
+	"Client": true, "Error": true, "DaptinClient": true, "DaptinError": true, "Links": true, "Query": true,
+	"ListParams": true, "Resource": true, "ListResponse": true, "SingleResponse": true, "RelatedResponse": true,
+	"ActionResponse": true, "ActionData": true, "ActionError": true, "ActionResult": true, "FileValue": true,
+	"FileValueInput": true, "DateTime": true, "Types": true, "NewTypes": true, "Relations": true, "Actions": true,
+	"ClientNotify": true, "ClientRedirect": true, "ClientFileDownload": true, "ClientStoreSet": true, "ClientCookieSet": true,
+}
+
+var invalidIdentifierCharacters = regexp.MustCompile(`[^_0-9A-Za-z]`)
+
+// Identifier makes a name safe to use as a field name in all the generated languages
+func Identifier(name string) string {
+	name = invalidIdentifierCharacters.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func camel(name string) string {
+	return strcase.ToCamel(Identifier(name))
+}
+
+// TypeName is the name of the type generated for a table
+func TypeName(name string) string {
+	typeName := camel(name)
+	if reservedTypeNames[typeName] {
+		return typeName + "Row"
+	}
+	return typeName
+}
+
+func fieldKind(columnType string) string {
+	if strings.Split(columnType, ".")[0] == "file" {
+		return KindFile
+	}
+	if resource.ColumnManager == nil {
+		return KindString
+	}
+	switch resource.ColumnManager.GetGraphqlType(columnType) {
+	case graphql.Int:
+		return KindInt
+	case graphql.Float:
+		return KindFloat
+	case graphql.Boolean:
+		return KindBoolean
+	case graphql.DateTime:
+		return KindDateTime
+	}
+	return KindString
+}
+
+func columnName(col api2go.ColumnInfo) string {
+	if col.ColumnName != "" {
+		return col.ColumnName
+	}
+	return col.Name
+}
+
+// BuildSchema collects the tables, relations and actions of the config, join tables are left out
+func BuildSchema(config *resource.CmsConfig) Schema {
+
+	schema := Schema{
+		Hostname: config.Hostname,
+		Types:    make([]Type, 0),
+		Actions:  make([]ActionType, 0),
+	}
+
+	tableNames := make(map[string]bool)
+	for _, table := range config.Tables {
+		if strings.Index(table.TableName, "_has_") > -1 || len(table.TableName) < 1 || tableNames[table.TableName] {
+			continue
+		}
+		tableNames[table.TableName] = true
+
+		typ := Type{
+			TableName: table.TableName,
+			TypeName:  TypeName(table.TableName),
+			Fields:    make([]Field, 0),
+			Relations: make([]Relation, 0),
+		}
+
+		done := make(map[string]bool)
+		for _, col := range table.Columns {
+			name := columnName(col)
+			// foreign keys to other tables are returned as relationships, file columns are foreign keys to a cloud store
+			if col.ExcludeFromApi || (col.IsForeignKey && col.ForeignKeyData.DataSource == "self") || name == "id" || done[name] {
+				continue
+			}
+			done[name] = true
+			typ.Fields = append(typ.Fields, Field{
+				Name:        name,
+				ColumnType:  col.ColumnType,
+				Kind:        fieldKind(col.ColumnType),
+				Nullable:    col.IsNullable,
+				Required:    !col.IsNullable && col.DefaultValue == "" && !resource.IsStandardColumn(name),
+				Description: col.Name,
+			})
+		}
+		for _, virtualColumn := range table.VirtualColumns {
+			col := virtualColumn.ColumnInfo()
+			if done[col.ColumnName] {
+				continue
+			}
+			done[col.ColumnName] = true
+			typ.Fields = append(typ.Fields, Field{
+				Name:        col.ColumnName,
+				ColumnType:  col.ColumnType,
+				Kind:        fieldKind(col.ColumnType),
+				Nullable:    true,
+				Description: col.ColumnDescription,
+				ReadOnly:    true,
+			})
+		}
+
+		relationNames := make(map[string]bool)
+		for _, relation := range table.Relations {
+			var rel Relation
+			if relation.Subject == table.TableName {
+				rel = Relation{
+					Name:   relation.GetObjectName(),
+					Target: relation.Object,
+					IsList: relation.Relation == "has_many" || relation.Relation == "has_many_and_belongs_to_many",
+				}
+			} else if relation.Object == table.TableName {
+				rel = Relation{
+					Name:   relation.GetSubjectName(),
+					Target: relation.Subject,
+					IsList: relation.Relation != "has_one",
+				}
+			} else {
+				continue
+			}
+			if relationNames[rel.Name] || done[rel.Name] {
+				continue
+			}
+			relationNames[rel.Name] = true
+			typ.Relations = append(typ.Relations, rel)
+		}
+		sort.Slice(typ.Relations, func(i, j int) bool {
+			return typ.Relations[i].Name < typ.Relations[j].Name
+		})
+
+		schema.Types = append(schema.Types, typ)
+	}
+	sort.Slice(schema.Types, func(i, j int) bool {
+		return schema.Types[i].TableName < schema.Types[j].TableName
+	})
+
+	// relations to tables which are not generated are left out
+	for i, typ := range schema.Types {
+		relations := make([]Relation, 0)
+		for _, relation := range typ.Relations {
+			if tableNames[relation.Target] {
+				relations = append(relations, relation)
+			}
+		}
+		schema.Types[i].Relations = relations
+	}
+
+	actionNames := make(map[string]bool)
+	for _, action := range config.Actions {
+		key := action.OnType + "/" + action.Name
+		if actionNames[key] || !tableNames[action.OnType] {
+			continue
+		}
+		actionNames[key] = true
+
+		actionType := ActionType{
+			Name:             action.Name,
+			Label:            action.Label,
+			OnType:           action.OnType,
+			TypeName:         fmt.Sprintf("%sOn%s", camel(action.Name), camel(action.OnType)),
+			InstanceOptional: action.InstanceOptional,
+			Fields:           make([]Field, 0),
+			ResponseTypes:    make([]string, 0),
+		}
+		if !action.InstanceOptional {
+			actionType.Fields = append(actionType.Fields, Field{
+				Name:        action.OnType + "_id",
+				Kind:        KindString,
+				Required:    true,
+				Description: "reference id of the " + action.OnType,
+			})
+		}
+		done := make(map[string]bool)
+		for _, col := range action.InFields {
+			name := columnName(col)
+			if done[name] {
+				continue
+			}
+			done[name] = true
+			actionType.Fields = append(actionType.Fields, Field{
+				Name:        name,
+				ColumnType:  col.ColumnType,
+				Kind:        fieldKind(col.ColumnType),
+				Nullable:    col.IsNullable || col.DefaultValue != "",
+				Required:    !col.IsNullable && col.DefaultValue == "",
+				Description: col.Name,
+			})
+		}
+
+		responseTypes := make(map[string]bool)
+		for _, outcome := range action.OutFields {
+			if _, ok := ActionResponseTypeNames[outcome.Type]; ok && !outcome.SkipInResponse && !responseTypes[outcome.Type] {
+				responseTypes[outcome.Type] = true
+				actionType.ResponseTypes = append(actionType.ResponseTypes, outcome.Type)
+			}
+		}
+		sort.Strings(actionType.ResponseTypes)
+
+		schema.Actions = append(schema.Actions, actionType)
+	}
+	sort.Slice(schema.Actions, func(i, j int) bool {
+		if schema.Actions[i].OnType == schema.Actions[j].OnType {
+			return schema.Actions[i].Name < schema.Actions[j].Name
+		}
+		return schema.Actions[i].OnType < schema.Actions[j].OnType
+	})
+
+	return schema
+}
+
+// responseTypeNames are the names of the typed responses, in a fixed order
+func responseTypeNames() []string {
+	names := make([]string, 0)
+	for responseType := range ActionResponseTypeNames {
+		names = append(names, responseType)
+	}
+	sort.Strings(names)
+	return names
+}
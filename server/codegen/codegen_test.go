@@ -0,0 +1,124 @@
+package codegen
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/resource"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func testConfig() *resource.CmsConfig {
+	return &resource.CmsConfig{
+		Hostname: "localhost",
+		Tables: []resource.TableInfo{
+			{
+				TableName: "todo",
+				Columns: []api2go.ColumnInfo{
+					{ColumnName: "id", ColumnType: "id"},
+					{ColumnName: "title", ColumnType: "label"},
+					{ColumnName: "notes", ColumnType: "content", IsNullable: true},
+					{ColumnName: "attachment", ColumnType: "file.*", IsNullable: true, IsForeignKey: true,
+						ForeignKeyData: api2go.ForeignKeyData{DataSource: "cloud_store", Namespace: "local", KeyName: "todo"}},
+					{ColumnName: "project_id", ColumnType: "alias", IsForeignKey: true,
+						ForeignKeyData: api2go.ForeignKeyData{DataSource: "self", Namespace: "project", KeyName: "id"}},
+				},
+				VirtualColumns: []resource.VirtualColumn{
+					{ColumnName: "summary", ColumnType: "label", Script: "title + ' ' + notes"},
+				},
+				Relations: []api2go.TableRelation{
+					api2go.NewTableRelation("todo", "belongs_to", "project"),
+				},
+			},
+			{
+				TableName: "project",
+				Columns: []api2go.ColumnInfo{
+					{ColumnName: "name", ColumnType: "label"},
+				},
+				Relations: []api2go.TableRelation{
+					api2go.NewTableRelation("todo", "belongs_to", "project"),
+				},
+			},
+			{TableName: "todo_has_tag"},
+		},
+		Actions: []resource.Action{
+			{
+				Name:   "mark_done",
+				Label:  "Mark as done",
+				OnType: "todo",
+				InFields: []api2go.ColumnInfo{
+					{ColumnName: "note", ColumnType: "content", IsNullable: true},
+				},
+				OutFields: []resource.Outcome{
+					{Type: "client.notify"},
+				},
+			},
+		},
+	}
+}
+
+func TestBuildSchema(t *testing.T) {
+
+	schema := BuildSchema(testConfig())
+	if len(schema.Types) != 2 || schema.Types[0].TableName != "project" || schema.Types[1].TableName != "todo" {
+		t.Fatalf("expected project and todo, got %v", schema.Types)
+	}
+
+	todo := schema.Types[1]
+	if len(todo.Fields) != 4 || todo.Fields[2].Kind != KindFile || !todo.Fields[0].Required || !todo.Fields[3].ReadOnly {
+		t.Errorf("unexpected fields of todo: %v", todo.Fields)
+	}
+	if len(todo.Relations) != 1 || todo.Relations[0].Name != "project_id" || todo.Relations[0].IsList {
+		t.Errorf("expected a single project relation on todo, got %v", todo.Relations)
+	}
+	project := schema.Types[0]
+	if len(project.Relations) != 1 || project.Relations[0].Name != "todo_id" || !project.Relations[0].IsList {
+		t.Errorf("expected a list of todo on project, got %v", project.Relations)
+	}
+
+	action := schema.Actions[0]
+	if action.TypeName != "MarkDoneOnTodo" || len(action.Fields) != 2 || action.Fields[0].Name != "todo_id" ||
+		len(action.ResponseTypes) != 1 {
+		t.Errorf("unexpected action: %v", action)
+	}
+}
+
+func TestGenerateClients(t *testing.T) {
+
+	schema := BuildSchema(testConfig())
+
+	source, err := GenerateGoClient(schema, "daptin")
+	if err != nil {
+		t.Fatalf("failed to generate the go client: %v", err)
+	}
+	if _, err = parser.ParseFile(token.NewFileSet(), "client.go", source, 0); err != nil {
+		t.Fatalf("generated go client does not parse: %v", err)
+	}
+	for _, expected := range []string{"func (c *Client) ListTodo(", "func (c *Client) TodoRelatedProjectId(",
+		"func (c *Client) ExecuteMarkDoneOnTodo(input MarkDoneOnTodoInput)", "`json:\"notes,omitempty\"`"} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("expected [%v] in the go client", expected)
+		}
+	}
+
+	newTodo := source[strings.Index(source, "type NewTodo struct"):]
+	if strings.Contains(newTodo[:strings.Index(newTodo, "}")], "summary") {
+		t.Errorf("expected no virtual column in NewTodo")
+	}
+
+	typescript := GenerateTypescript(schema)
+	for _, expected := range []string{"export interface Todo {", "export interface NewTodo {", "notes?: string | null;",
+		"project_id: { type: \"project\"; list: false };", "mark_done: { input: MarkDoneOnTodoInput; output: ClientNotify | ActionData };"} {
+		if !strings.Contains(typescript, expected) {
+			t.Errorf("expected [%v] in the typescript client", expected)
+		}
+	}
+
+	sdl := GenerateSdl(schema)
+	for _, expected := range []string{"type Todo {", "project_id: Project\n", "todo_id: [Todo]", "input MarkDoneOnTodoInput {\n  todo_id: String!"} {
+		if !strings.Contains(sdl, expected) {
+			t.Errorf("expected [%v] in the sdl", expected)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+)
+
+func goType(field Field, input bool) string {
+	switch field.Kind {
+	case KindInt:
+		return "int64"
+	case KindFloat:
+		return "float64"
+	case KindBoolean:
+		return "bool"
+	case KindFile:
+		if input {
+			return "[]FileValueInput"
+		}
+		return "[]FileValue"
+	}
+	// date times are kept as the string returned by the server
+	return "string"
+}
+
+// goFieldNames gives each field an exported name which is unique in the struct
+func goFieldNames(fields []Field, reserved ...string) []string {
+	used := make(map[string]bool)
+	for _, name := range reserved {
+		used[name] = true
+	}
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		name := camel(field.Name)
+		if name[0] == '_' {
+			name = "X" + name
+		}
+		unique := name
+		for n := 2; used[unique]; n++ {
+			unique = name + strconv.Itoa(n)
+		}
+		used[unique] = true
+		names[i] = unique
+	}
+	return names
+}
+
+// goStruct writes a struct of the fields, with pointers the fields which are left out when not set
+func goStruct(buffer *strings.Builder, name string, fields []Field, input bool, allOptional bool) {
+	buffer.WriteString(fmt.Sprintf("type %s struct {\n", name))
+	if !input {
+		buffer.WriteString("\tReferenceId string `json:\"reference_id\"`\n")
+	} else {
+		fields = inputFields(fields)
+	}
+	names := goFieldNames(fields, "ReferenceId")
+	for i, field := range fields {
+		fieldType := goType(field, input)
+		tag := field.Name
+		optional := allOptional || (input && !field.Required) || (!input && field.Nullable)
+		if optional && field.Kind != KindFile {
+			fieldType = "*" + fieldType
+		}
+		if optional && input {
+			tag = tag + ",omitempty"
+		}
+		buffer.WriteString(fmt.Sprintf("\t%s %s `json:%s`\n", names[i], fieldType, strconv.Quote(tag)))
+	}
+	buffer.WriteString("}\n\n")
+}
+
+// GenerateGoClient writes a go package with structs for the tables and actions and a client for the api
+func GenerateGoClient(schema Schema, packageName string) (string, error) {
+
+	buffer := &strings.Builder{}
+
+	buffer.WriteString(fmt.Sprintf("// Code generated by daptin from the schema of %s. DO NOT EDIT.\n\n", schema.Hostname))
+	buffer.WriteString(fmt.Sprintf("package %s\n\n", packageName))
+	buffer.WriteString(goClient)
+
+	for _, responseType := range responseTypeNames() {
+		name := ActionResponseTypeNames[responseType]
+		buffer.WriteString(fmt.Sprintf("// %s are the attributes of a %s response\n", name, responseType))
+		buffer.WriteString(fmt.Sprintf("type %s struct {\n", name))
+		fields := ActionResponseFields[responseType]
+		names := goFieldNames(fields)
+		for i, field := range fields {
+			buffer.WriteString(fmt.Sprintf("\t%s %s `json:\"%s,omitempty\"`\n", names[i], goType(field, false), field.Name))
+		}
+		buffer.WriteString("}\n\n")
+	}
+
+	for _, typ := range schema.Types {
+
+		buffer.WriteString(fmt.Sprintf("// %s is a row of %s\n", typ.TypeName, typ.TableName))
+		goStruct(buffer, typ.TypeName, typ.Fields, false, false)
+		buffer.WriteString(fmt.Sprintf("// New%s is the input to create a row of %s\n", typ.TypeName, typ.TableName))
+		goStruct(buffer, "New"+typ.TypeName, typ.Fields, true, false)
+		buffer.WriteString(fmt.Sprintf("// %sUpdate is the input to update a row of %s, only the set fields are changed\n", typ.TypeName, typ.TableName))
+		goStruct(buffer, typ.TypeName+"Update", typ.Fields, true, true)
+
+		buffer.WriteString(strings.NewReplacer("{{Type}}", typ.TypeName, "{{table}}", typ.TableName).Replace(goTypeMethods))
+
+		for _, relation := range typ.Relations {
+			method := fmt.Sprintf("%sRelated%s", typ.TypeName, camel(relation.Name))
+			target := TypeName(relation.Target)
+			result := target + "Resource"
+			decode := "var response struct {\n\t\tData " + result + " `json:\"data\"`\n\t}"
+			returnValue := "&response.Data"
+			if relation.IsList {
+				result = target + "List"
+				decode = "var response " + result
+				returnValue = "&response"
+			}
+			buffer.WriteString(fmt.Sprintf("// %s returns the %s of a %s\n", method, relation.Name, typ.TableName))
+			buffer.WriteString(fmt.Sprintf("func (c *Client) %s(id string, params *ListParams) (*%s, error) {\n", method, result))
+			buffer.WriteString("\t" + decode + "\n")
+			buffer.WriteString(fmt.Sprintf("\terr := c.Do(\"GET\", \"/api/%s/\"+url.PathEscape(id)+\"/%s\", params, nil, &response)\n", typ.TableName, relation.Name))
+			buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+			buffer.WriteString(fmt.Sprintf("\treturn %s, nil\n}\n\n", returnValue))
+		}
+	}
+
+	for _, action := range schema.Actions {
+		if action.Label != "" {
+			buffer.WriteString(fmt.Sprintf("// %sInput is the input of %s\n", action.TypeName, action.Label))
+		}
+		buffer.WriteString(fmt.Sprintf("type %sInput struct {\n", action.TypeName))
+		names := goFieldNames(action.Fields)
+		for i, field := range action.Fields {
+			fieldType := goType(field, true)
+			tag := field.Name
+			if !field.Required {
+				if field.Kind != KindFile {
+					fieldType = "*" + fieldType
+				}
+				tag = tag + ",omitempty"
+			}
+			buffer.WriteString(fmt.Sprintf("\t%s %s `json:%s`\n", names[i], fieldType, strconv.Quote(tag)))
+		}
+		buffer.WriteString("}\n\n")
+
+		responses := make([]string, 0)
+		for _, responseType := range action.ResponseTypes {
+			responses = append(responses, ActionResponseTypeNames[responseType])
+		}
+		buffer.WriteString(fmt.Sprintf("// Execute%s executes %s on %s", action.TypeName, action.Name, action.OnType))
+		if len(responses) > 0 {
+			buffer.WriteString(fmt.Sprintf(", the responses include %s", strings.Join(responses, ", ")))
+		}
+		buffer.WriteString("\n")
+		buffer.WriteString(fmt.Sprintf("func (c *Client) Execute%s(input %sInput) ([]ActionResponse, error) {\n", action.TypeName, action.TypeName))
+		buffer.WriteString(fmt.Sprintf("\treturn c.ExecuteAction(%q, %q, input)\n}\n\n", action.OnType, action.Name))
+	}
+
+	source, err := format.Source([]byte(buffer.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format the go client: %v", err)
+	}
+	return string(source), nil
+}
+
+const goClient = `import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// FileValue is a file stored in a file column
+type FileValue struct {
+	Name string ` + "`json:\"name,omitempty\"`" + `
+	Type string ` + "`json:\"type,omitempty\"`" + `
+	Size int64  ` + "`json:\"size,omitempty\"`" + `
+	Path string ` + "`json:\"path,omitempty\"`" + `
+	Md5  string ` + "`json:\"md5,omitempty\"`" + `
+}
+
+// FileValueInput is a file to upload, the contents are base64 encoded in File
+type FileValueInput struct {
+	Name string ` + "`json:\"name\"`" + `
+	File string ` + "`json:\"file\"`" + `
+	Type string ` + "`json:\"type,omitempty\"`" + `
+	Path string ` + "`json:\"path,omitempty\"`" + `
+}
+
+type Links struct {
+	CurrentPage int64 ` + "`json:\"current_page\"`" + `
+	From        int64 ` + "`json:\"from\"`" + `
+	LastPage    int64 ` + "`json:\"last_page\"`" + `
+	PerPage     int64 ` + "`json:\"per_page\"`" + `
+	To          int64 ` + "`json:\"to\"`" + `
+	Total       int64 ` + "`json:\"total\"`" + `
+}
+
+type Query struct {
+	Column   string      ` + "`json:\"column\"`" + `
+	Operator string      ` + "`json:\"operator\"`" + `
+	Value    interface{} ` + "`json:\"value\"`" + `
+}
+
+// ListParams are the paging, sorting and filtering parameters of the list requests
+type ListParams struct {
+	PageNumber        int
+	PageSize          int
+	Sort              []string
+	Filter            string
+	Query             []Query
+	IncludedRelations []string
+}
+
+func (p *ListParams) values() url.Values {
+	values := url.Values{}
+	if p == nil {
+		return values
+	}
+	if p.PageNumber > 0 {
+		values.Set("page[number]", strconv.Itoa(p.PageNumber))
+	}
+	if p.PageSize > 0 {
+		values.Set("page[size]", strconv.Itoa(p.PageSize))
+	}
+	if len(p.Sort) > 0 {
+		values.Set("sort", strings.Join(p.Sort, ","))
+	}
+	if p.Filter != "" {
+		values.Set("filter", p.Filter)
+	}
+	if len(p.Query) > 0 {
+		query, _ := json.Marshal(p.Query)
+		values.Set("query", string(query))
+	}
+	if len(p.IncludedRelations) > 0 {
+		values.Set("included_relations", strings.Join(p.IncludedRelations, ","))
+	}
+	return values
+}
+
+// ActionResponse is a response of an action, Decode reads the attributes into one of the response types
+type ActionResponse struct {
+	ResponseType string
+	Attributes   json.RawMessage
+}
+
+func (r ActionResponse) Decode(value interface{}) error {
+	return json.Unmarshal(r.Attributes, value)
+}
+
+// Error is returned for the responses which are not 2xx
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("daptin request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+type Client struct {
+	Endpoint   string
+	Token      string
+	HttpClient *http.Client
+}
+
+func NewClient(endpoint string, token string) *Client {
+	return &Client{
+		Endpoint:   strings.TrimRight(endpoint, "/"),
+		Token:      token,
+		HttpClient: http.DefaultClient,
+	}
+}
+
+// Do sends the request and reads the json response into result
+func (c *Client) Do(method string, path string, params *ListParams, body interface{}, result interface{}) error {
+
+	requestUrl := c.Endpoint + path
+	if values := params.values(); len(values) > 0 {
+		requestUrl = requestUrl + "?" + values.Encode()
+	}
+
+	var requestBody *bytes.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		requestBody = bytes.NewReader(payload)
+	} else {
+		requestBody = bytes.NewReader(nil)
+	}
+
+	request, err := http.NewRequest(method, requestUrl, requestBody)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.api+json")
+	request.Header.Set("Content-Type", "application/vnd.api+json")
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	response, err := c.HttpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &Error{StatusCode: response.StatusCode, Body: string(responseBody)}
+	}
+	if result == nil || len(responseBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(responseBody, result)
+}
+
+// ExecuteAction executes an action, the subject of the action is passed as <type>_id in the input
+func (c *Client) ExecuteAction(onType string, name string, input interface{}) ([]ActionResponse, error) {
+	var responses []ActionResponse
+	err := c.Do("POST", "/action/"+onType+"/"+name, nil, map[string]interface{}{
+		"attributes": input,
+	}, &responses)
+	return responses, err
+}
+
+`
+
+const goTypeMethods = `type {{Type}}Resource struct {
+	Type          string                 ` + "`json:\"type\"`" + `
+	Id            string                 ` + "`json:\"id\"`" + `
+	Attributes    {{Type}}                ` + "`json:\"attributes\"`" + `
+	Relationships map[string]interface{} ` + "`json:\"relationships,omitempty\"`" + `
+}
+
+type {{Type}}List struct {
+	Data  []{{Type}}Resource ` + "`json:\"data\"`" + `
+	Links Links              ` + "`json:\"links\"`" + `
+}
+
+func (c *Client) List{{Type}}(params *ListParams) (*{{Type}}List, error) {
+	var response {{Type}}List
+	err := c.Do("GET", "/api/{{table}}", params, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) Get{{Type}}(id string, params *ListParams) (*{{Type}}Resource, error) {
+	var response struct {
+		Data {{Type}}Resource ` + "`json:\"data\"`" + `
+	}
+	err := c.Do("GET", "/api/{{table}}/"+url.PathEscape(id), params, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+func (c *Client) Create{{Type}}(attributes New{{Type}}) (*{{Type}}Resource, error) {
+	var response struct {
+		Data {{Type}}Resource ` + "`json:\"data\"`" + `
+	}
+	err := c.Do("POST", "/api/{{table}}", nil, map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":       "{{table}}",
+			"attributes": attributes,
+		},
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+func (c *Client) Update{{Type}}(id string, attributes {{Type}}Update) (*{{Type}}Resource, error) {
+	var response struct {
+		Data {{Type}}Resource ` + "`json:\"data\"`" + `
+	}
+	err := c.Do("PATCH", "/api/{{table}}/"+url.PathEscape(id), nil, map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":       "{{table}}",
+			"id":         id,
+			"attributes": attributes,
+		},
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+func (c *Client) Delete{{Type}}(id string) error {
+	return c.Do("DELETE", "/api/{{table}}/"+url.PathEscape(id), nil, nil, nil)
+}
+
+`
@@ -0,0 +1,114 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+func sdlType(field Field) string {
+	switch field.Kind {
+	case KindInt:
+		return "Int"
+	case KindFloat:
+		return "Float"
+	case KindBoolean:
+		return "Boolean"
+	case KindDateTime:
+		return "DateTime"
+	case KindFile:
+		return "[FileValue]"
+	}
+	return "String"
+}
+
+func sdlDescription(buffer *strings.Builder, indent string, description string) {
+	if description == "" {
+		return
+	}
+	buffer.WriteString(fmt.Sprintf("%s\"%s\"\n", indent, strings.ReplaceAll(description, "\"", "'")))
+}
+
+// GenerateSdl writes the tables, relations and actions as a GraphQL schema definition
+func GenerateSdl(schema Schema) string {
+
+	buffer := &strings.Builder{}
+
+	buffer.WriteString("scalar DateTime\n\n")
+	buffer.WriteString("\"A file stored in a file column\"\n")
+	buffer.WriteString("type FileValue {\n  name: String\n  type: String\n  size: Int\n  path: String\n  md5: String\n}\n\n")
+	buffer.WriteString("\"A file to upload, the contents are base64 encoded in file\"\n")
+	buffer.WriteString("input FileValueInput {\n  name: String\n  file: String\n  type: String\n  path: String\n}\n\n")
+
+	for _, typ := range schema.Types {
+		buffer.WriteString(fmt.Sprintf("type %s {\n", typ.TypeName))
+		buffer.WriteString("  id: ID!\n")
+		for _, field := range typ.Fields {
+			nonNull := ""
+			if !field.Nullable {
+				nonNull = "!"
+			}
+			buffer.WriteString(fmt.Sprintf("  %s: %s%s\n", Identifier(field.Name), sdlType(field), nonNull))
+		}
+		for _, relation := range typ.Relations {
+			relationType := TypeName(relation.Target)
+			if relation.IsList {
+				relationType = "[" + relationType + "]"
+			}
+			buffer.WriteString(fmt.Sprintf("  %s: %s\n", Identifier(relation.Name), relationType))
+		}
+		buffer.WriteString("}\n\n")
+
+		buffer.WriteString(fmt.Sprintf("input New%s {\n", typ.TypeName))
+		for _, field := range inputFields(typ.Fields) {
+			fieldType := sdlType(field)
+			if field.Kind == KindFile {
+				fieldType = "[FileValueInput]"
+			}
+			nonNull := ""
+			if field.Required {
+				nonNull = "!"
+			}
+			buffer.WriteString(fmt.Sprintf("  %s: %s%s\n", Identifier(field.Name), fieldType, nonNull))
+		}
+		buffer.WriteString("}\n\n")
+	}
+
+	for _, action := range schema.Actions {
+		sdlDescription(buffer, "", action.Label)
+		buffer.WriteString(fmt.Sprintf("input %sInput {\n", action.TypeName))
+		if len(action.Fields) == 0 {
+			// inputs need at least one field
+			buffer.WriteString("  _empty: Boolean\n")
+		}
+		for _, field := range action.Fields {
+			fieldType := sdlType(field)
+			if field.Kind == KindFile {
+				fieldType = "[FileValueInput]"
+			}
+			nonNull := ""
+			if field.Required {
+				nonNull = "!"
+			}
+			buffer.WriteString(fmt.Sprintf("  %s: %s%s\n", Identifier(field.Name), fieldType, nonNull))
+		}
+		buffer.WriteString("}\n\n")
+	}
+
+	members := make([]string, 0)
+	for _, responseType := range responseTypeNames() {
+		name := ActionResponseTypeNames[responseType]
+		members = append(members, name)
+		buffer.WriteString(fmt.Sprintf("type %s {\n  ResponseType: String!\n", name))
+		for _, field := range ActionResponseFields[responseType] {
+			buffer.WriteString(fmt.Sprintf("  %s: %s\n", field.Name, sdlType(field)))
+		}
+		buffer.WriteString("}\n\n")
+	}
+	buffer.WriteString("type ActionError {\n  ResponseType: String!\n  message: String\n}\n\n")
+	buffer.WriteString("\"Any other response, eg a row created by the action\"\n")
+	buffer.WriteString("type ActionData {\n  ResponseType: String!\n  \"json encoded attributes of the response\"\n  data: String\n}\n\n")
+	members = append(members, "ActionError", "ActionData")
+	buffer.WriteString(fmt.Sprintf("union ActionResult = %s\n", strings.Join(members, " | ")))
+
+	return buffer.String()
+}
@@ -0,0 +1,312 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+func typescriptType(field Field, input bool) string {
+	switch field.Kind {
+	case KindInt, KindFloat:
+		return "number"
+	case KindBoolean:
+		return "boolean"
+	case KindDateTime:
+		return "DateTime"
+	case KindFile:
+		if input {
+			return "FileValueInput[]"
+		}
+		return "FileValue[]"
+	}
+	return "string"
+}
+
+// typescriptKey quotes the names which are not identifiers
+func typescriptKey(name string) string {
+	if Identifier(name) != name {
+		return fmt.Sprintf("%q", name)
+	}
+	return name
+}
+
+func typescriptFields(buffer *strings.Builder, fields []Field, input bool) {
+	if input {
+		fields = inputFields(fields)
+	}
+	for _, field := range fields {
+		optional := ""
+		if (input && !field.Required) || (!input && field.Nullable) {
+			optional = "?"
+		}
+		nullable := ""
+		if field.Nullable {
+			nullable = " | null"
+		}
+		buffer.WriteString(fmt.Sprintf("  %s%s: %s%s;\n", typescriptKey(field.Name), optional, typescriptType(field, input), nullable))
+	}
+}
+
+// GenerateTypescript writes interfaces for the tables, relations and actions and a typed client using fetch
+func GenerateTypescript(schema Schema) string {
+
+	buffer := &strings.Builder{}
+
+	buffer.WriteString(fmt.Sprintf("// Generated by daptin from the schema of %s, do not edit.\n\n", schema.Hostname))
+	buffer.WriteString("export type DateTime = string;\n\n")
+	buffer.WriteString("export interface FileValue {\n  name?: string;\n  type?: string;\n  size?: number;\n  path?: string;\n  md5?: string;\n}\n\n")
+	buffer.WriteString("// the contents of the file are base64 encoded in file\n")
+	buffer.WriteString("export interface FileValueInput {\n  name: string;\n  file: string;\n  type?: string;\n  path?: string;\n}\n\n")
+
+	for _, typ := range schema.Types {
+		buffer.WriteString(fmt.Sprintf("// %s\n", typ.TableName))
+		buffer.WriteString(fmt.Sprintf("export interface %s {\n", typ.TypeName))
+		buffer.WriteString("  reference_id: string;\n")
+		typescriptFields(buffer, typ.Fields, false)
+		buffer.WriteString("}\n\n")
+
+		buffer.WriteString(fmt.Sprintf("export interface New%s {\n", typ.TypeName))
+		typescriptFields(buffer, typ.Fields, true)
+		buffer.WriteString("}\n\n")
+	}
+
+	buffer.WriteString("export interface Types {\n")
+	for _, typ := range schema.Types {
+		buffer.WriteString(fmt.Sprintf("  %s: %s;\n", typescriptKey(typ.TableName), typ.TypeName))
+	}
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString("export interface NewTypes {\n")
+	for _, typ := range schema.Types {
+		buffer.WriteString(fmt.Sprintf("  %s: New%s;\n", typescriptKey(typ.TableName), typ.TypeName))
+	}
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString("// the related type of each relation, list is false for the relations to a single row\n")
+	buffer.WriteString("export interface Relations {\n")
+	for _, typ := range schema.Types {
+		buffer.WriteString(fmt.Sprintf("  %s: {\n", typescriptKey(typ.TableName)))
+		for _, relation := range typ.Relations {
+			buffer.WriteString(fmt.Sprintf("    %s: { type: %q; list: %v };\n", typescriptKey(relation.Name), relation.Target, relation.IsList))
+		}
+		buffer.WriteString("  };\n")
+	}
+	buffer.WriteString("}\n\n")
+
+	members := make([]string, 0)
+	for _, responseType := range responseTypeNames() {
+		name := ActionResponseTypeNames[responseType]
+		members = append(members, name)
+		buffer.WriteString(fmt.Sprintf("export interface %s {\n  ResponseType: %q;\n  Attributes: {\n", name, responseType))
+		for _, field := range ActionResponseFields[responseType] {
+			buffer.WriteString(fmt.Sprintf("    %s?: %s;\n", field.Name, typescriptType(field, false)))
+		}
+		buffer.WriteString("  };\n}\n\n")
+	}
+	buffer.WriteString("// any other response, eg a row created by the action\n")
+	buffer.WriteString("export interface ActionData {\n  ResponseType: string;\n  Attributes: any;\n}\n\n")
+	members = append(members, "ActionData")
+	buffer.WriteString(fmt.Sprintf("export type ActionResponse = %s;\n\n", strings.Join(members, " | ")))
+
+	for _, action := range schema.Actions {
+		if action.Label != "" {
+			buffer.WriteString(fmt.Sprintf("// %s\n", action.Label))
+		}
+		buffer.WriteString(fmt.Sprintf("export interface %sInput {\n", action.TypeName))
+		typescriptFields(buffer, action.Fields, true)
+		buffer.WriteString("}\n\n")
+	}
+
+	buffer.WriteString("export interface Actions {\n")
+	for i, action := range schema.Actions {
+		if i == 0 || schema.Actions[i-1].OnType != action.OnType {
+			buffer.WriteString(fmt.Sprintf("  %s: {\n", typescriptKey(action.OnType)))
+		}
+		outputs := make([]string, 0)
+		for _, responseType := range action.ResponseTypes {
+			outputs = append(outputs, ActionResponseTypeNames[responseType])
+		}
+		outputs = append(outputs, "ActionData")
+		buffer.WriteString(fmt.Sprintf("    %s: { input: %sInput; output: %s };\n",
+			typescriptKey(action.Name), action.TypeName, strings.Join(outputs, " | ")))
+		if i == len(schema.Actions)-1 || schema.Actions[i+1].OnType != action.OnType {
+			buffer.WriteString("  };\n")
+		}
+	}
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString(typescriptClient)
+
+	return buffer.String()
+}
+
+const typescriptClient = `export interface Resource<T> {
+  type: string;
+  id: string;
+  attributes: T;
+  relationships?: { [name: string]: any };
+}
+
+export interface Links {
+  current_page?: number;
+  from?: number;
+  last_page?: number;
+  per_page?: number;
+  to?: number;
+  total?: number;
+}
+
+export interface ListResponse<T> {
+  data: Resource<T>[];
+  links?: Links;
+  included?: Resource<any>[];
+}
+
+export interface SingleResponse<T> {
+  data: Resource<T>;
+  included?: Resource<any>[];
+}
+
+export interface Query {
+  column: string;
+  operator: string;
+  value: any;
+}
+
+export interface ListParams {
+  page?: { number?: number; size?: number };
+  sort?: string[];
+  filter?: string;
+  query?: Query[];
+  included_relations?: string[];
+}
+
+export type RelatedResponse<R> = R extends { type: infer T; list: infer L }
+  ? T extends keyof Types
+    ? L extends true
+      ? ListResponse<Types[T]>
+      : SingleResponse<Types[T]>
+    : never
+  : never;
+
+export class DaptinError extends Error {
+  constructor(public status: number, public body: any) {
+    super("daptin request failed with status " + status);
+  }
+}
+
+export class DaptinClient {
+  constructor(
+    private endpoint: string,
+    private token?: string,
+    private fetchFn: typeof fetch = fetch
+  ) {
+    this.endpoint = endpoint.replace(/\/+$/, "");
+  }
+
+  setToken(token?: string) {
+    this.token = token;
+  }
+
+  private async request<R>(method: string, path: string, body?: any, params?: ListParams): Promise<R> {
+    const headers: { [name: string]: string } = {
+      Accept: "application/vnd.api+json",
+      "Content-Type": "application/vnd.api+json",
+    };
+    if (this.token) {
+      headers["Authorization"] = "Bearer " + this.token;
+    }
+    // called unbound, window.fetch fails when called as a method of another object
+    const fetchFn = this.fetchFn;
+    const response = await fetchFn(this.endpoint + path + queryString(params), {
+      method: method,
+      headers: headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const text = await response.text();
+    const result = text.length > 0 ? JSON.parse(text) : undefined;
+    if (!response.ok) {
+      throw new DaptinError(response.status, result);
+    }
+    return result as R;
+  }
+
+  list<K extends keyof Types>(type: K, params?: ListParams): Promise<ListResponse<Types[K]>> {
+    return this.request("GET", "/api/" + String(type), undefined, params);
+  }
+
+  get<K extends keyof Types>(type: K, id: string, params?: ListParams): Promise<SingleResponse<Types[K]>> {
+    return this.request("GET", "/api/" + String(type) + "/" + encodeURIComponent(id), undefined, params);
+  }
+
+  create<K extends keyof NewTypes>(type: K, attributes: NewTypes[K]): Promise<SingleResponse<Types[K]>> {
+    return this.request("POST", "/api/" + String(type), {
+      data: { type: type, attributes: attributes },
+    });
+  }
+
+  update<K extends keyof NewTypes>(type: K, id: string, attributes: Partial<NewTypes[K]>): Promise<SingleResponse<Types[K]>> {
+    return this.request("PATCH", "/api/" + String(type) + "/" + encodeURIComponent(id), {
+      data: { type: type, id: id, attributes: attributes },
+    });
+  }
+
+  delete<K extends keyof Types>(type: K, id: string): Promise<void> {
+    return this.request("DELETE", "/api/" + String(type) + "/" + encodeURIComponent(id));
+  }
+
+  related<K extends keyof Relations, N extends keyof Relations[K]>(
+    type: K,
+    id: string,
+    relation: N,
+    params?: ListParams
+  ): Promise<RelatedResponse<Relations[K][N]>> {
+    return this.request(
+      "GET",
+      "/api/" + String(type) + "/" + encodeURIComponent(id) + "/" + String(relation),
+      undefined,
+      params
+    );
+  }
+
+  // the subject of the action is passed as <type>_id in the input
+  action<K extends keyof Actions, A extends keyof Actions[K]>(
+    type: K,
+    name: A,
+    input: Actions[K][A] extends { input: infer I } ? I : never
+  ): Promise<(Actions[K][A] extends { output: infer O } ? O : never)[]> {
+    return this.request("POST", "/action/" + String(type) + "/" + String(name), {
+      attributes: input,
+    });
+  }
+}
+
+function queryString(params?: ListParams): string {
+  if (!params) {
+    return "";
+  }
+  const values: string[] = [];
+  const add = (name: string, value: string) => {
+    values.push(encodeURIComponent(name) + "=" + encodeURIComponent(value));
+  };
+  if (params.page && params.page.number !== undefined) {
+    add("page[number]", String(params.page.number));
+  }
+  if (params.page && params.page.size !== undefined) {
+    add("page[size]", String(params.page.size));
+  }
+  if (params.sort && params.sort.length > 0) {
+    add("sort", params.sort.join(","));
+  }
+  if (params.filter) {
+    add("filter", params.filter);
+  }
+  if (params.query && params.query.length > 0) {
+    add("query", JSON.stringify(params.query));
+  }
+  if (params.included_relations && params.included_relations.length > 0) {
+    add("included_relations", params.included_relations.join(","));
+  }
+  return values.length > 0 ? "?" + values.join("&") : "";
+}
+`
@@ -115,6 +115,20 @@ func LoadConfigFiles() (resource.CmsConfig, []error) {
 			for j, col := range table.Columns {
 				table.Columns[j].ColumnName = flect.Underscore(col.ColumnName)
 			}
+			if err = table.ValidateVirtualColumns(); err != nil {
+				log.Errorf("Ignoring virtual columns of table [%v]: %v", table.TableName, err)
+				errs = append(errs, err)
+				table.VirtualColumns = nil
+			}
+			if err = table.ValidateColumnPermissions(); err != nil {
+				log.Errorf("Ignoring column permissions of table [%v]: %v", table.TableName, err)
+				errs = append(errs, err)
+				table.ColumnPermissions = nil
+			}
+			if err = table.ValidateRowPolicies(); err != nil {
+				log.Errorf("Invalid row policy of table [%v], it will not match any row: %v", table.TableName, err)
+				errs = append(errs, err)
+			}
 			tables = append(tables, table)
 		}
 		initConfig.Tables = tables
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"fmt"
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BufferedEvent is an event message with the id sent to server-sent-event clients as the event id
+type BufferedEvent struct {
+	Id      uint64
+	Message resource.EventMessage
+}
+
+// EventStreamBuffer listens to the table topics and retains the last events so that
+// server-sent-event clients can resume with Last-Event-ID after a reconnect
+// event ids are local to this node
+type EventStreamBuffer struct {
+	lock             sync.RWMutex
+	events           []BufferedEvent // ring of the last events, the oldest is at start
+	start            int
+	count            int
+	lastId           uint64
+	subscribers      map[uint64]chan BufferedEvent
+	lastSubscriberId uint64
+}
+
+func NewEventStreamBuffer(size int, dtopicMap *map[string]*olric.DTopic) *EventStreamBuffer {
+	buffer := &EventStreamBuffer{
+		events:      make([]BufferedEvent, size),
+		subscribers: make(map[uint64]chan BufferedEvent),
+	}
+
+	for topicName, topic := range *dtopicMap {
+		_, err := topic.AddListener(func(message olric.DTopicMessage) {
+			eventMessage, ok := message.Message.(resource.EventMessage)
+			if !ok {
+				return
+			}
+			buffer.Add(eventMessage)
+		})
+		resource.CheckErr(err, "Failed to add event stream listener to topic [%v]", topicName)
+	}
+
+	return buffer
+}
+
+// Add stores the event in the buffer and hands it over to the connected clients
+// a client which is not keeping up is disconnected, it resumes from the buffer on reconnect
+func (esb *EventStreamBuffer) Add(message resource.EventMessage) {
+	esb.lock.Lock()
+	defer esb.lock.Unlock()
+
+	esb.lastId += 1
+	event := BufferedEvent{
+		Id:      esb.lastId,
+		Message: message,
+	}
+
+	if esb.count < len(esb.events) {
+		esb.events[(esb.start+esb.count)%len(esb.events)] = event
+		esb.count += 1
+	} else {
+		// the oldest event is overwritten
+		esb.events[esb.start] = event
+		esb.start = (esb.start + 1) % len(esb.events)
+	}
+
+	for subscriberId, subscriber := range esb.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			close(subscriber)
+			delete(esb.subscribers, subscriberId)
+		}
+	}
+}
+
+// Subscribe returns the retained events after lastEventId and a channel for the new events. When lastEventId
+// was already dropped from the buffer the client gets all the retained events.
+func (esb *EventStreamBuffer) Subscribe(lastEventId uint64) (uint64, []BufferedEvent, chan BufferedEvent) {
+	esb.lock.Lock()
+	defer esb.lock.Unlock()
+
+	missed := make([]BufferedEvent, 0)
+	if lastEventId > 0 {
+		for i := 0; i < esb.count; i++ {
+			event := esb.events[(esb.start+i)%len(esb.events)]
+			if event.Id > lastEventId {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	esb.lastSubscriberId += 1
+	subscriber := make(chan BufferedEvent, channelBufferSize)
+	esb.subscribers[esb.lastSubscriberId] = subscriber
+
+	return esb.lastSubscriberId, missed, subscriber
+}
+
+func (esb *EventStreamBuffer) Unsubscribe(subscriberId uint64) {
+	esb.lock.Lock()
+	defer esb.lock.Unlock()
+
+	subscriber, ok := esb.subscribers[subscriberId]
+	if ok {
+		close(subscriber)
+		delete(esb.subscribers, subscriberId)
+	}
+}
+
+const channelBufferSize = 100
+
+// eventStreamFilter selects the events sent to a server-sent-event client
+type eventStreamFilter struct {
+	topics      map[string]bool
+	eventType   string
+	cruds       map[string]*resource.DbResource
+	sessionUser *auth.SessionUser
+}
+
+// readableEvent returns the event with the row as the user can read it, false when the user cannot
+func (f eventStreamFilter) readableEvent(event BufferedEvent) (BufferedEvent, bool) {
+	if len(f.topics) > 0 && !f.topics[event.Message.ObjectType] {
+		return event, false
+	}
+	if f.eventType != "" && event.Message.EventType != f.eventType {
+		return event, false
+	}
+
+	typeName, _ := event.Message.EventData["__type"].(string)
+	if _, tableExists := f.cruds[typeName]; tableExists {
+		eventData, ok := f.cruds[typeName].ReadableRow(event.Message.EventData, f.sessionUser)
+		if !ok {
+			return event, false
+		}
+		event.Message.EventData = eventData
+	}
+	return event, true
+}
+
+// CreateEventStreamHandler serves the table events as server-sent-events
+// topic query parameter selects the tables (comma separated, all tables by default)
+// event_type query parameter selects create/update/delete events
+func CreateEventStreamHandler(buffer *EventStreamBuffer, cruds map[string]*resource.DbResource) func(*gin.Context) {
+
+	return func(c *gin.Context) {
+
+		user := c.Request.Context().Value("user")
+		if user == nil {
+			c.AbortWithStatus(401)
+			return
+		}
+		sessionUser := user.(*auth.SessionUser)
+
+		topics := make(map[string]bool)
+		topicParam := c.Query("topic")
+		if len(topicParam) > 0 {
+			for _, topic := range strings.Split(topicParam, ",") {
+				if _, ok := cruds[topic]; !ok {
+					c.AbortWithError(400, fmt.Errorf("no such topic [%v]", topic))
+					return
+				}
+				if !sessionUser.ApiKeyScope.AllowsTable(topic, "GET") {
+					c.AbortWithError(403, fmt.Errorf("api key cannot read [%v]", topic))
+					return
+				}
+				topics[topic] = true
+			}
+		}
+		eventTypeFilter := c.Query("event_type")
+
+		lastEventIdValue := c.GetHeader("Last-Event-ID")
+		if lastEventIdValue == "" {
+			lastEventIdValue = c.Query("last_event_id")
+		}
+		var lastEventId uint64
+		if lastEventIdValue != "" {
+			lastEventId, _ = strconv.ParseUint(lastEventIdValue, 10, 64)
+		}
+
+		filter := eventStreamFilter{
+			topics:      topics,
+			eventType:   eventTypeFilter,
+			cruds:       cruds,
+			sessionUser: sessionUser,
+		}
+
+		subscriberId, missedEvents, events := buffer.Subscribe(lastEventId)
+		defer buffer.Unsubscribe(subscriberId)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(200)
+
+		sendEvent := func(event BufferedEvent) {
+			c.Render(-1, sse.Event{
+				Id:    fmt.Sprintf("%v", event.Id),
+				Event: event.Message.EventType,
+				Data:  event.Message,
+			})
+			c.Writer.Flush()
+		}
+
+		for _, event := range missedEvents {
+			if event, ok := filter.readableEvent(event); ok {
+				sendEvent(event)
+			}
+		}
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(30 * time.Second)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-keepAlive.C:
+				_, err := c.Writer.Write([]byte(": keep-alive\n\n"))
+				if err != nil {
+					return
+				}
+				c.Writer.Flush()
+			case event, ok := <-events:
+				if !ok {
+					log.Printf("Closing slow event stream client [%v]", sessionUser.UserReferenceId)
+					return
+				}
+				if event, ok := filter.readableEvent(event); ok {
+					sendEvent(event)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func eventIds(events []BufferedEvent) string {
+	ids := make([]uint64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+	return fmt.Sprintf("%v", ids)
+}
+
+func TestEventStreamBufferResume(t *testing.T) {
+
+	buffer := NewEventStreamBuffer(3, &map[string]*olric.DTopic{})
+	for i := 0; i < 5; i++ {
+		buffer.Add(resource.EventMessage{ObjectType: "todo", EventType: "create"})
+	}
+
+	// the buffer wraps around and keeps the last three events in order
+	_, missed, _ := buffer.Subscribe(2)
+	if ids := eventIds(missed); ids != "[3 4 5]" {
+		t.Errorf("expected the events after 2, got %v", ids)
+	}
+	_, missed, _ = buffer.Subscribe(4)
+	if ids := eventIds(missed); ids != "[5]" {
+		t.Errorf("expected the events after 4, got %v", ids)
+	}
+	// event 1 was dropped from the buffer, the client resumes from the oldest retained event
+	_, missed, _ = buffer.Subscribe(1)
+	if ids := eventIds(missed); ids != "[3 4 5]" {
+		t.Errorf("expected the retained events after an evicted id, got %v", ids)
+	}
+	_, missed, _ = buffer.Subscribe(0)
+	if len(missed) != 0 {
+		t.Errorf("expected no replay without a last event id, got %v", eventIds(missed))
+	}
+
+	buffer.Add(resource.EventMessage{ObjectType: "todo", EventType: "update"})
+	_, missed, _ = buffer.Subscribe(5)
+	if ids := eventIds(missed); ids != "[6]" || missed[0].Message.EventType != "update" {
+		t.Errorf("expected the newest event after the buffer wrapped again, got %v", ids)
+	}
+}
+
+func TestEventStreamBufferSlowSubscriber(t *testing.T) {
+
+	buffer := NewEventStreamBuffer(10, &map[string]*olric.DTopic{})
+	slowId, _, slow := buffer.Subscribe(0)
+	fastId, _, fast := buffer.Subscribe(0)
+	defer buffer.Unsubscribe(fastId)
+
+	for i := 0; i < channelBufferSize+1; i++ {
+		buffer.Add(resource.EventMessage{ObjectType: "todo", EventType: "create"})
+		<-fast
+	}
+
+	received := 0
+	for range slow {
+		received += 1
+	}
+	if received != channelBufferSize {
+		t.Errorf("expected the slow subscriber to be closed after %v events, got %v", channelBufferSize, received)
+	}
+	if _, ok := buffer.subscribers[slowId]; ok {
+		t.Errorf("expected the slow subscriber to be removed")
+	}
+	if _, ok := buffer.subscribers[fastId]; !ok {
+		t.Errorf("expected the subscriber which keeps up to stay")
+	}
+	// unsubscribing a closed subscriber when its client goes away does nothing
+	buffer.Unsubscribe(slowId)
+}
+
+func TestEventStreamReadableEvent(t *testing.T) {
+
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// the cache is only created by the first resource of the server
+	olricCache := resource.OlricCache
+	resource.OlricCache = &olric.DMap{}
+	defer func() {
+		resource.OlricCache = olricCache
+	}()
+	cruds := make(map[string]*resource.DbResource)
+	cruds["todo"], err = resource.NewDbResource(api2go.NewApi2GoModel("todo", nil, 0, nil), db, &resource.MiddlewareSet{},
+		cruds, nil, nil, resource.TableInfo{
+			TableName:         "todo",
+			ColumnPermissions: map[string]auth.AuthPermission{"note": auth.None},
+		})
+	if err != nil {
+		t.Fatalf("failed to create resource: %v", err)
+	}
+
+	event := func(objectType string, eventType string) BufferedEvent {
+		return BufferedEvent{Id: 1, Message: resource.EventMessage{
+			ObjectType: objectType,
+			EventType:  eventType,
+			EventData: map[string]interface{}{
+				"__type":          objectType,
+				"reference_id":    "t1",
+				"user_account_id": "owner",
+				"permission":      int64(auth.UserRead),
+				"title":           "buy milk",
+				"note":            "secret",
+			},
+		}}
+	}
+
+	owner := eventStreamFilter{cruds: cruds, sessionUser: &auth.SessionUser{UserReferenceId: "owner"}}
+	readable, ok := owner.readableEvent(event("todo", "create"))
+	if !ok || readable.Message.EventData["title"] != "buy milk" {
+		t.Fatalf("expected the owner to read the event, got %v", readable.Message.EventData)
+	}
+	if _, ok := readable.Message.EventData["note"]; ok {
+		t.Errorf("expected the unreadable column to be left out")
+	}
+
+	other := eventStreamFilter{cruds: cruds, sessionUser: &auth.SessionUser{UserReferenceId: "other"}}
+	if _, ok := other.readableEvent(event("todo", "create")); ok {
+		t.Errorf("expected other users not to get the event of a row they cannot read")
+	}
+
+	scoped := eventStreamFilter{cruds: cruds, sessionUser: &auth.SessionUser{UserReferenceId: "owner",
+		ApiKeyScope: &auth.ApiKeyScope{Tables: []string{"note"}, Operations: []string{"read"}}}}
+	if _, ok := scoped.readableEvent(event("todo", "create")); ok {
+		t.Errorf("expected an api key without the todo scope not to get the event")
+	}
+
+	owner.topics = map[string]bool{"project": true}
+	if _, ok := owner.readableEvent(event("todo", "create")); ok {
+		t.Errorf("expected the events of other topics to be skipped")
+	}
+	owner.topics = map[string]bool{"todo": true}
+	owner.eventType = "delete"
+	if _, ok := owner.readableEvent(event("todo", "create")); ok {
+		t.Errorf("expected the events of other event types to be skipped")
+	}
+	if _, ok := owner.readableEvent(event("todo", "delete")); !ok {
+		t.Errorf("expected the selected events to be sent")
+	}
+}
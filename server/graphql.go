@@ -17,7 +17,6 @@ import (
 	"github.com/json-iterator/go"
 	//"fmt"
 	"fmt"
-	"github.com/artpar/api2go/jsonapi"
 )
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -61,55 +60,9 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 
 	rootFields := make(graphql.Fields)
 	mutationFields := make(graphql.Fields)
+	subscriptionFields := make(graphql.Fields)
 
-	actionResponseType := graphql.NewObject(graphql.ObjectConfig{
-		Name:        "ActionResponse",
-		Description: "Action response",
-		Fields: graphql.Fields{
-			"ResponseType": &graphql.Field{
-				Type: graphql.String,
-			},
-			"Attributes": &graphql.Field{
-				Type: graphql.NewObject(graphql.ObjectConfig{
-					Name: "Attributes",
-					Fields: graphql.Fields{
-						"type": &graphql.Field{
-							Name: "type",
-							Type: graphql.String,
-						},
-						"message": &graphql.Field{
-							Name: "message",
-							Type: graphql.String,
-						},
-						"key": &graphql.Field{
-							Name: "key",
-							Type: graphql.String,
-						},
-						"value": &graphql.Field{
-							Name: "value",
-							Type: graphql.String,
-						},
-						"token": &graphql.Field{
-							Name: "token",
-							Type: graphql.String,
-						},
-						"title": &graphql.Field{
-							Name: "title",
-							Type: graphql.String,
-						},
-						"delay": &graphql.Field{
-							Name: "delay",
-							Type: graphql.String,
-						},
-						"location": &graphql.Field{
-							Name: "location",
-							Type: graphql.String,
-						},
-					},
-				}),
-			},
-		},
-	})
+	actionResultType := MakeActionResultType()
 
 	pageConfig := graphql.ArgumentConfig{
 		Type: graphql.NewInputObject(graphql.InputObjectConfig{
@@ -180,20 +133,6 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 
 	}
 
-	tableColumnMap := map[string]map[string]api2go.ColumnInfo{}
-
-	for _, table := range cmsConfig.Tables {
-		if table.IsJoinTable {
-			continue
-		}
-		columnMap := map[string]api2go.ColumnInfo{}
-
-		for _, col := range table.Columns {
-			columnMap[col.ColumnName] = col
-		}
-		tableColumnMap[table.TableName] = columnMap
-	}
-
 	for _, table := range cmsConfig.Tables {
 
 		if len(table.TableName) < 1 {
@@ -247,6 +186,15 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 			}
 		}
 
+		// virtual columns are read only, they are not part of the mutation inputs
+		for _, virtualColumn := range table.VirtualColumns {
+			column := virtualColumn.ColumnInfo()
+			fields[column.ColumnName] = &graphql.Field{
+				Type:        resource.ColumnManager.GetGraphqlType(column.ColumnType),
+				Description: column.ColumnDescription,
+			}
+		}
+
 		for _, relation := range table.Relations {
 
 			targetName := relation.GetSubjectName()
@@ -256,32 +204,72 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 				targetObject = relation.GetObject()
 			}
 
+			targetType, ok := inputTypesMap[targetObject]
+			if !ok {
+				log.Errorf("[247] target object has no proper input type: %v", targetObject)
+				continue
+			}
+
+			relationArgs := graphql.FieldConfigArgument{
+				"filter": &filterArgument,
+				"query":  &queryArgument,
+			}
+
+			// the reverse side of a belongs_to/has_one is a list of the subjects pointing to this row
+			isList := !isForwardRelation(relation, table.TableName)
+
 			switch relation.Relation {
 			case "belongs_to":
-				fields[targetName] = &graphql.Field{
-					Type:        graphql.NewNonNull(inputTypesMap[targetObject]),
-					Description: fmt.Sprintf("Belongs to %v", relation.Subject),
+				if isList {
+					relationArgs["page"] = &pageConfig
+					fields[targetName] = &graphql.Field{
+						Type:        graphql.NewList(targetType),
+						Description: fmt.Sprintf("%v belonging to this %v", relation.Subject, table.TableName),
+						Args:        relationArgs,
+						Resolve:     RelationFieldResolver(resources, table.TableName, targetName, relation, targetObject, isList),
+					}
+				} else {
+					fields[targetName] = &graphql.Field{
+						Type:        targetType,
+						Description: fmt.Sprintf("Belongs to %v", relation.Object),
+						Args:        relationArgs,
+						Resolve:     RelationFieldResolver(resources, table.TableName, targetName, relation, targetObject, isList),
+					}
 				}
 			case "has_one":
-				fields[targetName] = &graphql.Field{
-					Type:        inputTypesMap[targetObject],
-					Description: fmt.Sprintf("Has one %v", relation.Subject),
+				if isList {
+					relationArgs["page"] = &pageConfig
+					fields[targetName] = &graphql.Field{
+						Type:        graphql.NewList(targetType),
+						Description: fmt.Sprintf("%v having this %v", relation.Subject, table.TableName),
+						Args:        relationArgs,
+						Resolve:     RelationFieldResolver(resources, table.TableName, targetName, relation, targetObject, isList),
+					}
+				} else {
+					fields[targetName] = &graphql.Field{
+						Type:        targetType,
+						Description: fmt.Sprintf("Has one %v", relation.Object),
+						Args:        relationArgs,
+						Resolve:     RelationFieldResolver(resources, table.TableName, targetName, relation, targetObject, isList),
+					}
 				}
 
 			case "has_many":
-				listType, ok := inputTypesMap[targetObject]
-				if !ok {
-					log.Errorf("[247] target object has no proper input type: %v", targetObject)
-				}
+				relationArgs["page"] = &pageConfig
 				fields[targetName] = &graphql.Field{
-					Type:        graphql.NewList(listType),
-					Description: fmt.Sprintf("Has many %v", relation.Subject),
+					Type:        graphql.NewList(targetType),
+					Description: fmt.Sprintf("Has many %v", targetObject),
+					Args:        relationArgs,
+					Resolve:     RelationFieldResolver(resources, table.TableName, targetName, relation, targetObject, isList),
 				}
 
 			case "has_many_and_belongs_to_many":
+				relationArgs["page"] = &pageConfig
 				fields[targetName] = &graphql.Field{
-					Type:        graphql.NewList(inputTypesMap[targetObject]),
-					Description: fmt.Sprintf("Related %v", relation.Subject),
+					Type:        graphql.NewList(targetType),
+					Description: fmt.Sprintf("Related %v", targetObject),
+					Args:        relationArgs,
+					Resolve:     RelationFieldResolver(resources, table.TableName, targetName, relation, targetObject, isList),
 				}
 
 			}
@@ -313,23 +301,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 
 					//log.Printf("Arguments: %v", params.Args)
 
-					filters := make([]resource.Query, 0)
-
-					query, isQueried := params.Args["query"]
-					if isQueried {
-						queryMap, ok := query.([]interface{})
-						if ok {
-							for _, qu := range queryMap {
-								q := qu.(map[string]interface{})
-								query := resource.Query{
-									ColumnName: q["column"].(string),
-									Operator:   q["operator"].(string),
-									Value:      q["value"].(string),
-								}
-								filters = append(filters, query)
-							}
-						}
-					}
+					filters := graphqlQueryArguments(params.Args)
 
 					filter, isFiltered := params.Args["filter"]
 
@@ -342,34 +314,18 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 					}
 					pr = pr.WithContext(params.Context)
 
-					pageNumber := 1
-					pageSize := 10
-					pageParams, ok := params.Args["page"]
-					if ok {
-						pageParamsMap, ok := pageParams.(map[string]interface{})
-						if ok {
-							pageSizeNew, ok := pageParamsMap["size"]
-							if ok {
-								pageSize, ok = pageSizeNew.(int)
-							}
-							pageNumberNew, ok := pageParamsMap["number"]
-							if ok {
-								pageNumber, ok = pageNumberNew.(int)
-							}
-						}
-
-					}
+					pageNumber, pageSize, _ := graphqlPageArguments(params.Args)
 
+					// related objects are not included here, the relation fields load them in batches
 					jsStr, err := json.Marshal(filters)
 					req := api2go.Request{
 						PlainRequest: pr,
 
 						QueryParams: map[string][]string{
-							"query":              {string(jsStr)},
-							"filter":             {filter.(string)},
-							"page[number]":       {fmt.Sprintf("%v", pageNumber)},
-							"page[size]":         {fmt.Sprintf("%v", pageSize)},
-							"included_relations": {"*"},
+							"query":        {string(jsStr)},
+							"filter":       {filter.(string)},
+							"page[number]": {fmt.Sprintf("%v", pageNumber)},
+							"page[size]":   {fmt.Sprintf("%v", pageSize)},
 						},
 					}
 
@@ -386,42 +342,10 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 
 					}
 
-					columnMap := tableColumnMap[table.TableName]
-
 					for _, r := range results {
-
-						included := r.Includes
-						includedMap := make(map[string]jsonapi.MarshalIdentifier)
-
-						for _, included := range included {
-							includedMap[included.GetID()] = included
-						}
-
 						data := r.Data
-
-						for key, val := range data {
-							colInfo, ok := columnMap[key]
-							if !ok {
-								continue
-							}
-
-							strVal, ok := val.(string)
-							if !ok {
-								continue
-							}
-
-							if colInfo.IsForeignKey {
-								fObj, ok := includedMap[strVal]
-
-								if ok {
-									data[key] = fObj.GetAttributes()
-								}
-							}
-
-						}
-
+						data["id"] = data["reference_id"]
 						items = append(items, data)
-
 					}
 
 					return items, err
@@ -551,6 +475,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 					aggReq := resource.AggregationRequest{}
 
 					aggReq.RootEntity = table.TableName
+					aggReq.User = sessionUser
 
 					if params.Args["group"] != nil {
 						groupBys := params.Args["group"].([]interface{})
@@ -607,9 +532,12 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 						return nil, err
 					}
 					aggResponse, err := resources[table.TableName].DataStats(aggReq, transaction)
-					transaction.Rollback()
+					resource.RollbackTransaction(transaction)
+					if err != nil {
+						return nil, err
+					}
 
-					return aggResponse.Data, err
+					return aggResponse.Data, nil
 				}
 			}(table),
 		}
@@ -734,14 +662,14 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 
 					transaction, err := resources[table.TableName].Connection.Beginx()
 					if err != nil {
-						transaction.Rollback()
+						resource.RollbackTransaction(transaction)
 						return nil, err
 					}
 
 
 					existingObj, _, err := resources[table.TableName].GetSingleRowByReferenceIdWithTransaction(table.TableName, referenceId, nil, transaction)
 					if err != nil {
-						transaction.Rollback()
+						resource.RollbackTransaction(transaction)
 						return nil, err
 					}
 
@@ -781,12 +709,12 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 					created, err := resources[table.TableName].UpdateWithTransaction(obj, req, transaction)
 
 					if err != nil {
-						rollbackErr := transaction.Rollback()
+						rollbackErr := resource.RollbackTransaction(transaction)
 						auth.CheckErr(rollbackErr, "Failed to rollback")
 						log.Printf("Failed to update resource: %v", err)
 						return nil, err
 					}
-					err = transaction.Commit()
+					err = resource.CommitTransaction(transaction)
 
 					return created.Result().(api2go.Api2GoModel).Data, err
 				},
@@ -828,69 +756,27 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 				},
 			}
 
-		}(t)
-
-	}
-
-	for _, a := range cmsConfig.Actions {
-
-		func(action resource.Action) {
-
-			inputFields := make(graphql.FieldConfigArgument)
-
-			for _, col := range action.InFields {
-
-				var finalGraphqlType graphql.Type
-				finalGraphqlType = resource.ColumnManager.GetGraphqlType(col.ColumnType)
-
-				if !col.IsNullable {
-					finalGraphqlType = graphql.NewNonNull(finalGraphqlType)
-				}
-
-				inputFields[col.ColumnName] = &graphql.ArgumentConfig{
-					Type:         finalGraphqlType,
-					Description:  col.ColumnDescription,
-					DefaultValue: col.DefaultValue,
+			for _, eventType := range []string{"create", "update", "delete"} {
+				subscriptionFields[SubscriptionFieldName(table.TableName, eventType)] = &graphql.Field{
+					Type:        inputTypesMap[table.TableName],
+					Description: fmt.Sprintf("Subscribe to %v events of %v", eventType, strings.ReplaceAll(table.TableName, "_", " ")),
+					Args: graphql.FieldConfigArgument{
+						"reference_id": &graphql.ArgumentConfig{
+							Type:        graphql.String,
+							Description: "Only events of this resource",
+						},
+						"query": &queryArgument,
+					},
+					Resolve: SubscriptionFieldResolver(table.TableName, eventType),
 				}
-
 			}
 
-			//if !action.InstanceOptional {
-			//	inputFields[action.OnType+"_id"] = &graphql.ArgumentConfig{
-			//		Type:        graphql.NewNonNull(graphql.String),
-			//		Description: "reference id of subject " + action.OnType,
-			//	}
-			//}
-
-			mutationFields["execute"+strcase.ToCamel(action.Name)+"On"+strcase.ToCamel(action.OnType)] = &graphql.Field{
-				Type:        graphql.NewList(actionResponseType),
-				Description: "Execute " + strings.ReplaceAll(action.Name, "_", " ") + " on " + action.OnType,
-				Args:        inputFields,
-				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-
-					pr := &http.Request{
-						Method: "EXECUTE",
-					}
-
-					pr = pr.WithContext(params.Context)
-
-					req := api2go.Request{
-						PlainRequest: pr,
-					}
-
-					actionRequest := resource.ActionRequest{
-						Type:       action.OnType,
-						Action:     action.Name,
-						Attributes: params.Args,
-					}
-
-					response, err := resources[action.OnType].HandleActionRequest(actionRequest, req)
+		}(t)
 
-					return response, err
-				},
-			}
-		}(a)
+	}
 
+	for _, action := range cmsConfig.Actions {
+		mutationFields[ActionMutationName(action)] = ActionMutationField(action, resources, actionResultType)
 	}
 
 	//changeTodoStatusMutation := relay.MutationWithClientMutationID(relay.MutationConfig{
@@ -1067,10 +953,16 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 		Fields: mutationFields,
 	})
 
+	subscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Subscription",
+		Fields: subscriptionFields,
+	})
+
 	var err error
 	Schema, err = graphql.NewSchema(graphql.SchemaConfig{
-		Query:    rootQuery,
-		Mutation: mutationType,
+		Query:        rootQuery,
+		Mutation:     mutationType,
+		Subscription: subscriptionType,
 	})
 	if err != nil {
 		log.Errorf("Failed to generate graphql schema: %v", err)
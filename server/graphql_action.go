@@ -0,0 +1,387 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/iancoleman/strcase"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const graphqlMaxUploadMemory = 32 << 20
+
+// UploadScalar is the type of file fields in action inputs. Files are sent as a multipart request
+// (https://github.com/jaydenseric/graphql-multipart-request-spec), a data url string is also accepted
+var UploadScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Upload",
+	Description: "A file sent as a part of a multipart request, or a data url",
+	Serialize: func(value interface{}) interface{} {
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		switch upload := value.(type) {
+		case map[string]interface{}:
+			return upload
+		case string:
+			return dataUrlUpload(upload)
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		if value, ok := valueAST.(*ast.StringValue); ok {
+			return dataUrlUpload(value.Value)
+		}
+		return nil
+	},
+})
+
+func dataUrlUpload(dataUrl string) map[string]interface{} {
+	contentType := ""
+	if strings.HasPrefix(dataUrl, "data:") {
+		contentType = strings.Split(strings.TrimPrefix(dataUrl, "data:"), ";")[0]
+	}
+	return map[string]interface{}{
+		"name": "upload",
+		"file": dataUrl,
+		"type": contentType,
+	}
+}
+
+// uploadValue is the value of a file column as expected by the actions, same as sent by the dashboard
+func uploadValue(name string, contentType string, contents []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"name": name,
+		"file": "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(contents),
+		"type": contentType,
+		"size": len(contents),
+	}
+}
+
+// setOperationsPath sets the value at a path like "variables.input.file.0" in the decoded operations
+func setOperationsPath(operations interface{}, path string, value interface{}) error {
+	parts := strings.Split(path, ".")
+	current := operations
+	for i, part := range parts {
+		last := i == len(parts)-1
+		switch node := current.(type) {
+		case map[string]interface{}:
+			if last {
+				node[part] = value
+				return nil
+			}
+			current = node[part]
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return fmt.Errorf("invalid index [%v] in path [%v]", part, path)
+			}
+			if last {
+				node[index] = value
+				return nil
+			}
+			current = node[index]
+		default:
+			return fmt.Errorf("invalid path [%v]", path)
+		}
+	}
+	return fmt.Errorf("invalid path [%v]", path)
+}
+
+func isGraphqlMultipartRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+// rewriteGraphqlMultipartRequest replaces a multipart request by the json request in the operations
+// field, with the files from the map field set as upload values in the variables
+func rewriteGraphqlMultipartRequest(r *http.Request) error {
+
+	err := r.ParseMultipartForm(graphqlMaxUploadMemory)
+	if err != nil {
+		return err
+	}
+
+	var operations interface{}
+	err = json.Unmarshal([]byte(r.FormValue("operations")), &operations)
+	if err != nil {
+		return errors.New("invalid operations field in multipart request")
+	}
+
+	fileMap := make(map[string][]string)
+	if mapValue := r.FormValue("map"); mapValue != "" {
+		err = json.Unmarshal([]byte(mapValue), &fileMap)
+		if err != nil {
+			return errors.New("invalid map field in multipart request")
+		}
+	}
+
+	for fileKey, paths := range fileMap {
+		file, header, err := r.FormFile(fileKey)
+		if err != nil {
+			return fmt.Errorf("missing file [%v] in multipart request", fileKey)
+		}
+		contents, err := ioutil.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return err
+		}
+		contentType := header.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = http.DetectContentType(contents)
+		}
+		for _, path := range paths {
+			err = setOperationsPath(operations, path, uploadValue(header.Filename, contentType, contents))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	body, err := json.Marshal(operations)
+	if err != nil {
+		return err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// ActionResultTypeNames maps the response types of actions to the members of the ActionResult union
+// responses of any other type (eg rows created by the action) are returned as ActionData
+var ActionResultTypeNames = map[string]string{
+	"client.notify":        "ClientNotify",
+	"client.redirect":      "ClientRedirect",
+	"client.file.download": "ClientFileDownload",
+	"client.store.set":     "ClientStoreSet",
+	"client.cookie.set":    "ClientCookieSet",
+	"error":                "ActionError",
+}
+
+func actionResultObject(name string, description string, fields graphql.Fields) *graphql.Object {
+	fields["ResponseType"] = &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.String),
+		Description: "type of the response, eg client.notify",
+	}
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        name,
+		Description: description,
+		Fields:      fields,
+	})
+}
+
+// MakeActionResultType creates the union of the action response types
+func MakeActionResultType() *graphql.Union {
+
+	members := map[string]*graphql.Object{
+		"ClientNotify": actionResultObject("ClientNotify", "Show a notification to the user", graphql.Fields{
+			"type":    &graphql.Field{Type: graphql.String},
+			"title":   &graphql.Field{Type: graphql.String},
+			"message": &graphql.Field{Type: graphql.String},
+		}),
+		"ClientRedirect": actionResultObject("ClientRedirect", "Redirect the user to a location", graphql.Fields{
+			"location": &graphql.Field{Type: graphql.String},
+			"window":   &graphql.Field{Type: graphql.String},
+			"delay":    &graphql.Field{Type: graphql.Int},
+		}),
+		"ClientFileDownload": actionResultObject("ClientFileDownload", "A file for the user to download", graphql.Fields{
+			"name":        &graphql.Field{Type: graphql.String},
+			"content":     &graphql.Field{Type: graphql.String, Description: "base64 encoded contents"},
+			"contentType": &graphql.Field{Type: graphql.String},
+			"message":     &graphql.Field{Type: graphql.String},
+		}),
+		"ClientStoreSet": actionResultObject("ClientStoreSet", "Store a value on the client, eg the token", graphql.Fields{
+			"key":   &graphql.Field{Type: graphql.String},
+			"value": &graphql.Field{Type: graphql.String},
+		}),
+		"ClientCookieSet": actionResultObject("ClientCookieSet", "Set a cookie on the client", graphql.Fields{
+			"key":   &graphql.Field{Type: graphql.String},
+			"value": &graphql.Field{Type: graphql.String},
+		}),
+		"ActionError": actionResultObject("ActionError", "An error from an outcome of the action", graphql.Fields{
+			"message": &graphql.Field{Type: graphql.String},
+		}),
+		"ActionData": actionResultObject("ActionData", "Any other response, eg a row created by the action", graphql.Fields{
+			"data": &graphql.Field{Type: graphql.String, Description: "json encoded attributes of the response"},
+		}),
+	}
+
+	types := make([]*graphql.Object, 0, len(members))
+	for _, member := range members {
+		types = append(types, member)
+	}
+
+	return graphql.NewUnion(graphql.UnionConfig{
+		Name:        "ActionResult",
+		Description: "A response of an action",
+		Types:       types,
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			result, _ := p.Value.(map[string]interface{})
+			responseType, _ := result["ResponseType"].(string)
+			if name, ok := ActionResultTypeNames[responseType]; ok {
+				return members[name]
+			}
+			return members["ActionData"]
+		},
+	})
+}
+
+// ActionResponsesToGraphql flattens the attributes of the responses into the result objects of the union
+func ActionResponsesToGraphql(responses []resource.ActionResponse) []map[string]interface{} {
+	results := make([]map[string]interface{}, 0, len(responses))
+	for _, response := range responses {
+		result := map[string]interface{}{
+			"ResponseType": response.ResponseType,
+		}
+
+		if _, isKnown := ActionResultTypeNames[response.ResponseType]; !isKnown {
+			data, err := json.Marshal(response.Attributes)
+			resource.CheckErr(err, "Failed to marshal action response [%v]", response.ResponseType)
+			result["data"] = string(data)
+			results = append(results, result)
+			continue
+		}
+
+		switch attributes := response.Attributes.(type) {
+		case map[string]interface{}:
+			for key, value := range attributes {
+				result[key] = value
+			}
+		case map[string]string:
+			for key, value := range attributes {
+				result[key] = value
+			}
+		case api2go.Api2GoModel:
+			for key, value := range attributes.Data {
+				result[key] = value
+			}
+		default:
+			result["message"] = fmt.Sprintf("%v", attributes)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func isFileColumnType(columnType string) bool {
+	return strings.Split(columnType, ".")[0] == "file"
+}
+
+// ActionInputType creates the input object of an action from its in fields, nil when the action has none
+func ActionInputType(action resource.Action) *graphql.InputObject {
+
+	if len(action.InFields) == 0 {
+		return nil
+	}
+
+	fields := make(graphql.InputObjectConfigFieldMap)
+	for _, col := range action.InFields {
+
+		var fieldType graphql.Input
+		if isFileColumnType(col.ColumnType) {
+			fieldType = graphql.NewList(UploadScalar)
+		} else {
+			fieldType = resource.ColumnManager.GetGraphqlType(col.ColumnType)
+		}
+
+		// fields with a default value are optional, the same as GetValidatedInFields
+		if !col.IsNullable && col.DefaultValue == "" {
+			fieldType = graphql.NewNonNull(fieldType)
+		}
+
+		fields[col.ColumnName] = &graphql.InputObjectFieldConfig{
+			Type:        fieldType,
+			Description: col.ColumnDescription,
+		}
+	}
+
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        ActionMutationName(action)[len("execute"):] + "Input",
+		Description: "Input of " + strings.ReplaceAll(action.Name, "_", " ") + " on " + action.OnType,
+		Fields:      fields,
+	})
+}
+
+func ActionMutationName(action resource.Action) string {
+	return "execute" + strcase.ToCamel(action.Name) + "On" + strcase.ToCamel(action.OnType)
+}
+
+// ActionMutationField is the mutation executing the action, the subject is passed as <on type>_id
+func ActionMutationField(action resource.Action, resources map[string]*resource.DbResource,
+	actionResultType *graphql.Union) *graphql.Field {
+
+	args := make(graphql.FieldConfigArgument)
+	inputType := ActionInputType(action)
+	if inputType != nil {
+		var argType graphql.Input = inputType
+		for _, col := range action.InFields {
+			if !col.IsNullable && col.DefaultValue == "" {
+				argType = graphql.NewNonNull(inputType)
+				break
+			}
+		}
+		args["input"] = &graphql.ArgumentConfig{
+			Type:        argType,
+			Description: "values for the in fields of the action",
+		}
+	}
+
+	subjectArgument := action.OnType + "_id"
+	if action.InstanceOptional {
+		args[subjectArgument] = &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "reference id of the " + action.OnType + " to execute the action on",
+		}
+	} else {
+		args[subjectArgument] = &graphql.ArgumentConfig{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "reference id of the " + action.OnType + " to execute the action on",
+		}
+	}
+
+	return &graphql.Field{
+		Type:        graphql.NewList(actionResultType),
+		Description: "Execute " + strings.ReplaceAll(action.Name, "_", " ") + " on " + action.OnType,
+		Args:        args,
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+
+			attributes := make(map[string]interface{})
+			if input, ok := params.Args["input"].(map[string]interface{}); ok {
+				for key, value := range input {
+					attributes[key] = value
+				}
+			}
+			if subjectId, ok := params.Args[subjectArgument].(string); ok && subjectId != "" {
+				attributes[subjectArgument] = subjectId
+			}
+
+			pr := &http.Request{
+				Method: "EXECUTE",
+			}
+			pr = pr.WithContext(params.Context)
+
+			req := api2go.Request{
+				PlainRequest: pr,
+			}
+
+			actionRequest := resource.ActionRequest{
+				Type:       action.OnType,
+				Action:     action.Name,
+				Attributes: attributes,
+			}
+
+			responses, err := resources[action.OnType].HandleActionRequest(actionRequest, req)
+			if err != nil {
+				return nil, err
+			}
+			return ActionResponsesToGraphql(responses), nil
+		},
+	}
+}
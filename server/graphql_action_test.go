@@ -0,0 +1,52 @@
+//+build test
+
+package server
+
+import (
+	"github.com/daptin/daptin/server/resource"
+	"testing"
+)
+
+func TestSetOperationsPath(t *testing.T) {
+
+	var operations interface{}
+	err := json.Unmarshal([]byte(`{"query": "", "variables": {"input": {"dump_file": [null, null]}}}`), &operations)
+	if err != nil {
+		t.Fatalf("failed to parse operations: %v", err)
+	}
+
+	err = setOperationsPath(operations, "variables.input.dump_file.1", "uploaded")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	files := operations.(map[string]interface{})["variables"].(map[string]interface{})["input"].(map[string]interface{})["dump_file"].([]interface{})
+	if files[1] != "uploaded" || files[0] != nil {
+		t.Errorf("upload not set at the path: %v", files)
+	}
+
+	if setOperationsPath(operations, "variables.input.dump_file.5", "uploaded") == nil {
+		t.Errorf("expected an error for an index out of range")
+	}
+}
+
+func TestActionResponsesToGraphql(t *testing.T) {
+
+	results := ActionResponsesToGraphql([]resource.ActionResponse{
+		resource.NewActionResponse("client.notify", resource.NewClientNotification("success", "Done", "Success")),
+		resource.NewActionResponse("client.redirect", map[string]interface{}{"location": "/", "delay": 2000}),
+		resource.NewActionResponse("user_account", map[string]interface{}{"name": "test"}),
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %v", results)
+	}
+	if results[0]["ResponseType"] != "client.notify" || results[0]["message"] != "Done" {
+		t.Errorf("unexpected notify result: %v", results[0])
+	}
+	if results[1]["location"] != "/" || results[1]["delay"] != 2000 {
+		t.Errorf("unexpected redirect result: %v", results[1])
+	}
+	if results[2]["data"] != `{"name":"test"}` {
+		t.Errorf("unexpected data result: %v", results[2])
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"strconv"
+	"strings"
+)
+
+// lists without a page argument are counted with these sizes, the root queries use the
+// default page size, relation lists return all the related rows
+const graphqlDefaultPageSize = 10
+const graphqlUnpagedListSize = 100
+
+// GraphqlQueryLimits bound the queries accepted on the graphql endpoint, a zero value disables the limit
+type GraphqlQueryLimits struct {
+	MaxDepth int
+	MaxCost  int
+}
+
+// the cost of a field is 1 plus the cost of its selections, times the page size for lists
+// scalar fields are free, so the cost is roughly the number of objects a query can load
+type graphqlQueryAnalysis struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// AnalyzeGraphqlQuery returns the depth and the cost of the operation in the document
+func AnalyzeGraphqlQuery(schema *graphql.Schema, document *ast.Document, operationName string,
+	variables map[string]interface{}) (int, int, error) {
+
+	analysis := graphqlQueryAnalysis{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			analysis.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				if operation != nil && operationName == "" {
+					return 0, 0, fmt.Errorf("must provide operation name if query contains multiple operations")
+				}
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return 0, 0, fmt.Errorf("unknown operation [%v]", operationName)
+	}
+
+	var rootType *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeMutation:
+		rootType = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		rootType = schema.SubscriptionType()
+	default:
+		rootType = schema.QueryType()
+	}
+	if rootType == nil {
+		return 0, 0, fmt.Errorf("schema does not support [%v]", operation.Operation)
+	}
+
+	depth, cost := analysis.selectionSet(rootType, operation.SelectionSet, map[string]bool{})
+	return depth, cost, nil
+}
+
+// Check rejects the operation when it is deeper or costs more than allowed
+func (limits GraphqlQueryLimits) Check(schema *graphql.Schema, document *ast.Document, operationName string,
+	variables map[string]interface{}) error {
+
+	if limits.MaxDepth < 1 && limits.MaxCost < 1 {
+		return nil
+	}
+
+	depth, cost, err := AnalyzeGraphqlQuery(schema, document, operationName, variables)
+	if err != nil {
+		return err
+	}
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return fmt.Errorf("query depth %d is more than the allowed depth %d", depth, limits.MaxDepth)
+	}
+	if limits.MaxCost > 0 && cost > limits.MaxCost {
+		return fmt.Errorf("query cost %d is more than the allowed cost %d, use smaller pages", cost, limits.MaxCost)
+	}
+	return nil
+}
+
+func (analysis graphqlQueryAnalysis) selectionSet(parentType graphql.Type, selectionSet *ast.SelectionSet,
+	visitedFragments map[string]bool) (int, int) {
+
+	if selectionSet == nil {
+		return 0, 0
+	}
+
+	maxDepth := 0
+	totalCost := 0
+	for _, selection := range selectionSet.Selections {
+		depth, cost := 0, 0
+		switch selection := selection.(type) {
+		case *ast.Field:
+			depth, cost = analysis.field(parentType, selection, visitedFragments)
+		case *ast.InlineFragment:
+			fragmentType := parentType
+			if selection.TypeCondition != nil {
+				fragmentType = analysis.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			depth, cost = analysis.selectionSet(fragmentType, selection.SelectionSet, visitedFragments)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := analysis.fragments[name]
+			if !ok || visitedFragments[name] {
+				continue
+			}
+			visitedFragments[name] = true
+			depth, cost = analysis.selectionSet(analysis.schema.Type(fragment.TypeCondition.Name.Value),
+				fragment.SelectionSet, visitedFragments)
+			delete(visitedFragments, name)
+		}
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		totalCost += cost
+	}
+	return maxDepth, totalCost
+}
+
+func (analysis graphqlQueryAnalysis) field(parentType graphql.Type, field *ast.Field,
+	visitedFragments map[string]bool) (int, int) {
+
+	// introspection is not counted
+	if strings.HasPrefix(field.Name.Value, "__") {
+		return 0, 0
+	}
+
+	var fieldDefinition *graphql.FieldDefinition
+	switch parent := parentType.(type) {
+	case *graphql.Object:
+		fieldDefinition = parent.Fields()[field.Name.Value]
+	case *graphql.Interface:
+		fieldDefinition = parent.Fields()[field.Name.Value]
+	}
+	if fieldDefinition == nil {
+		return 1, 0
+	}
+
+	if field.SelectionSet == nil {
+		return 1, 0
+	}
+
+	namedType, _ := graphql.GetNamed(fieldDefinition.Type).(graphql.Type)
+	childDepth, childCost := analysis.selectionSet(namedType, field.SelectionSet, visitedFragments)
+	cost := 1 + childCost
+
+	fieldType := fieldDefinition.Type
+	if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+		fieldType = nonNull.OfType
+	}
+	if _, isList := fieldType.(*graphql.List); isList {
+		cost = cost * analysis.listSize(parentType, field)
+	}
+
+	return childDepth + 1, cost
+}
+
+// listSize is the page size asked for in the page argument of the field
+func (analysis graphqlQueryAnalysis) listSize(parentType graphql.Type, field *ast.Field) int {
+
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "page" {
+			continue
+		}
+		var size interface{}
+		switch value := argument.Value.(type) {
+		case *ast.ObjectValue:
+			for _, objectField := range value.Fields {
+				if objectField.Name.Value == "size" {
+					size = analysis.value(objectField.Value)
+				}
+			}
+		case *ast.Variable:
+			if page, ok := analysis.variables[value.Name.Value].(map[string]interface{}); ok {
+				size = page["size"]
+			}
+		}
+		if pageSize := intValue(size); pageSize > 0 {
+			return pageSize
+		}
+		return graphqlDefaultPageSize
+	}
+
+	if parentType == analysis.schema.QueryType() {
+		return graphqlDefaultPageSize
+	}
+	return graphqlUnpagedListSize
+}
+
+func (analysis graphqlQueryAnalysis) value(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.Variable:
+		return analysis.variables[value.Name.Value]
+	case *ast.IntValue:
+		return value.Value
+	}
+	return nil
+}
+
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		i, _ := strconv.Atoi(v)
+		return i
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+//+build test
+
+package server
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"testing"
+)
+
+func limitsTestSchema(t *testing.T) *graphql.Schema {
+
+	pageArgument := &graphql.ArgumentConfig{
+		Type: graphql.NewInputObject(graphql.InputObjectConfig{
+			Name: "page",
+			Fields: graphql.InputObjectConfigFieldMap{
+				"size": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			},
+		}),
+	}
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "user",
+		Fields: graphql.Fields{"name": &graphql.Field{Type: graphql.String}},
+	})
+	postType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "post",
+		Fields: graphql.Fields{
+			"title":  &graphql.Field{Type: graphql.String},
+			"author": &graphql.Field{Type: userType},
+		},
+	})
+	postType.AddFieldConfig("comments", &graphql.Field{
+		Type: graphql.NewList(postType),
+		Args: graphql.FieldConfigArgument{"page": pageArgument},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"post": &graphql.Field{
+					Type: graphql.NewList(postType),
+					Args: graphql.FieldConfigArgument{"page": pageArgument},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	return &schema
+}
+
+func TestAnalyzeGraphqlQuery(t *testing.T) {
+
+	schema := limitsTestSchema(t)
+
+	cases := []struct {
+		query     string
+		variables map[string]interface{}
+		depth     int
+		cost      int
+	}{
+		{`{ post { title } }`, nil, 2, 10},
+		{`{ post(page: {size: 100}) { title author { name } } }`, nil, 3, 200},
+		{`query($page: page) { post(page: $page) { ...postFields } } fragment postFields on post { comments(page: {size: 5}) { title } }`,
+			map[string]interface{}{"page": map[string]interface{}{"size": 2.0}}, 3, 12},
+		{`{ post(page: {size: 1}) { comments { title } __typename } }`, nil, 3, 101},
+	}
+
+	for _, c := range cases {
+		document, err := parser.Parse(parser.ParseParams{Source: c.query})
+		if err != nil {
+			t.Fatalf("failed to parse [%v]: %v", c.query, err)
+		}
+		depth, cost, err := AnalyzeGraphqlQuery(schema, document, "", c.variables)
+		if err != nil {
+			t.Errorf("unexpected error for [%v]: %v", c.query, err)
+		}
+		if depth != c.depth || cost != c.cost {
+			t.Errorf("expected depth %d cost %d for [%v], got %d %d", c.depth, c.cost, c.query, depth, cost)
+		}
+	}
+
+	document, _ := parser.Parse(parser.ParseParams{Source: `{ post(page: {size: 1000}) { title } }`})
+	err := GraphqlQueryLimits{MaxDepth: 5, MaxCost: 500}.Check(schema, document, "", nil)
+	if err == nil {
+		t.Errorf("expected the query to be over the budget")
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/graphql-go/graphql"
+	"golang.org/x/net/context"
+	"sync"
+)
+
+type graphqlRelationLoaderKey struct{}
+
+// relationBatch collects the parent keys of one relation field, the batch is loaded
+// with a single query when the first of its thunks is called
+type relationBatch struct {
+	keys    []string
+	seen    map[string]bool
+	loaded  bool
+	results map[string][]map[string]interface{}
+	err     error
+}
+
+// GraphqlRelationLoader batches the relation lookups of a graphql request (dataloader style)
+// graphql-go calls the thunks returned by the resolvers only after all the fields at the same
+// depth are resolved, so the related rows of every parent row in a list are loaded together
+type GraphqlRelationLoader struct {
+	lock    sync.Mutex
+	batches map[string]*relationBatch
+}
+
+func NewGraphqlRelationLoader() *GraphqlRelationLoader {
+	return &GraphqlRelationLoader{
+		batches: make(map[string]*relationBatch),
+	}
+}
+
+// WithGraphqlRelationLoader returns a context carrying a new loader, to be used for one graphql request
+func WithGraphqlRelationLoader(ctx context.Context) context.Context {
+	return context.WithValue(ctx, graphqlRelationLoaderKey{}, NewGraphqlRelationLoader())
+}
+
+func graphqlRelationLoaderFromContext(ctx context.Context) *GraphqlRelationLoader {
+	if ctx != nil {
+		if loader, ok := ctx.Value(graphqlRelationLoaderKey{}).(*GraphqlRelationLoader); ok {
+			return loader
+		}
+	}
+	// no batching outside of a request, eg when executing subscription events
+	return NewGraphqlRelationLoader()
+}
+
+// Load adds the parent key to the pending batch of batchKey and returns a thunk for its rows
+func (loader *GraphqlRelationLoader) Load(batchKey string, parentKey string,
+	fetch func(keys []string) (map[string][]map[string]interface{}, error)) func() ([]map[string]interface{}, error) {
+
+	loader.lock.Lock()
+	batch, ok := loader.batches[batchKey]
+	if !ok {
+		batch = &relationBatch{
+			seen: make(map[string]bool),
+		}
+		loader.batches[batchKey] = batch
+	}
+	if !batch.seen[parentKey] {
+		batch.seen[parentKey] = true
+		batch.keys = append(batch.keys, parentKey)
+	}
+	loader.lock.Unlock()
+
+	return func() ([]map[string]interface{}, error) {
+		loader.lock.Lock()
+		defer loader.lock.Unlock()
+		if !batch.loaded {
+			// keys added after this point start a new batch
+			if loader.batches[batchKey] == batch {
+				delete(loader.batches, batchKey)
+			}
+			batch.results, batch.err = fetch(batch.keys)
+			batch.loaded = true
+		}
+		return batch.results[parentKey], batch.err
+	}
+}
+
+func graphqlQueryArguments(args map[string]interface{}) []resource.Query {
+	filters := make([]resource.Query, 0)
+	queryList, ok := args["query"].([]interface{})
+	if !ok {
+		return filters
+	}
+	for _, qu := range queryList {
+		q, ok := qu.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		column, _ := q["column"].(string)
+		operator, _ := q["operator"].(string)
+		value, _ := q["value"].(string)
+		filters = append(filters, resource.Query{
+			ColumnName: column,
+			Operator:   operator,
+			Value:      value,
+		})
+	}
+	return filters
+}
+
+// graphqlPageArguments returns the page number and size, hasPage is false when no page was asked for
+func graphqlPageArguments(args map[string]interface{}) (pageNumber int, pageSize int, hasPage bool) {
+	pageNumber = 1
+	pageSize = 10
+	pageParamsMap, ok := args["page"].(map[string]interface{})
+	if !ok {
+		return pageNumber, pageSize, false
+	}
+	if size, ok := pageParamsMap["size"].(int); ok {
+		pageSize = size
+	}
+	if number, ok := pageParamsMap["number"].(int); ok {
+		pageNumber = number
+	}
+	return pageNumber, pageSize, true
+}
+
+func isForwardRelation(relation api2go.TableRelation, tableName string) bool {
+	return (relation.Relation == "belongs_to" || relation.Relation == "has_one") && relation.Subject == tableName
+}
+
+// RelationFieldResolver resolves the relation field of a row of tableName to the related rows
+// of targetTable, only the rows the user can read are returned
+func RelationFieldResolver(resources map[string]*resource.DbResource, tableName string, fieldName string,
+	relation api2go.TableRelation, targetTable string, isList bool) graphql.FieldResolveFn {
+
+	forward := isForwardRelation(relation, tableName)
+
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		parent, ok := params.Source.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+
+		parentKey := ""
+		if forward {
+			switch value := parent[fieldName].(type) {
+			case string:
+				parentKey = value
+			case map[string]interface{}:
+				parentKey, _ = value["reference_id"].(string)
+			}
+		} else {
+			parentKey, _ = parent["reference_id"].(string)
+		}
+		if parentKey == "" {
+			return nil, nil
+		}
+
+		queries := graphqlQueryArguments(params.Args)
+		filter, _ := params.Args["filter"].(string)
+		pageNumber, pageSize, hasPage := graphqlPageArguments(params.Args)
+
+		// the page is taken from the rows of each parent in the query, lists without a page are limited to
+		// the size the query cost is computed with
+		page := resource.RelatedObjectsPage{Limit: 1}
+		if isList {
+			page.Limit = graphqlUnpagedListSize
+			if hasPage {
+				if pageNumber < 1 || pageSize < 1 {
+					return []map[string]interface{}{}, nil
+				}
+				page.Offset = uint64((pageNumber - 1) * pageSize)
+				page.Limit = uint64(pageSize)
+			}
+		}
+
+		argsJson, _ := json.Marshal(params.Args)
+		batchKey := fmt.Sprintf("%s.%s/%s", tableName, fieldName, argsJson)
+
+		var sessionUser *auth.SessionUser
+		if user, ok := params.Context.Value("user").(*auth.SessionUser); ok {
+			sessionUser = user
+		} else {
+			sessionUser = &auth.SessionUser{}
+		}
+
+		thunk := graphqlRelationLoaderFromContext(params.Context).Load(batchKey, parentKey,
+			func(keys []string) (map[string][]map[string]interface{}, error) {
+
+				isAdmin := sessionUser.UserReferenceId != "" && resources["world"].IsAdmin(sessionUser.UserReferenceId)
+				if !isAdmin {
+					tablePermission := resources["world"].GetObjectPermissionByWhereClause("world", "table_name", targetTable)
+					if !tablePermission.CanRead(sessionUser.UserReferenceId, sessionUser.Groups) {
+						return nil, fmt.Errorf("unauthorized to read [%v]", targetTable)
+					}
+					if err := resources[targetTable].CheckQueryColumnPermissions(queries, nil); err != nil {
+						return nil, err
+					}
+				}
+
+				related, err := resources[targetTable].GetRelatedObjects(relation, tableName, keys, queries, filter, page, sessionUser)
+				if err != nil {
+					return nil, err
+				}
+
+				// permissions of the rows of all the parents are checked together
+				parentKeys := make([]string, 0)
+				allRows := make([]map[string]interface{}, 0)
+				for key, rows := range related {
+					for _, row := range rows {
+						parentKeys = append(parentKeys, key)
+						allRows = append(allRows, row)
+					}
+				}
+				var permissions []resource.PermissionInstance
+				if !isAdmin {
+					permissions = resources[targetTable].GetRowPermissions(targetTable, allRows)
+				}
+
+				allowed := make(map[string][]map[string]interface{}, len(related))
+				for i, row := range allRows {
+					if !isAdmin && !permissions[i].CanRead(sessionUser.UserReferenceId, sessionUser.Groups) {
+						continue
+					}
+					if !isAdmin {
+						row = resources[targetTable].ReadableColumns(row, permissions[i], sessionUser)
+					}
+					row["id"] = row["reference_id"]
+					allowed[parentKeys[i]] = append(allowed[parentKeys[i]], row)
+				}
+				return allowed, nil
+			})
+
+		return func() (interface{}, error) {
+			rows, err := thunk()
+			if err != nil {
+				return nil, err
+			}
+			if !isList {
+				if len(rows) == 0 {
+					return nil, nil
+				}
+				return rows[0], nil
+			}
+			return rows, nil
+		}, nil
+	}
+}
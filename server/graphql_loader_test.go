@@ -0,0 +1,47 @@
+//+build test
+
+package server
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGraphqlRelationLoaderBatchesKeys(t *testing.T) {
+
+	loader := NewGraphqlRelationLoader()
+	fetchCount := 0
+	fetch := func(keys []string) (map[string][]map[string]interface{}, error) {
+		fetchCount += 1
+		result := make(map[string][]map[string]interface{})
+		for _, key := range keys {
+			result[key] = []map[string]interface{}{{"reference_id": "author-" + key}}
+		}
+		return result, nil
+	}
+
+	thunks := make([]func() ([]map[string]interface{}, error), 0)
+	for i := 0; i < 100; i++ {
+		thunks = append(thunks, loader.Load("post.author", fmt.Sprintf("%d", i%10), fetch))
+	}
+
+	for i, thunk := range thunks {
+		rows, err := thunk()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if len(rows) != 1 || rows[0]["reference_id"] != fmt.Sprintf("author-%d", i%10) {
+			t.Errorf("wrong rows for %d: %v", i, rows)
+		}
+	}
+
+	if fetchCount != 1 {
+		t.Errorf("expected one fetch for the batch, got %d", fetchCount)
+	}
+
+	// a key added after the batch was loaded starts a new batch
+	rows, _ := loader.Load("post.author", "1", fetch)()
+	if len(rows) != 1 || fetchCount != 2 {
+		t.Errorf("expected a new batch, got %v after %d fetches", rows, fetchCount)
+	}
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const GraphqlQueryTableName = "graphql_query"
+
+// graphqlRequest is the body of a graphql request, extensions carries the persisted query hash
+// {"persistedQuery": {"version": 1, "sha256Hash": "<hex sha256 of the query>"}}
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+func (request graphqlRequest) persistedQueryHash() string {
+	persistedQuery, ok := request.Extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	hash, _ := persistedQuery["sha256Hash"].(string)
+	return strings.ToLower(hash)
+}
+
+func GraphqlQueryHash(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
+
+// GraphqlGuard checks the requests on the graphql endpoint before they are executed
+// queries deeper or costlier than the limits are rejected, persisted query hashes are replaced by the
+// stored query, and with persistedOnly set only the stored queries are accepted from non admin users
+type GraphqlGuard struct {
+	schema        *graphql.Schema
+	limits        GraphqlQueryLimits
+	persistedOnly bool
+	cruds         map[string]*resource.DbResource
+}
+
+func NewGraphqlGuard(schema *graphql.Schema, limits GraphqlQueryLimits, persistedOnly bool,
+	cruds map[string]*resource.DbResource) *GraphqlGuard {
+	return &GraphqlGuard{
+		schema:        schema,
+		limits:        limits,
+		persistedOnly: persistedOnly,
+		cruds:         cruds,
+	}
+}
+
+// parseGraphqlRequest reads the request the same way as the graphql handler, the body is left readable
+func parseGraphqlRequest(r *http.Request) (graphqlRequest, error) {
+	var request graphqlRequest
+
+	if r.Method == "GET" {
+		values := r.URL.Query()
+		request.Query = values.Get("query")
+		request.OperationName = values.Get("operationName")
+		if variables := values.Get("variables"); variables != "" {
+			_ = json.Unmarshal([]byte(variables), &request.Variables)
+		}
+		if extensions := values.Get("extensions"); extensions != "" {
+			_ = json.Unmarshal([]byte(extensions), &request.Extensions)
+		}
+		return request, nil
+	}
+
+	if r.Body == nil {
+		return request, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return request, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	contentType := strings.Split(r.Header.Get("Content-Type"), ";")[0]
+	switch contentType {
+	case "application/graphql":
+		request.Query = string(body)
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return request, err
+		}
+		request.Query = values.Get("query")
+		request.OperationName = values.Get("operationName")
+		if variables := values.Get("variables"); variables != "" {
+			_ = json.Unmarshal([]byte(variables), &request.Variables)
+		}
+	default:
+		// invalid json is reported by the graphql handler
+		_ = json.Unmarshal(body, &request)
+	}
+	return request, nil
+}
+
+// setGraphqlRequestQuery replaces the query of the request with the persisted query
+func setGraphqlRequestQuery(r *http.Request, request graphqlRequest) error {
+	if r.Method == "GET" {
+		values := r.URL.Query()
+		values.Set("query", request.Query)
+		r.URL.RawQuery = values.Encode()
+		return nil
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// GetPersistedQuery returns the stored query for the hash, and false if there is none
+func (guard *GraphqlGuard) GetPersistedQuery(hash string) (string, bool) {
+	dbResource, ok := guard.cruds[GraphqlQueryTableName]
+	if !ok {
+		return "", false
+	}
+
+	s, v, err := statementbuilder.Squirrel.Select("query_text").From(GraphqlQueryTableName).
+		Where(goqu.Ex{"query_hash": hash}).ToSQL()
+	if err != nil {
+		log.Errorf("Failed to create persisted query select: %v", err)
+		return "", false
+	}
+
+	var query string
+	err = dbResource.Connection.QueryRowx(s, v...).Scan(&query)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Failed to load persisted query [%v]: %v", hash, err)
+		}
+		return "", false
+	}
+
+	if GraphqlQueryHash(query) != hash {
+		log.Errorf("Persisted query [%v] does not match its hash, query_hash should be the sha256 of query_text", hash)
+		return "", false
+	}
+	return query, true
+}
+
+func (guard *GraphqlGuard) isAdmin(r *http.Request) bool {
+	user, ok := r.Context().Value("user").(*auth.SessionUser)
+	if !ok || user == nil || user.UserReferenceId == "" {
+		return false
+	}
+	return guard.cruds["world"].IsAdmin(user.UserReferenceId)
+}
+
+// Check returns a non zero status and the error when the request is rejected
+// the request is rewritten when it refers to a persisted query
+func (guard *GraphqlGuard) Check(r *http.Request) (int, error) {
+
+	request, err := parseGraphqlRequest(r)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	hash := request.persistedQueryHash()
+	if hash == "" && request.Query == "" {
+		// no operation, the graphql handler serves the playground or reports the error
+		return 0, nil
+	}
+
+	if hash != "" {
+		if request.Query != "" && GraphqlQueryHash(request.Query) != hash {
+			return http.StatusBadRequest, errors.New("provided sha256Hash does not match the query")
+		}
+		storedQuery, found := guard.GetPersistedQuery(hash)
+		if found {
+			if request.Query == "" {
+				request.Query = storedQuery
+				err = setGraphqlRequestQuery(r, request)
+				if err != nil {
+					return http.StatusInternalServerError, err
+				}
+			}
+		} else if request.Query == "" || (guard.persistedOnly && !guard.isAdmin(r)) {
+			// same message as other servers so that clients send the full query
+			return http.StatusOK, errors.New("PersistedQueryNotFound")
+		}
+	} else if guard.persistedOnly && !guard.isAdmin(r) {
+		return http.StatusForbidden, errors.New("only persisted queries are allowed")
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		// syntax errors are reported by the graphql handler
+		return 0, nil
+	}
+
+	err = guard.limits.Check(guard.schema, document, request.OperationName, request.Variables)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,442 @@
+package server
+
+import (
+	"context"
+	json1 "encoding/json"
+	"errors"
+	"fmt"
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/iancoleman/strcase"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// graphql-transport-ws is the protocol of the graphql-ws library, graphql-ws is the older
+// subscriptions-transport-ws protocol, both are accepted
+const (
+	graphqlTransportWsProtocol = "graphql-transport-ws"
+	graphqlWsProtocol          = "graphql-ws"
+)
+
+var graphqlEventNames = map[string]string{
+	"create": "Created",
+	"update": "Updated",
+	"delete": "Deleted",
+}
+
+// SubscriptionFieldName is the name of the subscription root field for events of a table, eg todoCreated
+func SubscriptionFieldName(tableName string, eventType string) string {
+	return strcase.ToLowerCamel(tableName) + graphqlEventNames[eventType]
+}
+
+type subscriptionSetupKey struct{}
+
+// subscriptionSetup collects the table, event type and arguments of a subscription operation
+// when it is executed once at subscribe time
+type subscriptionSetup struct {
+	TableName   string
+	EventType   string
+	ReferenceId string
+	Queries     []resource.Query
+}
+
+// SubscriptionFieldResolver resolves a subscription field from the event row in the root value
+// during the setup execution, it only records the arguments
+func SubscriptionFieldResolver(tableName string, eventType string) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+
+		setup, ok := params.Context.Value(subscriptionSetupKey{}).(*subscriptionSetup)
+		if ok {
+			setup.TableName = tableName
+			setup.EventType = eventType
+			setup.ReferenceId, _ = params.Args["reference_id"].(string)
+			queryList, _ := params.Args["query"].([]interface{})
+			for _, queryItem := range queryList {
+				queryMap, ok := queryItem.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				column, _ := queryMap["column"].(string)
+				operator, _ := queryMap["operator"].(string)
+				setup.Queries = append(setup.Queries, resource.Query{
+					ColumnName: column,
+					Operator:   operator,
+					Value:      queryMap["value"],
+				})
+			}
+			return nil, nil
+		}
+
+		root, _ := params.Source.(map[string]interface{})
+		return root[params.Info.FieldName], nil
+	}
+}
+
+type graphqlWsMessage struct {
+	Id      string           `json:"id,omitempty"`
+	Type    string           `json:"type"`
+	Payload json1.RawMessage `json:"payload,omitempty"`
+}
+
+type graphqlSubscribePayload struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type graphqlSubscription struct {
+	topic      *olric.DTopic
+	listenerId uint64
+}
+
+type graphqlSubscriptionConnection struct {
+	ws            *websocket.Conn
+	protocol      string
+	schema        *graphql.Schema
+	dtopicMap     *map[string]*olric.DTopic
+	cruds         map[string]*resource.DbResource
+	limits        GraphqlQueryLimits
+	user          *auth.SessionUser
+	lock          sync.Mutex
+	writeLock     sync.Mutex
+	subscriptions map[string]graphqlSubscription
+}
+
+// CreateGraphqlSubscriptionHandler serves graphql subscriptions over websocket, the events
+// come from the same olric topics as the /live websocket
+func CreateGraphqlSubscriptionHandler(schema *graphql.Schema, dtopicMap *map[string]*olric.DTopic,
+	cruds map[string]*resource.DbResource, limits GraphqlQueryLimits) func(*gin.Context) {
+
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			var err error
+			config.Origin, err = websocket.Origin(config, req)
+			if err != nil {
+				return err
+			}
+			for _, protocol := range config.Protocol {
+				if protocol == graphqlTransportWsProtocol || protocol == graphqlWsProtocol {
+					config.Protocol = []string{protocol}
+					return nil
+				}
+			}
+			return websocket.ErrBadWebSocketProtocol
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			user := ws.Request().Context().Value("user")
+			if user == nil {
+				_ = ws.WriteClose(4401)
+				return
+			}
+
+			connection := &graphqlSubscriptionConnection{
+				ws:            ws,
+				protocol:      ws.Config().Protocol[0],
+				schema:        schema,
+				dtopicMap:     dtopicMap,
+				cruds:         cruds,
+				limits:        limits,
+				user:          user.(*auth.SessionUser),
+				subscriptions: make(map[string]graphqlSubscription),
+			}
+			connection.listen()
+		},
+	}
+
+	return func(c *gin.Context) {
+		server.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+func (gc *graphqlSubscriptionConnection) send(message graphqlWsMessage) {
+	gc.writeLock.Lock()
+	defer gc.writeLock.Unlock()
+	err := websocket.JSON.Send(gc.ws, message)
+	if err != nil {
+		log.Printf("Failed to write to graphql subscription client: %v", err)
+	}
+}
+
+func (gc *graphqlSubscriptionConnection) sendPayload(id string, messageType string, payload interface{}) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("Failed to marshal graphql subscription payload: %v", err)
+		return
+	}
+	gc.send(graphqlWsMessage{Id: id, Type: messageType, Payload: payloadBytes})
+}
+
+func (gc *graphqlSubscriptionConnection) sendErrors(id string, errs []gqlerrors.FormattedError) {
+	if gc.protocol == graphqlWsProtocol {
+		// the older protocol has a single error object as payload
+		message := "subscription failed"
+		if len(errs) > 0 {
+			message = errs[0].Message
+		}
+		gc.sendPayload(id, "error", map[string]string{"message": message})
+		return
+	}
+	gc.sendPayload(id, "error", errs)
+}
+
+func (gc *graphqlSubscriptionConnection) listen() {
+
+	defer gc.stopAll()
+
+	keepAliveDone := make(chan bool)
+	defer close(keepAliveDone)
+
+	initialised := false
+	for {
+		var message graphqlWsMessage
+		err := websocket.JSON.Receive(gc.ws, &message)
+		if err != nil {
+			return
+		}
+
+		switch message.Type {
+		case "connection_init":
+			if initialised {
+				_ = gc.ws.WriteClose(4429)
+				return
+			}
+			initialised = true
+			gc.send(graphqlWsMessage{Type: "connection_ack"})
+			if gc.protocol == graphqlWsProtocol {
+				gc.send(graphqlWsMessage{Type: "ka"})
+				go gc.keepAlive(keepAliveDone)
+			}
+		case "ping":
+			gc.send(graphqlWsMessage{Type: "pong", Payload: message.Payload})
+		case "pong":
+		case "subscribe", "start":
+			if !initialised {
+				_ = gc.ws.WriteClose(4401)
+				return
+			}
+			gc.subscribe(message)
+		case "complete", "stop":
+			gc.stop(message.Id, true)
+		case "connection_terminate":
+			return
+		default:
+			log.Printf("Unknown graphql subscription message type: %v", message.Type)
+		}
+	}
+}
+
+func (gc *graphqlSubscriptionConnection) keepAlive(done chan bool) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			gc.send(graphqlWsMessage{Type: "ka"})
+		}
+	}
+}
+
+func subscriptionOperation(document *ast.Document, operationName string) (*ast.OperationDefinition, error) {
+	var selected *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			if selected != nil {
+				return nil, errors.New("operation name is required when the document has more than one operation")
+			}
+			selected = operation
+		}
+	}
+	if selected == nil {
+		return nil, errors.New("no operation found")
+	}
+	if selected.Operation != ast.OperationTypeSubscription {
+		return nil, errors.New("only subscription operations are served over websocket")
+	}
+	if selected.SelectionSet == nil || len(selected.SelectionSet.Selections) != 1 {
+		return nil, errors.New("subscription must select exactly one field")
+	}
+	return selected, nil
+}
+
+func (gc *graphqlSubscriptionConnection) subscribe(message graphqlWsMessage) {
+
+	var payload graphqlSubscribePayload
+	err := json.Unmarshal(message.Payload, &payload)
+	if err != nil {
+		gc.sendErrors(message.Id, gqlerrors.FormatErrors(err))
+		return
+	}
+
+	gc.lock.Lock()
+	_, exists := gc.subscriptions[message.Id]
+	gc.lock.Unlock()
+	if exists {
+		_ = gc.ws.WriteClose(4409)
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: payload.Query})
+	if err != nil {
+		gc.sendErrors(message.Id, gqlerrors.FormatErrors(err))
+		return
+	}
+	validation := graphql.ValidateDocument(gc.schema, document, nil)
+	if !validation.IsValid {
+		gc.sendErrors(message.Id, validation.Errors)
+		return
+	}
+
+	err = gc.limits.Check(gc.schema, document, payload.OperationName, payload.Variables)
+	if err != nil {
+		gc.sendErrors(message.Id, gqlerrors.FormatErrors(err))
+		return
+	}
+	if _, err = subscriptionOperation(document, payload.OperationName); err != nil {
+		gc.sendErrors(message.Id, gqlerrors.FormatErrors(err))
+		return
+	}
+
+	userContext := context.WithValue(context.Background(), "user", gc.user)
+	setup := &subscriptionSetup{}
+	setupResult := graphql.Execute(graphql.ExecuteParams{
+		Schema:        *gc.schema,
+		Root:          map[string]interface{}{},
+		AST:           document,
+		OperationName: payload.OperationName,
+		Args:          payload.Variables,
+		Context:       context.WithValue(userContext, subscriptionSetupKey{}, setup),
+	})
+	if setupResult.HasErrors() {
+		gc.sendErrors(message.Id, setupResult.Errors)
+		return
+	}
+
+	tablePermission := gc.cruds["world"].GetObjectPermissionByWhereClause("world", "table_name", setup.TableName)
+	if !tablePermission.CanRead(gc.user.UserReferenceId, gc.user.Groups) || !gc.user.ApiKeyScope.AllowsTable(setup.TableName, "GET") {
+		gc.sendErrors(message.Id, gqlerrors.FormatErrors(fmt.Errorf("unauthorized to subscribe to [%v]", setup.TableName)))
+		return
+	}
+	// the events which arrive would tell the values of the read restricted columns in the filters
+	if crud, ok := gc.cruds[setup.TableName]; ok && !gc.cruds["world"].IsAdmin(gc.user.UserReferenceId) {
+		if err = crud.CheckQueryColumnPermissions(setup.Queries, nil); err != nil {
+			gc.sendErrors(message.Id, gqlerrors.FormatErrors(err))
+			return
+		}
+	}
+
+	topic, ok := (*gc.dtopicMap)[setup.TableName]
+	if !ok {
+		gc.sendErrors(message.Id, gqlerrors.FormatErrors(fmt.Errorf("no events for [%v]", setup.TableName)))
+		return
+	}
+
+	nextMessageType := "next"
+	if gc.protocol == graphqlWsProtocol {
+		nextMessageType = "data"
+	}
+
+	listenerId, err := topic.AddListener(func(topicMessage olric.DTopicMessage) {
+		eventMessage, ok := topicMessage.Message.(resource.EventMessage)
+		if !ok || eventMessage.EventType != setup.EventType {
+			return
+		}
+		row := eventMessage.EventData
+		if row == nil {
+			return
+		}
+		if setup.ReferenceId != "" && row["reference_id"] != setup.ReferenceId {
+			return
+		}
+		typeName, _ := row["__type"].(string)
+		if _, tableExists := gc.cruds[typeName]; tableExists {
+			row, ok = gc.cruds[typeName].ReadableRow(row, gc.user)
+			if !ok {
+				return
+			}
+		}
+		if !resource.MatchesQueries(row, setup.Queries) {
+			return
+		}
+
+		eventRow := make(map[string]interface{}, len(row)+1)
+		for key, value := range row {
+			eventRow[key] = value
+		}
+		if _, ok := eventRow["id"]; !ok {
+			eventRow["id"] = row["reference_id"]
+		}
+
+		result := graphql.Execute(graphql.ExecuteParams{
+			Schema:        *gc.schema,
+			Root:          map[string]interface{}{SubscriptionFieldName(setup.TableName, setup.EventType): eventRow},
+			AST:           document,
+			OperationName: payload.OperationName,
+			Args:          payload.Variables,
+			Context:       userContext,
+		})
+		gc.sendPayload(message.Id, nextMessageType, result)
+	})
+	if err != nil {
+		gc.sendErrors(message.Id, gqlerrors.FormatErrors(err))
+		return
+	}
+
+	gc.lock.Lock()
+	gc.subscriptions[message.Id] = graphqlSubscription{
+		topic:      topic,
+		listenerId: listenerId,
+	}
+	gc.lock.Unlock()
+}
+
+// stop removes the listener of a subscription, notify is false when the connection is already closed
+func (gc *graphqlSubscriptionConnection) stop(id string, notify bool) {
+	gc.lock.Lock()
+	subscription, ok := gc.subscriptions[id]
+	delete(gc.subscriptions, id)
+	gc.lock.Unlock()
+	if !ok {
+		return
+	}
+	err := subscription.topic.RemoveListener(subscription.listenerId)
+	resource.CheckErr(err, "Failed to remove graphql subscription listener")
+	if notify && gc.protocol == graphqlWsProtocol {
+		gc.send(graphqlWsMessage{Id: id, Type: "complete"})
+	}
+}
+
+func (gc *graphqlSubscriptionConnection) stopAll() {
+	gc.lock.Lock()
+	ids := make([]string, 0, len(gc.subscriptions))
+	for id := range gc.subscriptions {
+		ids = append(ids, id)
+	}
+	gc.lock.Unlock()
+	for _, id := range ids {
+		gc.stop(id, false)
+	}
+}
+
+// isWebsocketUpgrade is true for requests asking to switch to the websocket protocol
+func isWebsocketUpgrade(request *http.Request) bool {
+	return strings.ToLower(request.Header.Get("Upgrade")) == "websocket"
+}
@@ -5,17 +5,50 @@ import (
 	"github.com/artpar/api2go"
 	"github.com/daptin/daptin/server/apiblueprint"
 	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/codegen"
 	"github.com/daptin/daptin/server/resource"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
+	"go/token"
 	"image/color"
 	"net/http"
 	"strings"
 )
 
-func CreateApiBlueprintHandler(initConfig *resource.CmsConfig, cruds map[string]*resource.DbResource) func(ctx *gin.Context) {
+func CreateApiBlueprintHandler(initConfig *resource.CmsConfig, cruds map[string]*resource.DbResource, configStore *resource.ConfigStore) func(ctx *gin.Context) {
 	return func(c *gin.Context) {
-		c.String(200, "%s", apiblueprint.BuildApiBlueprint(initConfig, cruds))
+		c.String(200, "%s", apiblueprint.BuildApiBlueprint(initConfig, cruds, configStore))
+	}
+}
+
+// CreateCodegenHandler serves the schema as graphql sdl, a typescript client or a go client, by the
+// extension of the requested file
+func CreateCodegenHandler(initConfig *resource.CmsConfig) func(ctx *gin.Context) {
+	return func(c *gin.Context) {
+		schema := codegen.BuildSchema(initConfig)
+
+		switch c.Param("filename") {
+		case "schema.graphql":
+			c.String(200, "%s", codegen.GenerateSdl(schema))
+		case "client.ts":
+			c.Header("Content-Type", "application/typescript; charset=utf-8")
+			c.String(200, "%s", codegen.GenerateTypescript(schema))
+		case "client.go":
+			packageName := c.DefaultQuery("package", "daptin")
+			if !token.IsIdentifier(packageName) {
+				c.AbortWithStatusJSON(400, ErrorResponse{Message: "invalid package name"})
+				return
+			}
+			source, err := codegen.GenerateGoClient(schema, packageName)
+			if err != nil {
+				resource.CheckErr(err, "Failed to generate go client")
+				c.AbortWithStatusJSON(500, ErrorResponse{Message: err.Error()})
+				return
+			}
+			c.String(200, "%s", source)
+		default:
+			c.AbortWithStatus(404)
+		}
 	}
 }
 
@@ -78,6 +111,7 @@ func CreateStatsHandler(initConfig *resource.CmsConfig, cruds map[string]*resour
 		aggReq.TimeFrom = c.Query("timefrom")
 		aggReq.TimeTo = c.Query("timeto")
 		aggReq.Order = c.QueryArray("order")
+		aggReq.User = sessionUser
 
 
 		transaction, err := cruds[typeName].Connection.Beginx()
@@ -86,8 +120,16 @@ func CreateStatsHandler(initConfig *resource.CmsConfig, cruds map[string]*resour
 			return
 		}
 		aggResponse, err := cruds[typeName].DataStats(aggReq, transaction)
-		transaction.Rollback()
+		resource.RollbackTransaction(transaction)
 
+		if columnErr, ok := err.(resource.ColumnPermissionError); ok {
+			c.JSON(403, resource.NewDaptinError("Failed to query stats", columnErr.Error()))
+			return
+		}
+		if httpErr, ok := err.(api2go.HTTPError); ok {
+			c.JSON(httpErr.Status(), resource.NewDaptinError("Failed to query stats", httpErr.Error()))
+			return
+		}
 		if err != nil {
 			log.Errorf("failed to execute aggregation [%v] - %v", typeName, err)
 			c.JSON(500, resource.NewDaptinError("Failed to query stats", "query failed - "+err.Error()))
@@ -259,6 +259,12 @@ func (m *JWTMiddleware) CheckJWT(w http.ResponseWriter, r *http.Request) (*jwt.T
 		return nil, errors.New("Token is invalid")
 	}
 
+	if err = CheckRevoked(parsedToken.Claims.(jwt.MapClaims)); err != nil {
+		m.logf("Token is revoked: %v", err)
+		m.Options.ErrorHandler(w, r, err.Error())
+		return nil, err
+	}
+
 	m.logf("JWT: %v", parsedToken)
 
 	//if TokenCache != nil {
@@ -296,6 +302,9 @@ func (m *JWTMiddleware) CheckExtractedJWT(w http.ResponseWriter, token string) (
 		tok, err := TokenCache.Get(k)
 		if err == nil {
 			cachedToken := tok.(jwt.Token)
+			if err = CheckRevoked(cachedToken.Claims.(jwt.MapClaims)); err != nil {
+				return nil, err
+			}
 			return &cachedToken, nil
 		}
 	}
@@ -346,6 +355,10 @@ func (m *JWTMiddleware) CheckExtractedJWT(w http.ResponseWriter, token string) (
 		return nil, errors.New("token is invalid")
 	}
 
+	if err = CheckRevoked(parsedToken.Claims.(jwt.MapClaims)); err != nil {
+		return nil, err
+	}
+
 	m.logf("JWT: %v", parsedToken)
 
 	if TokenCache != nil {
@@ -0,0 +1,84 @@
+package jwtmiddleware
+
+import (
+	"fmt"
+	"github.com/buraksezer/olric"
+	"github.com/golang-jwt/jwt/v4"
+	"time"
+)
+
+// RevokedTokens is the revocation list shared by all nodes of the cluster, entries expire once the tokens
+// they revoke would have expired anyway
+var RevokedTokens *olric.DMap
+
+func revoke(key string, value interface{}, ttl time.Duration) error {
+	if RevokedTokens == nil || ttl <= 0 {
+		return nil
+	}
+	return RevokedTokens.PutEx(key, value, ttl)
+}
+
+// RevokeToken rejects the token with the jti until it expires
+func RevokeToken(jti string, expiresAt time.Time) error {
+	return revoke("jti-"+jti, true, time.Until(expiresAt))
+}
+
+// RevokeSession rejects the access tokens issued for the session, ttl is the access token life time
+func RevokeSession(sessionId string, ttl time.Duration) error {
+	return revoke("sid-"+sessionId, true, ttl)
+}
+
+// RevokeUserTokens rejects the tokens of the user issued up to issuedBefore, ttl is the longest token life time.
+// iat has a resolution of seconds, tokens issued in the second of the revocation are rejected as well.
+func RevokeUserTokens(email string, issuedBefore time.Time, ttl time.Duration) error {
+	return revoke("user-"+email, issuedBefore.Unix(), ttl)
+}
+
+func isListed(key string) (interface{}, bool, error) {
+	value, err := RevokedTokens.Get(key)
+	if err == olric.ErrKeyNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// CheckRevoked fails for a token revoked by its jti, its session or its user
+func CheckRevoked(claims jwt.MapClaims) error {
+	if RevokedTokens == nil {
+		return nil
+	}
+
+	for _, claim := range []string{"jti", "sid"} {
+		id, ok := claims[claim].(string)
+		if !ok || id == "" {
+			continue
+		}
+		_, listed, err := isListed(claim + "-" + id)
+		if err != nil {
+			return fmt.Errorf("failed to check revoked tokens: %v", err)
+		}
+		if listed {
+			return fmt.Errorf("token has been revoked")
+		}
+	}
+
+	email, ok := claims["email"].(string)
+	if !ok {
+		return nil
+	}
+	revokedAt, listed, err := isListed("user-" + email)
+	if err != nil {
+		return fmt.Errorf("failed to check revoked tokens: %v", err)
+	}
+	if !listed {
+		return nil
+	}
+	issuedAt, ok := claims["iat"].(float64)
+	if !ok || int64(issuedAt) <= revokedAt.(int64) {
+		return fmt.Errorf("token has been revoked")
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package jwtmiddleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	log "github.com/sirupsen/logrus"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// keys are reloaded after this interval, so that keys rotated on another node are picked up
+const signingKeyReloadInterval = time.Minute
+
+// a token with an unknown kid reloads the keys at most once in this interval
+const unknownKidReloadInterval = 10 * time.Second
+
+// SigningKey is an asymmetric key which signs tokens, its public key verifies tokens until ValidUntil
+type SigningKey struct {
+	Kid         string
+	Method      jwt.SigningMethod
+	PrivateKey  crypto.Signer
+	GeneratedAt time.Time
+	ValidUntil  time.Time
+}
+
+// SigningKeyStore holds the signing keys loaded from the database, the newest key signs new tokens
+type SigningKeyStore struct {
+	lock         sync.RWMutex
+	keys         []*SigningKey
+	loader       func() ([]*SigningKey, error)
+	loadedAt     time.Time
+	secretAlways bool
+	secretUntil  time.Time
+}
+
+// SigningKeys is the key store used by the jwt middleware and by the token issuers
+var SigningKeys = &SigningKeyStore{}
+
+// SetLoader sets the function reading the keys from the database and loads them
+func (ks *SigningKeyStore) SetLoader(loader func() ([]*SigningKey, error)) error {
+	ks.lock.Lock()
+	ks.loader = loader
+	ks.lock.Unlock()
+	return ks.Reload()
+}
+
+// Reload reads the keys again, keys which are no longer valid are dropped
+func (ks *SigningKeyStore) Reload() error {
+	ks.lock.RLock()
+	loader := ks.loader
+	ks.lock.RUnlock()
+	if loader == nil {
+		return nil
+	}
+
+	keys, err := loader()
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	ks.loadedAt = time.Now()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	validKeys := make([]*SigningKey, 0, len(keys))
+	for _, key := range keys {
+		if key.ValidUntil.After(now) {
+			validKeys = append(validKeys, key)
+		}
+	}
+	sort.Slice(validKeys, func(i, j int) bool {
+		return validKeys[i].GeneratedAt.After(validKeys[j].GeneratedAt)
+	})
+	ks.keys = validKeys
+	return nil
+}
+
+func (ks *SigningKeyStore) reloadIfOlderThan(interval time.Duration) {
+	ks.lock.RLock()
+	stale := ks.loader != nil && time.Since(ks.loadedAt) > interval
+	ks.lock.RUnlock()
+	if stale {
+		CheckErr(ks.Reload(), "Failed to reload jwt signing keys")
+	}
+}
+
+// ValidKeys returns the keys which can verify tokens, newest first
+func (ks *SigningKeyStore) ValidKeys() []*SigningKey {
+	ks.reloadIfOlderThan(signingKeyReloadInterval)
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	now := time.Now()
+	keys := make([]*SigningKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		if key.ValidUntil.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Current returns the key which signs new tokens, nil when there is no key
+func (ks *SigningKeyStore) Current() *SigningKey {
+	keys := ks.ValidKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys[0]
+}
+
+// Get returns the valid key with the kid, the keys are reloaded once for a kid which is not known yet
+func (ks *SigningKeyStore) Get(kid string) *SigningKey {
+	for _, key := range ks.ValidKeys() {
+		if key.Kid == kid {
+			return key
+		}
+	}
+	ks.reloadIfOlderThan(unknownKidReloadInterval)
+	for _, key := range ks.ValidKeys() {
+		if key.Kid == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// AcceptSecret sets when HS256 tokens signed with the secret are valid, always when HS256 is the configured
+// algorithm, otherwise until the end of the migration window after switching to RS256 or ES256
+func (ks *SigningKeyStore) AcceptSecret(always bool, until time.Time) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	ks.secretAlways = always
+	ks.secretUntil = until
+}
+
+// AcceptsSecret is true when HS256 tokens signed with the secret are valid now
+func (ks *SigningKeyStore) AcceptsSecret() bool {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	return ks.secretAlways || time.Now().Before(ks.secretUntil)
+}
+
+// KeyFunc verifies HS256 tokens with the secret while it is accepted, and asymmetric tokens with the valid key
+// of their kid
+func (ks *SigningKeyStore) KeyFunc(secret []byte) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		alg, _ := token.Header["alg"].(string)
+		if alg == jwt.SigningMethodHS256.Alg() {
+			if !ks.AcceptsSecret() {
+				return nil, fmt.Errorf("tokens signed with the jwt secret are no longer accepted")
+			}
+			return secret, nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		key := ks.Get(kid)
+		if key == nil || key.Method.Alg() != alg {
+			log.Debugf("No valid %v signing key [%v]", alg, kid)
+			return nil, fmt.Errorf("unknown signing key [%v]", kid)
+		}
+		return key.PrivateKey.Public(), nil
+	}
+}
+
+func base64UrlUint(value *big.Int, size int) string {
+	bytes := value.Bytes()
+	if len(bytes) < size {
+		padded := make([]byte, size)
+		copy(padded[size-len(bytes):], bytes)
+		bytes = padded
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// JWK is the json web key of the public part of the signing key
+func (key *SigningKey) JWK() map[string]interface{} {
+	jwk := map[string]interface{}{
+		"kid": key.Kid,
+		"alg": key.Method.Alg(),
+		"use": "sig",
+	}
+	switch publicKey := key.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64UrlUint(publicKey.N, 0)
+		jwk["e"] = base64UrlUint(big.NewInt(int64(publicKey.E)), 0)
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk["kty"] = "EC"
+		jwk["crv"] = publicKey.Curve.Params().Name
+		jwk["x"] = base64UrlUint(publicKey.X, size)
+		jwk["y"] = base64UrlUint(publicKey.Y, size)
+	}
+	return jwk
+}
+
+// JWKS is the json web key set of all valid keys, served at /.well-known/jwks.json
+func (ks *SigningKeyStore) JWKS() map[string]interface{} {
+	keys := make([]map[string]interface{}, 0)
+	for _, key := range ks.ValidKeys() {
+		keys = append(keys, key.JWK())
+	}
+	return map[string]interface{}{
+		"keys": keys,
+	}
+}
@@ -0,0 +1,115 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type apiKeyCreatePerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *apiKeyCreatePerformer) Name() string {
+	return "api_key.create"
+}
+
+// DoAction creates an api key owned by the user, the key is only in the response, the table keeps its hash
+func (d *apiKeyCreatePerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser.UserId == 0 {
+		return nil, nil, []error{errors.New("sign in to create an api key")}
+	}
+	if sessionUser.ApiKeyScope != nil {
+		return nil, nil, []error{errors.New("api keys with scopes cannot create api keys")}
+	}
+	if err := rejectImpersonation(request); err != nil {
+		return nil, nil, []error{err}
+	}
+
+	name, _ := inFields["name"].(string)
+	if name == "" {
+		return nil, nil, []error{errors.New("name is required")}
+	}
+
+	scopes, err := apiKeyScopesValue(inFields["scopes"])
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	key, err := auth.GenerateApiKey()
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	apiKey := map[string]interface{}{
+		"name":       name,
+		"key_prefix": key[:len(auth.ApiKeyPrefix)+6],
+		"key_hash":   auth.HashApiKey(key),
+		"scopes":     scopes,
+	}
+	if expiresInDays := queryValueString(inFields["expires_in_days"]); expiresInDays != "" {
+		days, err := strconv.ParseFloat(expiresInDays, 64)
+		if err != nil || days <= 0 {
+			return nil, nil, []error{fmt.Errorf("invalid expires_in_days [%v]", expiresInDays)}
+		}
+		apiKey["expires_at"] = time.Now().Add(time.Duration(days * float64(24*time.Hour)))
+	}
+
+	httpReq := &http.Request{
+		Method: "POST",
+	}
+	httpReq = httpReq.WithContext(context.WithValue(context.Background(), "user", sessionUser))
+	created, err := d.cruds["api_key"].CreateWithoutFilter(api2go.NewApi2GoModelWithData("api_key", nil, 0, nil, apiKey),
+		api2go.Request{PlainRequest: httpReq}, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("api_key.created", map[string]interface{}{
+			"reference_id": created["reference_id"],
+			"name":         name,
+			"key":          key,
+			"expires_at":   apiKey["expires_at"],
+		}),
+		NewActionResponse("client.notify", NewClientNotification("message", "Copy the key now, it is not shown again", "API key created")),
+	}, nil
+}
+
+// apiKeyScopesValue validates the scopes of a new key, given as json text or as an object
+func apiKeyScopesValue(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	scopesJson, ok := value.(string)
+	if !ok {
+		scopesBytes, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		scopesJson = string(scopesBytes)
+	}
+	scope, err := auth.ParseApiKeyScope(scopesJson)
+	if err != nil || scope == nil {
+		return nil, err
+	}
+	return scopesJson, nil
+}
+
+func NewApiKeyCreatePerformer(cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := apiKeyCreatePerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
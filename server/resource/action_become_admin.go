@@ -23,6 +23,9 @@ func (d *becomeAdminActionPerformer) DoAction(request Outcome, inFieldMap map[st
 	if !d.cruds["world"].CanBecomeAdmin() {
 		return nil, nil, []error{errors.New("Unauthorized")}
 	}
+	if err := rejectImpersonation(request); err != nil {
+		return nil, nil, []error{err}
+	}
 	u := inFieldMap["user"]
 	if u == nil {
 		return nil, nil, []error{errors.New("Unauthorized")}
@@ -32,13 +35,13 @@ func (d *becomeAdminActionPerformer) DoAction(request Outcome, inFieldMap map[st
 	responseAttrs := make(map[string]interface{})
 
 	if d.cruds["world"].BecomeAdmin(user["id"].(int64), transaction) {
-		commitError := transaction.Commit()
+		commitError := CommitTransaction(transaction)
 		CheckErr(commitError, "failed to rollback")
 		responseAttrs["location"] = "/"
 		responseAttrs["window"] = "self"
 		responseAttrs["delay"] = 7000
 	}
-	rollbackError := transaction.Rollback()
+	rollbackError := RollbackTransaction(transaction)
 	CheckErr(rollbackError, "failed to rollback")
 
 	actionResponse := NewActionResponse("client.redirect", responseAttrs)
@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
 	"github.com/gocarina/gocsv"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
@@ -31,6 +32,10 @@ func (d *exportCsvDataPerformer) DoAction(request Outcome, inFields map[string]i
 
 	result := make(map[string]interface{})
 
+	// read restricted columns are only exported for administrators
+	user, _ := request.Attributes["user"].(*auth.SessionUser)
+	isAdmin := user != nil && IsAdminWithTransaction(user.UserReferenceId, transaction)
+
 	if ok && tableName != nil {
 
 		tableNameStr := tableName.(string)
@@ -40,6 +45,9 @@ func (d *exportCsvDataPerformer) DoAction(request Outcome, inFields map[string]i
 		if err != nil {
 			log.Errorf("Failed to get all objects of type [%v] : %v", tableNameStr, err)
 		}
+		if !isAdmin {
+			removeReadRestrictedColumns(d.cruds[tableNameStr].TableInfo(), objects)
+		}
 
 		result[tableNameStr] = objects
 		finalName = tableNameStr
@@ -51,6 +59,9 @@ func (d *exportCsvDataPerformer) DoAction(request Outcome, inFields map[string]i
 				log.Errorf("Failed to export objects of type [%v]: %v", tableInfo.TableName, err)
 				continue
 			}
+			if !isAdmin {
+				removeReadRestrictedColumns(d.cruds[tableInfo.TableName].TableInfo(), data)
+			}
 			result[tableInfo.TableName] = data
 		}
 
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 )
@@ -28,6 +29,10 @@ func (d *exportDataPerformer) DoAction(request Outcome, inFields map[string]inte
 	var finalString []byte
 	result := make(map[string]interface{})
 
+	// read restricted columns are only exported for administrators
+	user, _ := request.Attributes["user"].(*auth.SessionUser)
+	isAdmin := user != nil && IsAdminWithTransaction(user.UserReferenceId, transaction)
+
 	if ok && tableName != nil {
 
 		tableNameStr := tableName.(string)
@@ -37,6 +42,9 @@ func (d *exportDataPerformer) DoAction(request Outcome, inFields map[string]inte
 		if err != nil {
 			log.Errorf("Failed to get all objects of type [%v] : %v", tableNameStr, err)
 		}
+		if !isAdmin {
+			removeReadRestrictedColumns(d.cruds[tableNameStr].TableInfo(), objects)
+		}
 
 		result[tableNameStr] = objects
 		finalName = tableNameStr
@@ -48,6 +56,9 @@ func (d *exportDataPerformer) DoAction(request Outcome, inFields map[string]inte
 				log.Errorf("Failed to export objects of type [%v]: %v", tableInfo.TableName, err)
 				continue
 			}
+			if !isAdmin {
+				removeReadRestrictedColumns(d.cruds[tableInfo.TableName].TableInfo(), data)
+			}
 			result[tableInfo.TableName] = data
 		}
 
@@ -3,19 +3,16 @@ package resource
 import (
 	"fmt"
 	"github.com/artpar/api2go"
-	"github.com/artpar/go.uuid"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/doug-martin/goqu/v9"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
-	"time"
 )
 
 type generateJwtTokenActionPerformer struct {
-	cruds          map[string]*DbResource
-	secret         []byte
-	tokenLifeTime  int
-	jwtTokenIssuer string
+	cruds              map[string]*DbResource
+	sessionTokenIssuer *SessionTokenIssuer
+	encryptionSecret   []byte
+	loginGuard         *LoginGuard
 }
 
 func (d *generateJwtTokenActionPerformer) Name() string {
@@ -48,10 +45,31 @@ func (d *generateJwtTokenActionPerformer) DoAction(request Outcome, inFieldMap m
 		return nil, nil, []error{fmt.Errorf("email or password is empty")}
 	}
 
+	client, _ := request.Attributes["client"].(*ActionClient)
+	emailString := fmt.Sprintf("%v", email)
+	ipAddress := ""
+	if client != nil {
+		ipAddress = client.IpAddress
+	}
+
+	if !skipPasswordCheck {
+		lockedUntil, err := d.loginGuard.LockedUntil(emailString, ipAddress, transaction)
+		if err != nil {
+			return nil, nil, []error{err}
+		}
+		if !lockedUntil.IsZero() {
+			d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], nil, emailString, client, loginEventLocked, transaction)
+			return nil, []ActionResponse{
+				NewActionResponse("client.notify", NewClientNotification("error", lockoutMessage(lockedUntil), "Failed")),
+			}, nil
+		}
+	}
+
 	existingUsers, _, err := d.cruds[USER_ACCOUNT_TABLE_NAME].GetRowsByWhereClauseWithTransaction("user_account", nil, transaction, goqu.Ex{"email": email})
 
 	responseAttrs := make(map[string]interface{})
 	if err != nil || len(existingUsers) < 1 {
+		d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], nil, emailString, client, loginEventUnknownUser, transaction)
 		responseAttrs["type"] = "error"
 		responseAttrs["message"] = "Invalid username or password"
 		responseAttrs["title"] = "Failed"
@@ -61,44 +79,26 @@ func (d *generateJwtTokenActionPerformer) DoAction(request Outcome, inFieldMap m
 		existingUser := existingUsers[0]
 		if skipPasswordCheck || (existingUser["password"] != nil && BcryptCheckStringHash(password, existingUser["password"].(string))) {
 
-			// Create a new token object, specifying signing method and the claims
-			// you would like it to contain.
-			u, _ := uuid.NewV4()
-			timeNow := time.Now()
-
-			timeNow.Add(-2 * time.Minute) // allow clock skew of 2 minutes
-			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-				"email": existingUser["email"],
-				"sub":   existingUser["reference_id"],
-				"name":  existingUser["name"],
-				"nbf":   timeNow.Unix(),
-				"exp":   timeNow.Add(time.Duration(d.tokenLifeTime) * time.Hour).Unix(),
-				"iss":   d.jwtTokenIssuer,
-				"iat":   timeNow.Unix(),
-				"jti":   u.String(),
-			})
-
-			// Sign and get the complete encoded token as a string using the secret
-			tokenString, err := token.SignedString(d.secret)
-			//fmt.Printf("%v %v", tokenString, err)
+			// logins through an identity provider skip the password, and the second factor with it
+			if !skipPasswordCheck {
+				secondFactorResponses, reason, err := checkSecondFactor(existingUser, inFieldMap["two_factor_code"], d.encryptionSecret, transaction)
+				if err != nil {
+					return nil, nil, []error{err}
+				}
+				if secondFactorResponses != nil {
+					d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], existingUser, emailString, client, reason, transaction)
+					return nil, secondFactorResponses, nil
+				}
+			}
+
+			accessToken, refreshToken, err := d.sessionTokenIssuer.StartSession(existingUser, client, d.cruds[USER_SESSION_TABLE_NAME], transaction)
 			if err != nil {
-				log.Errorf("Failed to sign string: %v", err)
+				log.Errorf("Failed to start session: %v", err)
 				return nil, nil, []error{err}
 			}
 
-			responseAttrs = make(map[string]interface{})
-			responseAttrs["value"] = string(tokenString)
-			responseAttrs["key"] = "token"
-
-			actionResponse := NewActionResponse("client.store.set", responseAttrs)
-			responses = append(responses, actionResponse)
-
-			cookieResponseAttrs := make(map[string]interface{})
-			cookieResponseAttrs["value"] = string(tokenString) + "; SameSite=Strict"
-			cookieResponseAttrs["key"] = "token"
-
-			actionResponse = NewActionResponse("client.cookie.set", cookieResponseAttrs)
-			responses = append(responses, actionResponse)
+			responses = append(responses, SessionResponses(accessToken, refreshToken)...)
+			d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], existingUser, emailString, client, loginEventSuccess, transaction)
 
 			notificationAttrs := make(map[string]string)
 			notificationAttrs["message"] = "Logged in"
@@ -114,6 +114,7 @@ func (d *generateJwtTokenActionPerformer) DoAction(request Outcome, inFieldMap m
 			responses = append(responses, NewActionResponse("client.redirect", responseAttrs))
 
 		} else {
+			d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], existingUser, emailString, client, loginEventInvalidPassword, transaction)
 			responseAttrs = make(map[string]interface{})
 			responseAttrs["type"] = "error"
 			responseAttrs["title"] = "Failed"
@@ -128,29 +129,13 @@ func (d *generateJwtTokenActionPerformer) DoAction(request Outcome, inFieldMap m
 
 func NewGenerateJwtTokenPerformer(configStore *ConfigStore, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
 
-	secret, _ := configStore.GetConfigValueFor("jwt.secret", "backend")
-
-	tokenLifeTimeHours, err := configStore.GetConfigIntValueFor("jwt.token.life.hours", "backend")
-	CheckErr(err, "No default jwt token life time set in configuration")
-	if err != nil {
-		err = configStore.SetConfigIntValueFor("jwt.token.life.hours", 24*3, "backend")
-		CheckErr(err, "Failed to store default jwt token life time")
-		tokenLifeTimeHours = 24 * 3 // 3 days
-	}
-
-	jwtTokenIssuer, err := configStore.GetConfigValueFor("jwt.token.issuer", "backend")
-	CheckErr(err, "No default jwt token issuer set")
-	if err != nil {
-		uid, _ := uuid.NewV4()
-		jwtTokenIssuer = "daptin-" + uid.String()[0:6]
-		err = configStore.SetConfigValueFor("jwt.token.issuer", jwtTokenIssuer, "backend")
-	}
+	encryptionSecret, _ := configStore.GetConfigValueFor("encryption.secret", "backend")
 
 	handler := generateJwtTokenActionPerformer{
-		cruds:          cruds,
-		secret:         []byte(secret),
-		tokenLifeTime:  tokenLifeTimeHours,
-		jwtTokenIssuer: jwtTokenIssuer,
+		cruds:              cruds,
+		sessionTokenIssuer: NewSessionTokenIssuer(configStore),
+		encryptionSecret:   []byte(encryptionSecret),
+		loginGuard:         NewLoginGuard(configStore),
 	}
 
 	return &handler, nil
@@ -20,10 +20,10 @@ import (
 
 type generatePasswordResetActionPerformer struct {
 	cruds                  map[string]*DbResource
-	secret                 []byte
 	tokenLifeTime          int
 	jwtTokenIssuer         string
 	passwordResetEmailFrom string
+	sessionTokenIssuer     *SessionTokenIssuer
 }
 
 func (d *generatePasswordResetActionPerformer) Name() string {
@@ -52,18 +52,16 @@ func (d *generatePasswordResetActionPerformer) DoAction(request Outcome, inField
 		// you would like it to contain.
 		u, _ := uuid.NewV4()
 		email := existingUser["email"].(string)
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		// signed like the session tokens, with the current signing key
+		tokenString, err := d.sessionTokenIssuer.Sign(jwt.MapClaims{
 			"email": email,
 			"name":  existingUser["name"],
 			"nbf":   time.Now().Unix(),
 			"exp":   time.Now().Add(30 * time.Minute).Unix(),
 			"iss":   d.jwtTokenIssuer,
-			"iat":   time.Now(),
+			"iat":   time.Now().Unix(),
 			"jti":   u.String(),
 		})
-
-		// Sign and get the complete encoded token as a string using the secret
-		tokenString, err := token.SignedString(d.secret)
 		tokenStringBase64 := base64.StdEncoding.EncodeToString([]byte(tokenString))
 		fmt.Printf("%v %v", tokenStringBase64, err)
 		if err != nil {
@@ -116,8 +114,6 @@ func (d *generatePasswordResetActionPerformer) DoAction(request Outcome, inField
 
 func NewGeneratePasswordResetActionPerformer(configStore *ConfigStore, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
 
-	secret, _ := configStore.GetConfigValueFor("jwt.secret", "backend")
-
 	tokenLifeTimeHours, err := configStore.GetConfigIntValueFor("jwt.token.life.hours", "backend")
 	CheckErr(err, "No default jwt token life time set in configuration")
 	if err != nil {
@@ -147,9 +143,9 @@ func NewGeneratePasswordResetActionPerformer(configStore *ConfigStore, cruds map
 
 	handler := generatePasswordResetActionPerformer{
 		cruds:                  cruds,
-		secret:                 []byte(secret),
 		tokenLifeTime:          tokenLifeTimeHours,
 		passwordResetEmailFrom: passwordResetEmailFrom,
+		sessionTokenIssuer:     NewSessionTokenIssuer(configStore),
 
 		jwtTokenIssuer: jwtTokenIssuer,
 	}
@@ -2,8 +2,10 @@ package resource
 
 import (
 	"encoding/base64"
+	"errors"
 	"github.com/artpar/api2go"
 	"github.com/artpar/go.uuid"
+	"github.com/daptin/daptin/server/jwt"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/doug-martin/goqu/v9"
 	"github.com/jmoiron/sqlx"
@@ -48,9 +50,13 @@ func (d *generatePasswordResetVerifyActionPerformer) DoAction(request Outcome, i
 			responses = append(responses, actionResponse)
 		} else {
 
-			parsedToken, err := jwt.Parse(string(tokenString), func(token *jwt.Token) (interface{}, error) {
-				return d.secret, nil
-			})
+			parsedToken, err := jwt.Parse(string(tokenString), jwtmiddleware.SigningKeys.KeyFunc(d.secret))
+			if err == nil && parsedToken.Valid {
+				claims, _ := parsedToken.Claims.(jwt.MapClaims)
+				if claims["email"] != existingUsers[0]["email"] {
+					err = errors.New("token of another account")
+				}
+			}
 			if err != nil || !parsedToken.Valid {
 
 				notificationAttrs := make(map[string]string)
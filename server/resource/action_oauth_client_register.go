@@ -0,0 +1,128 @@
+package resource
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type oauthClientRegisterPerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *oauthClientRegisterPerformer) Name() string {
+	return "oauth_client.register"
+}
+
+// DoAction registers a client of the openid connect provider, the secret is only in the response and the
+// table keeps its hash
+func (d *oauthClientRegisterPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser.ApiKeyScope != nil || !IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+		return nil, nil, []error{errors.New("only administrators can register oauth clients")}
+	}
+	if err := rejectImpersonation(request); err != nil {
+		return nil, nil, []error{err}
+	}
+
+	name := strings.TrimSpace(queryValueString(inFields["name"]))
+	if name == "" {
+		return nil, nil, []error{errors.New("name is required")}
+	}
+	redirectUris, err := oauthRedirectUris(queryValueString(inFields["redirect_uris"]))
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	isPublic := queryValueBool(inFields["is_public"])
+
+	clientIdBytes := make([]byte, 16)
+	if _, err = rand.Read(clientIdBytes); err != nil {
+		return nil, nil, []error{err}
+	}
+	clientId := hex.EncodeToString(clientIdBytes)
+
+	redirectUrisJson, err := json.Marshal(redirectUris)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	oauthClientRow := map[string]interface{}{
+		"name":          name,
+		"client_id":     clientId,
+		"redirect_uris": string(redirectUrisJson),
+		"is_public":     isPublic,
+	}
+
+	// public clients, like single page and mobile apps, cannot keep a secret and use pkce instead
+	clientSecret := ""
+	if !isPublic {
+		clientSecret, err = auth.GenerateApiKey()
+		if err != nil {
+			return nil, nil, []error{err}
+		}
+		oauthClientRow["client_secret_hash"] = auth.HashApiKey(clientSecret)
+	}
+
+	httpReq := &http.Request{
+		Method: "POST",
+	}
+	httpReq = httpReq.WithContext(context.WithValue(context.Background(), "user", sessionUser))
+	created, err := d.cruds[OAUTH_CLIENT_TABLE_NAME].CreateWithoutFilter(api2go.NewApi2GoModelWithData(OAUTH_CLIENT_TABLE_NAME, nil, 0, nil, oauthClientRow),
+		api2go.Request{PlainRequest: httpReq}, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	registered := map[string]interface{}{
+		"reference_id":  created["reference_id"],
+		"name":          name,
+		"client_id":     clientId,
+		"redirect_uris": redirectUris,
+		"is_public":     isPublic,
+	}
+	message := "Client registered"
+	if clientSecret != "" {
+		registered["client_secret"] = clientSecret
+		message = "Copy the client secret now, it is not shown again"
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("oauth_client.registered", registered),
+		NewActionResponse("client.notify", NewClientNotification("message", message, "OAuth client registered")),
+	}, nil
+}
+
+// oauthRedirectUris parses the redirect uris of a client, one per line or separated by spaces or commas
+func oauthRedirectUris(value string) ([]string, error) {
+	redirectUris := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+	if len(redirectUris) == 0 {
+		return nil, errors.New("at least one redirect uri is required")
+	}
+	for _, redirectUri := range redirectUris {
+		parsed, err := url.Parse(redirectUri)
+		if err != nil || !parsed.IsAbs() || parsed.Host == "" || parsed.Fragment != "" {
+			return nil, fmt.Errorf("invalid redirect uri [%v], use an absolute uri without a fragment", redirectUri)
+		}
+	}
+	return redirectUris, nil
+}
+
+func NewOAuthClientRegisterPerformer(cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := oauthClientRegisterPerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
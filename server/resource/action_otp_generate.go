@@ -26,6 +26,10 @@ func (d *otpGenerateActionPerformer) Name() string {
 
 func (d *otpGenerateActionPerformer) DoAction(request Outcome, inFieldMap map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
 
+	if err := rejectImpersonation(request); err != nil {
+		return nil, nil, []error{err}
+	}
+
 	email, emailOk := inFieldMap["email"]
 	mobile, phoneOk := inFieldMap["mobile"]
 	var userAccount map[string]interface{}
@@ -119,11 +123,11 @@ func (d *otpGenerateActionPerformer) DoAction(request Outcome, inFieldMap map[st
 		_, err = d.cruds["user_otp_account"].UpdateWithoutFilters(
 			api2go.NewApi2GoModelWithData("user_otp_account", nil, 0, nil, userOtpProfile), req, transaction)
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			return nil, nil, []error{err}
 		} else {
-			commitErr := transaction.Commit()
+			commitErr := CommitTransaction(transaction)
 			CheckErr(commitErr, "failed to commmit")
 			if commitErr != nil {
 				return nil, nil, []error{commitErr}
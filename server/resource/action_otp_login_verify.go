@@ -3,14 +3,11 @@ package resource
 import (
 	"context"
 	"fmt"
-	"github.com/artpar/go.uuid"
 	"github.com/daptin/daptin/server/auth"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"net/http"
-	"strings"
 
 	//"golang.org/x/oauth2"
 	"github.com/artpar/api2go"
@@ -20,15 +17,14 @@ import (
 )
 
 type otpLoginVerifyActionPerformer struct {
-	responseAttrs    map[string]interface{}
-	cruds            map[string]*DbResource
-	configStore      *ConfigStore
-	encryptionSecret []byte
-	tokenLifeTime    int
-	jwtTokenIssuer   string
-	otpKey           string
-	secret           []byte
-	totpSecret       string
+	responseAttrs      map[string]interface{}
+	cruds              map[string]*DbResource
+	configStore        *ConfigStore
+	encryptionSecret   []byte
+	otpKey             string
+	totpSecret         string
+	sessionTokenIssuer *SessionTokenIssuer
+	loginGuard         *LoginGuard
 }
 
 func (d *otpLoginVerifyActionPerformer) Name() string {
@@ -47,6 +43,29 @@ func (d *otpLoginVerifyActionPerformer) DoAction(request Outcome, inFieldMap map
 		}
 	}
 	email, ok := inFieldMap["email"]
+
+	// the one time password stands in for the password, its attempts count towards the lockout of the
+	// email, or of the mobile number, and of the ip address
+	client, _ := request.Attributes["client"].(*ActionClient)
+	loginName := fmt.Sprintf("%v", email)
+	if email == nil || email == "" {
+		loginName = fmt.Sprintf("%v", inFieldMap["mobile"])
+	}
+	ipAddress := ""
+	if client != nil {
+		ipAddress = client.IpAddress
+	}
+	lockedUntil, err := d.loginGuard.LockedUntil(loginName, ipAddress, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if !lockedUntil.IsZero() {
+		d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], nil, loginName, client, loginEventLocked, transaction)
+		return nil, []ActionResponse{
+			NewActionResponse("client.notify", NewClientNotification("error", lockoutMessage(lockedUntil), "Failed")),
+		}, nil
+	}
+
 	var userAccount map[string]interface{}
 	var userOtpProfile map[string]interface{}
 	if email == nil || email == "" {
@@ -55,24 +74,21 @@ func (d *otpLoginVerifyActionPerformer) DoAction(request Outcome, inFieldMap map
 			return nil, nil, []error{errors.New("email or mobile missing")}
 		}
 		userOtpProfile, err = d.cruds["user_otp_account"].GetObjectByWhereClauseWithTransaction("user_otp_account", "mobile_number", phone.(string), transaction)
-		if err != nil || userOtpProfile == nil {
-			return nil, nil, []error{errors.New("unregistered mobile number")}
+		if err == nil && userOtpProfile != nil {
+			userAccount, _, err = d.cruds["user_account"].GetSingleRowByReferenceIdWithTransaction("user_account", userOtpProfile["otp_of_account"].(string), nil, transaction)
 		}
-		userAccount, _, err = d.cruds["user_account"].GetSingleRowByReferenceIdWithTransaction("user_account", userOtpProfile["otp_of_account"].(string), nil, transaction)
 	} else {
 		userAccount, err = d.cruds["user_account"].GetUserAccountRowByEmailWithTransaction(email.(string), transaction)
-		if err != nil {
-			return nil, nil, []error{errors.New("invalid email")}
+		if err == nil {
+			userOtpProfile, err = d.cruds["user_otp_account"].GetObjectByWhereClauseWithTransaction("user_otp_account", "otp_of_account", userAccount["id"], transaction)
 		}
-		userAccountId, ok := userAccount["id"]
-		if !ok {
-			return nil, nil, []error{errors.New("unregistered mobile number")}
-		}
-		userOtpProfile, err = d.cruds["user_otp_account"].GetObjectByWhereClauseWithTransaction("user_otp_account", "otp_of_account", userAccountId, transaction)
 	}
 
-	if err != nil || userOtpProfile == nil {
-		return nil, nil, []error{errors.New("Invalid OTP")}
+	if err != nil || userOtpProfile == nil || userAccount == nil {
+		d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], nil, loginName, client, loginEventUnknownUser, transaction)
+		return nil, []ActionResponse{
+			NewActionResponse("client.notify", NewClientNotification("error", "Invalid OTP", "Failed")),
+		}, nil
 	}
 
 	key, _ := Decrypt(d.encryptionSecret, userOtpProfile["otp_secret"].(string))
@@ -84,8 +100,10 @@ func (d *otpLoginVerifyActionPerformer) DoAction(request Outcome, inFieldMap map
 		Algorithm: otp.AlgorithmSHA1,
 	})
 	if !ok {
-		log.Errorf("Failed to validate otp key")
-		return nil, nil, []error{errors.New("Invalid OTP")}
+		d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], userAccount, loginName, client, loginEventInvalidOtp, transaction)
+		return nil, []ActionResponse{
+			NewActionResponse("client.notify", NewClientNotification("error", "Invalid OTP", "Failed")),
+		}, nil
 	}
 
 	if userOtpProfile["verified"].(int64) == 0 {
@@ -126,31 +144,23 @@ func (d *otpLoginVerifyActionPerformer) DoAction(request Outcome, inFieldMap map
 
 	} else {
 
-		u, _ := uuid.NewV4()
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"email":   userAccount["email"],
-			"name":    userAccount["name"],
-			"nbf":     time.Now().Unix(),
-			"exp":     time.Now().Add(time.Duration(d.tokenLifeTime) * time.Hour).Unix(),
-			"iss":     d.jwtTokenIssuer,
-			"picture": fmt.Sprintf("https://www.gravatar.com/avatar/%s&d=monsterid", GetMD5HashString(strings.ToLower(userAccount["email"].(string)))),
-			"iat":     time.Now(),
-			"jti":     u.String(),
-		})
-
-		// Sign and get the complete encoded token as a string using the secret
-		tokenString, err := token.SignedString(d.secret)
-		fmt.Printf("%v %v", tokenString, err)
+		// the one time password replaces the password, not the authenticator app
+		secondFactorResponses, reason, err := checkSecondFactor(userAccount, inFieldMap["two_factor_code"], d.encryptionSecret, transaction)
 		if err != nil {
-			log.Errorf("Failed to sign string: %v", err)
 			return nil, nil, []error{err}
 		}
+		if secondFactorResponses != nil {
+			d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], userAccount, loginName, client, reason, transaction)
+			return nil, secondFactorResponses, nil
+		}
 
-		responseAttrs := make(map[string]interface{})
-		responseAttrs["value"] = string(tokenString)
-		responseAttrs["key"] = "token"
-		actionResponse := NewActionResponse("client.store.set", responseAttrs)
-		responses = append(responses, actionResponse)
+		accessToken, refreshToken, err := d.sessionTokenIssuer.StartSession(userAccount, client, d.cruds[USER_SESSION_TABLE_NAME], transaction)
+		if err != nil {
+			log.Errorf("Failed to start session: %v", err)
+			return nil, nil, []error{err}
+		}
+		responses = append(responses, SessionResponses(accessToken, refreshToken)...)
+		d.loginGuard.Record(d.cruds[LOGIN_EVENT_TABLE_NAME], userAccount, loginName, client, loginEventSuccess, transaction)
 
 		notificationAttrs := make(map[string]string)
 		notificationAttrs["message"] = "Logged in"
@@ -172,34 +182,14 @@ func (d *otpLoginVerifyActionPerformer) DoAction(request Outcome, inFieldMap map
 
 func NewOtpLoginVerifyActionPerformer(cruds map[string]*DbResource, configStore *ConfigStore) (ActionPerformerInterface, error) {
 
-	configStore.GetConfigValueFor("jwt.secret", "backend")
-
-	jwtSecret, err := configStore.GetConfigValueFor("jwt.secret", "backend")
 	encryptionSecret, _ := configStore.GetConfigValueFor("encryption.secret", "backend")
 
-	tokenLifeTimeHours, err := configStore.GetConfigIntValueFor("jwt.token.life.hours", "backend")
-	CheckErr(err, "No default jwt token life time set in configuration")
-	if err != nil {
-		err = configStore.SetConfigIntValueFor("jwt.token.life.hours", 24*3, "backend")
-		CheckErr(err, "Failed to store default jwt token life time")
-		tokenLifeTimeHours = 24 * 3 // 3 days
-	}
-
-	jwtTokenIssuer, err := configStore.GetConfigValueFor("jwt.token.issuer", "backend")
-	CheckErr(err, "No default jwt token issuer set")
-	if err != nil {
-		uid, _ := uuid.NewV4()
-		jwtTokenIssuer = "daptin-" + uid.String()[0:6]
-		err = configStore.SetConfigValueFor("jwt.token.issuer", jwtTokenIssuer, "backend")
-	}
-
 	handler := otpLoginVerifyActionPerformer{
-		cruds:            cruds,
-		tokenLifeTime:    tokenLifeTimeHours,
-		configStore:      configStore,
-		encryptionSecret: []byte(encryptionSecret),
-		secret:           []byte(jwtSecret),
-		jwtTokenIssuer:   jwtTokenIssuer,
+		cruds:              cruds,
+		configStore:        configStore,
+		encryptionSecret:   []byte(encryptionSecret),
+		sessionTokenIssuer: NewSessionTokenIssuer(configStore),
+		loginGuard:         NewLoginGuard(configStore),
 	}
 
 	return &handler, nil
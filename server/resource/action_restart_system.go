@@ -8,11 +8,13 @@ import (
 	//"os/exec"
 	//"fmt"
 	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
 	"github.com/sadlil/go-trigger"
 )
 
 type restartSystemActionPerformer struct {
 	responseAttrs map[string]interface{}
+	configStore   *ConfigStore
 }
 
 func (d *restartSystemActionPerformer) Name() string {
@@ -38,14 +40,21 @@ func (d *restartSystemActionPerformer) DoAction(request Outcome, inFields map[st
 	actionResponse = NewActionResponse("client.redirect", restartAttrs)
 	responses = append(responses, actionResponse)
 
+	// the schema version recorded after the restart is attributed to this user
+	user, _ := request.Attributes["user"].(*auth.SessionUser)
+	err := SetSchemaPendingChange(d.configStore, "upload", user, transaction)
+	CheckErr(err, "Failed to mark pending schema change")
+
 	go restart()
 
 	return nil, responses, nil
 }
 
-func NewRestarSystemPerformer(initConfig *CmsConfig) (ActionPerformerInterface, error) {
+func NewRestarSystemPerformer(initConfig *CmsConfig, configStore *ConfigStore) (ActionPerformerInterface, error) {
 
-	handler := restartSystemActionPerformer{}
+	handler := restartSystemActionPerformer{
+		configStore: configStore,
+	}
 
 	return &handler, nil
 
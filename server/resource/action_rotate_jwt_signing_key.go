@@ -0,0 +1,46 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/jwt"
+	"github.com/jmoiron/sqlx"
+)
+
+type rotateJwtSigningKeyPerformer struct {
+	certificateManager *CertificateManager
+}
+
+func (d *rotateJwtSigningKeyPerformer) Name() string {
+	return "jwt.key.rotate"
+}
+
+// DoAction generates a new jwt signing key when the current one is due for rotation, or always with force
+func (d *rotateJwtSigningKeyPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	rotated, err := d.certificateManager.RotateJwtSigningKey(queryValueBool(inFields["force"]))
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	err = jwtmiddleware.SigningKeys.Reload()
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	message := "Signing key is not due for rotation"
+	if rotated {
+		message = "Signing key rotated"
+	}
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", message, "Success")),
+	}, nil
+}
+
+func NewRotateJwtSigningKeyPerformer(certificateManager *CertificateManager) (ActionPerformerInterface, error) {
+
+	handler := rotateJwtSigningKeyPerformer{
+		certificateManager: certificateManager,
+	}
+
+	return &handler, nil
+
+}
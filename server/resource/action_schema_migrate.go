@@ -0,0 +1,78 @@
+package resource
+
+import (
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+type schemaMigratePerformer struct {
+	cmsConfig *CmsConfig
+	cruds     map[string]*DbResource
+}
+
+func (d *schemaMigratePerformer) Name() string {
+	return "world.schema.migrate"
+}
+
+// DoAction plans the migration of the tables in the current config to the database, the plan is only
+// applied when apply is set, and destructive steps need confirm_destructive as well
+func (d *schemaMigratePerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	tableName, _ := inFields["table_name"].(string)
+	apply := queryValueBool(inFields["apply"])
+	confirmDestructive := queryValueBool(inFields["confirm_destructive"])
+
+	tables := make([]TableInfo, 0)
+	for _, table := range d.cmsConfig.Tables {
+		if tableName == "" || table.TableName == tableName {
+			tables = append(tables, table)
+		}
+	}
+	if len(tables) == 0 {
+		return nil, nil, []error{fmt.Errorf("no such table [%v]", tableName)}
+	}
+
+	plan, err := PlanSchemaMigration(tables, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	log.Printf("Schema migration plan:\n%v", plan.String())
+
+	responses := []ActionResponse{
+		NewActionResponse("migration.plan", plan),
+	}
+
+	if plan.IsEmpty() {
+		return nil, append(responses, NewActionResponse("client.notify",
+			NewClientNotification("message", "Database is up to date with the schema", "Success"))), nil
+	}
+
+	if !apply {
+		message := fmt.Sprintf("%d steps planned, %d destructive", len(plan.Steps), len(plan.DestructiveSteps()))
+		return nil, append(responses, NewActionResponse("client.notify",
+			NewClientNotification("message", message, "Dry run"))), nil
+	}
+
+	user, _ := request.Attributes["user"].(*auth.SessionUser)
+	err = ApplySchemaMigrationPlan(plan, confirmDestructive, user, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, append(responses, NewActionResponse("client.notify",
+		NewClientNotification("message", fmt.Sprintf("Applied %d migration steps", len(plan.Steps)), "Success"))), nil
+}
+
+func NewSchemaMigratePerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := schemaMigratePerformer{
+		cruds:     cruds,
+		cmsConfig: initConfig,
+	}
+
+	return &handler, nil
+
+}
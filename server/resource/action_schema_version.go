@@ -0,0 +1,182 @@
+package resource
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+type schemaDiffPerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *schemaDiffPerformer) Name() string {
+	return "world.schema.diff"
+}
+
+// DoAction returns the changes from from_version to to_version, or to the current schema when
+// to_version is not set
+func (d *schemaDiffPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	fromVersion, err := getSchemaVersionInField(inFields["from_version"], transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	var toTables []TableInfo
+	toVersionNumber := schemaVersionNumber(inFields["to_version"])
+	if toVersionNumber > 0 {
+		toVersion, err := getSchemaVersionInField(toVersionNumber, transaction)
+		if err != nil {
+			return nil, nil, []error{err}
+		}
+		toTables = toVersion.Tables
+	} else {
+		toTables, err = GetCurrentSchemaSnapshot(transaction)
+		if err != nil {
+			return nil, nil, []error{err}
+		}
+	}
+
+	diff := DiffSchemaSnapshots(fromVersion.Tables, toTables)
+
+	return nil, []ActionResponse{
+		NewActionResponse("schema.diff", diff),
+		NewActionResponse("client.notify", NewClientNotification("message", fmt.Sprintf("%d changes", len(diff)), "Schema diff")),
+	}, nil
+}
+
+func NewSchemaDiffPerformer(cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := schemaDiffPerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
+
+type schemaRollbackPerformer struct {
+	cruds       map[string]*DbResource
+	configStore *ConfigStore
+}
+
+func (d *schemaRollbackPerformer) Name() string {
+	return "world.schema.rollback"
+}
+
+// DoAction returns the changes and the migration plan to go back to the version, with apply set the
+// plan is executed, the world table is restored to the version and the system restarts
+func (d *schemaRollbackPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	version, err := getSchemaVersionInField(inFields["version"], transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	apply := queryValueBool(inFields["apply"])
+	confirmDestructive := queryValueBool(inFields["confirm_destructive"])
+
+	currentTables, err := GetCurrentSchemaSnapshot(transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	plan, newTables, err := PlanSchemaRollback(version, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	log.Printf("Schema rollback plan to version %d:\n%v", version.Version, plan.String())
+
+	responses := []ActionResponse{
+		NewActionResponse("schema.diff", DiffSchemaSnapshots(currentTables, version.Tables)),
+		NewActionResponse("migration.plan", plan),
+	}
+
+	message := fmt.Sprintf("%d steps planned, %d destructive", len(plan.Steps), len(plan.DestructiveSteps()))
+	if len(newTables) > 0 {
+		message = message + fmt.Sprintf(". Tables created after version %d are not removed: %v",
+			version.Version, strings.Join(newTables, ", "))
+	}
+
+	if !apply {
+		return nil, append(responses, NewActionResponse("client.notify",
+			NewClientNotification("message", message, "Dry run"))), nil
+	}
+
+	user, _ := request.Attributes["user"].(*auth.SessionUser)
+	err = ApplySchemaMigrationPlan(plan, confirmDestructive, user, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	err = RestoreSchemaVersion(version, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	err = SetSchemaPendingChange(d.configStore, fmt.Sprintf("rollback to %d", version.Version), user, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	archivedFiles, err := ArchiveUploadedSchemaFiles()
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	log.Printf("Archived uploaded schema files after rollback to version %d: %v", version.Version, archivedFiles)
+
+	responses = append(responses, NewActionResponse("client.notify",
+		NewClientNotification("success", fmt.Sprintf("Rolled back to version %d. %v", version.Version, message), "Success")))
+	responses = append(responses, NewActionResponse("client.redirect", map[string]interface{}{
+		"location": "/",
+		"window":   "self",
+		"delay":    5000,
+	}))
+
+	go restart()
+
+	return nil, responses, nil
+}
+
+func NewSchemaRollbackPerformer(cruds map[string]*DbResource, configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := schemaRollbackPerformer{
+		cruds:       cruds,
+		configStore: configStore,
+	}
+
+	return &handler, nil
+
+}
+
+func getSchemaVersionInField(value interface{}, transaction *sqlx.Tx) (*SchemaVersion, error) {
+	versionNumber := schemaVersionNumber(value)
+	if versionNumber < 1 {
+		return nil, fmt.Errorf("invalid schema version [%v]", value)
+	}
+	version, err := GetSchemaVersion(versionNumber, transaction)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no such schema version [%v]", versionNumber)
+	}
+	return version, err
+}
+
+// schemaVersionNumber reads the version from the action input, numbers in json requests are floats
+func schemaVersionNumber(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		version, _ := strconv.Atoi(strings.TrimSpace(v))
+		return version
+	}
+	return 0
+}
@@ -0,0 +1,209 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// softDeleteResource is the resource of table_name in the action fields, when the table has soft delete enabled
+func softDeleteResource(cruds map[string]*DbResource, inFields map[string]interface{}) (*DbResource, string, error) {
+	tableName, _ := inFields["table_name"].(string)
+	referenceId, _ := inFields["reference_id"].(string)
+	dbResource, ok := cruds[tableName]
+	if !ok || !dbResource.TableInfo().IsSoftDeleteEnabled {
+		return nil, "", fmt.Errorf("soft delete is not enabled on [%v]", tableName)
+	}
+	return dbResource, referenceId, nil
+}
+
+// checkSoftDeletePermission rejects a restore of a row the user cannot update, or a purge of a row the user
+// cannot delete. Administrators can restore and purge every row.
+func checkSoftDeletePermission(dbResource *DbResource, referenceId string, request Outcome, method string, transaction *sqlx.Tx) error {
+	sessionUser, _ := request.Attributes["user"].(*auth.SessionUser)
+	if sessionUser == nil {
+		sessionUser = &auth.SessionUser{}
+	}
+	tableName := dbResource.TableInfo().TableName
+	if !sessionUser.ApiKeyScope.AllowsTable(tableName, method) {
+		return api2go.NewHTTPError(nil, fmt.Sprintf("api key cannot change [%v]", tableName), 403)
+	}
+	if IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+		return nil
+	}
+
+	permission := dbResource.GetRowPermissionWithTransaction(map[string]interface{}{
+		"__type":       tableName,
+		"reference_id": referenceId,
+	}, transaction)
+	allowed := permission.CanUpdate(sessionUser.UserReferenceId, sessionUser.Groups)
+	if method == "DELETE" {
+		allowed = permission.CanDelete(sessionUser.UserReferenceId, sessionUser.Groups)
+	}
+	if !allowed {
+		return api2go.NewHTTPError(nil, fmt.Sprintf("not allowed to change [%v][%v]", tableName, referenceId), 403)
+	}
+	return nil
+}
+
+// softDeleteRequest is the delete request for the purges, as the user executing the action
+func softDeleteRequest(request Outcome) api2go.Request {
+	httpReq := &http.Request{
+		Method: "DELETE",
+	}
+	httpReq = httpReq.WithContext(context.WithValue(context.Background(), "user", request.Attributes["user"]))
+	return WithPurge(api2go.Request{
+		PlainRequest: httpReq,
+	})
+}
+
+type restoreRowPerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *restoreRowPerformer) Name() string {
+	return "world.row.restore"
+}
+
+func (d *restoreRowPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	dbResource, referenceId, err := softDeleteResource(d.cruds, inFields)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	err = checkSoftDeletePermission(dbResource, referenceId, request, "PATCH", transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	err = dbResource.RestoreWithTransaction(referenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", "Row restored", "Success")),
+	}, nil
+}
+
+func NewRestoreRowPerformer(cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := restoreRowPerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
+
+type purgeRowPerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *purgeRowPerformer) Name() string {
+	return "world.row.purge"
+}
+
+// DoAction permanently deletes a soft deleted row, with its relations and stored files
+func (d *purgeRowPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	dbResource, referenceId, err := softDeleteResource(d.cruds, inFields)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	row, err := dbResource.GetReferenceIdToObjectWithTransaction(dbResource.TableInfo().TableName, referenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if !isSoftDeleted(row) {
+		return nil, nil, []error{fmt.Errorf("row [%v][%v] is not deleted", dbResource.TableInfo().TableName, referenceId)}
+	}
+	err = checkSoftDeletePermission(dbResource, referenceId, request, "DELETE", transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	_, err = dbResource.DeleteWithTransaction(referenceId, softDeleteRequest(request), transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", "Row permanently deleted", "Success")),
+	}, nil
+}
+
+func NewPurgeRowPerformer(cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := purgeRowPerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
+
+type purgeExpiredRowsPerformer struct {
+	cruds       map[string]*DbResource
+	configStore *ConfigStore
+}
+
+func (d *purgeExpiredRowsPerformer) Name() string {
+	return "world.row.purge_expired"
+}
+
+// DoAction permanently deletes the rows of all soft delete tables which were deleted before the retention
+// period in soft_delete.retention_days, a retention of 0 days keeps the deleted rows
+func (d *purgeExpiredRowsPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	retentionDays, err := d.configStore.GetConfigIntValueFor("soft_delete.retention_days", "backend")
+	if err != nil {
+		retentionDays = DefaultSoftDeleteRetentionDays
+	}
+	if retentionDays < 1 {
+		return nil, []ActionResponse{}, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+	req := softDeleteRequest(request)
+	purged := 0
+	for tableName, dbResource := range d.cruds {
+		if !dbResource.TableInfo().IsSoftDeleteEnabled {
+			continue
+		}
+		referenceIds, err := dbResource.GetExpiredSoftDeletedReferenceIds(cutoff, softDeletePurgeBatchSize, transaction)
+		if err != nil {
+			return nil, nil, []error{err}
+		}
+		for _, referenceId := range referenceIds {
+			_, err = dbResource.DeleteWithTransaction(referenceId, req, transaction)
+			if err != nil {
+				return nil, nil, []error{fmt.Errorf("failed to purge [%v][%v]: %v", tableName, referenceId, err)}
+			}
+			purged++
+		}
+	}
+	log.Printf("Purged %d rows deleted before %v", purged, cutoff)
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", fmt.Sprintf("%d rows purged", purged), "Success")),
+	}, nil
+}
+
+func NewPurgeExpiredRowsPerformer(cruds map[string]*DbResource, configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := purgeExpiredRowsPerformer{
+		cruds:       cruds,
+		configStore: configStore,
+	}
+
+	return &handler, nil
+
+}
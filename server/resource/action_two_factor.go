@@ -0,0 +1,295 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/jmoiron/sqlx"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"image/png"
+	"net/http"
+	"time"
+)
+
+type totpEnrollPerformer struct {
+	cruds            map[string]*DbResource
+	encryptionSecret []byte
+	issuer           string
+}
+
+func (d *totpEnrollPerformer) Name() string {
+	return "totp.enroll"
+}
+
+// DoAction generates a new totp secret for the user, it guards signin once a first code is verified
+func (d *totpEnrollPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser.UserId == 0 {
+		return nil, nil, []error{errors.New("sign in to enable two factor authentication")}
+	}
+	if err := rejectImpersonation(request); err != nil {
+		return nil, nil, []error{err}
+	}
+	if sessionUser.ApiKeyScope != nil {
+		return nil, nil, []error{errors.New("api keys cannot enable two factor authentication")}
+	}
+
+	existing, err := getUserTotp(sessionUser.UserId, d.encryptionSecret, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if existing != nil && existing.Enabled {
+		return nil, nil, []error{errors.New("two factor authentication is already enabled")}
+	}
+	if existing != nil {
+		err = deleteUserTotp(sessionUser.UserId, transaction)
+		if err != nil {
+			return nil, nil, []error{err}
+		}
+	}
+
+	accountName := sessionUser.UserReferenceId
+	if user, ok := inFields["user"].(map[string]interface{}); ok {
+		if email, ok := user["email"].(string); ok && email != "" {
+			accountName = email
+		}
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      d.issuer,
+		AccountName: accountName,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	httpReq := &http.Request{
+		Method: "POST",
+	}
+	httpReq = httpReq.WithContext(context.WithValue(context.Background(), "user", sessionUser))
+	_, err = d.cruds[USER_TOTP_TABLE_NAME].CreateWithoutFilter(api2go.NewApi2GoModelWithData(USER_TOTP_TABLE_NAME, nil, 0, nil, map[string]interface{}{
+		"totp_secret": key.Secret(),
+		"enabled":     false,
+	}), api2go.Request{PlainRequest: httpReq}, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	enrollment := map[string]interface{}{
+		"secret": key.Secret(),
+		"uri":    key.URL(),
+	}
+	image, err := key.Image(256, 256)
+	if err == nil {
+		var qrCode bytes.Buffer
+		if err = png.Encode(&qrCode, image); err == nil {
+			enrollment["qr_code"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode.Bytes())
+		}
+	}
+	CheckErr(err, "Failed to generate totp qr code")
+
+	return nil, []ActionResponse{
+		NewActionResponse("totp.enrollment", enrollment),
+		NewActionResponse("client.notify", NewClientNotification("message", "Scan the code with your authenticator app and verify a code to finish", "Two factor authentication")),
+	}, nil
+}
+
+type totpEnablePerformer struct {
+	encryptionSecret []byte
+}
+
+func (d *totpEnablePerformer) Name() string {
+	return "totp.enable"
+}
+
+// DoAction enables the enrolled totp secret once the user verifies a code from it, and returns the recovery codes
+func (d *totpEnablePerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser.UserId == 0 {
+		return nil, nil, []error{errors.New("sign in to enable two factor authentication")}
+	}
+	if err := rejectImpersonation(request); err != nil {
+		return nil, nil, []error{err}
+	}
+
+	userTotpRow, err := getUserTotp(sessionUser.UserId, d.encryptionSecret, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if userTotpRow == nil {
+		return nil, nil, []error{errors.New("enroll an authenticator app first")}
+	}
+	if userTotpRow.Enabled {
+		return nil, nil, []error{errors.New("two factor authentication is already enabled")}
+	}
+
+	step, ok := userTotpRow.codeStep(queryValueString(inFields["code"]), time.Now())
+	if !ok {
+		return nil, []ActionResponse{
+			NewActionResponse("client.notify", NewClientNotification("error", "Invalid code", "Failed")),
+		}, nil
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	recoveryCodes, _ := json.Marshal(hashes)
+	err = userTotpRow.update(map[string]interface{}{
+		"enabled":        true,
+		"last_used_step": step,
+		"recovery_codes": string(recoveryCodes),
+	}, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("totp.recovery_codes", map[string]interface{}{
+			"recovery_codes": codes,
+		}),
+		NewActionResponse("client.notify", NewClientNotification("message", "Save the recovery codes now, they are not shown again", "Two factor authentication enabled")),
+	}, nil
+}
+
+type totpDisablePerformer struct {
+	encryptionSecret []byte
+}
+
+func (d *totpDisablePerformer) Name() string {
+	return "totp.disable"
+}
+
+// DoAction removes the totp enrollment of the user once they verify a code from the app or a recovery code
+func (d *totpDisablePerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser.UserId == 0 {
+		return nil, nil, []error{errors.New("sign in to disable two factor authentication")}
+	}
+	if err := rejectImpersonation(request); err != nil {
+		return nil, nil, []error{err}
+	}
+	if sessionUser.ApiKeyScope != nil {
+		return nil, nil, []error{errors.New("api keys cannot disable two factor authentication")}
+	}
+
+	userTotpRow, err := getUserTotp(sessionUser.UserId, d.encryptionSecret, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if userTotpRow == nil || !userTotpRow.Enabled {
+		return nil, nil, []error{errors.New("two factor authentication is not enabled")}
+	}
+
+	ok, err = userTotpRow.verifyCode(queryValueString(inFields["code"]), transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if !ok {
+		return nil, []ActionResponse{
+			NewActionResponse("client.notify", NewClientNotification("error", "Invalid code", "Failed")),
+		}, nil
+	}
+
+	err = deleteUserTotp(sessionUser.UserId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", "Two factor authentication disabled", "Success")),
+	}, nil
+}
+
+type totpResetPerformer struct {
+}
+
+func (d *totpResetPerformer) Name() string {
+	return "totp.reset"
+}
+
+// DoAction removes the totp enrollment of a user who lost their authenticator app, for administrators only
+func (d *totpResetPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser.Impersonator != nil || !IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+		return nil, nil, []error{errors.New("only administrators can reset two factor authentication")}
+	}
+
+	userReferenceId, _ := inFields["user_account_id"].(string)
+	userId, err := GetReferenceIdToIdWithTransaction(USER_ACCOUNT_TABLE_NAME, userReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	err = deleteUserTotp(userId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", "Two factor authentication reset", "Success")),
+	}, nil
+}
+
+func NewTotpEnrollPerformer(cruds map[string]*DbResource, configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	encryptionSecret, _ := configStore.GetConfigValueFor("encryption.secret", "backend")
+	issuer, err := configStore.GetConfigValueFor("totp.issuer", "backend")
+	if err != nil || issuer == "" {
+		issuer = "daptin"
+		err = configStore.SetConfigValueFor("totp.issuer", issuer, "backend")
+		CheckErr(err, "Failed to store default totp issuer")
+	}
+
+	handler := totpEnrollPerformer{
+		cruds:            cruds,
+		encryptionSecret: []byte(encryptionSecret),
+		issuer:           issuer,
+	}
+
+	return &handler, nil
+
+}
+
+func NewTotpEnablePerformer(configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	encryptionSecret, _ := configStore.GetConfigValueFor("encryption.secret", "backend")
+
+	handler := totpEnablePerformer{
+		encryptionSecret: []byte(encryptionSecret),
+	}
+
+	return &handler, nil
+
+}
+
+func NewTotpDisablePerformer(configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	encryptionSecret, _ := configStore.GetConfigValueFor("encryption.secret", "backend")
+
+	handler := totpDisablePerformer{
+		encryptionSecret: []byte(encryptionSecret),
+	}
+
+	return &handler, nil
+
+}
+
+func NewTotpResetPerformer() (ActionPerformerInterface, error) {
+
+	handler := totpResetPerformer{}
+
+	return &handler, nil
+
+}
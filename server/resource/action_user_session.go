@@ -0,0 +1,176 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+type refreshTokenPerformer struct {
+	sessionTokenIssuer *SessionTokenIssuer
+}
+
+func (d *refreshTokenPerformer) Name() string {
+	return "jwt.refresh"
+}
+
+// DoAction exchanges a refresh token for a new pair of tokens. Failures are returned as a 401 response
+// instead of an error so that revoking a session on a reused token is not rolled back.
+func (d *refreshTokenPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	refreshToken, _ := inFields["refresh_token"].(string)
+	if refreshToken == "" {
+		return nil, nil, []error{errors.New("refresh token is required")}
+	}
+
+	accessToken, newRefreshToken, err := d.sessionTokenIssuer.RefreshSession(refreshToken, "", transaction)
+	if err != nil {
+		log.Warnf("Failed to refresh session: %v", err)
+		return nil, []ActionResponse{
+			NewActionResponse("client.header.set", map[string]string{"Status": "401"}),
+			NewActionResponse("client.notify", NewClientNotification("error", err.Error(), "Failed")),
+		}, nil
+	}
+
+	return nil, SessionResponses(accessToken, newRefreshToken), nil
+}
+
+func NewRefreshTokenPerformer(configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := refreshTokenPerformer{
+		sessionTokenIssuer: NewSessionTokenIssuer(configStore),
+	}
+
+	return &handler, nil
+
+}
+
+type signOutPerformer struct {
+	sessionTokenIssuer *SessionTokenIssuer
+}
+
+func (d *signOutPerformer) Name() string {
+	return "session.signout"
+}
+
+// DoAction revokes the session of the access token of the request and clears the tokens on the client
+func (d *signOutPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if ok && sessionUser.SessionReferenceId != "" {
+		err := d.sessionTokenIssuer.RevokeSession(sessionUser.SessionReferenceId, transaction)
+		if err != nil {
+			return nil, nil, []error{err}
+		}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.store.set", map[string]interface{}{
+			"key":   "token",
+			"value": "",
+		}),
+		NewActionResponse("client.store.set", map[string]interface{}{
+			"key":   "refresh_token",
+			"value": "",
+		}),
+		NewActionResponse("client.cookie.set", map[string]interface{}{
+			"key":   "token",
+			"value": "; SameSite=Strict; Max-Age=0",
+		}),
+		NewActionResponse("client.notify", NewClientNotification("message", "Signed out", "Success")),
+	}, nil
+}
+
+func NewSignOutPerformer(configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := signOutPerformer{
+		sessionTokenIssuer: NewSessionTokenIssuer(configStore),
+	}
+
+	return &handler, nil
+
+}
+
+type revokeSessionPerformer struct {
+	sessionTokenIssuer *SessionTokenIssuer
+}
+
+func (d *revokeSessionPerformer) Name() string {
+	return "session.revoke"
+}
+
+func (d *revokeSessionPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionReferenceId, _ := inFields["user_session_id"].(string)
+	if sessionReferenceId == "" {
+		return nil, nil, []error{errors.New("session is required")}
+	}
+
+	err := d.sessionTokenIssuer.RevokeSession(sessionReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", "Session revoked", "Success")),
+	}, nil
+}
+
+func NewRevokeSessionPerformer(configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := revokeSessionPerformer{
+		sessionTokenIssuer: NewSessionTokenIssuer(configStore),
+	}
+
+	return &handler, nil
+
+}
+
+type revokeUserSessionsPerformer struct {
+	cruds              map[string]*DbResource
+	sessionTokenIssuer *SessionTokenIssuer
+}
+
+func (d *revokeUserSessionsPerformer) Name() string {
+	return "user.sessions.revoke"
+}
+
+// DoAction revokes all sessions and tokens of a user, for administrators or the user themselves
+func (d *revokeUserSessionsPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	userReferenceId, _ := inFields["user_account_id"].(string)
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || (sessionUser.UserReferenceId != userReferenceId && !IsAdminWithTransaction(sessionUser.UserReferenceId, transaction)) {
+		return nil, nil, []error{errors.New("only administrators can revoke the sessions of other users")}
+	}
+
+	userAccount, err := d.cruds[USER_ACCOUNT_TABLE_NAME].GetReferenceIdToObjectWithTransaction(USER_ACCOUNT_TABLE_NAME, userReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	userId, _ := userAccount["id"].(int64)
+	email, _ := userAccount["email"].(string)
+
+	count, err := d.sessionTokenIssuer.RevokeUserSessions(userId, email, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", fmt.Sprintf("%d sessions revoked", count), "Success")),
+	}, nil
+}
+
+func NewRevokeUserSessionsPerformer(cruds map[string]*DbResource, configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := revokeUserSessionsPerformer{
+		cruds:              cruds,
+		sessionTokenIssuer: NewSessionTokenIssuer(configStore),
+	}
+
+	return &handler, nil
+
+}
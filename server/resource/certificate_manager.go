@@ -205,12 +205,12 @@ func (cm *CertificateManager) GetTLSConfig(hostname string, createIfNotFound boo
 			}
 			_, err = cm.cruds["certificate"].UpdateWithoutFilters(data, req, transaction)
 			if err != nil {
-				rollbackErr := transaction.Rollback()
+				rollbackErr := RollbackTransaction(transaction)
 				CheckErr(rollbackErr, "Failed to rollback")
 				log.Printf("Failed to store locally generated certificate: %v", err)
 				return nil, nil, nil, nil, nil, err
 			} else {
-				commitErr := transaction.Commit()
+				commitErr := CommitTransaction(transaction)
 				CheckErr(commitErr, "Failed to commit")
 				if commitErr != nil {
 					return nil, nil, nil, nil, nil, commitErr
@@ -226,12 +226,12 @@ func (cm *CertificateManager) GetTLSConfig(hostname string, createIfNotFound boo
 			_, err = cm.cruds["certificate"].CreateWithoutFilter(data, req, transaction)
 
 			if err != nil {
-				rollbackErr := transaction.Rollback()
+				rollbackErr := RollbackTransaction(transaction)
 				CheckErr(rollbackErr, "Failed to rollback")
 				log.Printf("Failed to store locally generated certificate: %v", err)
 				return nil, nil, nil, nil, nil, err
 			}
-			commitErr := transaction.Commit()
+			commitErr := CommitTransaction(transaction)
 			CheckErr(commitErr, "failed to commit")
 			if commitErr != nil {
 				return nil, nil, nil, nil, nil, commitErr
@@ -0,0 +1,213 @@
+package resource
+
+import (
+	uuid "github.com/artpar/go.uuid"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+const ChangeLogTableName = "change_log"
+
+// ChangeLogEntry is one create/update/delete event from the change_log table
+// Sequence is the auto increment id of the row, consumers read from the last sequence they have seen
+type ChangeLogEntry struct {
+	Sequence          int64                  `json:"sequence"`
+	TableName         string                 `json:"table_name"`
+	EventType         string                 `json:"event_type"`
+	ObjectReferenceId string                 `json:"object_reference_id"`
+	UserReferenceId   string                 `json:"user_reference_id"`
+	ImpersonatedBy    string                 `json:"impersonated_by"`
+	Before            map[string]interface{} `json:"before"`
+	After             map[string]interface{} `json:"after"`
+	CreatedAt         interface{}            `json:"created_at"`
+}
+
+type changeLogRow struct {
+	Id                int64       `db:"id"`
+	TableName         string      `db:"table_name"`
+	EventType         string      `db:"event_type"`
+	ObjectReferenceId string      `db:"object_reference_id"`
+	UserReferenceId   *string     `db:"user_reference_id"`
+	ImpersonatedBy    *string     `db:"impersonated_by"`
+	BeforeImage       *string     `db:"before_image"`
+	AfterImage        *string     `db:"after_image"`
+	CreatedAt         interface{} `db:"created_at"`
+}
+
+// before images are captured in InterceptBefore and written with the after image in InterceptAfter, they are
+// kept with the transaction so that they are dropped when it commits or rolls back
+func changeLogKey(tableName string, referenceId string) string {
+	return tableName + "/" + referenceId
+}
+
+func captureBeforeImage(dr *DbResource, objects []map[string]interface{}, transaction *sqlx.Tx) {
+	tableName := dr.model.GetTableName()
+	if tableName == ChangeLogTableName {
+		return
+	}
+	state := stateOfTransaction(transaction)
+	for _, object := range objects {
+		referenceId, ok := object["reference_id"].(string)
+		if !ok || referenceId == "" {
+			continue
+		}
+		existing, err := dr.GetReferenceIdToObjectWithTransaction(tableName, referenceId, transaction)
+		if err != nil {
+			log.Errorf("Failed to load before image for change log [%v][%v]: %v", tableName, referenceId, err)
+			continue
+		}
+		state.lock.Lock()
+		if state.beforeImages == nil {
+			state.beforeImages = make(map[string]map[string]interface{})
+		}
+		state.beforeImages[changeLogKey(tableName, referenceId)] = existing
+		state.lock.Unlock()
+	}
+}
+
+func takeBeforeImage(transaction *sqlx.Tx, tableName string, referenceId string) map[string]interface{} {
+	value, ok := transactionStates.Load(transaction)
+	if !ok {
+		return nil
+	}
+	state := value.(*transactionState)
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	key := changeLogKey(tableName, referenceId)
+	image := state.beforeImages[key]
+	delete(state.beforeImages, key)
+	return image
+}
+
+// changeLogImage is the row as it is written to the change log and sent to webhooks. Secrets, columns
+// hidden from the api and read restricted columns are left out, the log is not read as any user.
+func changeLogImage(tableInfo *TableInfo, row map[string]interface{}) map[string]interface{} {
+	if tableInfo == nil || row == nil {
+		return row
+	}
+	excluded := tableInfo.readRestrictedColumns()
+	for _, column := range tableInfo.Columns {
+		if column.ExcludeFromApi || column.ColumnType == "password" || column.ColumnType == "bcrypt" || column.ColumnType == "encrypted" {
+			excluded[column.ColumnName] = true
+		}
+	}
+	image := make(map[string]interface{}, len(row))
+	for key, value := range row {
+		if !excluded[key] {
+			image[key] = value
+		}
+	}
+	return image
+}
+
+func marshalImage(image map[string]interface{}) interface{} {
+	if image == nil {
+		return nil
+	}
+	data, err := json.Marshal(image)
+	if err != nil {
+		log.Errorf("Failed to marshal change log image: %v", err)
+		return nil
+	}
+	return string(data)
+}
+
+// WriteChangeLog appends an event to the change_log table in the same transaction as the change itself
+// so that the log only contains committed changes. Changes made with an impersonation token name the
+// administrator in impersonated_by.
+func WriteChangeLog(transaction *sqlx.Tx, eventType string, tableName string, referenceId string,
+	before map[string]interface{}, after map[string]interface{}, user *auth.SessionUser) error {
+
+	if tableName == ChangeLogTableName {
+		return nil
+	}
+
+	var userReferenceId, impersonatedBy interface{}
+	if user != nil {
+		userReferenceId = user.UserReferenceId
+		if user.Impersonator != nil {
+			impersonatedBy = user.Impersonator.Email
+		}
+	}
+
+	newReferenceId, _ := uuid.NewV4()
+	s, v, err := statementbuilder.Squirrel.Insert(ChangeLogTableName).
+		Cols("table_name", "event_type", "object_reference_id", "user_reference_id", "impersonated_by",
+			"before_image", "after_image", "reference_id", "permission").
+		Vals([]interface{}{tableName, eventType, referenceId, userReferenceId, impersonatedBy,
+			marshalImage(before), marshalImage(after), newReferenceId.String(), auth.DEFAULT_PERMISSION}).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.Exec(s, v...)
+	return err
+}
+
+// GetChangeLogEntries returns the change log entries after the offset sequence in order
+// tableNames optionally restricts the entries to the given tables
+func (dbResource *DbResource) GetChangeLogEntries(offset int64, limit int, tableNames []string) ([]ChangeLogEntry, error) {
+
+	entries := make([]ChangeLogEntry, 0)
+
+	query := statementbuilder.Squirrel.Select("id", "table_name", "event_type", "object_reference_id",
+		"user_reference_id", "impersonated_by", "before_image", "after_image", "created_at").
+		From(ChangeLogTableName).
+		Where(goqu.Ex{"id": goqu.Op{"gt": offset}})
+	if len(tableNames) > 0 {
+		query = query.Where(goqu.Ex{"table_name": tableNames})
+	}
+	s, v, err := query.Order(goqu.C("id").Asc()).Limit(uint(limit)).ToSQL()
+	if err != nil {
+		return entries, err
+	}
+
+	rows, err := dbResource.db.Queryx(s, v...)
+	if err != nil {
+		return entries, err
+	}
+	defer func(rows *sqlx.Rows) {
+		err := rows.Close()
+		CheckErr(err, "Failed to close change log rows")
+	}(rows)
+
+	for rows.Next() {
+		var row changeLogRow
+		err = rows.StructScan(&row)
+		if err != nil {
+			return entries, err
+		}
+
+		entry := ChangeLogEntry{
+			Sequence:          row.Id,
+			TableName:         row.TableName,
+			EventType:         row.EventType,
+			ObjectReferenceId: row.ObjectReferenceId,
+			CreatedAt:         row.CreatedAt,
+		}
+		if createdAt, ok := row.CreatedAt.([]byte); ok {
+			entry.CreatedAt = string(createdAt)
+		}
+		if row.UserReferenceId != nil {
+			entry.UserReferenceId = *row.UserReferenceId
+		}
+		if row.ImpersonatedBy != nil {
+			entry.ImpersonatedBy = *row.ImpersonatedBy
+		}
+		if row.BeforeImage != nil {
+			err = json.Unmarshal([]byte(*row.BeforeImage), &entry.Before)
+			CheckErr(err, "Failed to unmarshal before image of change [%v]", row.Id)
+		}
+		if row.AfterImage != nil {
+			err = json.Unmarshal([]byte(*row.AfterImage), &entry.After)
+			CheckErr(err, "Failed to unmarshal after image of change [%v]", row.Id)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,91 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestChangeLogImage(t *testing.T) {
+
+	userAccount := &TableInfo{
+		TableName: "user_account",
+		Columns: []api2go.ColumnInfo{
+			{ColumnName: "email", ColumnType: "email"},
+			{ColumnName: "password", ColumnType: "password"},
+			{ColumnName: "secret", ColumnType: "encrypted"},
+			{ColumnName: "internal", ColumnType: "label", ExcludeFromApi: true},
+		},
+	}
+	row := map[string]interface{}{"email": "a@example.com", "password": "hash", "secret": "s", "internal": "i"}
+	image := changeLogImage(userAccount, row)
+	if len(image) != 1 || image["email"] != "a@example.com" {
+		t.Errorf("expected only the email in the change log image, got %v", image)
+	}
+	if row["password"] == nil {
+		t.Errorf("expected the row itself to be left unchanged")
+	}
+
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	transaction := db.MustBegin()
+	state := stateOfTransaction(transaction)
+	state.beforeImages = map[string]map[string]interface{}{changeLogKey("todo", "t1"): {"title": "before"}}
+	if image := takeBeforeImage(transaction, "todo", "t1"); image["title"] != "before" {
+		t.Errorf("expected the captured before image, got %v", image)
+	}
+	if image := takeBeforeImage(transaction, "todo", "t1"); image != nil {
+		t.Errorf("expected the before image to be taken once, got %v", image)
+	}
+
+	state.beforeImages[changeLogKey("todo", "t2")] = map[string]interface{}{"title": "aborted"}
+	if err = RollbackTransaction(transaction); err != nil {
+		t.Fatalf("failed to rollback: %v", err)
+	}
+	if _, ok := transactionStates.Load(transaction); ok {
+		t.Errorf("expected the before images to be dropped with the rolled back transaction")
+	}
+}
+
+func TestChangeLogImpersonatedBy(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	db.MustExec(`create table change_log (id integer primary key autoincrement, table_name varchar(100), event_type varchar(20),
+		object_reference_id varchar(64), user_reference_id varchar(64), impersonated_by varchar(80), before_image text,
+		after_image text, reference_id varchar(64), permission int, created_at timestamp default current_timestamp)`)
+
+	transaction := db.MustBegin()
+	impersonated := &auth.SessionUser{UserReferenceId: "user-ref",
+		Impersonator: &auth.Impersonator{UserReferenceId: "admin-ref", Email: "admin@example.com"}}
+	if err = WriteChangeLog(transaction, "update", "todo", "t1", nil, map[string]interface{}{"title": "a"}, impersonated); err != nil {
+		t.Fatalf("failed to write change log: %v", err)
+	}
+	if err = WriteChangeLog(transaction, "update", "todo", "t1", nil, map[string]interface{}{"title": "b"}, &auth.SessionUser{UserReferenceId: "user-ref"}); err != nil {
+		t.Fatalf("failed to write change log: %v", err)
+	}
+	if err = transaction.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	entries, err := (&DbResource{db: db}).GetChangeLogEntries(0, 10, nil)
+	if err != nil {
+		t.Fatalf("failed to read change log: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ImpersonatedBy != "admin@example.com" || entries[1].ImpersonatedBy != "" {
+		t.Errorf("expected the administrator on the impersonated change only, got %v", entries)
+	}
+}
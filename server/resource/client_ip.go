@@ -0,0 +1,71 @@
+package resource
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net"
+	"strings"
+)
+
+// trustedProxies are the networks of the reverse proxies whose X-Forwarded-For header is used, set once at
+// startup from http.trusted.proxies
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies parses the addresses and cidr ranges of the trusted proxies
+func SetTrustedProxies(proxies []string) error {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy [%v]", proxy)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			proxy = fmt.Sprintf("%v/%d", proxy, bits)
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy [%v]: %v", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	trustedProxies = networks
+	return nil
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP is the address the request comes from. Each proxy appends the address it got the request from to
+// X-Forwarded-For, so the header is read from the right and the first address which is not a trusted proxy is
+// the client. The entries before it are written by the client and are never used.
+func ClientIP(c *gin.Context) string {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		return ""
+	}
+	clientIP := net.ParseIP(host)
+	if clientIP == nil {
+		return ""
+	}
+
+	forwardedFor := strings.Join(c.Request.Header.Values("X-Forwarded-For"), ",")
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0 && isTrustedProxy(clientIP); i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		clientIP = hop
+	}
+	return clientIP.String()
+}
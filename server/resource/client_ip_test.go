@@ -0,0 +1,51 @@
+package resource
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+
+	if err := SetTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"}); err != nil {
+		t.Fatalf("failed to set trusted proxies: %v", err)
+	}
+	defer func() {
+		trustedProxies = nil
+	}()
+	if err := SetTrustedProxies([]string{"proxy.example.com"}); err == nil {
+		t.Errorf("expected an invalid trusted proxy to fail")
+	}
+
+	clientIP := func(remoteAddr string, forwardedFor ...string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/action/user_account/signin", nil)
+		c.Request.RemoteAddr = remoteAddr
+		for _, value := range forwardedFor {
+			c.Request.Header.Add("X-Forwarded-For", value)
+		}
+		return ClientIP(c)
+	}
+
+	if ip := clientIP("203.0.113.7:4000", "1.2.3.4"); ip != "203.0.113.7" {
+		t.Errorf("expected the header of an untrusted client to be ignored, got %v", ip)
+	}
+	if ip := clientIP("10.0.0.1:4000", "198.51.100.2"); ip != "198.51.100.2" {
+		t.Errorf("expected the address forwarded by the proxy, got %v", ip)
+	}
+	// the client sends a made up address, the proxy appends the address the request came from
+	if ip := clientIP("10.0.0.1:4000", "1.2.3.4, 198.51.100.2"); ip != "198.51.100.2" {
+		t.Errorf("expected the spoofed address to be skipped, got %v", ip)
+	}
+	if ip := clientIP("10.0.0.1:4000", "1.2.3.4", "198.51.100.2, 192.168.1.5"); ip != "198.51.100.2" {
+		t.Errorf("expected the trusted hops to be skipped, got %v", ip)
+	}
+	if ip := clientIP("10.0.0.1:4000", "1.2.3.4, not-an-ip"); ip != "10.0.0.1" {
+		t.Errorf("expected the proxy address for a malformed header, got %v", ip)
+	}
+	if ip := clientIP("10.0.0.1:4000"); ip != "10.0.0.1" {
+		t.Errorf("expected the proxy address without a header, got %v", ip)
+	}
+}
@@ -0,0 +1,260 @@
+package resource
+
+import (
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/jmoiron/sqlx"
+	"regexp"
+	"strings"
+)
+
+// columnReadPermission are the bits a column mask needs so that the column is as readable as its row
+const columnReadPermission = auth.GuestRead | auth.UserRead | auth.GroupRead
+
+// ColumnPermissionError is returned when a request writes or aggregates a column it is not allowed to
+type ColumnPermissionError struct {
+	TableName  string
+	ColumnName string
+	Access     string
+}
+
+func (e ColumnPermissionError) Error() string {
+	return fmt.Sprintf("not allowed to %v column [%v] of [%v]", e.Access, e.ColumnName, e.TableName)
+}
+
+// HttpError is the 403 error sent back for the denied column
+func (e ColumnPermissionError) HttpError() error {
+	return api2go.NewHTTPError(e, e.Error(), 403)
+}
+
+// ValidateColumnPermissions checks that the column permissions name columns of the table
+func (ti *TableInfo) ValidateColumnPermissions() error {
+	for columnName := range ti.ColumnPermissions {
+		_, isColumn := ti.GetColumnByName(columnName)
+		_, isVirtualColumn := ti.GetVirtualColumnByName(columnName)
+		if !isColumn && !isVirtualColumn {
+			return fmt.Errorf("column permission for unknown column [%v] in [%v]", columnName, ti.TableName)
+		}
+	}
+	return nil
+}
+
+// columnPermission is the permission of the row limited by the mask of the column, so a column never
+// allows more than its row
+func columnPermission(rowPermission PermissionInstance, mask auth.AuthPermission) PermissionInstance {
+	groups := make([]auth.GroupPermission, len(rowPermission.UserGroupId))
+	for i, group := range rowPermission.UserGroupId {
+		group.Permission = group.Permission & mask
+		groups[i] = group
+	}
+	return PermissionInstance{
+		UserId:      rowPermission.UserId,
+		UserGroupId: groups,
+		Permission:  rowPermission.Permission & mask,
+	}
+}
+
+// readRestrictedColumns are the columns whose mask takes away a read bit of the row, these are not
+// available in exports and aggregates which read many rows at once
+func (ti *TableInfo) readRestrictedColumns() map[string]bool {
+	restricted := make(map[string]bool)
+	for columnName, mask := range ti.ColumnPermissions {
+		if mask&columnReadPermission != columnReadPermission {
+			restricted[columnName] = true
+		}
+	}
+	return restricted
+}
+
+// removeUnreadableColumns returns a copy of the row without the columns the user cannot read
+func removeUnreadableColumns(tableInfo *TableInfo, row map[string]interface{}, rowPermission PermissionInstance, sessionUser *auth.SessionUser) map[string]interface{} {
+	if tableInfo == nil || len(tableInfo.ColumnPermissions) == 0 {
+		return row
+	}
+
+	var readableRow map[string]interface{}
+	for columnName, mask := range tableInfo.ColumnPermissions {
+		if _, ok := row[columnName]; !ok {
+			continue
+		}
+		if columnPermission(rowPermission, mask).CanRead(sessionUser.UserReferenceId, sessionUser.Groups) {
+			continue
+		}
+		if readableRow == nil {
+			readableRow = make(map[string]interface{}, len(row))
+			for key, value := range row {
+				readableRow[key] = value
+			}
+		}
+		delete(readableRow, columnName)
+	}
+
+	if readableRow == nil {
+		return row
+	}
+	return readableRow
+}
+
+// ReadableColumns returns the row without the columns the user cannot read, for rows which are not sent
+// through the api middlewares
+func (dbResource *DbResource) ReadableColumns(row map[string]interface{}, rowPermission PermissionInstance, sessionUser *auth.SessionUser) map[string]interface{} {
+	return removeUnreadableColumns(dbResource.tableInfo, row, rowPermission, sessionUser)
+}
+
+// ReadableRow returns the row of an event as the user can read it, false when the user cannot read the row.
+// Events, subscriptions and other rows sent outside of the api go through it.
+func (dbResource *DbResource) ReadableRow(row map[string]interface{}, sessionUser *auth.SessionUser) (map[string]interface{}, bool) {
+	if !sessionUser.ApiKeyScope.AllowsTable(dbResource.tableInfo.TableName, "GET") {
+		return nil, false
+	}
+	permission := dbResource.GetRowPermission(row)
+	if !permission.CanRead(sessionUser.UserReferenceId, sessionUser.Groups) {
+		return nil, false
+	}
+	return removeUnreadableColumns(dbResource.tableInfo, row, permission, sessionUser), true
+}
+
+// CheckQueryColumnPermissions rejects filters and sort orders on read restricted columns, the rows they
+// return would tell the values of the columns
+func (dbResource *DbResource) CheckQueryColumnPermissions(queries []Query, sortOrder []string) error {
+	restricted := dbResource.tableInfo.readRestrictedColumns()
+	if len(restricted) == 0 {
+		return nil
+	}
+	prefix := dbResource.tableInfo.TableName + "."
+	for _, query := range queries {
+		if restricted[strings.TrimPrefix(query.ColumnName, prefix)] {
+			return ColumnPermissionError{TableName: dbResource.tableInfo.TableName, ColumnName: query.ColumnName, Access: "filter"}.HttpError()
+		}
+	}
+	for _, sort := range sortOrder {
+		sort = aggregateQuotedValue.ReplaceAllString(sort, "")
+		for _, identifier := range aggregateIdentifier.FindAllString(sort, -1) {
+			if restricted[strings.TrimPrefix(identifier, prefix)] {
+				return ColumnPermissionError{TableName: dbResource.tableInfo.TableName, ColumnName: identifier, Access: "sort"}.HttpError()
+			}
+		}
+	}
+	return nil
+}
+
+// removeReadRestrictedColumns drops the read restricted columns from exported rows
+func removeReadRestrictedColumns(tableInfo *TableInfo, rows []map[string]interface{}) {
+	if tableInfo == nil {
+		return
+	}
+	restricted := tableInfo.readRestrictedColumns()
+	if len(restricted) == 0 {
+		return
+	}
+	for _, row := range rows {
+		for columnName := range restricted {
+			delete(row, columnName)
+		}
+	}
+}
+
+// checkColumnCreatePermission rejects a new row with a value for a column the user cannot create. There
+// is no row yet, the user creating it is its owner.
+func checkColumnCreatePermission(tableInfo *TableInfo, row map[string]interface{}, sessionUser *auth.SessionUser) error {
+	if tableInfo == nil {
+		return nil
+	}
+	for columnName, mask := range tableInfo.ColumnPermissions {
+		if value, ok := row[columnName]; !ok || value == nil {
+			continue
+		}
+		if mask&auth.GuestCreate == auth.GuestCreate ||
+			(sessionUser.UserReferenceId != "" && mask&auth.UserCreate == auth.UserCreate) ||
+			(len(sessionUser.Groups) > 0 && mask&auth.GroupCreate == auth.GroupCreate) {
+			continue
+		}
+		return ColumnPermissionError{TableName: tableInfo.TableName, ColumnName: columnName, Access: "create"}.HttpError()
+	}
+	return nil
+}
+
+// checkColumnUpdatePermission rejects changes to columns the user cannot update on the row
+func (dbResource *DbResource) checkColumnUpdatePermission(data api2go.Api2GoModel, req api2go.Request, transaction *sqlx.Tx) error {
+	if len(dbResource.tableInfo.ColumnPermissions) == 0 {
+		return nil
+	}
+
+	sessionUser := &auth.SessionUser{}
+	if user := req.PlainRequest.Context().Value("user"); user != nil {
+		sessionUser = user.(*auth.SessionUser)
+	}
+
+	changes := data.GetChanges()
+	var rowPermission *PermissionInstance
+	for columnName, mask := range dbResource.tableInfo.ColumnPermissions {
+		if _, ok := changes[columnName]; !ok {
+			continue
+		}
+		if rowPermission == nil {
+			if IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+				return nil
+			}
+			permission := dbResource.GetRowPermissionWithTransaction(map[string]interface{}{
+				"__type":       dbResource.tableInfo.TableName,
+				"reference_id": data.GetID(),
+			}, transaction)
+			rowPermission = &permission
+		}
+		if !columnPermission(*rowPermission, mask).CanUpdate(sessionUser.UserReferenceId, sessionUser.Groups) {
+			return ColumnPermissionError{TableName: dbResource.tableInfo.TableName, ColumnName: columnName, Access: "update"}.HttpError()
+		}
+	}
+	return nil
+}
+
+var aggregateIdentifier = regexp.MustCompile("[a-zA-Z_][a-zA-Z0-9_]*(\\.[a-zA-Z_][a-zA-Z0-9_]*)?")
+var aggregateQuotedValue = regexp.MustCompile("'[^']*'|\"[^\"]*\"")
+
+// checkAggregateColumnPermissions rejects an aggregation which uses a read restricted column of the root
+// entity or of a joined table anywhere in its expressions
+func checkAggregateColumnPermissions(cruds map[string]*DbResource, req AggregationRequest) error {
+
+	tableNames := []string{req.RootEntity}
+	for _, join := range req.Join {
+		tableNames = append(tableNames, strings.Split(join, "@")[0])
+	}
+
+	restricted := make(map[string]map[string]bool)
+	for _, tableName := range tableNames {
+		if cruds[tableName] == nil {
+			continue
+		}
+		if columns := cruds[tableName].TableInfo().readRestrictedColumns(); len(columns) > 0 {
+			restricted[tableName] = columns
+		}
+	}
+	if len(restricted) == 0 {
+		return nil
+	}
+
+	expressions := make([]string, 0)
+	for _, list := range [][]string{req.ProjectColumn, req.GroupBy, req.Filter, req.Having, req.Order, req.Join} {
+		expressions = append(expressions, list...)
+	}
+
+	for _, expression := range expressions {
+		expression = aggregateQuotedValue.ReplaceAllString(expression, "")
+		for _, identifier := range aggregateIdentifier.FindAllString(expression, -1) {
+			parts := strings.SplitN(identifier, ".", 2)
+			if len(parts) == 2 {
+				if restricted[parts[0]][parts[1]] {
+					return ColumnPermissionError{TableName: parts[0], ColumnName: parts[1], Access: "aggregate"}
+				}
+				continue
+			}
+			for tableName, columns := range restricted {
+				if columns[identifier] {
+					return ColumnPermissionError{TableName: tableName, ColumnName: identifier, Access: "aggregate"}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"testing"
+)
+
+func TestColumnPermissions(t *testing.T) {
+
+	employee := &TableInfo{
+		TableName: "employee",
+		Columns: []api2go.ColumnInfo{
+			{ColumnName: "name"},
+			{ColumnName: "salary"},
+			{ColumnName: "notes"},
+		},
+		ColumnPermissions: map[string]auth.AuthPermission{
+			// only the owner and the hr group read the salary, only the hr group sets it
+			"salary": auth.UserRead | auth.GroupRead | auth.GroupCreate | auth.GroupUpdate,
+			"notes":  auth.GuestRead | auth.UserCRUD | auth.GroupCRUD,
+		},
+	}
+	if err := employee.ValidateColumnPermissions(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	invalid := &TableInfo{TableName: "employee", ColumnPermissions: map[string]auth.AuthPermission{"bonus": auth.UserRead}}
+	if err := invalid.ValidateColumnPermissions(); err == nil {
+		t.Errorf("expected an error for a permission on an unknown column")
+	}
+
+	hr := auth.GroupPermission{GroupReferenceId: "hr", Permission: auth.GroupCRUD}
+	rowPermission := PermissionInstance{
+		UserId:      "owner",
+		UserGroupId: []auth.GroupPermission{hr},
+		Permission:  auth.GuestRead | auth.UserCRUD,
+	}
+	row := map[string]interface{}{"name": "n", "salary": 100, "notes": "x"}
+
+	cases := map[string]struct {
+		user    *auth.SessionUser
+		visible bool
+	}{
+		"guest":    {&auth.SessionUser{}, false},
+		"owner":    {&auth.SessionUser{UserReferenceId: "owner"}, true},
+		"hr":       {&auth.SessionUser{UserReferenceId: "other", Groups: []auth.GroupPermission{hr}}, true},
+		"outsider": {&auth.SessionUser{UserReferenceId: "other"}, false},
+	}
+	for name, c := range cases {
+		readable := removeUnreadableColumns(employee, row, rowPermission, c.user)
+		if _, ok := readable["salary"]; ok != c.visible {
+			t.Errorf("[%v] expected salary visible to be %v, got %v", name, c.visible, readable)
+		}
+		if readable["name"] != "n" || readable["notes"] != "x" {
+			t.Errorf("[%v] expected the other columns to be kept, got %v", name, readable)
+		}
+	}
+	if _, ok := row["salary"]; !ok {
+		t.Errorf("expected the original row to be left unchanged")
+	}
+
+	// the mask never allows more than the row
+	if columnPermission(PermissionInstance{UserId: "owner", Permission: auth.UserPeek}, employee.ColumnPermissions["notes"]).CanRead("owner", nil) {
+		t.Errorf("expected the column to be limited by the permission of the row")
+	}
+
+	if err := checkColumnCreatePermission(employee, map[string]interface{}{"name": "n", "notes": "x"}, &auth.SessionUser{UserReferenceId: "owner"}); err != nil {
+		t.Errorf("expected create without the salary to be allowed: %v", err)
+	}
+	if err := checkColumnCreatePermission(employee, row, &auth.SessionUser{UserReferenceId: "owner"}); err == nil {
+		t.Errorf("expected create with the salary to be rejected for a user without a group")
+	}
+	if err := checkColumnCreatePermission(employee, row, &auth.SessionUser{UserReferenceId: "other", Groups: []auth.GroupPermission{hr}}); err != nil {
+		t.Errorf("expected create with the salary to be allowed for a group member: %v", err)
+	}
+
+	cruds := map[string]*DbResource{
+		"employee": {tableInfo: employee},
+		"team":     {tableInfo: &TableInfo{TableName: "team"}},
+	}
+	if err := checkAggregateColumnPermissions(cruds, AggregationRequest{RootEntity: "employee", ProjectColumn: []string{"count", "name"}, Filter: []string{"eq(notes,'salary')"}}); err != nil {
+		t.Errorf("expected aggregation without the salary to be allowed: %v", err)
+	}
+	if err := checkAggregateColumnPermissions(cruds, AggregationRequest{RootEntity: "employee", ProjectColumn: []string{"sum(salary) as total"}}); err == nil {
+		t.Errorf("expected aggregation over the salary to be rejected")
+	}
+	if err := checkAggregateColumnPermissions(cruds, AggregationRequest{RootEntity: "team", Join: []string{"employee@eq(employee.team_id,team.id)"}, GroupBy: []string{"employee.salary"}}); err == nil {
+		t.Errorf("expected grouping by the salary of a joined table to be rejected")
+	}
+
+	if err := cruds["employee"].CheckQueryColumnPermissions([]Query{{ColumnName: "name", Operator: "is", Value: "n"}}, []string{"-notes"}); err != nil {
+		t.Errorf("expected filter and sort on readable columns to be allowed: %v", err)
+	}
+	if err := cruds["employee"].CheckQueryColumnPermissions([]Query{{ColumnName: "salary", Operator: "more than", Value: 100}}, nil); err == nil {
+		t.Errorf("expected filter on the salary to be rejected")
+	}
+	if err := cruds["employee"].CheckQueryColumnPermissions(nil, []string{"-employee.salary"}); err == nil {
+		t.Errorf("expected sort on the salary to be rejected")
+	}
+	if logged := changeLogImage(employee, row); logged["salary"] != nil || logged["name"] != "n" || row["salary"] == nil {
+		t.Errorf("expected a copy of the row without the salary, got %v", logged)
+	}
+}
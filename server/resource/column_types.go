@@ -74,7 +74,8 @@ func (ct ColumnType) Fake() interface{} {
 	case "json":
 		return "{}"
 	case "password":
-		pass := fake.SimplePassword()
+		// long enough for the default password policy, it is emailed by the password reset flow
+		pass := fake.Password(16, 20, true, true, false)
 		return pass
 	case "bcrypt":
 		pass, _ := BcryptHashString(fake.SimplePassword())
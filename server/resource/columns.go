@@ -82,6 +82,7 @@ var StandardRelations = []api2go.TableRelation{
 	api2go.NewTableRelation("mail_account", "belongs_to", "mail_server"),
 	api2go.NewTableRelation("mail_box", "belongs_to", "mail_account"),
 	api2go.NewTableRelation("mail", "belongs_to", "mail_box"),
+	api2go.NewTableRelation("webhook_delivery", "belongs_to", "webhook"),
 	api2go.NewTableRelationWithNames("task", "task_executed", "has_one", USER_ACCOUNT_TABLE_NAME, "as_user_id"),
 	api2go.NewTableRelation("calendar", "has_one", "collection"),
 	api2go.NewTableRelationWithNames("user_otp_account", "primary_user_otp", "belongs_to", "user_account", "otp_of_account"),
@@ -203,6 +204,29 @@ var SystemActions = []Action{
 			},
 		},
 	},
+	{
+		Name:             "rotate_jwt_signing_key",
+		Label:            "Rotate JWT signing key",
+		OnType:           "certificate",
+		InstanceOptional: true,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "force",
+				ColumnName: "force",
+				ColumnType: "truefalse",
+				IsNullable: true,
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "jwt.key.rotate",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"force": "~force",
+				},
+			},
+		},
+	},
 	{
 		Name:             "register_otp",
 		Label:            "Register Mobile Number",
@@ -323,14 +347,21 @@ var SystemActions = []Action{
 				ColumnName: "mobile_number",
 				ColumnType: "label",
 			},
+			{
+				Name:       "two_factor_code",
+				ColumnName: "two_factor_code",
+				ColumnType: "label",
+				IsNullable: true,
+			},
 		},
 		OutFields: []Outcome{
 			{
 				Type:   "otp.login.verify",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"otp":    "~otp",
-					"mobile": "~mobile_number",
+					"otp":             "~otp",
+					"mobile":          "~mobile_number",
+					"two_factor_code": "~two_factor_code",
 				},
 			},
 		},
@@ -409,1010 +440,1138 @@ var SystemActions = []Action{
 		},
 	},
 	{
-		Name:             "sync_site_storage",
-		Label:            "Sync site storage",
-		OnType:           "site",
-		InstanceOptional: false,
+		Name:             "migrate_schema",
+		Label:            "Migrate database schema",
+		OnType:           "world",
+		InstanceOptional: true,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "Path",
-				ColumnName: "path",
+				Name:       "table_name",
+				ColumnName: "table_name",
 				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				Name:       "apply",
+				ColumnName: "apply",
+				ColumnType: "truefalse",
+				IsNullable: true,
+			},
+			{
+				Name:       "confirm_destructive",
+				ColumnName: "confirm_destructive",
+				ColumnType: "truefalse",
+				IsNullable: true,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "site.storage.sync",
+				Type:   "world.schema.migrate",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"cloud_store_id": "$.cloud_store_id",
-					"site_id":        "$.reference_id",
-					"path":           "~path",
+					"table_name":          "~table_name",
+					"apply":               "~apply",
+					"confirm_destructive": "~confirm_destructive",
 				},
 			},
 		},
 	},
 	{
-		Name:             "sync_column_storage",
-		Label:            "Sync column storage",
+		Name:             "diff_schema_versions",
+		Label:            "Compare schema versions",
 		OnType:           "world",
 		InstanceOptional: true,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "Table name",
-				ColumnName: "table_name",
-				ColumnType: "label",
+				Name:       "from_version",
+				ColumnName: "from_version",
+				ColumnType: "measurement",
 			},
 			{
-				Name:       "Column name",
-				ColumnName: "column_name",
-				ColumnType: "label",
+				Name:       "to_version",
+				ColumnName: "to_version",
+				ColumnType: "measurement",
+				IsNullable: true,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "column.storage.sync",
+				Type:   "world.schema.diff",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"column_name": "~column_name",
-					"table_name":  "~table_name",
+					"from_version": "~from_version",
+					"to_version":   "~to_version",
 				},
 			},
 		},
 	},
 	{
-		Name:             "sync_mail_servers",
-		Label:            "Sync Mail Servers",
-		OnType:           "mail_server",
+		Name:             "rollback_schema",
+		Label:            "Rollback schema to version",
+		OnType:           "world",
 		InstanceOptional: true,
-		InFields:         []api2go.ColumnInfo{},
-		OutFields: []Outcome{
+		InFields: []api2go.ColumnInfo{
 			{
-				Type:       "mail.servers.sync",
-				Method:     "EXECUTE",
-				Attributes: map[string]interface{}{},
+				Name:       "version",
+				ColumnName: "version",
+				ColumnType: "measurement",
+			},
+			{
+				Name:       "apply",
+				ColumnName: "apply",
+				ColumnType: "truefalse",
+				IsNullable: true,
+			},
+			{
+				Name:       "confirm_destructive",
+				ColumnName: "confirm_destructive",
+				ColumnType: "truefalse",
+				IsNullable: true,
 			},
 		},
-	},
-	{
-		Name:             "restart_daptin",
-		Label:            "Restart system",
-		OnType:           "world",
-		InstanceOptional: true,
-		InFields:         []api2go.ColumnInfo{},
 		OutFields: []Outcome{
 			{
-				Type:   "system_json_schema_update",
+				Type:   "world.schema.rollback",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"json_schema": "!JSON.parse('[{\"name\":\"empty.json\",\"file\":\"data:application/json;base64,e30K\",\"type\":\"application/json\"}]')",
+					"version":             "~version",
+					"apply":               "~apply",
+					"confirm_destructive": "~confirm_destructive",
 				},
 			},
 		},
 	},
 	{
-		Name:             "generate_random_data",
-		Label:            "Generate random data",
-		OnType:           "world",
+		Name:             "create_api_key",
+		Label:            "Create API key",
+		OnType:           "api_key",
 		InstanceOptional: true,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "Number of records",
-				ColumnName: "count",
+				Name:       "name",
+				ColumnName: "name",
+				ColumnType: "label",
+			},
+			{
+				Name:       "expires_in_days",
+				ColumnName: "expires_in_days",
 				ColumnType: "measurement",
+				IsNullable: true,
 			},
 			{
-				Name:       "Table name",
-				ColumnName: "table_name",
-				ColumnType: "label",
+				Name:       "scopes",
+				ColumnName: "scopes",
+				ColumnType: "json",
+				IsNullable: true,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "generate.random.data",
+				Type:   "api_key.create",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"count":             "~count",
-					"table_name":        "~table_name",
-					"user_reference_id": "$user.reference_id",
-					"user_account_id":   "$user.id",
+					"name":            "~name",
+					"expires_in_days": "~expires_in_days",
+					"scopes":          "~scopes",
 				},
 			},
 		},
-		Validations: []ColumnTag{
-			{
-				ColumnName: "count",
-				Tags:       "gt=0",
-			},
-		},
 	},
 	{
-		Name:             "export_data",
-		Label:            "Export data for backup",
-		OnType:           "world",
+		Name:             "refresh_token",
+		Label:            "Refresh session token",
 		InstanceOptional: true,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				ColumnName: "table_name",
-				Name:       "table_name",
+				Name:       "refresh_token",
+				ColumnName: "refresh_token",
 				ColumnType: "label",
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "__data_export",
+				Type:   "jwt.refresh",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"table_name": "~table_name",
+					"refresh_token": "~refresh_token",
 				},
 			},
 		},
 	},
 	{
-		Name:             "export_csv_data",
-		Label:            "Export CSV data",
-		OnType:           "world",
+		Name:             "signout",
+		Label:            "Sign out",
 		InstanceOptional: true,
-		InFields: []api2go.ColumnInfo{
+		OnType:           USER_ACCOUNT_TABLE_NAME,
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
 			{
-				ColumnName: "table_name",
-				Name:       "table_name",
-				ColumnType: "label",
+				Type:       "session.signout",
+				Method:     "EXECUTE",
+				Attributes: map[string]interface{}{},
 			},
 		},
+	},
+	{
+		Name:             "revoke_session",
+		Label:            "Revoke session",
+		InstanceOptional: false,
+		OnType:           USER_SESSION_TABLE_NAME,
+		InFields:         []api2go.ColumnInfo{},
 		OutFields: []Outcome{
 			{
-				Type:   "__csv_data_export",
+				Type:   "session.revoke",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"table_name": "~table_name",
+					"user_session_id": "$.reference_id",
 				},
 			},
 		},
 	},
 	{
-		Name:             "import_data",
-		Label:            "Import data from dump",
-		OnType:           "world",
+		Name:             "revoke_all_sessions",
+		Label:            "Revoke all sessions",
 		InstanceOptional: false,
-		InFields: []api2go.ColumnInfo{
-			{
-				Name:       "JSON Dump file",
-				ColumnName: "dump_file",
-				ColumnType: "file.json|yaml|toml|hcl",
-				IsNullable: false,
-			},
-			{
-				Name:       "truncate_before_insert",
-				ColumnName: "truncate_before_insert",
-				ColumnType: "truefalse",
-			},
-		},
+		OnType:           USER_ACCOUNT_TABLE_NAME,
+		InFields:         []api2go.ColumnInfo{},
 		OutFields: []Outcome{
 			{
-				Type:   "__data_import",
+				Type:   "user.sessions.revoke",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"world_reference_id":     "$.reference_id",
-					"truncate_before_insert": "~truncate_before_insert",
-					"dump_file":              "~dump_file",
-					"table_name":             "$.table_name",
-					"user":                   "~user",
+					"user_account_id": "$.reference_id",
 				},
 			},
 		},
 	},
 	{
-		Name:             "upload_file",
-		Label:            "Upload file to external store",
-		OnType:           "cloud_store",
+		Name:             "impersonate_user",
+		Label:            "Act as this user",
 		InstanceOptional: false,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "File",
-				ColumnName: "file",
-				ColumnType: "file.*",
-				IsNullable: false,
+				Name:       "minutes",
+				ColumnName: "minutes",
+				ColumnType: "measurement",
+				IsNullable: true,
 			},
+		},
+		OutFields: []Outcome{
 			{
-				Name:         "Path",
-				ColumnName:   "path",
-				ColumnType:   "label",
-				IsNullable:   true,
-				DefaultValue: "",
+				Type:   "user.impersonate",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"user_account_id": "$.reference_id",
+					"minutes":         "~minutes",
+				},
 			},
 		},
+	},
+	{
+		Name:             "revoke_impersonation",
+		Label:            "Revoke impersonation token",
+		InstanceOptional: false,
+		OnType:           LOGIN_EVENT_TABLE_NAME,
+		InFields:         []api2go.ColumnInfo{},
 		OutFields: []Outcome{
 			{
-				Type:   "cloudstore.file.upload",
+				Type:   "impersonation.revoke",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"file":           "~file",
-					"oauth_token_id": "$.oauth_token_id",
-					"store_provider": "$.store_provider",
-					"path":           "~path",
-					"root_path":      "$.root_path",
+					"login_event_id": "$.reference_id",
 				},
 			},
 		},
 	},
 	{
-		Name:             "create_site",
-		Label:            "Create new site on this store",
-		OnType:           "cloud_store",
-		InstanceOptional: false,
+		Name:             "register_oauth_client",
+		Label:            "Register OAuth client",
+		InstanceOptional: true,
+		OnType:           OAUTH_CLIENT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "Site type",
-				ColumnName: "site_type",
+				Name:       "name",
+				ColumnName: "name",
 				ColumnType: "label",
-				IsNullable: false,
 			},
 			{
-				Name:         "Path",
-				ColumnName:   "path",
-				ColumnType:   "label",
-				IsNullable:   false,
-				DefaultValue: "",
+				Name:       "redirect_uris",
+				ColumnName: "redirect_uris",
+				ColumnType: "content",
 			},
 			{
-				Name:       "Hostname",
-				ColumnName: "hostname",
-				ColumnType: "label",
-				IsNullable: false,
+				Name:       "is_public",
+				ColumnName: "is_public",
+				ColumnType: "truefalse",
+				IsNullable: true,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "cloudstore.site.create",
+				Type:   "oauth_client.register",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"oauth_token_id":  "$.oauth_token_id",
-					"store_provider":  "$.store_provider",
-					"cloud_store_id":  "$.reference_id",
-					"path":            "~path",
-					"user_account_id": "$user.reference_id",
-					"hostname":        "~hostname",
-					"site_type":       "~site_type",
-					"root_path":       "$.root_path",
+					"name":          "~name",
+					"redirect_uris": "~redirect_uris",
+					"is_public":     "~is_public",
 				},
 			},
 		},
 	},
-
 	{
-		Name:             "delete_path",
-		Label:            "Delete path on a cloud store",
-		OnType:           "cloud_store",
-		InstanceOptional: false,
+		Name:             "enable_two_factor",
+		Label:            "Enable two factor authentication",
+		InstanceOptional: true,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:       "totp.enroll",
+				Method:     "EXECUTE",
+				Attributes: map[string]interface{}{},
+			},
+		},
+	},
+	{
+		Name:             "verify_two_factor",
+		Label:            "Verify two factor code",
+		InstanceOptional: true,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:         "Path",
-				ColumnName:   "path",
-				ColumnType:   "label",
-				IsNullable:   true,
-				DefaultValue: "",
+				Name:       "code",
+				ColumnName: "code",
+				ColumnType: "label",
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "cloudstore.file.delete",
+				Type:   "totp.enable",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"oauth_token_id": "$.oauth_token_id",
-					"store_provider": "$.store_provider",
-					"path":           "~path",
-					"root_path":      "$.root_path",
+					"code": "~code",
 				},
 			},
 		},
 	},
-
 	{
-		Name:             "create_folder",
-		Label:            "Create folder on a cloud store",
-		OnType:           "cloud_store",
-		InstanceOptional: false,
+		Name:             "disable_two_factor",
+		Label:            "Disable two factor authentication",
+		InstanceOptional: true,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:         "Path",
-				ColumnName:   "path",
-				ColumnType:   "label",
-				IsNullable:   true,
-				DefaultValue: "",
-			},
-			{
-				Name:       "Name",
-				ColumnName: "name",
+				Name:       "code",
+				ColumnName: "code",
 				ColumnType: "label",
-				IsNullable: false,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "cloudstore.folder.create",
+				Type:   "totp.disable",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"oauth_token_id": "$.oauth_token_id",
-					"store_provider": "$.store_provider",
-					"path":           "~path",
-					"name":           "~name",
-					"root_path":      "$.root_path",
+					"code": "~code",
 				},
 			},
 		},
 	},
-
 	{
-		Name:             "move_path",
-		Label:            "Create folder on a cloud store",
-		OnType:           "cloud_store",
+		Name:             "reset_two_factor",
+		Label:            "Reset two factor authentication",
 		InstanceOptional: false,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:   "totp.reset",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"user_account_id": "$.reference_id",
+				},
+			},
+		},
+	},
+	{
+		Name:             "restore_deleted_row",
+		Label:            "Restore deleted row",
+		OnType:           "world",
+		InstanceOptional: true,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "Source path",
-				ColumnName: "source",
+				Name:       "table_name",
+				ColumnName: "table_name",
 				ColumnType: "label",
 			},
 			{
-				Name:       "Destination path",
-				ColumnName: "destination",
+				Name:       "reference_id",
+				ColumnName: "reference_id",
 				ColumnType: "label",
-				IsNullable: false,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "cloudstore.path.move",
+				Type:   "world.row.restore",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"oauth_token_id": "$.oauth_token_id",
-					"store_provider": "$.store_provider",
-					"source":         "~source",
-					"destination":    "~destination",
-					"root_path":      "$.root_path",
+					"table_name":   "~table_name",
+					"reference_id": "~reference_id",
 				},
 			},
 		},
 	},
 	{
-		Name:             "list_files",
-		Label:            "List files in the site path",
-		OnType:           "site",
-		InstanceOptional: false,
+		Name:             "purge_deleted_row",
+		Label:            "Permanently delete row",
+		OnType:           "world",
+		InstanceOptional: true,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "path",
-				ColumnName: "path",
+				Name:       "table_name",
+				ColumnName: "table_name",
+				ColumnType: "label",
+			},
+			{
+				Name:       "reference_id",
+				ColumnName: "reference_id",
 				ColumnType: "label",
-				IsNullable: false,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "site.file.list",
+				Type:   "world.row.purge",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"site_id": "$.reference_id",
-					"path":    "~path",
+					"table_name":   "~table_name",
+					"reference_id": "~reference_id",
 				},
 			},
 		},
 	},
-
 	{
-		Name:             "get_file",
-		Label:            "Get file at the path in site",
+		Name:             "purge_expired_deleted_rows",
+		Label:            "Purge expired deleted rows",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:       "world.row.purge_expired",
+				Method:     "EXECUTE",
+				Attributes: map[string]interface{}{},
+			},
+		},
+	},
+	{
+		Name:             "sync_site_storage",
+		Label:            "Sync site storage",
 		OnType:           "site",
 		InstanceOptional: false,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "path",
+				Name:       "Path",
 				ColumnName: "path",
 				ColumnType: "label",
-				IsNullable: false,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "site.file.get",
+				Type:   "site.storage.sync",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"site_id": "$.reference_id",
-					"path":    "~path",
+					"cloud_store_id": "$.cloud_store_id",
+					"site_id":        "$.reference_id",
+					"path":           "~path",
 				},
 			},
 		},
 	},
 	{
-		Name:             "delete_file",
-		Label:            "Delete file in the site",
-		OnType:           "site",
-		InstanceOptional: false,
+		Name:             "sync_column_storage",
+		Label:            "Sync column storage",
+		OnType:           "world",
+		InstanceOptional: true,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "path",
-				ColumnName: "path",
+				Name:       "Table name",
+				ColumnName: "table_name",
+				ColumnType: "label",
+			},
+			{
+				Name:       "Column name",
+				ColumnName: "column_name",
 				ColumnType: "label",
-				IsNullable: false,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "site.file.delete",
+				Type:   "column.storage.sync",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"site_id": "$.reference_id",
-					"path":    "~path",
+					"column_name": "~column_name",
+					"table_name":  "~table_name",
 				},
 			},
 		},
 	},
 	{
-		Name:             "upload_system_schema",
-		Label:            "Upload features",
-		OnType:           "world",
+		Name:             "sync_mail_servers",
+		Label:            "Sync Mail Servers",
+		OnType:           "mail_server",
 		InstanceOptional: true,
-		InFields: []api2go.ColumnInfo{
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
 			{
-				Name:       "Schema file",
-				ColumnName: "schema_file",
-				ColumnType: "file.json|yaml|toml|hcl",
-				IsNullable: false,
+				Type:       "mail.servers.sync",
+				Method:     "EXECUTE",
+				Attributes: map[string]interface{}{},
 			},
 		},
+	},
+	{
+		Name:             "restart_daptin",
+		Label:            "Restart system",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields:         []api2go.ColumnInfo{},
 		OutFields: []Outcome{
 			{
 				Type:   "system_json_schema_update",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"json_schema": "~schema_file",
+					"json_schema": "!JSON.parse('[{\"name\":\"empty.json\",\"file\":\"data:application/json;base64,e30K\",\"type\":\"application/json\"}]')",
 				},
 			},
 		},
 	},
 	{
-		Name:             "upload_xls_to_system_schema",
-		Label:            "Upload xls to entity",
+		Name:             "generate_random_data",
+		Label:            "Generate random data",
 		OnType:           "world",
 		InstanceOptional: true,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "XLSX file",
-				ColumnName: "data_xls_file",
-				ColumnType: "file.xls|xlsx",
-				IsNullable: false,
+				Name:       "Number of records",
+				ColumnName: "count",
+				ColumnType: "measurement",
 			},
 			{
-				Name:       "Entity name",
-				ColumnName: "entity_name",
+				Name:       "Table name",
+				ColumnName: "table_name",
 				ColumnType: "label",
-				IsNullable: false,
 			},
+		},
+		OutFields: []Outcome{
 			{
-				Name:       "Create entity if not exists",
-				ColumnName: "create_if_not_exists",
-				ColumnType: "truefalse",
-				IsNullable: false,
+				Type:   "generate.random.data",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"count":             "~count",
+					"table_name":        "~table_name",
+					"user_reference_id": "$user.reference_id",
+					"user_account_id":   "$user.id",
+				},
 			},
+		},
+		Validations: []ColumnTag{
 			{
-				Name:       "Add missing columns",
-				ColumnName: "add_missing_columns",
-				ColumnType: "truefalse",
-				IsNullable: false,
+				ColumnName: "count",
+				Tags:       "gt=0",
 			},
 		},
-		Validations: []ColumnTag{
+	},
+	{
+		Name:             "export_data",
+		Label:            "Export data for backup",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields: []api2go.ColumnInfo{
 			{
-				ColumnName: "entity_name",
-				Tags:       "required",
+				ColumnName: "table_name",
+				Name:       "table_name",
+				ColumnType: "label",
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "__upload_xlsx_file_to_entity",
+				Type:   "__data_export",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"data_xls_file":        "~data_xls_file",
-					"entity_name":          "~entity_name",
-					"add_missing_columns":  "~add_missing_columns",
-					"create_if_not_exists": "~create_if_not_exists",
+					"table_name": "~table_name",
 				},
 			},
 		},
 	},
 	{
-		Name:             "upload_csv_to_system_schema",
-		Label:            "Upload CSV to entity",
+		Name:             "export_csv_data",
+		Label:            "Export CSV data",
 		OnType:           "world",
 		InstanceOptional: true,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "CSV file",
-				ColumnName: "data_csv_file",
-				ColumnType: "file.csv",
-				IsNullable: false,
-			},
-			{
-				Name:       "Entity name",
-				ColumnName: "entity_name",
+				ColumnName: "table_name",
+				Name:       "table_name",
 				ColumnType: "label",
-				IsNullable: false,
-			},
-			{
-				Name:         "Create entity if not exists",
-				ColumnName:   "create_if_not_exists",
-				ColumnType:   "truefalse",
-				DefaultValue: "false",
-				IsNullable:   true,
-			},
-			{
-				Name:         "Add missing columns",
-				ColumnName:   "add_missing_columns",
-				ColumnType:   "truefalse",
-				DefaultValue: "false",
-				IsNullable:   true,
-			},
-		},
-		Validations: []ColumnTag{
-			{
-				ColumnName: "entity_name",
-				Tags:       "required",
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "__upload_csv_file_to_entity",
+				Type:   "__csv_data_export",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"data_csv_file":        "~data_csv_file",
-					"entity_name":          "~entity_name",
-					"add_missing_columns":  "~add_missing_columns",
-					"create_if_not_exists": "~create_if_not_exists",
+					"table_name": "~table_name",
 				},
 			},
 		},
 	},
 	{
-		Name:             "download_system_schema",
-		Label:            "Download system schema",
+		Name:             "import_data",
+		Label:            "Import data from dump",
 		OnType:           "world",
-		InstanceOptional: true,
-		InFields:         []api2go.ColumnInfo{},
-		OutFields: []Outcome{
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
 			{
-				Type:       "__download_cms_config",
-				Method:     "EXECUTE",
-				Attributes: map[string]interface{}{},
+				Name:       "JSON Dump file",
+				ColumnName: "dump_file",
+				ColumnType: "file.json|yaml|toml|hcl",
+				IsNullable: false,
+			},
+			{
+				Name:       "truncate_before_insert",
+				ColumnName: "truncate_before_insert",
+				ColumnType: "truefalse",
 			},
 		},
-	},
-	{
-		Name:             "become_an_administrator",
-		Label:            "Become Daptin Administrator",
-		InstanceOptional: true,
-		OnType:           "world",
-		InFields:         []api2go.ColumnInfo{},
 		OutFields: []Outcome{
 			{
-				Type:   "__become_admin",
+				Type:   "__data_import",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"user_account_id": "$user.id",
-					"user":            "~user",
+					"world_reference_id":     "$.reference_id",
+					"truncate_before_insert": "~truncate_before_insert",
+					"dump_file":              "~dump_file",
+					"table_name":             "$.table_name",
+					"user":                   "~user",
 				},
 			},
 		},
 	},
 	{
-		Name:             "signup",
-		Label:            "Sign up",
-		InstanceOptional: true,
-		OnType:           USER_ACCOUNT_TABLE_NAME,
+		Name:             "upload_file",
+		Label:            "Upload file to external store",
+		OnType:           "cloud_store",
+		InstanceOptional: false,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
-				ColumnType: "label",
+				Name:       "File",
+				ColumnName: "file",
+				ColumnType: "file.*",
 				IsNullable: false,
 			},
 			{
-				Name:       "email",
-				ColumnName: "email",
-				ColumnType: "email",
-				IsNullable: false,
+				Name:         "Path",
+				ColumnName:   "path",
+				ColumnType:   "label",
+				IsNullable:   true,
+				DefaultValue: "",
 			},
+		},
+		OutFields: []Outcome{
 			{
-				Name:       "mobile",
-				ColumnName: "mobile",
-				ColumnType: "label",
-				IsNullable: true,
+				Type:   "cloudstore.file.upload",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"file":           "~file",
+					"oauth_token_id": "$.oauth_token_id",
+					"store_provider": "$.store_provider",
+					"path":           "~path",
+					"root_path":      "$.root_path",
+				},
 			},
+		},
+	},
+	{
+		Name:             "create_site",
+		Label:            "Create new site on this store",
+		OnType:           "cloud_store",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "password",
-				ColumnName: "password",
-				ColumnType: "password",
+				Name:       "Site type",
+				ColumnName: "site_type",
+				ColumnType: "label",
 				IsNullable: false,
 			},
 			{
-				Name:       "Password Confirm",
-				ColumnName: "passwordConfirm",
-				ColumnType: "password",
+				Name:         "Path",
+				ColumnName:   "path",
+				ColumnType:   "label",
+				IsNullable:   false,
+				DefaultValue: "",
+			},
+			{
+				Name:       "Hostname",
+				ColumnName: "hostname",
+				ColumnType: "label",
 				IsNullable: false,
 			},
 		},
-		Validations: []ColumnTag{
+		OutFields: []Outcome{
 			{
-				ColumnName: "email",
-				Tags:       "email",
+				Type:   "cloudstore.site.create",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"oauth_token_id":  "$.oauth_token_id",
+					"store_provider":  "$.store_provider",
+					"cloud_store_id":  "$.reference_id",
+					"path":            "~path",
+					"user_account_id": "$user.reference_id",
+					"hostname":        "~hostname",
+					"site_type":       "~site_type",
+					"root_path":       "$.root_path",
+				},
 			},
+		},
+	},
+
+	{
+		Name:             "delete_path",
+		Label:            "Delete path on a cloud store",
+		OnType:           "cloud_store",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
 			{
-				ColumnName: "name",
-				Tags:       "required",
+				Name:         "Path",
+				ColumnName:   "path",
+				ColumnType:   "label",
+				IsNullable:   true,
+				DefaultValue: "",
 			},
+		},
+		OutFields: []Outcome{
 			{
-				ColumnName: "password",
-				Tags:       "eqfield=InnerStructField[passwordConfirm],min=8",
+				Type:   "cloudstore.file.delete",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"oauth_token_id": "$.oauth_token_id",
+					"store_provider": "$.store_provider",
+					"path":           "~path",
+					"root_path":      "$.root_path",
+				},
 			},
 		},
-		Conformations: []ColumnTag{
+	},
+
+	{
+		Name:             "create_folder",
+		Label:            "Create folder on a cloud store",
+		OnType:           "cloud_store",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
 			{
-				ColumnName: "email",
-				Tags:       "email",
+				Name:         "Path",
+				ColumnName:   "path",
+				ColumnType:   "label",
+				IsNullable:   true,
+				DefaultValue: "",
 			},
 			{
+				Name:       "Name",
 				ColumnName: "name",
-				Tags:       "trim",
-			},
-			{
-				ColumnName: "mobile",
-				Tags:       "trim",
+				ColumnType: "label",
+				IsNullable: false,
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:           USER_ACCOUNT_TABLE_NAME,
-				Method:         "POST",
-				Reference:      "user",
-				SkipInResponse: true,
+				Type:   "cloudstore.folder.create",
+				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"name":      "~name",
-					"email":     "~email",
-					"password":  "~password",
-					"confirmed": "0",
+					"oauth_token_id": "$.oauth_token_id",
+					"store_provider": "$.store_provider",
+					"path":           "~path",
+					"name":           "~name",
+					"root_path":      "$.root_path",
 				},
 			},
+		},
+	},
+
+	{
+		Name:             "move_path",
+		Label:            "Create folder on a cloud store",
+		OnType:           "cloud_store",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
 			{
-				Type:           "otp.generate",
-				Method:         "EXECUTE",
-				Reference:      "otp",
-				SkipInResponse: true,
-				Condition:      "!mobile != null && mobile != undefined && mobile != ''",
-				Attributes: map[string]interface{}{
-					"mobile": "~mobile",
-					"email":  "~email",
-				},
+				Name:       "Source path",
+				ColumnName: "source",
+				ColumnType: "label",
 			},
 			{
-				Type:   "client.notify",
-				Method: "ACTIONRESPONSE",
-				Attributes: map[string]interface{}{
-					"type":    "success",
-					"title":   "Success",
-					"message": "Sign-up successful. Redirecting to sign in",
-				},
+				Name:       "Destination path",
+				ColumnName: "destination",
+				ColumnType: "label",
+				IsNullable: false,
 			},
+		},
+		OutFields: []Outcome{
 			{
-				Type:   "client.redirect",
-				Method: "ACTIONRESPONSE",
+				Type:   "cloudstore.path.move",
+				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"location": "/auth/signin",
-					"window":   "self",
-					"delay":    2000,
+					"oauth_token_id": "$.oauth_token_id",
+					"store_provider": "$.store_provider",
+					"source":         "~source",
+					"destination":    "~destination",
+					"root_path":      "$.root_path",
 				},
 			},
 		},
 	},
 	{
-		Name:             "reset-password",
-		Label:            "Reset password",
-		InstanceOptional: true,
-		OnType:           USER_ACCOUNT_TABLE_NAME,
+		Name:             "list_files",
+		Label:            "List files in the site path",
+		OnType:           "site",
+		InstanceOptional: false,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "email",
-				ColumnName: "email",
-				ColumnType: "email",
+				Name:       "path",
+				ColumnName: "path",
+				ColumnType: "label",
 				IsNullable: false,
 			},
 		},
-		Validations: []ColumnTag{
+		OutFields: []Outcome{
 			{
-				ColumnName: "email",
-				Tags:       "email",
+				Type:   "site.file.list",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"site_id": "$.reference_id",
+					"path":    "~path",
+				},
 			},
 		},
-		Conformations: []ColumnTag{
-			{
-				ColumnName: "email",
-				Tags:       "email",
-			},
+	},
+
+	{
+		Name:             "get_file",
+		Label:            "Get file at the path in site",
+		OnType:           "site",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "path",
+				ColumnName: "path",
+				ColumnType: "label",
+				IsNullable: false,
+			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:           USER_ACCOUNT_TABLE_NAME,
-				Method:         "GET",
-				Reference:      "user",
-				SkipInResponse: true,
+				Type:   "site.file.get",
+				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"query": "[{\"column\": \"email\", \"operator\": \"is\", \"value\": \"$email\"}]",
+					"site_id": "$.reference_id",
+					"path":    "~path",
 				},
 			},
+		},
+	},
+	{
+		Name:             "delete_file",
+		Label:            "Delete file in the site",
+		OnType:           "site",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
 			{
-				Type:           "otp.generate",
-				Method:         "EXECUTE",
-				Reference:      "otp",
-				SkipInResponse: true,
-				Attributes: map[string]interface{}{
-					"email": "$email",
-				},
+				Name:       "path",
+				ColumnName: "path",
+				ColumnType: "label",
+				IsNullable: false,
 			},
+		},
+		OutFields: []Outcome{
 			{
-				Type:           "mail.send",
-				Method:         "EXECUTE",
-				SkipInResponse: true,
+				Type:   "site.file.delete",
+				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"to":      "~email",
-					"subject": "Request for password reset",
-					"body":    "Your verification code is: $otp.otp",
-					"from":    "no-reply@localhost",
+					"site_id": "$.reference_id",
+					"path":    "~path",
 				},
 			},
 		},
 	},
 	{
-		Name:             "reset-password-verify",
-		Label:            "Reset password verify code",
+		Name:             "upload_system_schema",
+		Label:            "Upload features",
+		OnType:           "world",
 		InstanceOptional: true,
-		OnType:           USER_ACCOUNT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "email",
-				ColumnName: "email",
-				ColumnType: "email",
-				IsNullable: false,
-			}, {
-				Name:       "otp",
-				ColumnName: "otp",
-				ColumnType: "value",
+				Name:       "Schema file",
+				ColumnName: "schema_file",
+				ColumnType: "file.json|yaml|toml|hcl",
 				IsNullable: false,
 			},
 		},
-		Validations: []ColumnTag{
+		OutFields: []Outcome{
 			{
-				ColumnName: "email",
-				Tags:       "email",
+				Type:   "system_json_schema_update",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"json_schema": "~schema_file",
+				},
 			},
 		},
-		Conformations: []ColumnTag{
+	},
+	{
+		Name:             "upload_xls_to_system_schema",
+		Label:            "Upload xls to entity",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields: []api2go.ColumnInfo{
 			{
-				ColumnName: "email",
-				Tags:       "email",
+				Name:       "XLSX file",
+				ColumnName: "data_xls_file",
+				ColumnType: "file.xls|xlsx",
+				IsNullable: false,
 			},
-		},
-		OutFields: []Outcome{
 			{
-				Type:           USER_ACCOUNT_TABLE_NAME,
-				Method:         "GET",
-				Reference:      "user",
-				SkipInResponse: true,
-				Attributes: map[string]interface{}{
-					"query": "[{\"column\": \"email\", \"operator\": \"is\", \"value\": \"$email\"}]",
-				},
+				Name:       "Entity name",
+				ColumnName: "entity_name",
+				ColumnType: "label",
+				IsNullable: false,
 			},
 			{
-				Type:   "otp.login.verify",
-				Method: "EXECUTE",
-				Attributes: map[string]interface{}{
-					"otp":   "~otp",
-					"email": "~email",
-				},
+				Name:       "Create entity if not exists",
+				ColumnName: "create_if_not_exists",
+				ColumnType: "truefalse",
+				IsNullable: false,
 			},
 			{
-				Type:           "random.generate",
-				Method:         "EXECUTE",
-				Reference:      "newPassword",
-				SkipInResponse: true,
-				Attributes: map[string]interface{}{
-					"type": "password",
-				},
+				Name:       "Add missing columns",
+				ColumnName: "add_missing_columns",
+				ColumnType: "truefalse",
+				IsNullable: false,
 			},
+		},
+		Validations: []ColumnTag{
 			{
-				Type:           USER_ACCOUNT_TABLE_NAME,
-				SkipInResponse: true,
-				Method:         "UPDATE",
-				Attributes: map[string]interface{}{
-					"reference_id": "$user[0].reference_id",
-					"password":     "!newPassword.value",
-				},
+				ColumnName: "entity_name",
+				Tags:       "required",
 			},
+		},
+		OutFields: []Outcome{
 			{
-				Type:           "mail.send",
-				Method:         "EXECUTE",
-				SkipInResponse: true,
+				Type:   "__upload_xlsx_file_to_entity",
+				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"to":      "~email",
-					"subject": "Request for password reset",
-					"body":    "Your new password is: $newPassword.value",
-					"from":    "no-reply@localhost",
+					"data_xls_file":        "~data_xls_file",
+					"entity_name":          "~entity_name",
+					"add_missing_columns":  "~add_missing_columns",
+					"create_if_not_exists": "~create_if_not_exists",
 				},
 			},
 		},
 	},
 	{
-		Name:             "signin",
-		Label:            "Sign in",
+		Name:             "upload_csv_to_system_schema",
+		Label:            "Upload CSV to entity",
+		OnType:           "world",
 		InstanceOptional: true,
-		OnType:           USER_ACCOUNT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "email",
-				ColumnName: "email",
-				ColumnType: "email",
+				Name:       "CSV file",
+				ColumnName: "data_csv_file",
+				ColumnType: "file.csv",
 				IsNullable: false,
 			},
 			{
-				Name:       "password",
-				ColumnName: "password",
-				ColumnType: "password",
+				Name:       "Entity name",
+				ColumnName: "entity_name",
+				ColumnType: "label",
 				IsNullable: false,
 			},
+			{
+				Name:         "Create entity if not exists",
+				ColumnName:   "create_if_not_exists",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
+				IsNullable:   true,
+			},
+			{
+				Name:         "Add missing columns",
+				ColumnName:   "add_missing_columns",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
+				IsNullable:   true,
+			},
+		},
+		Validations: []ColumnTag{
+			{
+				ColumnName: "entity_name",
+				Tags:       "required",
+			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "jwt.token",
+				Type:   "__upload_csv_file_to_entity",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"email":    "~email",
-					"password": "~password",
+					"data_csv_file":        "~data_csv_file",
+					"entity_name":          "~entity_name",
+					"add_missing_columns":  "~add_missing_columns",
+					"create_if_not_exists": "~create_if_not_exists",
 				},
 			},
 		},
 	},
 	{
-		Name:     "oauth_login_begin",
-		Label:    "Authenticate via OAuth",
-		OnType:   "oauth_connect",
-		InFields: []api2go.ColumnInfo{},
+		Name:             "download_system_schema",
+		Label:            "Download system schema",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields:         []api2go.ColumnInfo{},
 		OutFields: []Outcome{
 			{
-				Type:   "oauth.client.redirect",
+				Type:       "__download_cms_config",
+				Method:     "EXECUTE",
+				Attributes: map[string]interface{}{},
+			},
+		},
+	},
+	{
+		Name:             "become_an_administrator",
+		Label:            "Become Daptin Administrator",
+		InstanceOptional: true,
+		OnType:           "world",
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:   "__become_admin",
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
-					"authenticator": "$.name",
-					"scope":         "$.scope",
+					"user_account_id": "$user.id",
+					"user":            "~user",
 				},
 			},
 		},
 	},
 	{
-		Name:             "oauth.login.response",
-		Label:            "Handle OAuth login response code and state",
+		Name:             "signup",
+		Label:            "Sign up",
 		InstanceOptional: true,
-		OnType:           "oauth_token",
+		OnType:           USER_ACCOUNT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "code",
-				ColumnName: "code",
-				ColumnType: "hidden",
+				Name:       "name",
+				ColumnName: "name",
+				ColumnType: "label",
 				IsNullable: false,
 			},
 			{
-				Name:       "state",
-				ColumnName: "state",
-				ColumnType: "hidden",
+				Name:       "email",
+				ColumnName: "email",
+				ColumnType: "email",
 				IsNullable: false,
 			},
 			{
-				Name:       "authenticator",
-				ColumnName: "authenticator",
-				ColumnType: "hidden",
-				IsNullable: false,
+				Name:       "mobile",
+				ColumnName: "mobile",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				Name:       "password",
+				ColumnName: "password",
+				ColumnType: "password",
+				IsNullable: false,
+			},
+			{
+				Name:       "Password Confirm",
+				ColumnName: "passwordConfirm",
+				ColumnType: "password",
+				IsNullable: false,
 			},
 		},
-		OutFields: []Outcome{
+		Validations: []ColumnTag{
 			{
-				Type:           "oauth_connect",
-				Method:         "GET",
-				SkipInResponse: true,
-				Reference:      "Connection",
-				Attributes: map[string]interface{}{
-					"filter":       "~authenticator",
-					"page[number]": "1",
-					"page[size]":   "1",
-				},
+				ColumnName: "email",
+				Tags:       "email",
 			},
 			{
-				Type:           "oauth.login.response",
-				Method:         "EXECUTE",
-				SkipInResponse: true,
-				Reference:      "auth",
-				Attributes: map[string]interface{}{
-					"authenticator":     "~authenticator",
-					"user_account_id":   "~user.id",
-					"user_reference_id": "~user.reference_id",
-					"state":             "~state",
-					"code":              "~code",
-				},
+				ColumnName: "name",
+				Tags:       "required",
 			},
 			{
-				Type:           "oauth.profile.exchange",
-				Method:         "EXECUTE",
-				Reference:      "profile",
-				SkipInResponse: true,
-				Condition:      "$Connection[0].allow_login",
-				Attributes: map[string]interface{}{
-					"authenticator": "~authenticator",
-					"token":         "$auth.access_token",
-					"tokenInfoUrl":  "$Connection[0].token_url",
-					"profileUrl":    "$Connection[0].profile_url",
-				},
+				ColumnName: "password",
+				Tags:       "eqfield=InnerStructField[passwordConfirm],min=8",
 			},
+		},
+		Conformations: []ColumnTag{
 			{
-				Type:           USER_ACCOUNT_TABLE_NAME,
-				Method:         "GET",
-				Reference:      "user",
-				SkipInResponse: true,
-				Condition:      "$Connection[0].allow_login",
-				Attributes: map[string]interface{}{
-					"filter": "!profile.email || profile.emailAddress",
-				},
+				ColumnName: "email",
+				Tags:       "email",
 			},
 			{
-				Type:           USER_ACCOUNT_TABLE_NAME,
-				Method:         "POST",
-				Reference:      "user",
+				ColumnName: "name",
+				Tags:       "trim",
+			},
+			{
+				ColumnName: "mobile",
+				Tags:       "trim",
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:           "password.check",
+				Method:         "EXECUTE",
 				SkipInResponse: true,
-				Condition:      "!!user || (!user.length && !user.reference_id)",
 				Attributes: map[string]interface{}{
-					"email":    "!profile.email || profile.emailAddress",
-					"name":     "$profile.displayName",
-					"password": "$profile.id",
+					"email":    "~email",
+					"password": "~password",
 				},
 			},
 			{
-				Type:           "usergroup",
+				Type:           USER_ACCOUNT_TABLE_NAME,
 				Method:         "POST",
-				Reference:      "usergroup",
+				Reference:      "user",
 				SkipInResponse: true,
-				Condition:      "!!user || (!user.length && !user.reference_id)",
 				Attributes: map[string]interface{}{
-					"name": "!'Home group for ' + profile.emails[0].value",
+					"name":      "~name",
+					"email":     "~email",
+					"password":  "~password",
+					"confirmed": "0",
 				},
 			},
 			{
-				Type:           "user_account_user_account_id_has_usergroup_usergroup_id",
-				Method:         "POST",
+				Type:           "otp.generate",
+				Method:         "EXECUTE",
+				Reference:      "otp",
 				SkipInResponse: true,
-				Condition:      "!!user || (!user.length && !user.reference_id)",
+				Condition:      "!mobile != null && mobile != undefined && mobile != ''",
 				Attributes: map[string]interface{}{
-					"user_account_id": "$user.reference_id",
-					"usergroup_id":    "$usergroup.reference_id",
+					"mobile": "~mobile",
+					"email":  "~email",
 				},
 			},
 			{
-				Type:   "jwt.token",
-				Method: "EXECUTE",
+				Type:   "client.notify",
+				Method: "ACTIONRESPONSE",
 				Attributes: map[string]interface{}{
-					"email":             "!profile.email || profile.emailAddress",
-					"skipPasswordCheck": true,
+					"type":    "success",
+					"title":   "Success",
+					"message": "Sign-up successful. Redirecting to sign in",
 				},
 			},
 			{
 				Type:   "client.redirect",
 				Method: "ACTIONRESPONSE",
 				Attributes: map[string]interface{}{
-					"location": "/",
+					"location": "/auth/signin",
 					"window":   "self",
 					"delay":    2000,
 				},
@@ -1420,1500 +1579,2505 @@ var SystemActions = []Action{
 		},
 	},
 	{
-		Name:             "add_exchange",
-		Label:            "Add new data exchange",
-		OnType:           "world",
-		InstanceOptional: false,
+		Name:             "reset-password",
+		Label:            "Reset password",
+		InstanceOptional: true,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
 		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
-				ColumnType: "label",
+				Name:       "email",
+				ColumnName: "email",
+				ColumnType: "email",
 				IsNullable: false,
 			},
+		},
+		Validations: []ColumnTag{
 			{
-				Name:       "sheet_id",
-				ColumnName: "sheet_id",
-				ColumnType: "alias",
-				IsNullable: false,
+				ColumnName: "email",
+				Tags:       "email",
 			},
+		},
+		Conformations: []ColumnTag{
 			{
-				Name:       "app_key Key",
-				ColumnName: "app_key",
-				ColumnType: "alias",
-				IsNullable: false,
+				ColumnName: "email",
+				Tags:       "email",
 			},
 		},
 		OutFields: []Outcome{
 			{
-				Type:   "data_exchange",
-				Method: "POST",
+				Type:           USER_ACCOUNT_TABLE_NAME,
+				Method:         "GET",
+				Reference:      "user",
+				SkipInResponse: true,
 				Attributes: map[string]interface{}{
-					"name":              "!'Export ' + subject.table_name + ' to excel sheet'",
-					"source_attributes": "!JSON.stringify({name: subject.table_name})",
-					"source_type":       "self",
-					"target_type":       "gsheet-append",
-					"options":           "!JSON.stringify({hasHeader: true})",
-					"attributes":        "!JSON.stringify([{SourceColumn: '$self.description', TargetColumn: 'Task description'}])",
-					"target_attributes": "!JSON.stringify({sheetUrl: 'https://content-sheets.googleapis.com/v4/spreadsheets/' + sheet_id + '/values/A1:append', appKey: app_key})",
+					"query": "[{\"column\": \"email\", \"operator\": \"is\", \"value\": \"$email\"}]",
+				},
+			},
+			{
+				Type:           "otp.generate",
+				Method:         "EXECUTE",
+				Reference:      "otp",
+				SkipInResponse: true,
+				Attributes: map[string]interface{}{
+					"email": "$email",
+				},
+			},
+			{
+				Type:           "mail.send",
+				Method:         "EXECUTE",
+				SkipInResponse: true,
+				Attributes: map[string]interface{}{
+					"to":      "~email",
+					"subject": "Request for password reset",
+					"body":    "Your verification code is: $otp.otp",
+					"from":    "no-reply@localhost",
 				},
 			},
 		},
 	},
-}
-
-var adminsGroup = []string{"administrators"}
-
-var StandardTasks []Task
-
-var StandardTables = []TableInfo{
 	{
-		TableName:     "document",
-		IsHidden:      true,
-		DefaultGroups: adminsGroup,
-		Icon:          "fa-file",
-		Columns: []api2go.ColumnInfo{
+		Name:             "reset-password-verify",
+		Label:            "Reset password verify code",
+		InstanceOptional: true,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
+		InFields: []api2go.ColumnInfo{
 			{
-				ColumnName: "document_name",
-				Name:       "document_name",
-				ColumnType: "label",
-				DataType:   "varchar(100)",
+				Name:       "email",
+				ColumnName: "email",
+				ColumnType: "email",
 				IsNullable: false,
-				IsIndexed:  true,
-			},
-			{
-				ColumnName: "document_path",
-				Name:       "document_path",
-				ColumnType: "label",
-				DataType:   "varchar(2000)",
+			}, {
+				Name:       "otp",
+				ColumnName: "otp",
+				ColumnType: "value",
 				IsNullable: false,
-				IsIndexed:  true,
 			},
+		},
+		Validations: []ColumnTag{
 			{
-				ColumnName: "document_extension",
-				Name:       "document_extension",
-				ColumnType: "label",
-				DataType:   "varchar(50)",
-				IsNullable: false,
-				IsIndexed:  true,
+				ColumnName: "email",
+				Tags:       "email",
 			},
+		},
+		Conformations: []ColumnTag{
 			{
-				ColumnName: "mime_type",
-				Name:       "mime_type",
-				ColumnType: "label",
-				DataType:   "varchar(50)",
-				IsNullable: false,
-				IsIndexed:  true,
+				ColumnName: "email",
+				Tags:       "email",
 			},
+		},
+		OutFields: []Outcome{
 			{
-				ColumnName:   "document_content",
-				Name:         "document_content",
-				IsForeignKey: true,
-				IsNullable:   false,
-				ColumnType:   "file.*",
-				DataType:     "longblob",
-				ForeignKeyData: api2go.ForeignKeyData{
-					DataSource: "cloud_store",
-					Namespace:  "localstore",
-					KeyName:    "documents",
+				Type:           USER_ACCOUNT_TABLE_NAME,
+				Method:         "GET",
+				Reference:      "user",
+				SkipInResponse: true,
+				Attributes: map[string]interface{}{
+					"query": "[{\"column\": \"email\", \"operator\": \"is\", \"value\": \"$email\"}]",
 				},
 			},
-		},
-	},
-	{
+			{
+				Type:   "otp.login.verify",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"otp":   "~otp",
+					"email": "~email",
+				},
+			},
+			{
+				Type:           "random.generate",
+				Method:         "EXECUTE",
+				Reference:      "newPassword",
+				SkipInResponse: true,
+				Attributes: map[string]interface{}{
+					"type": "password",
+				},
+			},
+			{
+				Type:           USER_ACCOUNT_TABLE_NAME,
+				SkipInResponse: true,
+				Method:         "UPDATE",
+				Attributes: map[string]interface{}{
+					"reference_id": "$user[0].reference_id",
+					"password":     "!newPassword.value",
+				},
+			},
+			{
+				Type:           "mail.send",
+				Method:         "EXECUTE",
+				SkipInResponse: true,
+				Attributes: map[string]interface{}{
+					"to":      "~email",
+					"subject": "Request for password reset",
+					"body":    "Your new password is: $newPassword.value",
+					"from":    "no-reply@localhost",
+				},
+			},
+		},
+	},
+	{
+		Name:             "signin",
+		Label:            "Sign in",
+		InstanceOptional: true,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "email",
+				ColumnName: "email",
+				ColumnType: "email",
+				IsNullable: false,
+			},
+			{
+				Name:       "password",
+				ColumnName: "password",
+				ColumnType: "password",
+				IsNullable: false,
+			},
+			{
+				Name:       "two_factor_code",
+				ColumnName: "two_factor_code",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "jwt.token",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"email":           "~email",
+					"password":        "~password",
+					"two_factor_code": "~two_factor_code",
+				},
+			},
+		},
+	},
+	{
+		Name:     "oauth_login_begin",
+		Label:    "Authenticate via OAuth",
+		OnType:   "oauth_connect",
+		InFields: []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:   "oauth.client.redirect",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"authenticator": "$.name",
+					"scope":         "$.scope",
+				},
+			},
+		},
+	},
+	{
+		Name:             "oauth.login.response",
+		Label:            "Handle OAuth login response code and state",
+		InstanceOptional: true,
+		OnType:           "oauth_token",
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "code",
+				ColumnName: "code",
+				ColumnType: "hidden",
+				IsNullable: false,
+			},
+			{
+				Name:       "state",
+				ColumnName: "state",
+				ColumnType: "hidden",
+				IsNullable: false,
+			},
+			{
+				Name:       "authenticator",
+				ColumnName: "authenticator",
+				ColumnType: "hidden",
+				IsNullable: false,
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:           "oauth_connect",
+				Method:         "GET",
+				SkipInResponse: true,
+				Reference:      "Connection",
+				Attributes: map[string]interface{}{
+					"filter":       "~authenticator",
+					"page[number]": "1",
+					"page[size]":   "1",
+				},
+			},
+			{
+				Type:           "oauth.login.response",
+				Method:         "EXECUTE",
+				SkipInResponse: true,
+				Reference:      "auth",
+				Attributes: map[string]interface{}{
+					"authenticator":     "~authenticator",
+					"user_account_id":   "~user.id",
+					"user_reference_id": "~user.reference_id",
+					"state":             "~state",
+					"code":              "~code",
+				},
+			},
+			{
+				Type:           "oauth.profile.exchange",
+				Method:         "EXECUTE",
+				Reference:      "profile",
+				SkipInResponse: true,
+				Condition:      "$Connection[0].allow_login",
+				Attributes: map[string]interface{}{
+					"authenticator": "~authenticator",
+					"token":         "$auth.access_token",
+					"tokenInfoUrl":  "$Connection[0].token_url",
+					"profileUrl":    "$Connection[0].profile_url",
+				},
+			},
+			{
+				Type:           USER_ACCOUNT_TABLE_NAME,
+				Method:         "GET",
+				Reference:      "user",
+				SkipInResponse: true,
+				Condition:      "$Connection[0].allow_login",
+				Attributes: map[string]interface{}{
+					"filter": "!profile.email || profile.emailAddress",
+				},
+			},
+			{
+				Type:           USER_ACCOUNT_TABLE_NAME,
+				Method:         "POST",
+				Reference:      "user",
+				SkipInResponse: true,
+				Condition:      "!!user || (!user.length && !user.reference_id)",
+				Attributes: map[string]interface{}{
+					"email":    "!profile.email || profile.emailAddress",
+					"name":     "$profile.displayName",
+					"password": "$profile.id",
+				},
+			},
+			{
+				Type:           "usergroup",
+				Method:         "POST",
+				Reference:      "usergroup",
+				SkipInResponse: true,
+				Condition:      "!!user || (!user.length && !user.reference_id)",
+				Attributes: map[string]interface{}{
+					"name": "!'Home group for ' + profile.emails[0].value",
+				},
+			},
+			{
+				Type:           "user_account_user_account_id_has_usergroup_usergroup_id",
+				Method:         "POST",
+				SkipInResponse: true,
+				Condition:      "!!user || (!user.length && !user.reference_id)",
+				Attributes: map[string]interface{}{
+					"user_account_id": "$user.reference_id",
+					"usergroup_id":    "$usergroup.reference_id",
+				},
+			},
+			{
+				Type:   "jwt.token",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"email":             "!profile.email || profile.emailAddress",
+					"skipPasswordCheck": true,
+				},
+			},
+			{
+				Type:   "client.redirect",
+				Method: "ACTIONRESPONSE",
+				Attributes: map[string]interface{}{
+					"location": "/",
+					"window":   "self",
+					"delay":    2000,
+				},
+			},
+		},
+	},
+	{
+		Name:             "add_exchange",
+		Label:            "Add new data exchange",
+		OnType:           "world",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "name",
+				ColumnName: "name",
+				ColumnType: "label",
+				IsNullable: false,
+			},
+			{
+				Name:       "sheet_id",
+				ColumnName: "sheet_id",
+				ColumnType: "alias",
+				IsNullable: false,
+			},
+			{
+				Name:       "app_key Key",
+				ColumnName: "app_key",
+				ColumnType: "alias",
+				IsNullable: false,
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "data_exchange",
+				Method: "POST",
+				Attributes: map[string]interface{}{
+					"name":              "!'Export ' + subject.table_name + ' to excel sheet'",
+					"source_attributes": "!JSON.stringify({name: subject.table_name})",
+					"source_type":       "self",
+					"target_type":       "gsheet-append",
+					"options":           "!JSON.stringify({hasHeader: true})",
+					"attributes":        "!JSON.stringify([{SourceColumn: '$self.description', TargetColumn: 'Task description'}])",
+					"target_attributes": "!JSON.stringify({sheetUrl: 'https://content-sheets.googleapis.com/v4/spreadsheets/' + sheet_id + '/values/A1:append', appKey: app_key})",
+				},
+			},
+		},
+	},
+}
+
+var adminsGroup = []string{"administrators"}
+
+var StandardTasks []Task
+
+var StandardTables = []TableInfo{
+	{
+		TableName:     "document",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-file",
+		Columns: []api2go.ColumnInfo{
+			{
+				ColumnName: "document_name",
+				Name:       "document_name",
+				ColumnType: "label",
+				DataType:   "varchar(100)",
+				IsNullable: false,
+				IsIndexed:  true,
+			},
+			{
+				ColumnName: "document_path",
+				Name:       "document_path",
+				ColumnType: "label",
+				DataType:   "varchar(2000)",
+				IsNullable: false,
+				IsIndexed:  true,
+			},
+			{
+				ColumnName: "document_extension",
+				Name:       "document_extension",
+				ColumnType: "label",
+				DataType:   "varchar(50)",
+				IsNullable: false,
+				IsIndexed:  true,
+			},
+			{
+				ColumnName: "mime_type",
+				Name:       "mime_type",
+				ColumnType: "label",
+				DataType:   "varchar(50)",
+				IsNullable: false,
+				IsIndexed:  true,
+			},
+			{
+				ColumnName:   "document_content",
+				Name:         "document_content",
+				IsForeignKey: true,
+				IsNullable:   false,
+				ColumnType:   "file.*",
+				DataType:     "longblob",
+				ForeignKeyData: api2go.ForeignKeyData{
+					DataSource: "cloud_store",
+					Namespace:  "localstore",
+					KeyName:    "documents",
+				},
+			},
+		},
+	},
+	{
 		TableName:     "calendar",
 		IsHidden:      true,
 		DefaultGroups: adminsGroup,
-		Icon:          "fa-calendar-alt",
+		Icon:          "fa-calendar-alt",
+		Columns: []api2go.ColumnInfo{
+			{
+				ColumnName: "rpath",
+				Name:       "rpath",
+				ColumnType: "label",
+				IsUnique:   true,
+				DataType:   "varchar(500)",
+				IsNullable: false,
+			},
+			{
+				ColumnName:   "content",
+				Name:         "content",
+				ColumnType:   "file.ical",
+				DataType:     "longblob",
+				IsNullable:   false,
+				IsForeignKey: true,
+				ForeignKeyData: api2go.ForeignKeyData{
+					DataSource: "cloud_store",
+					Namespace:  "localstore",
+					KeyName:    "caldav",
+				},
+			},
+			//{
+			//	ColumnName: "event_title",
+			//	Name:       "event_title",
+			//	ColumnType: "label",
+			//	DataType:   "varchar(100)",
+			//	IsNullable: false,
+			//	IsIndexed:  true,
+			//},
+			//{
+			//	ColumnName: "event_url",
+			//	Name:       "event_url",
+			//	ColumnType: "label",
+			//	DataType:   "varchar(500)",
+			//	IsNullable: true,
+			//},
+			//{
+			//	ColumnName:   "event_type",
+			//	Name:         "event_type",
+			//	ColumnType:   "label",
+			//	DataType:     "varchar(50)",
+			//	IsIndexed:    true,
+			//	IsNullable:   false,
+			//	DefaultValue: "'event'",
+			//},
+			//{
+			//	ColumnName:   "all_day",
+			//	Name:         "all_day",
+			//	ColumnType:   "truefalse",
+			//	DataType:     "bool",
+			//	IsNullable:   false,
+			//	DefaultValue: "false",
+			//},
+			//{
+			//	ColumnName: "event_location",
+			//	Name:       "event_location",
+			//	IsIndexed:  true,
+			//	ColumnType: "label",
+			//	DataType:   "varchar(500)",
+			//	IsNullable: true,
+			//},
+			//{
+			//	ColumnName: "event_end_date",
+			//	Name:       "event_end_date",
+			//	ColumnType: "datetime",
+			//	IsIndexed:  true,
+			//	DataType:   "timestamp",
+			//	IsNullable: true,
+			//},
+			//{
+			//	ColumnName:   "event_timezone",
+			//	Name:         "event_timezone",
+			//	ColumnType:   "label",
+			//	DataType:     "varchar(50)",
+			//	IsNullable:   false,
+			//	DefaultValue: "'local'",
+			//},
+			//{
+			//	ColumnName: "event_start_date",
+			//	Name:       "event_start_date",
+			//	ColumnType: "datetime",
+			//	IsIndexed:  true,
+			//	DataType:   "timestamp",
+			//	IsNullable: false,
+			//},
+			//{
+			//	ColumnName: "start_time",
+			//	Name:       "start_time",
+			//	ColumnType: "label",
+			//	IsIndexed:  true,
+			//	DataType:   "varchar(50)",
+			//	IsNullable: true,
+			//},
+			//{
+			//	ColumnName: "end_time",
+			//	Name:       "end_time",
+			//	IsIndexed:  true,
+			//	ColumnType: "label",
+			//	DataType:   "varchar(50)",
+			//	IsNullable: true,
+			//},
+			//{
+			//	ColumnName: "start_recur",
+			//	Name:       "start_recur",
+			//	ColumnType: "label",
+			//	DataType:   "varchar(50)",
+			//	IsNullable: true,
+			//},
+			//{
+			//	ColumnName: "days_of_week",
+			//	Name:       "days_of_week",
+			//	IsIndexed:  true,
+			//	ColumnType: "label",
+			//	IsNullable: true,
+			//	DataType:   "varchar(100)",
+			//},
+			//{
+			//	ColumnName: "event_description",
+			//	Name:       "event_description",
+			//	ColumnType: "content",
+			//	DataType:   "text",
+			//	IsIndexed:  false,
+			//	IsNullable: true,
+			//},
+		},
+	},
+	{
+		TableName:     "collection",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-calendar-alt",
+		Columns: []api2go.ColumnInfo{
+			{
+				ColumnName: "name",
+				Name:       "name",
+				ColumnType: "label",
+				DataType:   "varchar(500)",
+				IsNullable: false,
+				IsIndexed:  true,
+			},
+			{
+				ColumnName: "description",
+				Name:       "description",
+				ColumnType: "label",
+				DataType:   "text",
+				IsNullable: false,
+				IsIndexed:  true,
+			},
+		},
+	},
+	{
+		TableName:     "certificate",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-certificate",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "hostname",
+				ColumnName: "hostname",
+				IsUnique:   true,
+				IsIndexed:  true,
+				ColumnType: "label",
+				DataType:   "varchar(100)",
+				IsNullable: false,
+			},
+			{
+				Name:         "issuer",
+				ColumnName:   "issuer",
+				ColumnType:   "label",
+				DataType:     "varchar(100)",
+				IsNullable:   false,
+				DefaultValue: "'self'",
+			},
+			{
+				Name:       "generated_at",
+				ColumnName: "generated_at",
+				ColumnType: "datetime",
+				DataType:   "timestamp",
+				IsNullable: true,
+			},
+			{
+				Name:       "certificate_pem",
+				ColumnName: "certificate_pem",
+				ColumnType: "content",
+				DataType:   "text",
+				IsNullable: true,
+			},
+			{
+				Name:       "root_certificate",
+				ColumnName: "root_certificate",
+				ColumnType: "content",
+				DataType:   "text",
+				IsNullable: true,
+			},
+			{
+				Name:       "private_key_pem",
+				ColumnName: "private_key_pem",
+				ColumnType: "encrypted",
+				DataType:   "text",
+				IsNullable: true,
+			},
+			{
+				Name:       "public_key_pem",
+				ColumnName: "public_key_pem",
+				ColumnType: "content",
+				DataType:   "text",
+				IsNullable: true,
+			},
+		},
+	},
+	{
+		TableName:     "feed",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-rss",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "feed_name",
+				ColumnName: "feed_name",
+				IsUnique:   true,
+				IsIndexed:  true,
+				ColumnType: "label",
+				DataType:   "varchar(100)",
+				IsNullable: false,
+			},
+			{
+				Name:         "title",
+				ColumnName:   "title",
+				ColumnType:   "label",
+				DataType:     "varchar(500)",
+				IsNullable:   false,
+				DefaultValue: "''",
+			},
+			{
+				Name:         "title",
+				ColumnName:   "title",
+				ColumnType:   "label",
+				DataType:     "varchar(500)",
+				IsNullable:   false,
+				DefaultValue: "''",
+			},
+			{
+				Name:       "description",
+				ColumnName: "description",
+				ColumnType: "label",
+				DataType:   "text",
+				IsNullable: false,
+			},
+			{
+				Name:         "link",
+				ColumnName:   "link",
+				ColumnType:   "label",
+				DataType:     "varchar(1000)",
+				IsNullable:   false,
+				DefaultValue: "''",
+			},
+			{
+				Name:         "author_name",
+				ColumnName:   "author_name",
+				ColumnType:   "label",
+				DataType:     "varchar(500)",
+				IsNullable:   false,
+				DefaultValue: "''",
+			},
+			{
+				Name:         "author_email",
+				ColumnName:   "author_email",
+				ColumnType:   "label",
+				DataType:     "varchar(500)",
+				IsNullable:   false,
+				DefaultValue: "''",
+			},
+			{
+				Name:         "enable",
+				ColumnName:   "enable",
+				ColumnType:   "truefalse",
+				DataType:     "bool",
+				IsNullable:   false,
+				DefaultValue: "false",
+			},
+			{
+				Name:         "enable_atom",
+				ColumnName:   "enable_atom",
+				ColumnType:   "truefalse",
+				DataType:     "bool",
+				IsNullable:   false,
+				DefaultValue: "true",
+			},
+			{
+				Name:         "enable_json",
+				ColumnName:   "enable_json",
+				ColumnType:   "truefalse",
+				DataType:     "bool",
+				IsNullable:   false,
+				DefaultValue: "true",
+			},
+			{
+				Name:         "enable_rss",
+				ColumnName:   "enable_rss",
+				ColumnType:   "truefalse",
+				DataType:     "bool",
+				IsNullable:   false,
+				DefaultValue: "true",
+			},
+			{
+				Name:         "page_size",
+				ColumnName:   "page_size",
+				ColumnType:   "measurement",
+				DataType:     "int(11)",
+				IsNullable:   false,
+				DefaultValue: "1000",
+			},
+		},
+	},
+	{
+		TableName:     "integration",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-exchange-alt",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "name",
+				ColumnName: "name",
+				IsUnique:   true,
+				IsIndexed:  true,
+				ColumnType: "label",
+				DataType:   "varchar(100)",
+				IsNullable: false,
+			},
+			{
+				Name:       "specification_language",
+				ColumnName: "specification_language",
+				ColumnType: "label",
+				DataType:   "varchar(20)",
+				IsNullable: false,
+			},
+			{
+				Name:         "specification_format",
+				ColumnName:   "specification_format",
+				ColumnType:   "label",
+				DataType:     "varchar(10)",
+				DefaultValue: "'json'",
+			},
+			{
+				Name:       "specification",
+				ColumnName: "specification",
+				ColumnType: "content",
+				DataType:   "mediumtext",
+				IsNullable: false,
+			},
+			{
+				Name:       "authentication_type",
+				ColumnName: "authentication_type",
+				ColumnType: "label",
+			},
+			{
+				Name:       "authentication_specification",
+				ColumnName: "authentication_specification",
+				ColumnType: "encrypted",
+				DataType:   "text",
+				IsNullable: false,
+			},
+			{
+				Name:         "enable",
+				ColumnName:   "enable",
+				ColumnType:   "truefalse",
+				DataType:     "bool",
+				DefaultValue: "true",
+				IsNullable:   false,
+			},
+		},
+	},
+	{
+		TableName:     "task",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-clock",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "name",
+				ColumnName: "name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsIndexed:  true,
+			},
+			{
+				Name:       "action_name",
+				ColumnName: "action_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "entity_name",
+				ColumnName: "entity_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "schedule",
+				ColumnName: "schedule",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "active",
+				ColumnName: "active",
+				DataType:   "bool",
+				ColumnType: "truefalse",
+			},
+			{
+				Name:       "attributes",
+				ColumnName: "attributes",
+				DataType:   "text",
+				ColumnType: "json",
+			},
+			{
+				Name:       "job_type",
+				ColumnName: "job_type",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+		},
+	},
+	//{
+	//	TableName:     "marketplace",
+	//	IsHidden:      true,
+	//	DefaultGroups: adminsGroup,
+	//	Icon:          "fa-shopping-cart",
+	//	Columns: []api2go.ColumnInfo{
+	//		{
+	//			Name:       "name",
+	//			ColumnName: "name",
+	//			DataType:   "varchar(100)",
+	//			ColumnType: "label",
+	//			IsIndexed:  true,
+	//		},
+	//		{
+	//			Name:       "endpoint",
+	//			ColumnName: "endpoint",
+	//			DataType:   "varchar(200)",
+	//			ColumnType: "url",
+	//		},
+	//		{
+	//			Name:         "root_path",
+	//			ColumnName:   "root_path",
+	//			DataType:     "varchar(100)",
+	//			ColumnType:   "label",
+	//			DefaultValue: "''",
+	//		},
+	//	},
+	//},
+	{
+		TableName:     "json_schema",
+		Icon:          "fa-code",
+		DefaultGroups: adminsGroup,
+		IsHidden:      true,
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "schema_name",
+				ColumnName: "schema_name",
+				ColumnType: "label",
+				DataType:   "varchar(100)",
+				IsNullable: false,
+			},
+			{
+				Name:       "json_schema",
+				ColumnType: "json",
+				DataType:   "text",
+				ColumnName: "json_schema",
+			},
+		},
+	},
+	{
+		TableName:     "timeline",
+		Icon:          "fa-clock-o",
+		DefaultGroups: adminsGroup,
+		IsHidden:      true,
 		Columns: []api2go.ColumnInfo{
 			{
-				ColumnName: "rpath",
-				Name:       "rpath",
+				Name:       "event_type",
+				ColumnName: "event_type",
 				ColumnType: "label",
+				DataType:   "varchar(50)",
+				IsNullable: false,
+			},
+			{
+				Name:       "title",
+				ColumnName: "title",
+				ColumnType: "label",
+				IsIndexed:  true,
+				DataType:   "varchar(50)",
+				IsNullable: false,
+			},
+			{
+				Name:       "payload",
+				ColumnName: "payload",
+				ColumnType: "content",
+				DataType:   "text",
+				IsNullable: true,
+			},
+		},
+	},
+	{
+		TableName:     "world",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-home",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "table_name",
+				ColumnName: "table_name",
+				IsNullable: false,
 				IsUnique:   true,
-				DataType:   "varchar(500)",
+				IsIndexed:  true,
+				DataType:   "varchar(200)",
+				ColumnType: "name",
+			},
+			{
+				Name:       "world_schema_json",
+				ColumnName: "world_schema_json",
+				DataType:   "text",
 				IsNullable: false,
+				ColumnType: "json",
 			},
 			{
-				ColumnName:   "content",
-				Name:         "content",
-				ColumnType:   "file.ical",
-				DataType:     "longblob",
+				Name:         "default_permission",
+				ColumnName:   "default_permission",
+				DataType:     "int(4)",
 				IsNullable:   false,
-				IsForeignKey: true,
-				ForeignKeyData: api2go.ForeignKeyData{
-					DataSource: "cloud_store",
-					Namespace:  "localstore",
-					KeyName:    "caldav",
-				},
+				DefaultValue: "644",
+				ColumnType:   "value",
+			},
+			{
+				Name:         "is_top_level",
+				ColumnName:   "is_top_level",
+				DataType:     "bool",
+				IsNullable:   false,
+				DefaultValue: "true",
+				ColumnType:   "truefalse",
+			},
+			{
+				Name:         "is_hidden",
+				ColumnName:   "is_hidden",
+				DataType:     "bool",
+				IsNullable:   false,
+				DefaultValue: "false",
+				ColumnType:   "truefalse",
+			},
+			{
+				Name:         "is_join_table",
+				ColumnName:   "is_join_table",
+				DataType:     "bool",
+				IsNullable:   false,
+				DefaultValue: "false",
+				ColumnType:   "truefalse",
+			},
+			{
+				Name:         "is_state_tracking_enabled",
+				ColumnName:   "is_state_tracking_enabled",
+				DataType:     "bool",
+				IsNullable:   false,
+				DefaultValue: "false",
+				ColumnType:   "truefalse",
+			},
+			{
+				Name:         "default_order",
+				ColumnName:   "default_order",
+				DataType:     "varchar(100)",
+				IsNullable:   true,
+				DefaultValue: "'+id'",
+				ColumnType:   "value",
+			},
+			{
+				Name:         "icon",
+				ColumnName:   "icon",
+				DataType:     "varchar(20)",
+				IsNullable:   true,
+				DefaultValue: "'fa-star'",
+				ColumnType:   "label",
 			},
-			//{
-			//	ColumnName: "event_title",
-			//	Name:       "event_title",
-			//	ColumnType: "label",
-			//	DataType:   "varchar(100)",
-			//	IsNullable: false,
-			//	IsIndexed:  true,
-			//},
-			//{
-			//	ColumnName: "event_url",
-			//	Name:       "event_url",
-			//	ColumnType: "label",
-			//	DataType:   "varchar(500)",
-			//	IsNullable: true,
-			//},
-			//{
-			//	ColumnName:   "event_type",
-			//	Name:         "event_type",
-			//	ColumnType:   "label",
-			//	DataType:     "varchar(50)",
-			//	IsIndexed:    true,
-			//	IsNullable:   false,
-			//	DefaultValue: "'event'",
-			//},
-			//{
-			//	ColumnName:   "all_day",
-			//	Name:         "all_day",
-			//	ColumnType:   "truefalse",
-			//	DataType:     "bool",
-			//	IsNullable:   false,
-			//	DefaultValue: "false",
-			//},
-			//{
-			//	ColumnName: "event_location",
-			//	Name:       "event_location",
-			//	IsIndexed:  true,
-			//	ColumnType: "label",
-			//	DataType:   "varchar(500)",
-			//	IsNullable: true,
-			//},
-			//{
-			//	ColumnName: "event_end_date",
-			//	Name:       "event_end_date",
-			//	ColumnType: "datetime",
-			//	IsIndexed:  true,
-			//	DataType:   "timestamp",
-			//	IsNullable: true,
-			//},
-			//{
-			//	ColumnName:   "event_timezone",
-			//	Name:         "event_timezone",
-			//	ColumnType:   "label",
-			//	DataType:     "varchar(50)",
-			//	IsNullable:   false,
-			//	DefaultValue: "'local'",
-			//},
-			//{
-			//	ColumnName: "event_start_date",
-			//	Name:       "event_start_date",
-			//	ColumnType: "datetime",
-			//	IsIndexed:  true,
-			//	DataType:   "timestamp",
-			//	IsNullable: false,
-			//},
-			//{
-			//	ColumnName: "start_time",
-			//	Name:       "start_time",
-			//	ColumnType: "label",
-			//	IsIndexed:  true,
-			//	DataType:   "varchar(50)",
-			//	IsNullable: true,
-			//},
-			//{
-			//	ColumnName: "end_time",
-			//	Name:       "end_time",
-			//	IsIndexed:  true,
-			//	ColumnType: "label",
-			//	DataType:   "varchar(50)",
-			//	IsNullable: true,
-			//},
-			//{
-			//	ColumnName: "start_recur",
-			//	Name:       "start_recur",
-			//	ColumnType: "label",
-			//	DataType:   "varchar(50)",
-			//	IsNullable: true,
-			//},
-			//{
-			//	ColumnName: "days_of_week",
-			//	Name:       "days_of_week",
-			//	IsIndexed:  true,
-			//	ColumnType: "label",
-			//	IsNullable: true,
-			//	DataType:   "varchar(100)",
-			//},
-			//{
-			//	ColumnName: "event_description",
-			//	Name:       "event_description",
-			//	ColumnType: "content",
-			//	DataType:   "text",
-			//	IsIndexed:  false,
-			//	IsNullable: true,
-			//},
 		},
 	},
 	{
-		TableName:     "collection",
+		TableName:     "stream",
+		Icon:          "fa-strikethrough",
+		DefaultGroups: adminsGroup,
+		IsHidden:      true,
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "stream_name",
+				ColumnName: "stream_name",
+				DataType:   "varchar(100)",
+				IsNullable: false,
+				ColumnType: "label",
+				IsIndexed:  true,
+			},
+			{
+				Name:         "enable",
+				ColumnName:   "enable",
+				DataType:     "bool",
+				IsNullable:   false,
+				ColumnType:   "truefalse",
+				DefaultValue: "true",
+			},
+			{
+				Name:       "stream_contract",
+				ColumnName: "stream_contract",
+				DataType:   "text",
+				IsNullable: false,
+				ColumnType: "json",
+			},
+		},
+	},
+	{
+		TableName:     "user_otp_account",
+		Icon:          "fa-sms",
 		IsHidden:      true,
-		DefaultGroups: adminsGroup,
-		Icon:          "fa-calendar-alt",
+		DefaultGroups: []string{},
 		Columns: []api2go.ColumnInfo{
 			{
-				ColumnName: "name",
-				Name:       "name",
-				ColumnType: "label",
-				DataType:   "varchar(500)",
-				IsNullable: false,
+				ColumnName: "mobile_number",
 				IsIndexed:  true,
+				IsNullable: true,
+				DataType:   "varchar(20)",
+				ColumnType: "label",
 			},
 			{
-				ColumnName: "description",
-				Name:       "description",
-				ColumnType: "label",
-				DataType:   "text",
-				IsNullable: false,
-				IsIndexed:  true,
+				ColumnName:     "otp_secret",
+				IsIndexed:      true,
+				ExcludeFromApi: true,
+				DataType:       "varchar(100)",
+				ColumnType:     "encrypted",
+			},
+			{
+				ColumnName:   "verified",
+				DataType:     "bool",
+				DefaultValue: "false",
+				ColumnType:   "truefalse",
+				Name:         "verified",
 			},
 		},
 	},
 	{
-		TableName:     "certificate",
-		IsHidden:      true,
-		DefaultGroups: adminsGroup,
-		Icon:          "fa-certificate",
+		TableName:     USER_ACCOUNT_TABLE_NAME,
+		Icon:          "fa-user",
+		DefaultGroups: []string{"users"},
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "hostname",
-				ColumnName: "hostname",
-				IsUnique:   true,
+				Name:       "name",
+				ColumnName: "name",
 				IsIndexed:  true,
+				DataType:   "varchar(80)",
 				ColumnType: "label",
-				DataType:   "varchar(100)",
-				IsNullable: false,
 			},
 			{
-				Name:         "issuer",
-				ColumnName:   "issuer",
-				ColumnType:   "label",
-				DataType:     "varchar(100)",
-				IsNullable:   false,
-				DefaultValue: "'self'",
+				Name:       "email",
+				ColumnName: "email",
+				DataType:   "varchar(80)",
+				IsIndexed:  true,
+				IsUnique:   true,
+				ColumnType: "email",
 			},
+
 			{
-				Name:       "generated_at",
-				ColumnName: "generated_at",
-				ColumnType: "datetime",
-				DataType:   "timestamp",
+				Name:       "password",
+				ColumnName: "password",
+				DataType:   "varchar(100)",
+				ColumnType: "password",
 				IsNullable: true,
 			},
 			{
-				Name:       "certificate_pem",
-				ColumnName: "certificate_pem",
-				ColumnType: "content",
-				DataType:   "text",
-				IsNullable: true,
+				Name:           "password_history",
+				ColumnName:     passwordHistoryColumn,
+				DataType:       "text",
+				ColumnType:     "json",
+				IsNullable:     true,
+				ExcludeFromApi: true,
 			},
 			{
-				Name:       "root_certificate",
-				ColumnName: "root_certificate",
-				ColumnType: "content",
-				DataType:   "text",
-				IsNullable: true,
+				Name:         "confirmed",
+				ColumnName:   "confirmed",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				IsNullable:   false,
+				DefaultValue: "false",
 			},
+		},
+		ColumnPermissions: map[string]auth.AuthPermission{
+			passwordHistoryColumn: auth.None,
+		},
+		Validations: []ColumnTag{
 			{
-				Name:       "private_key_pem",
-				ColumnName: "private_key_pem",
-				ColumnType: "encrypted",
-				DataType:   "text",
-				IsNullable: true,
+				ColumnName: "email",
+				Tags:       "email",
 			},
 			{
-				Name:       "public_key_pem",
-				ColumnName: "public_key_pem",
-				ColumnType: "content",
-				DataType:   "text",
-				IsNullable: true,
+				ColumnName: "password",
+				Tags:       "required",
+			},
+			{
+				ColumnName: "name",
+				Tags:       "required",
+			},
+		},
+		Conformations: []ColumnTag{
+			{
+				ColumnName: "email",
+				Tags:       "email",
 			},
 		},
 	},
 	{
-		TableName:     "feed",
-		IsHidden:      true,
-		DefaultGroups: adminsGroup,
-		Icon:          "fa-rss",
+		TableName: "usergroup",
+		Icon:      "fa-users",
+		IsHidden:  true,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "feed_name",
-				ColumnName: "feed_name",
-				IsUnique:   true,
+				Name:       "name",
+				ColumnName: "name",
 				IsIndexed:  true,
+				IsUnique:   true,
+				DataType:   "varchar(80)",
 				ColumnType: "label",
-				DataType:   "varchar(100)",
-				IsNullable: false,
-			},
-			{
-				Name:         "title",
-				ColumnName:   "title",
-				ColumnType:   "label",
-				DataType:     "varchar(500)",
-				IsNullable:   false,
-				DefaultValue: "''",
 			},
 			{
-				Name:         "title",
-				ColumnName:   "title",
-				ColumnType:   "label",
-				DataType:     "varchar(500)",
-				IsNullable:   false,
-				DefaultValue: "''",
+				Name:         "parent_usergroup",
+				ColumnName:   auth.ParentUserGroupColumn,
+				IsForeignKey: true,
+				IsNullable:   true,
+				IsIndexed:    true,
+				ColumnType:   "alias",
+				DataType:     "int(11)",
+				ForeignKeyData: api2go.ForeignKeyData{
+					DataSource: "self",
+					Namespace:  "usergroup",
+					KeyName:    "id",
+				},
 			},
+		},
+	},
+	{
+		TableName:     "action",
+		DefaultGroups: adminsGroup,
+		IsHidden:      true,
+		Icon:          "fa-bolt",
+		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "description",
-				ColumnName: "description",
+				Name:       "action_name",
+				IsIndexed:  true,
+				ColumnName: "action_name",
+				DataType:   "varchar(100)",
 				ColumnType: "label",
-				DataType:   "text",
-				IsNullable: false,
-			},
-			{
-				Name:         "link",
-				ColumnName:   "link",
-				ColumnType:   "label",
-				DataType:     "varchar(1000)",
-				IsNullable:   false,
-				DefaultValue: "''",
 			},
 			{
-				Name:         "author_name",
-				ColumnName:   "author_name",
-				ColumnType:   "label",
-				DataType:     "varchar(500)",
-				IsNullable:   false,
-				DefaultValue: "''",
+				Name:       "label",
+				ColumnName: "label",
+				IsIndexed:  true,
+				DataType:   "varchar(100)",
+				ColumnType: "label",
 			},
 			{
-				Name:         "author_email",
-				ColumnName:   "author_email",
-				ColumnType:   "label",
-				DataType:     "varchar(500)",
-				IsNullable:   false,
-				DefaultValue: "''",
+				Name:         "instance_optional",
+				ColumnName:   "instance_optional",
+				IsIndexed:    false,
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "true",
 			},
 			{
-				Name:         "enable",
-				ColumnName:   "enable",
-				ColumnType:   "truefalse",
-				DataType:     "bool",
-				IsNullable:   false,
-				DefaultValue: "false",
+				Name:       "action_schema",
+				ColumnName: "action_schema",
+				DataType:   "text",
+				ColumnType: "json",
 			},
+		},
+	},
+	{
+		TableName:     "smd",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Columns: []api2go.ColumnInfo{
 			{
-				Name:         "enable_atom",
-				ColumnName:   "enable_atom",
-				ColumnType:   "truefalse",
-				DataType:     "bool",
-				IsNullable:   false,
-				DefaultValue: "true",
+				Name:       "name",
+				ColumnName: "name",
+				IsIndexed:  true,
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsNullable: false,
 			},
 			{
-				Name:         "enable_json",
-				ColumnName:   "enable_json",
-				ColumnType:   "truefalse",
-				DataType:     "bool",
-				IsNullable:   false,
-				DefaultValue: "true",
+				Name:       "label",
+				ColumnName: "label",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsNullable: false,
 			},
 			{
-				Name:         "enable_rss",
-				ColumnName:   "enable_rss",
-				ColumnType:   "truefalse",
-				DataType:     "bool",
-				IsNullable:   false,
-				DefaultValue: "true",
+				Name:       "initial_state",
+				ColumnName: "initial_state",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsNullable: false,
 			},
 			{
-				Name:         "page_size",
-				ColumnName:   "page_size",
-				ColumnType:   "measurement",
-				DataType:     "int(11)",
-				IsNullable:   false,
-				DefaultValue: "1000",
+				Name:       "events",
+				ColumnName: "events",
+				DataType:   "text",
+				ColumnType: "json",
+				IsNullable: false,
 			},
 		},
 	},
 	{
-		TableName:     "integration",
+		TableName:     "oauth_connect",
 		IsHidden:      true,
 		DefaultGroups: adminsGroup,
-		Icon:          "fa-exchange-alt",
 		Columns: []api2go.ColumnInfo{
 			{
 				Name:       "name",
 				ColumnName: "name",
 				IsUnique:   true,
 				IsIndexed:  true,
+				DataType:   "varchar(80)",
 				ColumnType: "label",
-				DataType:   "varchar(100)",
-				IsNullable: false,
 			},
 			{
-				Name:       "specification_language",
-				ColumnName: "specification_language",
+				Name:       "client_id",
+				ColumnName: "client_id",
+				DataType:   "varchar(150)",
 				ColumnType: "label",
-				DataType:   "varchar(20)",
-				IsNullable: false,
 			},
 			{
-				Name:         "specification_format",
-				ColumnName:   "specification_format",
+				Name:       "client_secret",
+				ColumnName: "client_secret",
+				DataType:   "varchar(500)",
+				ColumnType: "encrypted",
+			},
+			{
+				Name:         "scope",
+				ColumnName:   "scope",
+				DataType:     "varchar(1000)",
+				ColumnType:   "content",
+				DefaultValue: "'https://www.googleapis.com/auth/spreadsheets'",
+			},
+			{
+				Name:         "response_type",
+				ColumnName:   "response_type",
+				DataType:     "varchar(80)",
 				ColumnType:   "label",
-				DataType:     "varchar(10)",
-				DefaultValue: "'json'",
+				DefaultValue: "'code'",
 			},
 			{
-				Name:       "specification",
-				ColumnName: "specification",
-				ColumnType: "content",
-				DataType:   "mediumtext",
-				IsNullable: false,
+				Name:         "redirect_uri",
+				ColumnName:   "redirect_uri",
+				DataType:     "varchar(80)",
+				ColumnType:   "url",
+				DefaultValue: "'/oauth/response'",
 			},
 			{
-				Name:       "authentication_type",
-				ColumnName: "authentication_type",
-				ColumnType: "label",
+				Name:         "auth_url",
+				ColumnName:   "auth_url",
+				DataType:     "varchar(200)",
+				DefaultValue: "'https://accounts.google.com/o/oauth2/auth'",
+				ColumnType:   "url",
 			},
 			{
-				Name:       "authentication_specification",
-				ColumnName: "authentication_specification",
-				ColumnType: "encrypted",
-				DataType:   "text",
-				IsNullable: false,
+				Name:         "token_url",
+				ColumnName:   "token_url",
+				DataType:     "varchar(200)",
+				DefaultValue: "'https://accounts.google.com/o/oauth2/token'",
+				ColumnType:   "url",
 			},
 			{
-				Name:         "enable",
-				ColumnName:   "enable",
+				Name:         "profile_url",
+				ColumnName:   "profile_url",
+				DataType:     "varchar(200)",
+				DefaultValue: "'https://www.googleapis.com/oauth2/v1/userinfo?alt=json'",
+				ColumnType:   "url",
+			},
+			{
+				Name:         "profile_email_path",
+				ColumnName:   "profile_email_path",
+				DataType:     "varchar(200)",
+				DefaultValue: "'email'",
+				ColumnType:   "label",
+			},
+			{
+				Name:         "allow_login",
+				ColumnName:   "allow_login",
+				DataType:     "bool",
+				DefaultValue: "false",
 				ColumnType:   "truefalse",
+			},
+			{
+				Name:         "access_type_offline",
+				ColumnName:   "access_type_offline",
 				DataType:     "bool",
-				DefaultValue: "true",
-				IsNullable:   false,
+				DefaultValue: "false",
+				ColumnType:   "truefalse",
 			},
 		},
 	},
 	{
-		TableName:     "task",
+		TableName:     "data_exchange",
 		IsHidden:      true,
 		DefaultGroups: adminsGroup,
-		Icon:          "fa-clock",
 		Columns: []api2go.ColumnInfo{
 			{
 				Name:       "name",
 				ColumnName: "name",
-				DataType:   "varchar(100)",
 				ColumnType: "label",
+				IsUnique:   true,
+				DataType:   "varchar(200)",
 				IsIndexed:  true,
 			},
 			{
-				Name:       "action_name",
-				ColumnName: "action_name",
-				DataType:   "varchar(100)",
-				ColumnType: "label",
+				Name:       "source_attributes",
+				ColumnName: "source_attributes",
+				ColumnType: "json",
+				DataType:   "text",
 			},
 			{
-				Name:       "entity_name",
-				ColumnName: "entity_name",
-				DataType:   "varchar(100)",
+				Name:       "source_type",
+				ColumnName: "source_type",
 				ColumnType: "label",
-			},
-			{
-				Name:       "schedule",
-				ColumnName: "schedule",
 				DataType:   "varchar(100)",
-				ColumnType: "label",
 			},
 			{
-				Name:       "active",
-				ColumnName: "active",
-				DataType:   "bool",
-				ColumnType: "truefalse",
+				Name:       "target_attributes",
+				ColumnName: "target_attributes",
+				ColumnType: "json",
+				DataType:   "text",
 			},
 			{
 				Name:       "attributes",
 				ColumnName: "attributes",
-				DataType:   "text",
 				ColumnType: "json",
+				DataType:   "text",
 			},
 			{
-				Name:       "job_type",
-				ColumnName: "job_type",
-				DataType:   "varchar(100)",
+				Name:       "target_type",
+				ColumnName: "target_type",
 				ColumnType: "label",
+				DataType:   "varchar(100)",
+			},
+			{
+				Name:       "options",
+				ColumnName: "options",
+				ColumnType: "json",
+				DataType:   "text",
 			},
 		},
 	},
-	//{
-	//	TableName:     "marketplace",
-	//	IsHidden:      true,
-	//	DefaultGroups: adminsGroup,
-	//	Icon:          "fa-shopping-cart",
-	//	Columns: []api2go.ColumnInfo{
-	//		{
-	//			Name:       "name",
-	//			ColumnName: "name",
-	//			DataType:   "varchar(100)",
-	//			ColumnType: "label",
-	//			IsIndexed:  true,
-	//		},
-	//		{
-	//			Name:       "endpoint",
-	//			ColumnName: "endpoint",
-	//			DataType:   "varchar(200)",
-	//			ColumnType: "url",
-	//		},
-	//		{
-	//			Name:         "root_path",
-	//			ColumnName:   "root_path",
-	//			DataType:     "varchar(100)",
-	//			ColumnType:   "label",
-	//			DefaultValue: "''",
-	//		},
-	//	},
-	//},
 	{
-		TableName:     "json_schema",
-		Icon:          "fa-code",
-		DefaultGroups: adminsGroup,
+		TableName:     "oauth_token",
 		IsHidden:      true,
+		DefaultGroups: adminsGroup,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "schema_name",
-				ColumnName: "schema_name",
-				ColumnType: "label",
-				DataType:   "varchar(100)",
-				IsNullable: false,
+				Name:       "access_token",
+				ColumnName: "access_token",
+				ColumnType: "encrypted",
+				DataType:   "varchar(1000)",
 			},
 			{
-				Name:       "json_schema",
-				ColumnType: "json",
-				DataType:   "text",
-				ColumnName: "json_schema",
+				Name:       "expires_in",
+				ColumnName: "expires_in",
+				ColumnType: "measurement",
+				DataType:   "int(11)",
+			},
+			{
+				Name:       "refresh_token",
+				ColumnName: "refresh_token",
+				ColumnType: "encrypted",
+				DataType:   "varchar(1000)",
+			},
+			{
+				Name:       "token_type",
+				ColumnName: "token_type",
+				ColumnType: "label",
+				DataType:   "varchar(20)",
 			},
 		},
 	},
 	{
-		TableName:     "timeline",
-		Icon:          "fa-clock-o",
+		TableName:     "cloud_store",
 		DefaultGroups: adminsGroup,
 		IsHidden:      true,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "event_type",
-				ColumnName: "event_type",
+				Name:       "Name",
+				ColumnName: "name",
 				ColumnType: "label",
-				DataType:   "varchar(50)",
-				IsNullable: false,
+				DataType:   "varchar(100)",
+				IsUnique:   true,
 			},
 			{
-				Name:       "title",
-				ColumnName: "title",
+				Name:       "store_type",
+				ColumnName: "store_type",
 				ColumnType: "label",
-				IsIndexed:  true,
-				DataType:   "varchar(50)",
-				IsNullable: false,
+				DataType:   "varchar(100)",
 			},
 			{
-				Name:       "payload",
-				ColumnName: "payload",
-				ColumnType: "content",
+				Name:       "store_provider",
+				ColumnName: "store_provider",
+				ColumnType: "label",
+				DataType:   "varchar(100)",
+			},
+			{
+				Name:       "root_path",
+				ColumnName: "root_path",
+				ColumnType: "label",
+				DataType:   "varchar(1000)",
+			},
+			{
+				Name:       "store_parameters",
+				ColumnName: "store_parameters",
+				ColumnType: "json",
 				DataType:   "text",
-				IsNullable: true,
 			},
 		},
 	},
 	{
-		TableName:     "world",
-		IsHidden:      true,
+		TableName:     "site",
 		DefaultGroups: adminsGroup,
-		Icon:          "fa-home",
+		IsHidden:      true,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "table_name",
-				ColumnName: "table_name",
-				IsNullable: false,
-				IsUnique:   true,
-				IsIndexed:  true,
-				DataType:   "varchar(200)",
-				ColumnType: "name",
+				Name:       "name",
+				ColumnName: "name",
+				ColumnType: "label",
+				DataType:   "varchar(100)",
 			},
 			{
-				Name:       "world_schema_json",
-				ColumnName: "world_schema_json",
-				DataType:   "text",
-				IsNullable: false,
-				ColumnType: "json",
+				Name:       "hostname",
+				ColumnName: "hostname",
+				ColumnType: "label",
+				DataType:   "varchar(100)",
 			},
 			{
-				Name:         "default_permission",
-				ColumnName:   "default_permission",
-				DataType:     "int(4)",
-				IsNullable:   false,
-				DefaultValue: "644",
-				ColumnType:   "value",
+				Name:              "path",
+				ColumnName:        "path",
+				ColumnType:        "label",
+				DataType:          "varchar(100)",
+				ColumnDescription: "path on the cloud store to host as base directory",
 			},
 			{
-				Name:         "is_top_level",
-				ColumnName:   "is_top_level",
+				Name:         "enable",
+				ColumnName:   "enable",
+				ColumnType:   "truefalse",
 				DataType:     "bool",
-				IsNullable:   false,
 				DefaultValue: "true",
-				ColumnType:   "truefalse",
 			},
 			{
-				Name:         "is_hidden",
-				ColumnName:   "is_hidden",
-				DataType:     "bool",
-				IsNullable:   false,
-				DefaultValue: "false",
+				Name:         "enable_https",
+				ColumnName:   "enable_https",
 				ColumnType:   "truefalse",
-			},
-			{
-				Name:         "is_join_table",
-				ColumnName:   "is_join_table",
 				DataType:     "bool",
-				IsNullable:   false,
 				DefaultValue: "false",
-				ColumnType:   "truefalse",
 			},
 			{
-				Name:         "is_state_tracking_enabled",
-				ColumnName:   "is_state_tracking_enabled",
+				Name:         "ftp_enabled",
+				ColumnName:   "ftp_enabled",
+				ColumnType:   "truefalse",
 				DataType:     "bool",
-				IsNullable:   false,
 				DefaultValue: "false",
-				ColumnType:   "truefalse",
-			},
-			{
-				Name:         "default_order",
-				ColumnName:   "default_order",
-				DataType:     "varchar(100)",
-				IsNullable:   true,
-				DefaultValue: "'+id'",
-				ColumnType:   "value",
 			},
 			{
-				Name:         "icon",
-				ColumnName:   "icon",
-				DataType:     "varchar(20)",
-				IsNullable:   true,
-				DefaultValue: "'fa-star'",
+				Name:         "site_type",
+				ColumnName:   "site_type",
 				ColumnType:   "label",
+				DataType:     "varchar(20)",
+				DefaultValue: "'static'",
 			},
 		},
 	},
 	{
-		TableName:     "stream",
-		Icon:          "fa-strikethrough",
-		DefaultGroups: adminsGroup,
+		TableName:     "mail_server",
 		IsHidden:      true,
+		Icon:          "fa-envelope",
+		DefaultGroups: adminsGroup,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "stream_name",
-				ColumnName: "stream_name",
+				Name:       "hostname",
+				ColumnName: "hostname",
 				DataType:   "varchar(100)",
-				IsNullable: false,
 				ColumnType: "label",
-				IsIndexed:  true,
 			},
 			{
-				Name:         "enable",
-				ColumnName:   "enable",
+				Name:         "is_enabled",
+				ColumnName:   "is_enabled",
 				DataType:     "bool",
-				IsNullable:   false,
 				ColumnType:   "truefalse",
-				DefaultValue: "true",
+				DefaultValue: "false",
 			},
 			{
-				Name:       "stream_contract",
-				ColumnName: "stream_contract",
-				DataType:   "text",
-				IsNullable: false,
-				ColumnType: "json",
+				Name:         "listen_interface",
+				ColumnName:   "listen_interface",
+				DataType:     "varchar(100)",
+				ColumnType:   "label",
+				DefaultValue: "'0.0.0.0:465'",
 			},
-		},
-	},
-	{
-		TableName:     "user_otp_account",
-		Icon:          "fa-sms",
-		IsHidden:      true,
-		DefaultGroups: []string{},
-		Columns: []api2go.ColumnInfo{
 			{
-				ColumnName: "mobile_number",
-				IsIndexed:  true,
-				IsNullable: true,
-				DataType:   "varchar(20)",
-				ColumnType: "label",
+				Name:         "max_size",
+				ColumnName:   "max_size",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "10000",
 			},
 			{
-				ColumnName:     "otp_secret",
-				IsIndexed:      true,
-				ExcludeFromApi: true,
-				DataType:       "varchar(100)",
-				ColumnType:     "encrypted",
+				Name:         "max_clients",
+				ColumnName:   "max_clients",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "20",
 			},
 			{
-				ColumnName:   "verified",
+				Name:         "xclient_on",
+				ColumnName:   "xclient_on",
 				DataType:     "bool",
+				ColumnType:   "truefalse",
 				DefaultValue: "false",
+			},
+			{
+				Name:         "always_on_tls",
+				ColumnName:   "always_on_tls",
+				DataType:     "bool",
 				ColumnType:   "truefalse",
-				Name:         "verified",
+				DefaultValue: "true",
+			},
+			{
+				Name:         "authentication_required",
+				ColumnName:   "authentication_required",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "true",
 			},
 		},
 	},
 	{
-		TableName:     USER_ACCOUNT_TABLE_NAME,
-		Icon:          "fa-user",
-		DefaultGroups: []string{"users"},
+		TableName:     "mail_account",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-envelope",
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
-				IsIndexed:  true,
-				DataType:   "varchar(80)",
+				Name:       "username",
+				ColumnName: "username",
+				DataType:   "varchar(100)",
 				ColumnType: "label",
-			},
-			{
-				Name:       "email",
-				ColumnName: "email",
-				DataType:   "varchar(80)",
-				IsIndexed:  true,
 				IsUnique:   true,
-				ColumnType: "email",
 			},
-
 			{
 				Name:       "password",
 				ColumnName: "password",
-				DataType:   "varchar(100)",
 				ColumnType: "password",
-				IsNullable: true,
 			},
 			{
-				Name:         "confirmed",
-				ColumnName:   "confirmed",
+				Name:       "password_md5",
+				ColumnName: "password_md5",
+				ColumnType: "md5-bcrypt",
+			},
+		},
+	},
+	{
+		TableName:     "mail_box",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "name",
+				ColumnName: "name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:         "subscribed",
+				ColumnName:   "subscribed",
 				DataType:     "bool",
 				ColumnType:   "truefalse",
-				IsNullable:   false,
-				DefaultValue: "false",
+				DefaultValue: "true",
 			},
-		},
-		Validations: []ColumnTag{
 			{
-				ColumnName: "email",
-				Tags:       "email",
+				Name:         "uidvalidity",
+				ColumnName:   "uidvalidity",
+				DataType:     "int(11)",
+				ColumnType:   "value",
+				DefaultValue: "1",
 			},
 			{
-				ColumnName: "password",
-				Tags:       "required",
+				Name:         "nextuid",
+				ColumnName:   "nextuid",
+				DataType:     "int(11)",
+				ColumnType:   "value",
+				DefaultValue: "1",
 			},
 			{
-				ColumnName: "name",
-				Tags:       "required",
+				Name:       "attributes",
+				ColumnName: "attributes",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
 			},
-		},
-		Conformations: []ColumnTag{
 			{
-				ColumnName: "email",
-				Tags:       "email",
+				Name:       "flags",
+				ColumnName: "flags",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
 			},
-		},
-	},
-	{
-		TableName: "usergroup",
-		Icon:      "fa-users",
-		IsHidden:  true,
-		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
-				IsIndexed:  true,
-				IsUnique:   true,
-				DataType:   "varchar(80)",
+				Name:       "permanent_flags",
+				ColumnName: "permanent_flags",
+				DataType:   "varchar(100)",
 				ColumnType: "label",
 			},
 		},
 	},
 	{
-		TableName:     "action",
-		DefaultGroups: adminsGroup,
+		TableName:     "mail",
 		IsHidden:      true,
-		Icon:          "fa-bolt",
+		Icon:          "fa-envelope",
+		DefaultGroups: adminsGroup,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "action_name",
-				IsIndexed:  true,
-				ColumnName: "action_name",
-				DataType:   "varchar(100)",
+				Name:       "message_id",
+				ColumnName: "message_id",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "mail_id",
+				ColumnName: "mail_id",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "from_address",
+				ColumnName: "from_address",
+				DataType:   "varchar(200)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "internal_date",
+				ColumnName: "internal_date",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+			},
+			{
+				Name:       "to_address",
+				ColumnName: "to_address",
+				DataType:   "varchar(200)",
 				ColumnType: "label",
 			},
 			{
-				Name:       "label",
-				ColumnName: "label",
-				IsIndexed:  true,
-				DataType:   "varchar(100)",
+				Name:       "reply_to_address",
+				ColumnName: "reply_to_address",
+				DataType:   "varchar(200)",
 				ColumnType: "label",
 			},
 			{
-				Name:         "instance_optional",
-				ColumnName:   "instance_optional",
-				IsIndexed:    false,
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "true",
+				Name:       "sender_address",
+				ColumnName: "sender_address",
+				DataType:   "varchar(200)",
+				ColumnType: "label",
 			},
 			{
-				Name:       "action_schema",
-				ColumnName: "action_schema",
-				DataType:   "text",
-				ColumnType: "json",
+				Name:       "subject",
+				ColumnName: "subject",
+				DataType:   "varchar(200)",
+				ColumnType: "label",
 			},
-		},
-	},
-	{
-		TableName:     "smd",
-		IsHidden:      true,
-		DefaultGroups: adminsGroup,
-		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
-				IsIndexed:  true,
-				DataType:   "varchar(100)",
+				Name:       "body",
+				ColumnName: "body",
+				DataType:   "text",
 				ColumnType: "label",
-				IsNullable: false,
 			},
 			{
-				Name:       "label",
-				ColumnName: "label",
-				DataType:   "varchar(100)",
-				ColumnType: "label",
-				IsNullable: false,
+				Name:       "mail",
+				ColumnName: "mail",
+				ColumnType: "gzip",
+				DataType:   "blob",
 			},
 			{
-				Name:       "initial_state",
-				ColumnName: "initial_state",
+				Name:       "spam_score",
+				ColumnName: "spam_score",
+				ColumnType: "measurement",
+				DataType:   "float",
+			},
+			{
+				Name:       "hash",
+				ColumnName: "hash",
 				DataType:   "varchar(100)",
 				ColumnType: "label",
-				IsNullable: false,
 			},
 			{
-				Name:       "events",
-				ColumnName: "events",
+				Name:       "content_type",
+				ColumnName: "content_type",
 				DataType:   "text",
-				ColumnType: "json",
-				IsNullable: false,
+				ColumnType: "content",
 			},
-		},
-	},
-	{
-		TableName:     "oauth_connect",
-		IsHidden:      true,
-		DefaultGroups: adminsGroup,
-		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
-				IsUnique:   true,
-				IsIndexed:  true,
-				DataType:   "varchar(80)",
+				Name:       "recipient",
+				ColumnName: "recipient",
+				DataType:   "varchar(200)",
 				ColumnType: "label",
 			},
 			{
-				Name:       "client_id",
-				ColumnName: "client_id",
-				DataType:   "varchar(150)",
+				Name:         "has_attachment",
+				ColumnName:   "has_attachment",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
+			},
+			{
+				Name:       "ip_addr",
+				ColumnName: "ip_addr",
+				DataType:   "varchar(30)",
 				ColumnType: "label",
 			},
 			{
-				Name:       "client_secret",
-				ColumnName: "client_secret",
-				DataType:   "varchar(500)",
-				ColumnType: "encrypted",
+				Name:       "return_path",
+				ColumnName: "return_path",
+				DataType:   "VARCHAR(255)",
+				ColumnType: "content",
 			},
 			{
-				Name:         "scope",
-				ColumnName:   "scope",
-				DataType:     "varchar(1000)",
-				ColumnType:   "content",
-				DefaultValue: "'https://www.googleapis.com/auth/spreadsheets'",
+				Name:         "is_tls",
+				ColumnName:   "is_tls",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
 			},
 			{
-				Name:         "response_type",
-				ColumnName:   "response_type",
-				DataType:     "varchar(80)",
-				ColumnType:   "label",
-				DefaultValue: "'code'",
+				Name:         "seen",
+				ColumnName:   "seen",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
 			},
 			{
-				Name:         "redirect_uri",
-				ColumnName:   "redirect_uri",
-				DataType:     "varchar(80)",
-				ColumnType:   "url",
-				DefaultValue: "'/oauth/response'",
+				Name:         "recent",
+				ColumnName:   "recent",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "true",
 			},
 			{
-				Name:         "auth_url",
-				ColumnName:   "auth_url",
-				DataType:     "varchar(200)",
-				DefaultValue: "'https://accounts.google.com/o/oauth2/auth'",
-				ColumnType:   "url",
+				Name:         "deleted",
+				ColumnName:   "deleted",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
 			},
 			{
-				Name:         "token_url",
-				ColumnName:   "token_url",
-				DataType:     "varchar(200)",
-				DefaultValue: "'https://accounts.google.com/o/oauth2/token'",
-				ColumnType:   "url",
+				Name:         "spam",
+				ColumnName:   "spam",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
 			},
 			{
-				Name:         "profile_url",
-				ColumnName:   "profile_url",
-				DataType:     "varchar(200)",
-				DefaultValue: "'https://www.googleapis.com/oauth2/v1/userinfo?alt=json'",
-				ColumnType:   "url",
+				Name:       "size",
+				ColumnName: "size",
+				DataType:   "int(11)",
+				ColumnType: "value",
 			},
 			{
-				Name:         "profile_email_path",
-				ColumnName:   "profile_email_path",
-				DataType:     "varchar(200)",
-				DefaultValue: "'email'",
+				Name:         "flags",
+				ColumnName:   "flags",
+				DataType:     "varchar(500)",
 				ColumnType:   "label",
+				DefaultValue: "",
 			},
+		},
+	},
+	{
+		TableName:     "outbox",
+		IsHidden:      true,
+		Icon:          "fa-envelope",
+		DefaultGroups: adminsGroup,
+		Columns: []api2go.ColumnInfo{
 			{
-				Name:         "allow_login",
-				ColumnName:   "allow_login",
-				DataType:     "bool",
-				DefaultValue: "false",
-				ColumnType:   "truefalse",
+				Name:       "from_address",
+				ColumnName: "from_address",
+				DataType:   "varchar(200)",
+				ColumnType: "label",
 			},
 			{
-				Name:         "access_type_offline",
-				ColumnName:   "access_type_offline",
+				Name:       "to_address",
+				ColumnName: "to_address",
+				DataType:   "varchar(200)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "to_host",
+				ColumnName: "to_host",
+				DataType:   "varchar(200)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "mail",
+				ColumnName: "mail",
+				ColumnType: "gzip",
+				DataType:   "blob",
+			},
+			{
+				Name:         "sent",
+				ColumnName:   "sent",
+				ColumnType:   "truefalse",
 				DataType:     "bool",
 				DefaultValue: "false",
-				ColumnType:   "truefalse",
 			},
 		},
 	},
 	{
-		TableName:     "data_exchange",
+		TableName:     "webhook",
 		IsHidden:      true,
+		Icon:          "fa-paper-plane",
 		DefaultGroups: adminsGroup,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
+				Name:       "webhook_name",
+				ColumnName: "webhook_name",
+				DataType:   "varchar(100)",
 				ColumnType: "label",
-				IsUnique:   true,
-				DataType:   "varchar(200)",
 				IsIndexed:  true,
 			},
 			{
-				Name:       "source_attributes",
-				ColumnName: "source_attributes",
-				ColumnType: "json",
-				DataType:   "text",
+				Name:       "url",
+				ColumnName: "url",
+				DataType:   "varchar(500)",
+				ColumnType: "url",
 			},
 			{
-				Name:       "source_type",
-				ColumnName: "source_type",
-				ColumnType: "label",
-				DataType:   "varchar(100)",
+				Name:         "table_names",
+				ColumnName:   "table_names",
+				DataType:     "varchar(1000)",
+				ColumnType:   "label",
+				DefaultValue: "'*'",
 			},
 			{
-				Name:       "target_attributes",
-				ColumnName: "target_attributes",
-				ColumnType: "json",
-				DataType:   "text",
+				Name:         "event_types",
+				ColumnName:   "event_types",
+				DataType:     "varchar(100)",
+				ColumnType:   "label",
+				DefaultValue: "'create,update,delete'",
 			},
 			{
-				Name:       "attributes",
-				ColumnName: "attributes",
-				ColumnType: "json",
+				Name:       "event_filter",
+				ColumnName: "event_filter",
 				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
 			},
 			{
-				Name:       "target_type",
-				ColumnName: "target_type",
-				ColumnType: "label",
-				DataType:   "varchar(100)",
+				Name:       "secret",
+				ColumnName: "secret",
+				DataType:   "varchar(500)",
+				ColumnType: "encrypted",
+				IsNullable: true,
+			},
+			{
+				Name:         "max_retries",
+				ColumnName:   "max_retries",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "5",
 			},
 			{
-				Name:       "options",
-				ColumnName: "options",
-				ColumnType: "json",
-				DataType:   "text",
+				Name:         "enabled",
+				ColumnName:   "enabled",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "true",
 			},
 		},
 	},
 	{
-		TableName:     "oauth_token",
+		TableName:     "webhook_delivery",
 		IsHidden:      true,
+		Icon:          "fa-paper-plane",
 		DefaultGroups: adminsGroup,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "access_token",
-				ColumnName: "access_token",
-				ColumnType: "encrypted",
-				DataType:   "varchar(1000)",
+				Name:       "delivery_id",
+				ColumnName: "delivery_id",
+				DataType:   "varchar(64)",
+				ColumnType: "alias",
+				IsIndexed:  true,
 			},
 			{
-				Name:       "expires_in",
-				ColumnName: "expires_in",
+				Name:       "event_type",
+				ColumnName: "event_type",
+				DataType:   "varchar(20)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "table_name",
+				ColumnName: "table_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "object_reference_id",
+				ColumnName: "object_reference_id",
+				DataType:   "varchar(64)",
+				ColumnType: "alias",
+				IsNullable: true,
+			},
+			{
+				Name:       "attempt",
+				ColumnName: "attempt",
+				DataType:   "int(11)",
 				ColumnType: "measurement",
+			},
+			{
+				Name:       "status_code",
+				ColumnName: "status_code",
 				DataType:   "int(11)",
+				ColumnType: "measurement",
 			},
 			{
-				Name:       "refresh_token",
-				ColumnName: "refresh_token",
-				ColumnType: "encrypted",
-				DataType:   "varchar(1000)",
+				Name:         "success",
+				ColumnName:   "success",
+				DataType:     "bool",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
 			},
 			{
-				Name:       "token_type",
-				ColumnName: "token_type",
-				ColumnType: "label",
-				DataType:   "varchar(20)",
+				Name:       "response",
+				ColumnName: "response",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+			{
+				Name:       "error",
+				ColumnName: "error",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+			{
+				Name:       "duration_ms",
+				ColumnName: "duration_ms",
+				DataType:   "int(11)",
+				ColumnType: "measurement",
 			},
 		},
 	},
 	{
-		TableName:     "cloud_store",
-		DefaultGroups: adminsGroup,
+		TableName:     "change_log",
 		IsHidden:      true,
+		Icon:          "fa-history",
+		DefaultGroups: adminsGroup,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "Name",
-				ColumnName: "name",
-				ColumnType: "label",
+				Name:       "table_name",
+				ColumnName: "table_name",
 				DataType:   "varchar(100)",
-				IsUnique:   true,
+				ColumnType: "label",
+				IsIndexed:  true,
 			},
 			{
-				Name:       "store_type",
-				ColumnName: "store_type",
+				Name:       "event_type",
+				ColumnName: "event_type",
+				DataType:   "varchar(20)",
 				ColumnType: "label",
-				DataType:   "varchar(100)",
 			},
 			{
-				Name:       "store_provider",
-				ColumnName: "store_provider",
-				ColumnType: "label",
-				DataType:   "varchar(100)",
+				Name:       "object_reference_id",
+				ColumnName: "object_reference_id",
+				DataType:   "varchar(64)",
+				ColumnType: "alias",
+				IsIndexed:  true,
 			},
 			{
-				Name:       "root_path",
-				ColumnName: "root_path",
-				ColumnType: "label",
-				DataType:   "varchar(1000)",
+				Name:       "user_reference_id",
+				ColumnName: "user_reference_id",
+				DataType:   "varchar(64)",
+				ColumnType: "alias",
+				IsNullable: true,
 			},
 			{
-				Name:       "store_parameters",
-				ColumnName: "store_parameters",
+				Name:       "impersonated_by",
+				ColumnName: "impersonated_by",
+				DataType:   "varchar(80)",
+				ColumnType: "email",
+				IsNullable: true,
+			},
+			{
+				Name:       "before_image",
+				ColumnName: "before_image",
+				DataType:   "text",
 				ColumnType: "json",
+				IsNullable: true,
+			},
+			{
+				Name:       "after_image",
+				ColumnName: "after_image",
 				DataType:   "text",
+				ColumnType: "json",
+				IsNullable: true,
 			},
 		},
 	},
 	{
-		TableName:     "site",
+		TableName:     "graphql_query",
+		Icon:          "fa-code",
 		DefaultGroups: adminsGroup,
-		IsHidden:      true,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
-				ColumnType: "label",
+				Name:       "query_name",
+				ColumnName: "query_name",
 				DataType:   "varchar(100)",
-			},
-			{
-				Name:       "hostname",
-				ColumnName: "hostname",
 				ColumnType: "label",
-				DataType:   "varchar(100)",
-			},
-			{
-				Name:              "path",
-				ColumnName:        "path",
-				ColumnType:        "label",
-				DataType:          "varchar(100)",
-				ColumnDescription: "path on the cloud store to host as base directory",
-			},
-			{
-				Name:         "enable",
-				ColumnName:   "enable",
-				ColumnType:   "truefalse",
-				DataType:     "bool",
-				DefaultValue: "true",
-			},
-			{
-				Name:         "enable_https",
-				ColumnName:   "enable_https",
-				ColumnType:   "truefalse",
-				DataType:     "bool",
-				DefaultValue: "false",
+				IsIndexed:  true,
 			},
 			{
-				Name:         "ftp_enabled",
-				ColumnName:   "ftp_enabled",
-				ColumnType:   "truefalse",
-				DataType:     "bool",
-				DefaultValue: "false",
+				Name:       "query_hash",
+				ColumnName: "query_hash",
+				DataType:   "varchar(64)",
+				ColumnType: "label",
+				IsUnique:   true,
+				IsIndexed:  true,
 			},
 			{
-				Name:         "site_type",
-				ColumnName:   "site_type",
-				ColumnType:   "label",
-				DataType:     "varchar(20)",
-				DefaultValue: "'static'",
+				Name:       "query_text",
+				ColumnName: "query_text",
+				DataType:   "text",
+				ColumnType: "content",
 			},
 		},
 	},
 	{
-		TableName:     "mail_server",
+		TableName:     "schema_migration",
 		IsHidden:      true,
-		Icon:          "fa-envelope",
+		Icon:          "fa-database",
 		DefaultGroups: adminsGroup,
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "hostname",
-				ColumnName: "hostname",
+				Name:       "table_name",
+				ColumnName: "table_name",
 				DataType:   "varchar(100)",
 				ColumnType: "label",
+				IsIndexed:  true,
 			},
 			{
-				Name:         "is_enabled",
-				ColumnName:   "is_enabled",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "false",
+				Name:       "step_type",
+				ColumnName: "step_type",
+				DataType:   "varchar(20)",
+				ColumnType: "label",
 			},
 			{
-				Name:         "listen_interface",
-				ColumnName:   "listen_interface",
-				DataType:     "varchar(100)",
-				ColumnType:   "label",
-				DefaultValue: "'0.0.0.0:465'",
+				Name:       "column_name",
+				ColumnName: "column_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsNullable: true,
 			},
 			{
-				Name:         "max_size",
-				ColumnName:   "max_size",
-				DataType:     "int(11)",
-				ColumnType:   "measurement",
-				DefaultValue: "10000",
+				Name:       "description",
+				ColumnName: "description",
+				DataType:   "text",
+				ColumnType: "content",
 			},
 			{
-				Name:         "max_clients",
-				ColumnName:   "max_clients",
-				DataType:     "int(11)",
-				ColumnType:   "measurement",
-				DefaultValue: "20",
+				Name:       "statements",
+				ColumnName: "statements",
+				DataType:   "text",
+				ColumnType: "json",
 			},
 			{
-				Name:         "xclient_on",
-				ColumnName:   "xclient_on",
+				Name:         "destructive",
+				ColumnName:   "destructive",
 				DataType:     "bool",
 				ColumnType:   "truefalse",
 				DefaultValue: "false",
 			},
 			{
-				Name:         "always_on_tls",
-				ColumnName:   "always_on_tls",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "true",
-			},
-			{
-				Name:         "authentication_required",
-				ColumnName:   "authentication_required",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "true",
+				Name:       "user_reference_id",
+				ColumnName: "user_reference_id",
+				DataType:   "varchar(64)",
+				ColumnType: "alias",
+				IsNullable: true,
 			},
 		},
 	},
 	{
-		TableName:     "mail_account",
+		TableName:     "schema_version",
 		IsHidden:      true,
+		Icon:          "fa-code-fork",
 		DefaultGroups: adminsGroup,
-		Icon:          "fa-envelope",
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "username",
-				ColumnName: "username",
+				Name:       "version",
+				ColumnName: "version",
+				DataType:   "int(11)",
+				ColumnType: "measurement",
+				IsUnique:   true,
+				IsIndexed:  true,
+			},
+			{
+				Name:       "source",
+				ColumnName: "source",
 				DataType:   "varchar(100)",
 				ColumnType: "label",
-				IsUnique:   true,
 			},
 			{
-				Name:       "password",
-				ColumnName: "password",
-				ColumnType: "password",
+				Name:       "user_reference_id",
+				ColumnName: "user_reference_id",
+				DataType:   "varchar(64)",
+				ColumnType: "alias",
+				IsNullable: true,
 			},
 			{
-				Name:       "password_md5",
-				ColumnName: "password_md5",
-				ColumnType: "md5-bcrypt",
+				Name:       "schema_json",
+				ColumnName: "schema_json",
+				DataType:   "mediumtext",
+				ColumnType: "json",
+			},
+			{
+				Name:       "diff",
+				ColumnName: "diff",
+				DataType:   "mediumtext",
+				ColumnType: "json",
+				IsNullable: true,
 			},
 		},
 	},
 	{
-		TableName:     "mail_box",
+		TableName:     USER_SESSION_TABLE_NAME,
 		IsHidden:      true,
-		DefaultGroups: adminsGroup,
+		Icon:          "fa-sign-in",
+		DefaultGroups: []string{},
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
-				DataType:   "varchar(100)",
+				Name:           "refresh_token_hash",
+				ColumnName:     "refresh_token_hash",
+				DataType:       "varchar(64)",
+				ColumnType:     "label",
+				IsIndexed:      true,
+				IsNullable:     true,
+				ExcludeFromApi: true,
+			},
+			{
+				Name:           "previous_refresh_token_hash",
+				ColumnName:     "previous_refresh_token_hash",
+				DataType:       "varchar(64)",
+				ColumnType:     "label",
+				IsIndexed:      true,
+				IsNullable:     true,
+				ExcludeFromApi: true,
+			},
+			{
+				Name:       "ip_address",
+				ColumnName: "ip_address",
+				DataType:   "varchar(50)",
 				ColumnType: "label",
+				IsNullable: true,
 			},
 			{
-				Name:         "subscribed",
-				ColumnName:   "subscribed",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "true",
+				Name:       "user_agent",
+				ColumnName: "user_agent",
+				DataType:   "varchar(500)",
+				ColumnType: "label",
+				IsNullable: true,
 			},
 			{
-				Name:         "uidvalidity",
-				ColumnName:   "uidvalidity",
-				DataType:     "int(11)",
-				ColumnType:   "value",
-				DefaultValue: "1",
+				Name:       "expires_at",
+				ColumnName: "expires_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
 			},
 			{
-				Name:         "nextuid",
-				ColumnName:   "nextuid",
-				DataType:     "int(11)",
-				ColumnType:   "value",
-				DefaultValue: "1",
+				Name:       "last_used_at",
+				ColumnName: "last_used_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
 			},
 			{
-				Name:       "attributes",
-				ColumnName: "attributes",
-				DataType:   "varchar(100)",
-				ColumnType: "label",
+				Name:       "revoked_at",
+				ColumnName: "revoked_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
 			},
 			{
-				Name:       "flags",
-				ColumnName: "flags",
-				DataType:   "varchar(100)",
+				Name:       "client_id",
+				ColumnName: "client_id",
+				DataType:   "varchar(64)",
 				ColumnType: "label",
+				IsNullable: true,
 			},
 			{
-				Name:       "permanent_flags",
-				ColumnName: "permanent_flags",
-				DataType:   "varchar(100)",
+				Name:       "scope",
+				ColumnName: "scope",
+				DataType:   "varchar(200)",
 				ColumnType: "label",
+				IsNullable: true,
 			},
 		},
 	},
 	{
-		TableName:     "mail",
+		TableName:     USER_TOTP_TABLE_NAME,
 		IsHidden:      true,
-		Icon:          "fa-envelope",
-		DefaultGroups: adminsGroup,
+		Icon:          "fa-mobile",
+		DefaultGroups: []string{},
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "message_id",
-				ColumnName: "message_id",
-				DataType:   "varchar(100)",
-				ColumnType: "label",
+				Name:           "totp_secret",
+				ColumnName:     "totp_secret",
+				DataType:       "varchar(200)",
+				ColumnType:     "encrypted",
+				ExcludeFromApi: true,
 			},
 			{
-				Name:       "mail_id",
-				ColumnName: "mail_id",
-				DataType:   "varchar(100)",
-				ColumnType: "label",
+				Name:         "enabled",
+				ColumnName:   "enabled",
+				DataType:     "bool",
+				DefaultValue: "false",
+				ColumnType:   "truefalse",
 			},
 			{
-				Name:       "from_address",
-				ColumnName: "from_address",
-				DataType:   "varchar(200)",
-				ColumnType: "label",
+				Name:           "last_used_step",
+				ColumnName:     "last_used_step",
+				DataType:       "int(11)",
+				ColumnType:     "measurement",
+				IsNullable:     true,
+				ExcludeFromApi: true,
 			},
 			{
-				Name:       "internal_date",
-				ColumnName: "internal_date",
-				DataType:   "timestamp",
-				ColumnType: "datetime",
+				Name:           "recovery_codes",
+				ColumnName:     "recovery_codes",
+				DataType:       "text",
+				ColumnType:     "json",
+				IsNullable:     true,
+				ExcludeFromApi: true,
 			},
+		},
+		// the enrollment is only changed by the totp actions
+		ColumnPermissions: map[string]auth.AuthPermission{
+			"totp_secret":    auth.None,
+			"enabled":        auth.UserRead | auth.GroupRead,
+			"last_used_step": auth.None,
+			"recovery_codes": auth.None,
+		},
+	},
+	{
+		TableName:     LOGIN_EVENT_TABLE_NAME,
+		IsHidden:      true,
+		Icon:          "fa-history",
+		DefaultGroups: []string{},
+		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "to_address",
-				ColumnName: "to_address",
-				DataType:   "varchar(200)",
-				ColumnType: "label",
+				Name:       "email",
+				ColumnName: "email",
+				DataType:   "varchar(80)",
+				ColumnType: "email",
+				IsIndexed:  true,
 			},
 			{
-				Name:       "reply_to_address",
-				ColumnName: "reply_to_address",
-				DataType:   "varchar(200)",
-				ColumnType: "label",
+				Name:         "success",
+				ColumnName:   "success",
+				DataType:     "bool",
+				DefaultValue: "false",
+				ColumnType:   "truefalse",
 			},
 			{
-				Name:       "sender_address",
-				ColumnName: "sender_address",
-				DataType:   "varchar(200)",
+				Name:       "reason",
+				ColumnName: "reason",
+				DataType:   "varchar(50)",
 				ColumnType: "label",
 			},
 			{
-				Name:       "subject",
-				ColumnName: "subject",
-				DataType:   "varchar(200)",
+				Name:       "ip_address",
+				ColumnName: "ip_address",
+				DataType:   "varchar(50)",
 				ColumnType: "label",
+				IsIndexed:  true,
+				IsNullable: true,
 			},
 			{
-				Name:       "body",
-				ColumnName: "body",
-				DataType:   "text",
+				Name:       "user_agent",
+				ColumnName: "user_agent",
+				DataType:   "varchar(500)",
 				ColumnType: "label",
+				IsNullable: true,
 			},
 			{
-				Name:       "mail",
-				ColumnName: "mail",
-				ColumnType: "gzip",
-				DataType:   "blob",
+				Name:       "impersonated_by",
+				ColumnName: "impersonated_by",
+				DataType:   "varchar(80)",
+				ColumnType: "email",
+				IsNullable: true,
 			},
 			{
-				Name:       "spam_score",
-				ColumnName: "spam_score",
-				ColumnType: "measurement",
-				DataType:   "float",
+				Name:       "token_id",
+				ColumnName: "token_id",
+				DataType:   "varchar(64)",
+				ColumnType: "alias",
+				IsNullable: true,
 			},
+		},
+	},
+	{
+		TableName:     "api_key",
+		IsHidden:      true,
+		Icon:          "fa-key",
+		DefaultGroups: []string{},
+		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "hash",
-				ColumnName: "hash",
+				Name:       "name",
+				ColumnName: "name",
 				DataType:   "varchar(100)",
 				ColumnType: "label",
 			},
 			{
-				Name:       "content_type",
-				ColumnName: "content_type",
-				DataType:   "text",
-				ColumnType: "content",
+				Name:       "key_prefix",
+				ColumnName: "key_prefix",
+				DataType:   "varchar(20)",
+				ColumnType: "label",
 			},
 			{
-				Name:       "recipient",
-				ColumnName: "recipient",
-				DataType:   "varchar(200)",
-				ColumnType: "label",
+				Name:           "key_hash",
+				ColumnName:     "key_hash",
+				DataType:       "varchar(64)",
+				ColumnType:     "label",
+				IsUnique:       true,
+				IsIndexed:      true,
+				ExcludeFromApi: true,
 			},
 			{
-				Name:         "has_attachment",
-				ColumnName:   "has_attachment",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "false",
+				Name:       "scopes",
+				ColumnName: "scopes",
+				DataType:   "text",
+				ColumnType: "json",
+				IsNullable: true,
 			},
 			{
-				Name:       "ip_addr",
-				ColumnName: "ip_addr",
-				DataType:   "varchar(30)",
-				ColumnType: "label",
+				Name:       "expires_at",
+				ColumnName: "expires_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
 			},
 			{
-				Name:       "return_path",
-				ColumnName: "return_path",
-				DataType:   "VARCHAR(255)",
-				ColumnType: "content",
+				Name:       "last_used_at",
+				ColumnName: "last_used_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
 			},
+		},
+	},
+	{
+		TableName:     OAUTH_CLIENT_TABLE_NAME,
+		IsHidden:      true,
+		Icon:          "fa-id-badge",
+		DefaultGroups: []string{},
+		Columns: []api2go.ColumnInfo{
 			{
-				Name:         "is_tls",
-				ColumnName:   "is_tls",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "false",
+				Name:       "name",
+				ColumnName: "name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
 			},
 			{
-				Name:         "seen",
-				ColumnName:   "seen",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "false",
+				Name:       "client_id",
+				ColumnName: "client_id",
+				DataType:   "varchar(64)",
+				ColumnType: "label",
+				IsUnique:   true,
+				IsIndexed:  true,
 			},
 			{
-				Name:         "recent",
-				ColumnName:   "recent",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "true",
+				Name:           "client_secret_hash",
+				ColumnName:     "client_secret_hash",
+				DataType:       "varchar(64)",
+				ColumnType:     "label",
+				IsNullable:     true,
+				ExcludeFromApi: true,
 			},
 			{
-				Name:         "deleted",
-				ColumnName:   "deleted",
-				DataType:     "bool",
-				ColumnType:   "truefalse",
-				DefaultValue: "false",
+				Name:       "redirect_uris",
+				ColumnName: "redirect_uris",
+				DataType:   "text",
+				ColumnType: "json",
 			},
 			{
-				Name:         "spam",
-				ColumnName:   "spam",
+				Name:         "is_public",
+				ColumnName:   "is_public",
 				DataType:     "bool",
-				ColumnType:   "truefalse",
 				DefaultValue: "false",
-			},
-			{
-				Name:       "size",
-				ColumnName: "size",
-				DataType:   "int(11)",
-				ColumnType: "value",
-			},
-			{
-				Name:         "flags",
-				ColumnName:   "flags",
-				DataType:     "varchar(500)",
-				ColumnType:   "label",
-				DefaultValue: "",
+				ColumnType:   "truefalse",
 			},
 		},
+		// clients are registered with the register_oauth_client action
+		ColumnPermissions: map[string]auth.AuthPermission{
+			"client_id":          auth.UserRead | auth.GroupRead,
+			"client_secret_hash": auth.None,
+			"is_public":          auth.UserRead | auth.GroupRead,
+		},
 	},
 	{
-		TableName:     "outbox",
+		TableName:     OAUTH_AUTHORIZATION_CODE_TABLE_NAME,
 		IsHidden:      true,
-		Icon:          "fa-envelope",
-		DefaultGroups: adminsGroup,
+		Icon:          "fa-ticket",
+		DefaultGroups: []string{},
 		Columns: []api2go.ColumnInfo{
 			{
-				Name:       "from_address",
-				ColumnName: "from_address",
-				DataType:   "varchar(200)",
-				ColumnType: "label",
+				Name:           "code_hash",
+				ColumnName:     "code_hash",
+				DataType:       "varchar(64)",
+				ColumnType:     "label",
+				IsUnique:       true,
+				IsIndexed:      true,
+				ExcludeFromApi: true,
 			},
 			{
-				Name:       "to_address",
-				ColumnName: "to_address",
-				DataType:   "varchar(200)",
+				Name:       "client_id",
+				ColumnName: "client_id",
+				DataType:   "varchar(64)",
 				ColumnType: "label",
+				IsIndexed:  true,
 			},
 			{
-				Name:       "to_host",
-				ColumnName: "to_host",
+				Name:       "redirect_uri",
+				ColumnName: "redirect_uri",
+				DataType:   "varchar(500)",
+				ColumnType: "url",
+			},
+			{
+				Name:       "scope",
+				ColumnName: "scope",
 				DataType:   "varchar(200)",
 				ColumnType: "label",
 			},
 			{
-				Name:       "mail",
-				ColumnName: "mail",
-				ColumnType: "gzip",
-				DataType:   "blob",
+				Name:           "nonce",
+				ColumnName:     "nonce",
+				DataType:       "varchar(200)",
+				ColumnType:     "label",
+				IsNullable:     true,
+				ExcludeFromApi: true,
 			},
 			{
-				Name:         "sent",
-				ColumnName:   "sent",
-				ColumnType:   "truefalse",
-				DataType:     "bool",
-				DefaultValue: "false",
+				Name:           "code_challenge",
+				ColumnName:     "code_challenge",
+				DataType:       "varchar(100)",
+				ColumnType:     "label",
+				IsNullable:     true,
+				ExcludeFromApi: true,
+			},
+			{
+				Name:       "expires_at",
+				ColumnName: "expires_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+			},
+			{
+				Name:       "used_at",
+				ColumnName: "used_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
 			},
 		},
+		// codes are only written by the authorization and token endpoints
+		ColumnPermissions: map[string]auth.AuthPermission{
+			"code_hash":      auth.None,
+			"client_id":      auth.UserRead,
+			"redirect_uri":   auth.UserRead,
+			"scope":          auth.UserRead,
+			"nonce":          auth.None,
+			"code_challenge": auth.None,
+			"expires_at":     auth.UserRead,
+			"used_at":        auth.UserRead,
+		},
 	},
 }
 
@@ -3002,6 +4166,7 @@ type TableInfo struct {
 	IsStateTrackingEnabled bool                `db:"is_state_tracking_enabled"`
 	IsAuditEnabled         bool                `db:"is_audit_enabled"`
 	TranslationsEnabled    bool                `db:"translation_enabled"`
+	IsSoftDeleteEnabled    bool                `db:"is_soft_delete_enabled"`
 	DefaultGroups          []string            `db:"default_groups"`
 	DefaultRelations       map[string][]string `db:"default_relations"`
 	Validations            []ColumnTag
@@ -3009,6 +4174,14 @@ type TableInfo struct {
 	DefaultOrder           string
 	Icon                   string
 	CompositeKeys          [][]string
+	// old column name to new column name, used by the migration planner to rename instead of drop and add
+	RenamedColumns map[string]string
+	// read only columns computed from the row, they are not created in the table
+	VirtualColumns []VirtualColumn
+	// column name to a permission mask, which limits the permission of the row for that column
+	ColumnPermissions map[string]auth.AuthPermission
+	// filters limiting the rows users can access, see RowPolicy
+	RowPolicies []RowPolicy
 }
 
 func (ti *TableInfo) GetColumnByName(name string) (*api2go.ColumnInfo, bool) {
@@ -2,3 +2,5 @@ package resource
 
 const USER_ACCOUNT_TABLE_NAME = "user_account"
 const USER_ACCOUNT_ID_COLUMN = "user_account_id"
+const USER_SESSION_TABLE_NAME = "user_session"
+const USER_TOTP_TABLE_NAME = "user_totp"
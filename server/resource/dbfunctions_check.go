@@ -224,6 +224,8 @@ func CheckRelations(config *CmsConfig) {
 	//newRelations := make([]api2go.TableRelation, 0)
 	convertRelationsToColumns(finalRelations, config)
 	convertRelationsToColumns(StandardRelations, config)
+	checkSoftDeleteColumns(config)
+	checkRollupRelations(config)
 
 	//config.Tables[stateMachineDescriptionTableIndex] = stateMachineDescriptionTable
 
@@ -304,13 +306,13 @@ func CheckAllTableStatus(initConfig *CmsConfig, db database.DatabaseConnection)
 			}
 			err = CheckTable(&table, db, tx)
 			if err != nil {
-				err = tx.Rollback()
+				err = RollbackTransaction(tx)
 				CheckErr(err, "Failed to rollback create table txn after failure")
 				tx, err = db.Beginx()
 				CheckErr(err, "Failed to create new transaction create table txn after failure")
 			} else {
 				tables = append(tables, table)
-				err = tx.Commit()
+				err = CommitTransaction(tx)
 				CheckErr(err, "Failed to commit create table txn after failure")
 				tableCreatedMap[table.TableName] = true
 			}
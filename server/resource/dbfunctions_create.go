@@ -187,7 +187,7 @@ func CreateRelations(initConfig *CmsConfig, db database.DatabaseConnection) {
 				_, err := db.Exec(alterSql)
 				if err != nil {
 					log.Printf("Failed to create foreign key [%v],  %v on column [%v][%v]", err, keyName, table.TableName, column.ColumnName)
-					tx.Rollback()
+					RollbackTransaction(tx)
 					tx, errb = db.Beginx()
 					CheckErr(err, "Failed to create a new transaction after rollback.")
 				} else {
@@ -200,7 +200,7 @@ func CreateRelations(initConfig *CmsConfig, db database.DatabaseConnection) {
 				_, err = db.Exec(createFkIndex)
 				if err != nil {
 					log.Printf("Failed to create foreign key index [%v],  %v on column [%v][%v]", err, fkIndexName, table.TableName, column.ColumnName)
-					tx.Rollback()
+					RollbackTransaction(tx)
 					tx, errb = db.Beginx()
 					CheckErr(err, "Failed to create a new transaction after rollback.")
 				} else {
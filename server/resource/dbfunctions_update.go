@@ -611,14 +611,14 @@ func UpdateActionTable(initConfig *CmsConfig, db database.DatabaseConnection) er
 
 	currentActions, err := GetActionMapByTypeName(transaction)
 	if err != nil {
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "Failed to rollback")
 		return err
 	}
 
 	worldTableMap, err := GetWorldTableMapBy("table_name", transaction)
 	if err != nil {
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "Failed to rollback")
 		return err
 	}
@@ -658,7 +658,7 @@ func UpdateActionTable(initConfig *CmsConfig, db database.DatabaseConnection) er
 
 			_, err = transaction.Exec(s, v...)
 			if err != nil {
-				rollbackErr := transaction.Rollback()
+				rollbackErr := RollbackTransaction(transaction)
 				CheckErr(rollbackErr, "Failed to rollback")
 				log.Errorf("Failed to insert action [%v]: %v", action.Name, err)
 				return err
@@ -689,14 +689,14 @@ func UpdateActionTable(initConfig *CmsConfig, db database.DatabaseConnection) er
 
 			_, err = transaction.Exec(s, v...)
 			if err != nil {
-				rollbackErr := transaction.Rollback()
+				rollbackErr := RollbackTransaction(transaction)
 				CheckErr(rollbackErr, "Failed to rollback")
 				log.Errorf("Failed to insert action [%v]: %v", action.Name, err)
 				return err
 			}
 		}
 	}
-	commitErr := transaction.Commit()
+	commitErr := CommitTransaction(transaction)
 	CheckErr(commitErr, "failed to commit")
 	log.Printf("Checked %d actions", actionCheckCount)
 
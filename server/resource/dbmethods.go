@@ -1207,7 +1207,7 @@ func (dbResource *DbResource) BecomeAdmin(userId int64, transaction *sqlx.Tx) bo
 	query, args, err = statementbuilder.Squirrel.Update("action").
 		Set(goqu.Record{"permission": int64(auth.GuestPeek | auth.GuestExecute | auth.UserRead | auth.UserExecute | auth.GroupRead | auth.GroupExecute)}).
 		Where(goqu.Ex{
-			"action_name": "signin",
+			"action_name": []string{"signin", "refresh_token"},
 		}).
 		ToSQL()
 	if err != nil {
@@ -1820,7 +1820,11 @@ func (dbResource *DbResource) GetSingleRowByReferenceId(typeName string, referen
 func (dbResource *DbResource) GetSingleRowByReferenceIdWithTransaction(typeName string, referenceId string,
 	includedRelations map[string]bool, transaction *sqlx.Tx) (map[string]interface{}, []map[string]interface{}, error) {
 	//log.Printf("Get single row by id: [%v][%v]", typeName, referenceId)
-	s, q, err := statementbuilder.Squirrel.Select("*").From(typeName).Where(goqu.Ex{"reference_id": referenceId}).ToSQL()
+	selectColumns := []interface{}{goqu.Star()}
+	if typeResource, ok := dbResource.Cruds[typeName]; ok {
+		selectColumns = append(selectColumns, ColumnToInterfaceArray(virtualColumnSelects(typeResource.tableInfo, typeName, nil, false))...)
+	}
+	s, q, err := statementbuilder.Squirrel.Select(selectColumns...).From(typeName).Where(goqu.Ex{"reference_id": referenceId}).ToSQL()
 	if err != nil {
 		log.Errorf("failed to create select query by ref id: %v", referenceId)
 		return nil, nil, err
@@ -3444,6 +3448,8 @@ func (dbResource *DbResource) ResultToArrayOfMap(rows *sqlx.Rows, columnMap map[
 
 		}
 
+		EvaluateVirtualColumns(dbResource.tableInfo, row)
+
 		for _, relation := range dbResource.tableInfo.Relations {
 
 			if !(includedRelationMap[relation.GetObjectName()] || includedRelationMap[relation.GetSubjectName()]) {
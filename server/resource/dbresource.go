@@ -31,6 +31,7 @@ type DbResource struct {
 	ms                 *MiddlewareSet
 	ActionHandlerMap   map[string]ActionPerformerInterface
 	configStore        *ConfigStore
+	passwordPolicy     *PasswordPolicy
 	contextCache       map[string]interface{}
 	defaultGroups      []int64
 	defaultRelations   map[string][]int64
@@ -150,6 +151,12 @@ func NewDbResource(model api2go.Api2GoModel, db database.DatabaseConnection,
 		return nil, err
 	}
 
+	// the password policy reads the breached password list, it is built once for the user accounts
+	var passwordPolicy *PasswordPolicy
+	if tableInfo.TableName == USER_ACCOUNT_TABLE_NAME && configStore != nil {
+		passwordPolicy = NewPasswordPolicy(configStore)
+	}
+
 	//log.Printf("Columns [%v]: %v\n", model.GetName(), model.GetColumnNames())
 	return &DbResource{
 		model:              model,
@@ -157,6 +164,7 @@ func NewDbResource(model api2go.Api2GoModel, db database.DatabaseConnection,
 		Connection:         db,
 		ms:                 ms,
 		configStore:        configStore,
+		passwordPolicy:     passwordPolicy,
 		Cruds:              cruds,
 		tableInfo:          &tableInfo,
 		OlricDb:            olricDb,
@@ -2,10 +2,12 @@ package resource
 
 import "github.com/buraksezer/olric"
 
-func NewCreateEventHandler(cruds *map[string]*DbResource, dtopicMap *map[string]*olric.DTopic) DatabaseRequestInterceptor {
+func NewCreateEventHandler(cruds *map[string]*DbResource, dtopicMap *map[string]*olric.DTopic,
+	webhookDispatcher *WebhookDispatcher) DatabaseRequestInterceptor {
 
 	return &eventHandlerMiddleware{
-		cruds:     cruds,
-		dtopicMap: dtopicMap,
+		cruds:             cruds,
+		dtopicMap:         dtopicMap,
+		webhookDispatcher: webhookDispatcher,
 	}
 }
@@ -2,9 +2,11 @@ package resource
 
 import "github.com/buraksezer/olric"
 
-func NewUpdateEventHandler(cruds *map[string]*DbResource, dtopicMap *map[string]*olric.DTopic) DatabaseRequestInterceptor {
+func NewUpdateEventHandler(cruds *map[string]*DbResource, dtopicMap *map[string]*olric.DTopic,
+	webhookDispatcher *WebhookDispatcher) DatabaseRequestInterceptor {
 	return &eventHandlerMiddleware{
-		cruds:     cruds,
-		dtopicMap: dtopicMap,
+		cruds:             cruds,
+		dtopicMap:         dtopicMap,
+		webhookDispatcher: webhookDispatcher,
 	}
 }
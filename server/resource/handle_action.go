@@ -109,7 +109,10 @@ func CreatePostActionHandler(initConfig *CmsConfig,
 			},
 		}
 
-		req.PlainRequest = req.PlainRequest.WithContext(ginContext.Request.Context())
+		req.PlainRequest = req.PlainRequest.WithContext(context.WithValue(ginContext.Request.Context(), "client", &ActionClient{
+			IpAddress: ClientIP(ginContext),
+			UserAgent: ginContext.Request.UserAgent(),
+		}))
 
 		actionCrudResource, ok := cruds[actionType]
 		if !ok {
@@ -191,6 +194,11 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 		sessionUser = user.(*auth.SessionUser)
 	}
 
+	if !sessionUser.ApiKeyScope.AllowsAction(actionRequest.Type, actionRequest.Action) {
+		log.Warnf("api key scope does not allow action: %v - %v", actionRequest.Type, actionRequest.Action)
+		return nil, api2go.NewHTTPError(errors.New("forbidden"), "forbidden", 403)
+	}
+
 	var subjectInstance api2go.Api2GoModel
 	var subjectInstanceMap map[string]interface{}
 
@@ -203,7 +211,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 	CheckErr(err, "Failed to get action by Type/action [%v][%v]", actionRequest.Type, actionRequest.Action)
 	if err != nil {
 		log.Warnf("invalid action: %v - %v", actionRequest.Action, actionRequest.Type)
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "failed to rollback")
 		return nil, api2go.NewHTTPError(err, "no such action", 400)
 	}
@@ -218,7 +226,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 		referencedObject, err := db.FindOneWithTransaction(subjectInstanceReferenceId.(string), req, transaction)
 		if err != nil {
 			log.Warnf("failed to load subject for action: %v - [%v][%v]", actionRequest.Action, actionRequest.Type, subjectInstanceReferenceId)
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			return nil, api2go.NewHTTPError(err, "failed to load subject", 400)
 		}
@@ -228,7 +236,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 
 		if subjectInstanceMap == nil {
 			log.Warnf("subject is empty: %v - %v", actionRequest.Action, subjectInstanceReferenceId)
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			return nil, api2go.NewHTTPError(errors.New("subject not found"), "subject not found", 400)
 		}
@@ -238,7 +246,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 
 		if !permission.CanExecute(sessionUser.UserReferenceId, sessionUser.Groups) {
 			log.Warnf("user not allowed action on this object: %v - %v", actionRequest.Action, subjectInstanceReferenceId)
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			return nil, api2go.NewHTTPError(errors.New("forbidden"), "forbidden", 403)
 		}
@@ -246,7 +254,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 
 	if !isAdmin && !db.IsUserActionAllowedWithTransaction(sessionUser.UserReferenceId, sessionUser.Groups, actionRequest.Type, actionRequest.Action, transaction) {
 		log.Warnf("user not allowed action: %v - %v", actionRequest.Action, subjectInstanceReferenceId)
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "failed to rollback")
 		return nil, api2go.NewHTTPError(errors.New("forbidden"), "forbidden", 403)
 	}
@@ -255,7 +263,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 
 	if !action.InstanceOptional && (subjectInstanceReferenceId == "" || subjectInstance.GetID() != subjectInstanceReferenceId) {
 		log.Warnf("subject is unidentified: %v - %v", actionRequest.Action, actionRequest.Type)
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "failed to rollback")
 		return nil, api2go.NewHTTPError(errors.New("required reference id not provided or incorrect"), "no reference id", 400)
 	}
@@ -277,7 +285,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 			log.Warnf("validation on input fields failed: %v - %v", actionRequest.Action, actionRequest.Type)
 			validationErrors := errs.(validator.ValidationErrors)
 			firstError := validationErrors[0]
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			return nil, api2go.NewHTTPError(errors.New(fmt.Sprintf("invalid value for %s", validation.ColumnName)), firstError.Tag(), 400)
 		}
@@ -301,7 +309,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 	inFieldMap["attributes"] = actionRequest.Attributes
 
 	if err != nil {
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "failed to rollback")
 		return nil, api2go.NewHTTPError(err, "failed to validate fields", 400)
 	}
@@ -309,7 +317,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 	if sessionUser.UserReferenceId != "" {
 		user, err := db.GetReferenceIdToObjectWithTransaction(USER_ACCOUNT_TABLE_NAME, sessionUser.UserReferenceId, transaction)
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			return nil, api2go.NewHTTPError(err, "failed to identify user", 401)
 		}
@@ -505,6 +513,7 @@ OutFields:
 			} else {
 				var responder api2go.Responder
 				outcome.Attributes["user"] = sessionUser
+				outcome.Attributes["client"] = req.PlainRequest.Context().Value("client")
 				responder, responses1, errors1 = performer.DoAction(outcome, model.Data, transaction)
 				actionResponses = append(actionResponses, responses1...)
 				if len(errors1) > 0 {
@@ -540,7 +549,7 @@ OutFields:
 		}
 		if err != nil {
 			log.Errorf("failed to execute outcome [%v] => %v", outcome.Type, err)
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			return nil, err
 		}
@@ -586,10 +595,10 @@ OutFields:
 
 	}
 	if err != nil {
-		transaction.Rollback()
+		RollbackTransaction(transaction)
 		return nil, err
 	}
-	commitErr := transaction.Commit()
+	commitErr := CommitTransaction(transaction)
 	CheckErr(commitErr, "Failed to commit")
 
 	return responses, commitErr
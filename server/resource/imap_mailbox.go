@@ -384,7 +384,7 @@ func (dimb *DaptinImapMailBox) SearchMessages(uid bool, criteria *imap.SearchCri
 	}
 	results, _, _, _, err := dimb.dbResource["mail"].PaginatedFindAllWithoutFilters(searchRequest, transaction)
 
-	transaction.Commit()
+	CommitTransaction(transaction)
 
 	if err != nil {
 		return nil, err
@@ -665,7 +665,7 @@ func (dimb *DaptinImapMailBox) CopyMessages(uid bool, seqset *imap.SeqSet, dest
 		}
 
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "Failed to rollback")
 			return err
 		}
@@ -689,14 +689,14 @@ func (dimb *DaptinImapMailBox) CopyMessages(uid bool, seqset *imap.SeqSet, dest
 				Data: mail,
 			}, req, transaction)
 			if err != nil {
-				rollbackErr := transaction.Rollback()
+				rollbackErr := RollbackTransaction(transaction)
 				CheckErr(rollbackErr, "Failed to rollback")
 				return err
 			}
 		}
 
 	}
-	transaction.Commit()
+	CommitTransaction(transaction)
 	return err
 }
 
@@ -0,0 +1,218 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/artpar/go.uuid"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/jwt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+// impersonationBlockedTables hold the credentials of a user, they cannot be written with an impersonation token
+var impersonationBlockedTables = map[string]bool{
+	USER_TOTP_TABLE_NAME: true,
+	"user_otp_account":   true,
+	"api_key":            true,
+}
+
+// impersonationBlockedColumns of the user account are its credentials
+var impersonationBlockedColumns = []string{"email", "password"}
+
+var errImpersonationNotAllowed = errors.New("not allowed while impersonating a user")
+
+// rejectImpersonation fails an action which an impersonation token must not run
+func rejectImpersonation(request Outcome) error {
+	if sessionUser, ok := request.Attributes["user"].(*auth.SessionUser); ok && sessionUser.Impersonator != nil {
+		return errImpersonationNotAllowed
+	}
+	return nil
+}
+
+// checkImpersonatedWrite returns a 403 for a write of the credentials of the user with an impersonation token
+func (dbResource *DbResource) checkImpersonatedWrite(sessionUser *auth.SessionUser, changes map[string]api2go.Change) error {
+	if sessionUser == nil || sessionUser.Impersonator == nil {
+		return nil
+	}
+	tableName := dbResource.tableInfo.TableName
+	if impersonationBlockedTables[tableName] {
+		return api2go.NewHTTPError(errImpersonationNotAllowed, fmt.Sprintf("cannot change [%v] while impersonating a user", tableName), 403)
+	}
+	if tableName != USER_ACCOUNT_TABLE_NAME {
+		return nil
+	}
+	for _, columnName := range impersonationBlockedColumns {
+		if _, ok := changes[columnName]; ok {
+			return api2go.NewHTTPError(errImpersonationNotAllowed, fmt.Sprintf("cannot change [%v] while impersonating a user", columnName), 403)
+		}
+	}
+	return nil
+}
+
+// ImpersonationToken signs an access token for the user with the administrator in the impersonator claim. It
+// has no session, so it cannot be refreshed and lives for lifeTime only. The jti of the token is returned to
+// revoke it.
+func (s *SessionTokenIssuer) ImpersonationToken(userAccount map[string]interface{}, impersonator *auth.Impersonator, lifeTime time.Duration) (string, string, time.Time, error) {
+	u, _ := uuid.NewV4()
+	timeNow := time.Now()
+	expiresAt := timeNow.Add(lifeTime)
+	token, err := s.Sign(jwt.MapClaims{
+		"email": userAccount["email"],
+		"sub":   userAccount["reference_id"],
+		"name":  userAccount["name"],
+		"nbf":   timeNow.Unix(),
+		"exp":   expiresAt.Unix(),
+		"iss":   s.issuer,
+		"iat":   timeNow.Unix(),
+		"jti":   u.String(),
+		auth.ImpersonatorClaim: map[string]interface{}{
+			"sub":   impersonator.UserReferenceId,
+			"email": impersonator.Email,
+		},
+	})
+	return token, u.String(), expiresAt, err
+}
+
+type impersonateUserPerformer struct {
+	cruds              map[string]*DbResource
+	sessionTokenIssuer *SessionTokenIssuer
+	loginGuard         *LoginGuard
+	defaultLifeTime    time.Duration
+	maxLifeTime        time.Duration
+}
+
+func (d *impersonateUserPerformer) Name() string {
+	return "user.impersonate"
+}
+
+// DoAction issues an administrator a short lived token to act as a user who is not an administrator. The
+// token is recorded as a login event of the user.
+func (d *impersonateUserPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser.Impersonator != nil || sessionUser.ApiKeyScope != nil ||
+		!IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+		return nil, nil, []error{errors.New("only administrators can impersonate users")}
+	}
+
+	userReferenceId, _ := inFields["user_account_id"].(string)
+	if userReferenceId == sessionUser.UserReferenceId {
+		return nil, nil, []error{errors.New("cannot impersonate yourself")}
+	}
+	if IsAdminWithTransaction(userReferenceId, transaction) {
+		return nil, nil, []error{errors.New("cannot impersonate an administrator")}
+	}
+	userAccount, err := d.cruds[USER_ACCOUNT_TABLE_NAME].GetReferenceIdToObjectWithTransaction(USER_ACCOUNT_TABLE_NAME, userReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	lifeTime := d.defaultLifeTime
+	if minutes := queryValueString(inFields["minutes"]); minutes != "" {
+		value, err := strconv.Atoi(minutes)
+		if err != nil || value < 1 {
+			return nil, nil, []error{fmt.Errorf("invalid minutes [%v]", minutes)}
+		}
+		lifeTime = time.Duration(value) * time.Minute
+	}
+	if lifeTime > d.maxLifeTime {
+		lifeTime = d.maxLifeTime
+	}
+
+	impersonator := &auth.Impersonator{
+		UserReferenceId: sessionUser.UserReferenceId,
+	}
+	if user, ok := inFields["user"].(map[string]interface{}); ok {
+		impersonator.Email, _ = user["email"].(string)
+	}
+
+	token, tokenId, expiresAt, err := d.sessionTokenIssuer.ImpersonationToken(userAccount, impersonator, lifeTime)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	email, _ := userAccount["email"].(string)
+	client, _ := request.Attributes["client"].(*ActionClient)
+	d.loginGuard.RecordImpersonation(d.cruds[LOGIN_EVENT_TABLE_NAME], userAccount, email, impersonator.Email, tokenId, client, transaction)
+	log.Infof("[impersonation] %v started acting as %v until %v", impersonator.Email, email, expiresAt.Format(time.RFC3339))
+
+	return nil, []ActionResponse{
+		NewActionResponse("impersonation.token", map[string]interface{}{
+			"token":      token,
+			"token_id":   tokenId,
+			"email":      email,
+			"expires_at": expiresAt,
+		}),
+		NewActionResponse("client.notify", NewClientNotification("message", "Acting as "+email+" until "+expiresAt.Format(time.Kitchen), "Impersonation")),
+	}, nil
+}
+
+func NewImpersonateUserPerformer(cruds map[string]*DbResource, configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := impersonateUserPerformer{
+		cruds:              cruds,
+		sessionTokenIssuer: NewSessionTokenIssuer(configStore),
+		loginGuard:         NewLoginGuard(configStore),
+		defaultLifeTime:    time.Duration(configIntValue(configStore, "impersonation.token.life.minutes", 15, 1)) * time.Minute,
+		maxLifeTime:        time.Duration(configIntValue(configStore, "impersonation.token.max.minutes", 60, 1)) * time.Minute,
+	}
+
+	return &handler, nil
+
+}
+
+type revokeImpersonationPerformer struct {
+	cruds       map[string]*DbResource
+	maxLifeTime time.Duration
+}
+
+func (d *revokeImpersonationPerformer) Name() string {
+	return "impersonation.revoke"
+}
+
+// DoAction rejects the impersonation token of the login event on all nodes. The token expires within the
+// longest impersonation, so it is kept in the revocation list for that long.
+func (d *revokeImpersonationPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser.Impersonator != nil || sessionUser.ApiKeyScope != nil ||
+		!IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+		return nil, nil, []error{errors.New("only administrators can revoke impersonation tokens")}
+	}
+
+	loginEventReferenceId, _ := inFields["login_event_id"].(string)
+	loginEvent, err := d.cruds[LOGIN_EVENT_TABLE_NAME].GetReferenceIdToObjectWithTransaction(LOGIN_EVENT_TABLE_NAME, loginEventReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	tokenId, _ := loginEvent["token_id"].(string)
+	if loginEvent["reason"] != loginEventImpersonated || tokenId == "" {
+		return nil, nil, []error{fmt.Errorf("login event [%v] has no impersonation token", loginEventReferenceId)}
+	}
+
+	err = jwtmiddleware.RevokeToken(tokenId, time.Now().Add(d.maxLifeTime))
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	log.Infof("[impersonation] token [%v] of %v revoked", tokenId, loginEvent["email"])
+
+	return nil, []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("message", "Impersonation token revoked", "Success")),
+	}, nil
+}
+
+func NewRevokeImpersonationPerformer(cruds map[string]*DbResource, configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := revokeImpersonationPerformer{
+		cruds:       cruds,
+		maxLifeTime: time.Duration(configIntValue(configStore, "impersonation.token.max.minutes", 60, 1)) * time.Minute,
+	}
+
+	return &handler, nil
+
+}
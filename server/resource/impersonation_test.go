@@ -0,0 +1,65 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/golang-jwt/jwt/v4"
+	"testing"
+	"time"
+)
+
+func TestImpersonation(t *testing.T) {
+
+	issuer := &SessionTokenIssuer{secret: []byte("secret"), algorithm: "HS256", issuer: "daptin-test"}
+	token, tokenId, expiresAt, err := issuer.ImpersonationToken(map[string]interface{}{
+		"email":        "user@example.com",
+		"reference_id": "user-ref",
+		"name":         "user",
+	}, &auth.Impersonator{UserReferenceId: "admin-ref", Email: "admin@example.com"}, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to sign impersonation token: %v", err)
+	}
+	if time.Until(expiresAt) > 10*time.Minute {
+		t.Errorf("expected the token to expire in ten minutes, got %v", expiresAt)
+	}
+
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte("secret"), nil
+	})
+	if err != nil {
+		t.Fatalf("failed to parse impersonation token: %v", err)
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+	impersonator := auth.ImpersonatorFromClaims(claims)
+	if claims["sub"] != "user-ref" || claims["sid"] != nil || claims["jti"] != tokenId || impersonator == nil ||
+		impersonator.UserReferenceId != "admin-ref" || impersonator.Email != "admin@example.com" {
+		t.Errorf("unexpected impersonation claims %v", claims)
+	}
+	if auth.ImpersonatorFromClaims(jwt.MapClaims{"sub": "user-ref"}) != nil {
+		t.Errorf("expected no impersonator in other tokens")
+	}
+
+	impersonated := &auth.SessionUser{UserReferenceId: "user-ref", Impersonator: impersonator}
+	userAccount := &DbResource{tableInfo: &TableInfo{TableName: USER_ACCOUNT_TABLE_NAME}}
+	if err = userAccount.checkImpersonatedWrite(impersonated, map[string]api2go.Change{"name": {}}); err != nil {
+		t.Errorf("expected the name of the user to be writable while impersonating: %v", err)
+	}
+	if err = userAccount.checkImpersonatedWrite(impersonated, map[string]api2go.Change{"password": {}}); err == nil {
+		t.Errorf("expected the password not to be writable while impersonating")
+	}
+	if err = userAccount.checkImpersonatedWrite(&auth.SessionUser{UserReferenceId: "user-ref"}, map[string]api2go.Change{"password": {}}); err != nil {
+		t.Errorf("expected the user to change their password: %v", err)
+	}
+	apiKey := &DbResource{tableInfo: &TableInfo{TableName: "api_key"}}
+	if err = apiKey.checkImpersonatedWrite(impersonated, nil); err == nil {
+		t.Errorf("expected api keys not to be writable while impersonating")
+	}
+	if err = rejectImpersonation(Outcome{Attributes: map[string]interface{}{"user": impersonated}}); err == nil {
+		t.Errorf("expected actions to be rejected while impersonating")
+	}
+
+	revoke := &revokeImpersonationPerformer{maxLifeTime: time.Hour}
+	if _, _, errs := revoke.DoAction(Outcome{Attributes: map[string]interface{}{"user": impersonated}}, map[string]interface{}{}, nil); len(errs) == 0 {
+		t.Errorf("expected impersonation tokens not to be revoked while impersonating")
+	}
+}
@@ -0,0 +1,274 @@
+package resource
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/artpar/go.uuid"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/jwt"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwt signing keys are rows of the certificate table, the issuer is jwt- followed by the algorithm and the
+// hostname is the kid of the key
+const jwtSigningKeyIssuerPrefix = "jwt-"
+
+// DefaultJwtSigningAlgorithm signs the tokens when jwt.signing.algorithm is not set, HS256 signs with jwt.secret
+const DefaultJwtSigningAlgorithm = "RS256"
+
+const defaultJwtSigningKeyRotationDays = 30
+const defaultJwtSigningKeyOverlapHours = 24
+
+// jwtSigningKeyConfig returns the algorithm, how long a key signs tokens before it is rotated and how long
+// it still verifies tokens after that
+func (cm *CertificateManager) jwtSigningKeyConfig() (string, time.Duration, time.Duration) {
+
+	algorithm, err := cm.configStore.GetConfigValueFor("jwt.signing.algorithm", "backend")
+	if err != nil || algorithm == "" {
+		algorithm = DefaultJwtSigningAlgorithm
+		err = cm.configStore.SetConfigValueFor("jwt.signing.algorithm", algorithm, "backend")
+		CheckErr(err, "Failed to store default jwt signing algorithm")
+	}
+
+	rotationDays, err := cm.configStore.GetConfigIntValueFor("jwt.signing.key.rotation.days", "backend")
+	if err != nil || rotationDays < 1 {
+		rotationDays = defaultJwtSigningKeyRotationDays
+		err = cm.configStore.SetConfigIntValueFor("jwt.signing.key.rotation.days", rotationDays, "backend")
+		CheckErr(err, "Failed to store default jwt signing key rotation")
+	}
+
+	overlapHours, err := cm.configStore.GetConfigIntValueFor("jwt.signing.key.overlap.hours", "backend")
+	if err != nil || overlapHours < 0 {
+		overlapHours = defaultJwtSigningKeyOverlapHours
+		err = cm.configStore.SetConfigIntValueFor("jwt.signing.key.overlap.hours", overlapHours, "backend")
+		CheckErr(err, "Failed to store default jwt signing key overlap")
+	}
+
+	return strings.ToUpper(algorithm), time.Duration(rotationDays) * 24 * time.Hour, time.Duration(overlapHours) * time.Hour
+}
+
+// JwtSecretWindow tells when tokens signed with jwt.secret are valid. With HS256 the secret signs every token.
+// After switching to RS256 or ES256 the tokens signed before the switch stay valid for the overlap of the
+// signing keys, the end of this migration window is stored in jwt.secret.accepted.until on the first start
+// after the switch.
+func (cm *CertificateManager) JwtSecretWindow() (bool, time.Time) {
+
+	algorithm, _, overlap := cm.jwtSigningKeyConfig()
+	if algorithm == jwt.SigningMethodHS256.Alg() {
+		err := cm.configStore.SetConfigValueFor("jwt.secret.accepted.until", "", "backend")
+		CheckErr(err, "Failed to clear the jwt secret migration window")
+		return true, time.Time{}
+	}
+
+	acceptedUntil, err := cm.configStore.GetConfigValueFor("jwt.secret.accepted.until", "backend")
+	if err == nil && acceptedUntil != "" {
+		until, err := time.Parse(time.RFC3339, acceptedUntil)
+		if err == nil {
+			return false, until
+		}
+		log.Warnf("Invalid jwt.secret.accepted.until [%v], tokens signed with the jwt secret are not accepted", acceptedUntil)
+		return false, time.Time{}
+	}
+
+	until := time.Now().Add(overlap)
+	err = cm.configStore.SetConfigValueFor("jwt.secret.accepted.until", until.Format(time.RFC3339), "backend")
+	CheckErr(err, "Failed to store the jwt secret migration window")
+	return false, until
+}
+
+// generateJwtSigningKeyPem returns a new private key for RS256 or ES256 and its public key, as pem
+func generateJwtSigningKeyPem(algorithm string) ([]byte, []byte, error) {
+	switch algorithm {
+	case "RS256":
+		publicKeyPem, privateKeyPem, _, err := GetPublicPrivateKeyPEMBytes()
+		return privateKeyPem, publicKeyPem, err
+	case "ES256":
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		privateKeyBytes, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		publicKeyBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKeyBytes}),
+			pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}), nil
+	}
+	return nil, nil, fmt.Errorf("unsupported jwt signing algorithm [%v], use HS256, RS256 or ES256", algorithm)
+}
+
+func parseJwtSigningKeyPem(algorithm string, privateKeyPem string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(privateKeyPem))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key pem")
+	}
+	switch algorithm {
+	case "RS256":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "ES256":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported jwt signing algorithm [%v]", algorithm)
+}
+
+// LoadJwtSigningKeys reads the jwt signing keys from the certificate table
+func (cm *CertificateManager) LoadJwtSigningKeys() ([]*jwtmiddleware.SigningKey, error) {
+
+	_, rotation, overlap := cm.jwtSigningKeyConfig()
+
+	rows, _, err := cm.cruds["certificate"].GetRowsByWhereClause("certificate", nil,
+		goqu.Ex{"issuer": goqu.Op{"like": jwtSigningKeyIssuerPrefix + "%"}})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*jwtmiddleware.SigningKey, 0, len(rows))
+	for _, row := range rows {
+		kid := AsStringOrEmpty(row["hostname"])
+		algorithm := strings.TrimPrefix(AsStringOrEmpty(row["issuer"]), jwtSigningKeyIssuerPrefix)
+		generatedAt, ok := auth.ParseStoredTime(row["generated_at"])
+		if !ok {
+			log.Warnf("Skipping jwt signing key [%v] without generation time", kid)
+			continue
+		}
+		privateKeyPem, err := Decrypt([]byte(cm.encryptionSecret), AsStringOrEmpty(row["private_key_pem"]))
+		if err != nil {
+			log.Warnf("Skipping jwt signing key [%v] which can not be decrypted: %v", kid, err)
+			continue
+		}
+		privateKey, err := parseJwtSigningKeyPem(algorithm, privateKeyPem)
+		if err != nil {
+			log.Warnf("Skipping invalid jwt signing key [%v]: %v", kid, err)
+			continue
+		}
+		keys = append(keys, &jwtmiddleware.SigningKey{
+			Kid:         kid,
+			Method:      jwt.GetSigningMethod(algorithm),
+			PrivateKey:  privateKey,
+			GeneratedAt: generatedAt,
+			ValidUntil:  generatedAt.Add(rotation + overlap),
+		})
+	}
+	return keys, nil
+}
+
+// RotateJwtSigningKey generates a new signing key when the newest key is older than the rotation period, was
+// generated for another algorithm or when forced. Keys past their overlap window are deleted.
+func (cm *CertificateManager) RotateJwtSigningKey(force bool) (bool, error) {
+
+	algorithm, rotation, _ := cm.jwtSigningKeyConfig()
+	if algorithm == jwt.SigningMethodHS256.Alg() {
+		return false, nil
+	}
+
+	keys, err := cm.LoadJwtSigningKeys()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	var newest *jwtmiddleware.SigningKey
+	expiredKids := make([]string, 0)
+	for _, key := range keys {
+		if !key.ValidUntil.After(now) {
+			expiredKids = append(expiredKids, key.Kid)
+		} else if newest == nil || key.GeneratedAt.After(newest.GeneratedAt) {
+			newest = key
+		}
+	}
+
+	transaction, err := cm.cruds["certificate"].Connection.Beginx()
+	if err != nil {
+		return false, err
+	}
+
+	if len(expiredKids) > 0 {
+		query, args, err := statementbuilder.Squirrel.Delete("certificate").
+			Where(goqu.Ex{"hostname": expiredKids}).ToSQL()
+		if err == nil {
+			_, err = transaction.Exec(query, args...)
+		}
+		if err != nil {
+			CheckErr(RollbackTransaction(transaction), "Failed to rollback")
+			return false, err
+		}
+		log.Printf("Deleted %d expired jwt signing keys", len(expiredKids))
+	}
+
+	rotate := force || newest == nil || newest.Method.Alg() != algorithm || now.Sub(newest.GeneratedAt) >= rotation
+	if rotate {
+		err = cm.createJwtSigningKey(algorithm, transaction)
+		if err != nil {
+			CheckErr(RollbackTransaction(transaction), "Failed to rollback")
+			return false, err
+		}
+	}
+
+	err = CommitTransaction(transaction)
+	if err != nil {
+		return false, err
+	}
+	return rotate, nil
+}
+
+func (cm *CertificateManager) createJwtSigningKey(algorithm string, transaction *sqlx.Tx) error {
+
+	privateKeyPem, publicKeyPem, err := generateJwtSigningKeyPem(algorithm)
+	if err != nil {
+		return err
+	}
+
+	u, _ := uuid.NewV4()
+	kid := "jwt-" + u.String()[0:8]
+
+	adminUserReferenceId := ""
+	adminId := int64(1)
+	for id := range GetAdminReferenceIdWithTransaction(transaction) {
+		adminUserReferenceId = id
+		break
+	}
+	if adminUserReferenceId != "" {
+		adminId, err = GetReferenceIdToIdWithTransaction(USER_ACCOUNT_TABLE_NAME, adminUserReferenceId, transaction)
+		CheckErr(err, "Failed to get admin id for user [%v]", adminUserReferenceId)
+	}
+
+	request := &http.Request{
+		Method: "POST",
+	}
+	request = request.WithContext(context.WithValue(context.Background(), "user", &auth.SessionUser{
+		UserReferenceId: adminUserReferenceId,
+		UserId:          adminId,
+	}))
+
+	_, err = cm.cruds["certificate"].CreateWithoutFilter(api2go.NewApi2GoModelWithData("certificate", nil, 0, nil, map[string]interface{}{
+		"hostname":        kid,
+		"issuer":          jwtSigningKeyIssuerPrefix + algorithm,
+		"generated_at":    time.Now().Format(time.RFC3339),
+		"private_key_pem": string(privateKeyPem),
+		"public_key_pem":  string(publicKeyPem),
+	}), api2go.Request{PlainRequest: request}, transaction)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Generated %v jwt signing key [%v]", algorithm, kid)
+	return nil
+}
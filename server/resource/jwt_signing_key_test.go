@@ -0,0 +1,105 @@
+package resource
+
+import (
+	"github.com/daptin/daptin/server/jwt"
+	"github.com/golang-jwt/jwt/v4"
+	"testing"
+	"time"
+)
+
+func TestJwtSigningKeyRotation(t *testing.T) {
+
+	keys := make([]*jwtmiddleware.SigningKey, 0)
+	for i, algorithm := range []string{"RS256", "ES256"} {
+		privateKeyPem, _, err := generateJwtSigningKeyPem(algorithm)
+		if err != nil {
+			t.Fatalf("failed to generate %v key: %v", algorithm, err)
+		}
+		privateKey, err := parseJwtSigningKeyPem(algorithm, string(privateKeyPem))
+		if err != nil {
+			t.Fatalf("failed to parse %v key: %v", algorithm, err)
+		}
+		generatedAt := time.Now().Add(-time.Duration(2-i) * time.Hour)
+		keys = append(keys, &jwtmiddleware.SigningKey{
+			Kid:         "jwt-" + algorithm,
+			Method:      jwt.GetSigningMethod(algorithm),
+			PrivateKey:  privateKey,
+			GeneratedAt: generatedAt,
+			ValidUntil:  generatedAt.Add(3 * time.Hour),
+		})
+	}
+	if _, _, err := generateJwtSigningKeyPem("PS512"); err == nil {
+		t.Errorf("expected an error for an unsupported algorithm")
+	}
+
+	err := jwtmiddleware.SigningKeys.SetLoader(func() ([]*jwtmiddleware.SigningKey, error) {
+		return keys, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	defer jwtmiddleware.SigningKeys.SetLoader(func() ([]*jwtmiddleware.SigningKey, error) {
+		return nil, nil
+	})
+
+	if current := jwtmiddleware.SigningKeys.Current(); current == nil || current.Kid != "jwt-ES256" {
+		t.Fatalf("expected the newest key to sign, got %v", current)
+	}
+	jwks := jwtmiddleware.SigningKeys.JWKS()["keys"].([]map[string]interface{})
+	if len(jwks) != 2 || jwks[0]["kty"] != "EC" || jwks[0]["crv"] != "P-256" || jwks[1]["kty"] != "RSA" || jwks[1]["e"] != "AQAB" {
+		t.Errorf("unexpected jwks: %v", jwks)
+	}
+
+	issuer := &SessionTokenIssuer{secret: []byte("secret"), algorithm: "ES256", issuer: "daptin-test", accessTokenLifeTime: time.Minute}
+	keyFunc := jwtmiddleware.SigningKeys.KeyFunc([]byte("secret"))
+
+	tokenString, err := issuer.AccessToken("user@example.com", "user", "u1", "s1")
+	if err != nil {
+		t.Fatalf("failed to sign access token: %v", err)
+	}
+	token, err := jwt.Parse(tokenString, keyFunc)
+	if err != nil || token.Header["kid"] != "jwt-ES256" || token.Header["alg"] != "ES256" {
+		t.Errorf("expected a valid ES256 token with kid, got %v %v", token.Header, err)
+	}
+
+	// a token of the previous key is valid during the overlap
+	previousKeyToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"email": "user@example.com"})
+	previousKeyToken.Header["kid"] = "jwt-RS256"
+	signed, _ := previousKeyToken.SignedString(keys[0].PrivateKey)
+	if _, err = jwt.Parse(signed, keyFunc); err != nil {
+		t.Errorf("expected the token of the previous key to be valid: %v", err)
+	}
+
+	// the kid has to name a key of the algorithm of the token
+	previousKeyToken.Header["kid"] = "jwt-ES256"
+	signed, _ = previousKeyToken.SignedString(keys[0].PrivateKey)
+	if _, err = jwt.Parse(signed, keyFunc); err == nil {
+		t.Errorf("expected a token with the kid of a key of another algorithm to be rejected")
+	}
+
+	keys[0].ValidUntil = time.Now().Add(-time.Minute)
+	if err = jwtmiddleware.SigningKeys.Reload(); err != nil {
+		t.Fatalf("failed to reload keys: %v", err)
+	}
+	previousKeyToken.Header["kid"] = "jwt-RS256"
+	signed, _ = previousKeyToken.SignedString(keys[0].PrivateKey)
+	if _, err = jwt.Parse(signed, keyFunc); err == nil {
+		t.Errorf("expected the token of an expired key to be rejected")
+	}
+
+	// tokens signed with the secret are valid with HS256 or during the migration window only
+	defer jwtmiddleware.SigningKeys.AcceptSecret(false, time.Time{})
+	hs256, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"email": "user@example.com"}).SignedString([]byte("secret"))
+	jwtmiddleware.SigningKeys.AcceptSecret(false, time.Now().Add(-time.Minute))
+	if _, err = jwt.Parse(hs256, keyFunc); err == nil {
+		t.Errorf("expected tokens signed with the secret to be rejected after the migration window")
+	}
+	jwtmiddleware.SigningKeys.AcceptSecret(false, time.Now().Add(time.Hour))
+	if _, err = jwt.Parse(hs256, keyFunc); err != nil {
+		t.Errorf("expected tokens signed with the secret to be valid during the migration window: %v", err)
+	}
+	jwtmiddleware.SigningKeys.AcceptSecret(true, time.Time{})
+	if _, err = jwt.Parse(hs256, keyFunc); err != nil {
+		t.Errorf("expected tokens signed with the secret to be valid with HS256: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+const LOGIN_EVENT_TABLE_NAME = "login_event"
+
+// reasons of the login events, only the failures which guess a credential count towards a lockout
+const (
+	loginEventSuccess           = "success"
+	loginEventUnknownUser       = "unknown_user"
+	loginEventInvalidPassword   = "invalid_password"
+	loginEventTwoFactorRequired = "two_factor_required"
+	loginEventInvalidTwoFactor  = "invalid_two_factor"
+	loginEventInvalidOtp        = "invalid_otp"
+	loginEventLocked            = "locked"
+	loginEventImpersonated      = "impersonated"
+)
+
+var lockoutReasons = map[string]bool{
+	loginEventUnknownUser:      true,
+	loginEventInvalidPassword:  true,
+	loginEventInvalidTwoFactor: true,
+	loginEventInvalidOtp:       true,
+}
+
+// failures older than the window or beyond the lookback do not count towards a lockout
+const loginEventLockoutWindow = 24 * time.Hour
+const loginEventLookback = 100
+
+// LoginGuard records the sign in attempts as login events, and locks an account or an ip address out after
+// too many failures. Each failure past the threshold doubles the lockout, up to the max. A successful sign
+// in resets the failures of the account, not of the ip address.
+type LoginGuard struct {
+	AccountThreshold int
+	IpThreshold      int
+	BaseLockout      time.Duration
+	MaxLockout       time.Duration
+}
+
+func NewLoginGuard(configStore *ConfigStore) *LoginGuard {
+	return &LoginGuard{
+		AccountThreshold: configIntValue(configStore, "login.lockout.account.threshold", 5, 1),
+		IpThreshold:      configIntValue(configStore, "login.lockout.ip.threshold", 20, 1),
+		BaseLockout:      time.Duration(configIntValue(configStore, "login.lockout.base.seconds", 60, 1)) * time.Second,
+		MaxLockout:       time.Duration(configIntValue(configStore, "login.lockout.max.seconds", 3600, 1)) * time.Second,
+	}
+}
+
+// lockoutUntil is the end of the lockout after the failures, the last of them at lastFailure
+func (g *LoginGuard) lockoutUntil(failures int, threshold int, lastFailure time.Time) time.Time {
+	if failures < threshold {
+		return time.Time{}
+	}
+	lockout := g.BaseLockout
+	for i := threshold; i < failures && lockout < g.MaxLockout; i++ {
+		lockout = lockout * 2
+	}
+	if lockout > g.MaxLockout {
+		lockout = g.MaxLockout
+	}
+	return lastFailure.Add(lockout)
+}
+
+// recentFailures counts the failed attempts matching the where clause in the lockout window, back to the
+// last success when stopAtSuccess, and returns the time of the last of them
+func recentFailures(where goqu.Ex, stopAtSuccess bool, transaction *sqlx.Tx) (int, time.Time, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select("reason", "created_at").From(LOGIN_EVENT_TABLE_NAME).
+		Where(where).Order(goqu.C("id").Desc()).Limit(loginEventLookback).ToSQL()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	rows, err := transaction.Queryx(query, args...)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer rows.Close()
+
+	windowStart := time.Now().Add(-loginEventLockoutWindow)
+	count := 0
+	var lastFailure time.Time
+	for rows.Next() {
+		var reason string
+		var createdAt interface{}
+		if err = rows.Scan(&reason, &createdAt); err != nil {
+			return 0, time.Time{}, err
+		}
+		if at, ok := auth.ParseStoredTime(createdAt); !ok || at.Before(windowStart) {
+			break
+		} else if reason == loginEventSuccess && stopAtSuccess {
+			break
+		} else if lockoutReasons[reason] {
+			if count == 0 {
+				lastFailure = at
+			}
+			count++
+		}
+	}
+	return count, lastFailure, rows.Err()
+}
+
+// LockedUntil returns the end of the lockout of the email or the ip address, zero when neither is locked out
+func (g *LoginGuard) LockedUntil(email string, ipAddress string, transaction *sqlx.Tx) (time.Time, error) {
+
+	count, lastFailure, err := recentFailures(goqu.Ex{"email": email}, true, transaction)
+	if err != nil {
+		return time.Time{}, err
+	}
+	lockedUntil := g.lockoutUntil(count, g.AccountThreshold, lastFailure)
+
+	if ipAddress != "" {
+		count, lastFailure, err = recentFailures(goqu.Ex{"ip_address": ipAddress}, false, transaction)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ipLockedUntil := g.lockoutUntil(count, g.IpThreshold, lastFailure); ipLockedUntil.After(lockedUntil) {
+			lockedUntil = ipLockedUntil
+		}
+	}
+
+	if lockedUntil.Before(time.Now()) {
+		return time.Time{}, nil
+	}
+	return lockedUntil, nil
+}
+
+// Record stores a login event, owned by the user account when the email belongs to one so users can see
+// the sign ins to their account
+func (g *LoginGuard) Record(loginEventResource *DbResource, userAccount map[string]interface{}, email string,
+	client *ActionClient, reason string, transaction *sqlx.Tx) {
+	g.record(loginEventResource, userAccount, email, client, map[string]interface{}{
+		"success": reason == loginEventSuccess,
+		"reason":  reason,
+	}, transaction)
+}
+
+// RecordImpersonation stores the login event of an administrator starting to act as the user, with the jti of
+// the token so that it can be revoked
+func (g *LoginGuard) RecordImpersonation(loginEventResource *DbResource, userAccount map[string]interface{}, email string,
+	impersonatorEmail string, tokenId string, client *ActionClient, transaction *sqlx.Tx) {
+	g.record(loginEventResource, userAccount, email, client, map[string]interface{}{
+		"success":         false,
+		"reason":          loginEventImpersonated,
+		"impersonated_by": impersonatorEmail,
+		"token_id":        tokenId,
+	}, transaction)
+}
+
+func (g *LoginGuard) record(loginEventResource *DbResource, userAccount map[string]interface{}, email string,
+	client *ActionClient, loginEvent map[string]interface{}, transaction *sqlx.Tx) {
+
+	loginEvent["email"] = email
+	if client != nil {
+		loginEvent["ip_address"] = client.IpAddress
+		loginEvent["user_agent"] = client.UserAgent
+	}
+
+	sessionUser := &auth.SessionUser{}
+	if userAccount != nil {
+		sessionUser.UserId, _ = userAccount["id"].(int64)
+		sessionUser.UserReferenceId, _ = userAccount["reference_id"].(string)
+	}
+	httpReq := &http.Request{
+		Method: "POST",
+	}
+	httpReq = httpReq.WithContext(context.WithValue(context.Background(), "user", sessionUser))
+	_, err := loginEventResource.CreateWithoutFilter(api2go.NewApi2GoModelWithData(LOGIN_EVENT_TABLE_NAME, nil, 0, nil, loginEvent),
+		api2go.Request{PlainRequest: httpReq}, transaction)
+	if err != nil {
+		log.Errorf("Failed to record login event for [%v]: %v", email, err)
+	}
+}
+
+// lockoutMessage tells the user when to try again
+func lockoutMessage(lockedUntil time.Time) string {
+	wait := time.Until(lockedUntil).Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return fmt.Sprintf("Too many failed sign in attempts, try again in %v", wait)
+}
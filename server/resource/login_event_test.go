@@ -0,0 +1,72 @@
+package resource
+
+import (
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+	"time"
+)
+
+func TestLoginGuard(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.MustExec("create table login_event (id integer primary key, email varchar(80), success bool, reason varchar(50), ip_address varchar(50), created_at timestamp)")
+
+	guard := &LoginGuard{AccountThreshold: 3, IpThreshold: 5, BaseLockout: time.Minute, MaxLockout: 10 * time.Minute}
+
+	now := time.Now()
+	if until := guard.lockoutUntil(2, 3, now); !until.IsZero() {
+		t.Errorf("expected no lockout below the threshold")
+	}
+	if until := guard.lockoutUntil(3, 3, now); !until.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected the base lockout at the threshold, got %v", until.Sub(now))
+	}
+	if until := guard.lockoutUntil(5, 3, now); !until.Equal(now.Add(4 * time.Minute)) {
+		t.Errorf("expected the lockout to double with each failure, got %v", until.Sub(now))
+	}
+	if until := guard.lockoutUntil(50, 3, now); !until.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("expected the lockout to be capped, got %v", until.Sub(now))
+	}
+
+	event := func(email string, reason string, ipAddress string, at time.Time) {
+		db.MustExec("insert into login_event (email, success, reason, ip_address, created_at) values (?, ?, ?, ?, ?)",
+			email, reason == loginEventSuccess, reason, ipAddress, at)
+	}
+	event("a@example.com", loginEventInvalidPassword, "10.0.0.1", now.Add(-48*time.Hour))
+	event("a@example.com", loginEventInvalidPassword, "10.0.0.1", now.Add(-3*time.Minute))
+	event("a@example.com", loginEventTwoFactorRequired, "10.0.0.1", now.Add(-2*time.Minute))
+	event("a@example.com", loginEventInvalidPassword, "10.0.0.1", now.Add(-2*time.Minute))
+
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	if until, err := guard.LockedUntil("a@example.com", "10.0.0.1", transaction); err != nil || !until.IsZero() {
+		t.Errorf("expected two recent failures not to lock the account, got %v, %v", until, err)
+	}
+
+	transaction.MustExec("insert into login_event (email, success, reason, ip_address, created_at) values (?, ?, ?, ?, ?)",
+		"a@example.com", false, loginEventInvalidTwoFactor, "10.0.0.1", now)
+	if until, err := guard.LockedUntil("a@example.com", "", transaction); err != nil || until.IsZero() {
+		t.Errorf("expected the third failure to lock the account, got %v, %v", until, err)
+	}
+
+	transaction.MustExec("insert into login_event (email, success, reason, ip_address, created_at) values (?, ?, ?, ?, ?)",
+		"a@example.com", true, loginEventSuccess, "10.0.0.2", now)
+	if until, err := guard.LockedUntil("a@example.com", "10.0.0.2", transaction); err != nil || !until.IsZero() {
+		t.Errorf("expected a success to reset the failures of the account, got %v, %v", until, err)
+	}
+
+	for _, email := range []string{"b@example.com", "c@example.com"} {
+		transaction.MustExec("insert into login_event (email, success, reason, ip_address, created_at) values (?, ?, ?, ?, ?)",
+			email, false, loginEventUnknownUser, "10.0.0.1", now)
+	}
+	if until, err := guard.LockedUntil("d@example.com", "10.0.0.1", transaction); err != nil || until.IsZero() {
+		t.Errorf("expected failures over several accounts to lock the ip address, got %v, %v", until, err)
+	}
+}
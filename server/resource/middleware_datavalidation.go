@@ -2,6 +2,9 @@ package resource
 
 import (
 	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"strings"
@@ -35,8 +38,25 @@ func (dvm *DataValidationMiddleware) InterceptBefore(dr *DbResource, req *api2go
 
 	switch strings.ToLower(req.PlainRequest.Method) {
 	case "get":
-		fallthrough
+		break
 	case "delete":
+		// the authenticator app is only removed with a valid code through the disable action
+		if dr.model.GetName() == USER_TOTP_TABLE_NAME {
+			sessionUser := &auth.SessionUser{}
+			if user, ok := req.PlainRequest.Context().Value("user").(*auth.SessionUser); ok {
+				sessionUser = user
+			}
+			if !IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+				return nil, api2go.NewHTTPError(nil, "use the disable_two_factor action to remove two factor authentication", 403)
+			}
+		}
+		if dr.model.GetName() == "api_key" {
+			for _, obj := range objects {
+				if err = invalidateApiKey(obj, transaction); err != nil {
+					return nil, err
+				}
+			}
+		}
 		break
 	case "post":
 		fallthrough
@@ -46,8 +66,25 @@ func (dvm *DataValidationMiddleware) InterceptBefore(dr *DbResource, req *api2go
 
 		//log.Printf("We have %d objects to validate", len(objects))
 
+		tableInfo := dvm.tableInfoMap[dr.model.GetName()]
 		for i, obj := range objects {
 
+			RemoveVirtualColumns(&tableInfo, obj)
+			if tableInfo.IsSoftDeleteEnabled {
+				delete(obj, SoftDeleteColumn)
+			}
+
+			if dr.model.GetName() == "usergroup" {
+				if err = checkUserGroupParent(dr, req, obj, transaction); err != nil {
+					return nil, err
+				}
+			}
+			if dr.model.GetName() == "api_key" && strings.ToLower(req.PlainRequest.Method) == "patch" {
+				if err = invalidateApiKey(obj, transaction); err != nil {
+					return nil, err
+				}
+			}
+
 			for _, validate := range validations {
 
 				colValue, ok := obj[validate.ColumnName]
@@ -110,3 +147,83 @@ func NewDataValidationMiddleware(cmsConfig *CmsConfig, cruds *map[string]*DbReso
 		translator:   en1,
 	}
 }
+
+// checkUserGroupParent rejects a parent which would make the usergroup its own ancestor, and a parent which
+// the user cannot update or refer to, since the members of the group inherit the permissions of its parent
+func checkUserGroupParent(dr *DbResource, req *api2go.Request, obj map[string]interface{}, transaction *sqlx.Tx) error {
+	parentValue, ok := obj[auth.ParentUserGroupColumn]
+	if !ok {
+		return nil
+	}
+	parentReferenceId, _ := parentValue.(string)
+	referenceId, _ := obj["reference_id"].(string)
+
+	if parentReferenceId != "" {
+		sessionUser := &auth.SessionUser{}
+		if user, ok := req.PlainRequest.Context().Value("user").(*auth.SessionUser); ok {
+			sessionUser = user
+		}
+		if !IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+			permission := dr.GetRowPermissionWithTransaction(map[string]interface{}{
+				"__type":       "usergroup",
+				"reference_id": parentReferenceId,
+			}, transaction)
+			if !permission.CanUpdate(sessionUser.UserReferenceId, sessionUser.Groups) &&
+				!permission.CanRefer(sessionUser.UserReferenceId, sessionUser.Groups) {
+				return api2go.NewHTTPError(nil, fmt.Sprintf("cannot use usergroup [%v] as a parent", parentReferenceId), 403)
+			}
+		}
+		if parentReferenceId == referenceId {
+			return api2go.NewHTTPError(nil, "usergroup cannot be its own parent", 400)
+		}
+	}
+
+	parents, err := auth.LoadUserGroupParents(transaction)
+	if err != nil {
+		return err
+	}
+	if referenceId == "" {
+		// a new group has no members yet
+		AfterCommit(transaction, auth.InvalidateUserGroupParents)
+		return nil
+	}
+	for _, ancestor := range auth.UserGroupAncestors(parentReferenceId, parents) {
+		if ancestor == referenceId {
+			return api2go.NewHTTPError(nil, fmt.Sprintf("usergroup [%v] is an ancestor of [%v], it cannot be its parent", referenceId, parentReferenceId), 400)
+		}
+	}
+
+	// members of the group and of the groups below it get other groups once the change is committed
+	members, err := auth.UserGroupMemberEmails(transaction, append(auth.UserGroupDescendants(referenceId, parents), referenceId))
+	if err != nil {
+		return err
+	}
+	AfterCommit(transaction, func() {
+		auth.InvalidateUserGroupParents()
+		auth.InvalidateCachedUsers(members)
+	})
+	return nil
+}
+
+// invalidateApiKey drops a changed or deleted api key from the auth cache once the change is committed, so
+// revoked keys stop working right away
+func invalidateApiKey(obj map[string]interface{}, transaction *sqlx.Tx) error {
+	referenceId, _ := obj["reference_id"].(string)
+	if referenceId == "" {
+		return nil
+	}
+	query, args, err := statementbuilder.Squirrel.Select("key_hash").From("api_key").
+		Where(goqu.Ex{"reference_id": referenceId}).ToSQL()
+	if err != nil {
+		return err
+	}
+	var keyHash string
+	if err = transaction.QueryRowx(query, args...).Scan(&keyHash); err != nil {
+		// there is no key to drop
+		return nil
+	}
+	AfterCommit(transaction, func() {
+		auth.InvalidateCachedApiKey(keyHash)
+	})
+	return nil
+}
@@ -3,14 +3,16 @@ package resource
 import (
 	"github.com/artpar/api2go"
 	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/auth"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"strings"
 )
 
 type eventHandlerMiddleware struct {
-	dtopicMap *map[string]*olric.DTopic
-	cruds     *map[string]*DbResource
+	dtopicMap         *map[string]*olric.DTopic
+	cruds             *map[string]*DbResource
+	webhookDispatcher *WebhookDispatcher
 }
 
 func (pc eventHandlerMiddleware) String() string {
@@ -26,6 +28,11 @@ type EventMessage struct {
 
 func (pc *eventHandlerMiddleware) InterceptAfter(dr *DbResource, req *api2go.Request, results []map[string]interface{}, transaction *sqlx.Tx) ([]map[string]interface{}, error) {
 
+	before, err := pc.recordChange(dr, req, results, transaction)
+	if err != nil {
+		return nil, err
+	}
+
 	topic := (*pc.dtopicMap)[dr.model.GetTableName()]
 	if topic == nil {
 		return results, nil
@@ -46,12 +53,18 @@ func (pc *eventHandlerMiddleware) InterceptAfter(dr *DbResource, req *api2go.Req
 		}()
 		break
 	case "delete":
+		// subscribers get the deleted row when it was loaded before the delete, without its secrets as in
+		// the change log
+		deletedRow := results[0]
+		if before != nil {
+			deletedRow = changeLogImage(dr.tableInfo, before)
+		}
 		go func() {
 			err := topic.Publish(EventMessage{
 				MessageSource: "database",
 				EventType:     "delete",
 				ObjectType:    dr.model.GetTableName(),
-				EventData:     results[0],
+				EventData:     deletedRow,
 			})
 			CheckErr(err, "Failed to delete create message")
 
@@ -86,8 +99,10 @@ func (pc *eventHandlerMiddleware) InterceptBefore(dr *DbResource, req *api2go.Re
 	case "POST":
 		break
 	case "DELETE":
+		captureBeforeImage(dr, objects, transaction)
 		break
 	case "PATCH":
+		captureBeforeImage(dr, objects, transaction)
 		break
 	default:
 		log.Errorf("Invalid method: %v", reqmethod)
@@ -99,3 +114,54 @@ func (pc *eventHandlerMiddleware) InterceptBefore(dr *DbResource, req *api2go.Re
 	return objects, nil
 
 }
+
+// recordChange writes the change with its before and after images to the change log
+// and hands it over to the webhooks after commit, the before image is returned for the delete event
+func (pc *eventHandlerMiddleware) recordChange(dr *DbResource, req *api2go.Request, results []map[string]interface{}, transaction *sqlx.Tx) (map[string]interface{}, error) {
+
+	if len(results) == 0 || results[0] == nil {
+		return nil, nil
+	}
+	referenceId, _ := results[0]["reference_id"].(string)
+	tableName := dr.model.GetTableName()
+
+	var eventType string
+	var before, after map[string]interface{}
+	switch strings.ToLower(req.PlainRequest.Method) {
+	case "post":
+		eventType = "create"
+		after = results[0]
+	case "patch":
+		eventType = "update"
+		before = takeBeforeImage(transaction, tableName, referenceId)
+		after = results[0]
+	case "delete":
+		eventType = "delete"
+		before = takeBeforeImage(transaction, tableName, referenceId)
+	default:
+		return nil, nil
+	}
+
+	var sessionUser *auth.SessionUser
+	user := req.PlainRequest.Context().Value("user")
+	if user != nil {
+		sessionUser, _ = user.(*auth.SessionUser)
+	}
+
+	loggedBefore := changeLogImage(dr.tableInfo, before)
+	loggedAfter := changeLogImage(dr.tableInfo, after)
+
+	err := WriteChangeLog(transaction, eventType, tableName, referenceId, loggedBefore, loggedAfter, sessionUser)
+	if err != nil {
+		log.Errorf("Failed to write change log for [%v][%v]: %v", tableName, referenceId, err)
+		return nil, err
+	}
+
+	// webhooks only hear about committed changes
+	if pc.webhookDispatcher != nil {
+		AfterCommit(transaction, func() {
+			go pc.webhookDispatcher.Dispatch(eventType, tableName, loggedBefore, loggedAfter)
+		})
+	}
+	return before, nil
+}
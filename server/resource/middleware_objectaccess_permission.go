@@ -2,6 +2,7 @@ package resource
 
 import (
 	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
 	"strings"
 
 	"github.com/artpar/api2go"
@@ -40,12 +41,17 @@ func (pc *ObjectAccessPermissionChecker) InterceptAfter(dr *DbResource, req *api
 		sessionUser = user.(*auth.SessionUser)
 	}
 
+	if sessionUser.ApiKeyScope != nil {
+		results = apiKeyScopedResults(dr, req, sessionUser.ApiKeyScope, results)
+	}
+
 	if IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
 		return results, nil
 	}
 
 	notIncludedMapCache := make(map[string]bool)
 	includedMapCache := make(map[string]bool)
+	permissionCache := make(map[string]PermissionInstance)
 
 	for _, result := range results {
 		//log.Printf("Result: %v", result)
@@ -69,7 +75,7 @@ func (pc *ObjectAccessPermissionChecker) InterceptAfter(dr *DbResource, req *api
 		}
 		_, ok = includedMapCache[referenceId]
 		if ok {
-			returnMap = append(returnMap, result)
+			returnMap = append(returnMap, dr.readableColumns(result, permissionCache[referenceId], sessionUser))
 			continue
 		}
 
@@ -78,15 +84,19 @@ func (pc *ObjectAccessPermissionChecker) InterceptAfter(dr *DbResource, req *api
 		//log.Printf("Row Permission for [%v] for [%v]", permission, result)
 
 		if req.PlainRequest.Method == "GET" {
-			if permission.CanRead(sessionUser.UserReferenceId, sessionUser.Groups) {
-				returnMap = append(returnMap, result)
+			// rows of the table itself are limited by its row policies in the query, included rows are checked here
+			if permission.CanRead(sessionUser.UserReferenceId, sessionUser.Groups) &&
+				(result["__type"] == dr.tableInfo.TableName || rowPolicyAllows(dr, "GET", result, sessionUser, transaction)) {
+				returnMap = append(returnMap, dr.readableColumns(result, permission, sessionUser))
 				includedMapCache[referenceId] = true
+				permissionCache[referenceId] = permission
 			} else {
 				notIncludedMapCache[referenceId] = true
 			}
 		} else if permission.CanPeek(sessionUser.UserReferenceId, sessionUser.Groups) {
-			returnMap = append(returnMap, result)
+			returnMap = append(returnMap, dr.readableColumns(result, permission, sessionUser))
 			includedMapCache[referenceId] = true
+			permissionCache[referenceId] = permission
 		} else {
 			//log.Printf("[ObjectAccessPermissionChecker] Result not to be included: %v", result["reference_id"])
 			notIncludedMapCache[referenceId] = true
@@ -96,6 +106,47 @@ func (pc *ObjectAccessPermissionChecker) InterceptAfter(dr *DbResource, req *api
 	return returnMap, nil
 
 }
+
+// readableColumns drops the columns of the result which the user cannot read, included rows are checked with
+// the column permissions of their own table
+func (dr *DbResource) readableColumns(result map[string]interface{}, permission PermissionInstance, sessionUser *auth.SessionUser) map[string]interface{} {
+	tableInfo := dr.tableInfo
+	if typeName, _ := result["__type"].(string); typeName != dr.tableInfo.TableName && dr.Cruds[typeName] != nil {
+		tableInfo = dr.Cruds[typeName].tableInfo
+	}
+	return removeUnreadableColumns(tableInfo, result, permission, sessionUser)
+}
+
+// apiKeyScopedResults drops the included rows of the tables which the api key cannot read
+func apiKeyScopedResults(dr *DbResource, req *api2go.Request, scope *auth.ApiKeyScope, results []map[string]interface{}) []map[string]interface{} {
+	scopedResults := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		typeName, _ := result["__type"].(string)
+		if typeName == dr.tableInfo.TableName || strings.Index(typeName, ".") > -1 || scope.AllowsTable(typeName, "GET") {
+			scopedResults = append(scopedResults, result)
+		}
+	}
+	return scopedResults
+}
+
+// rowPolicyAllows checks the row policies of the table of the row for the method of the request
+func rowPolicyAllows(dr *DbResource, method string, result map[string]interface{}, sessionUser *auth.SessionUser, transaction *sqlx.Tx) bool {
+	rowResource := dr
+	if typeName, _ := result["__type"].(string); dr.Cruds[typeName] != nil {
+		rowResource = dr.Cruds[typeName]
+	}
+	referenceId, _ := result["reference_id"].(string)
+	err := rowResource.checkRowPolicy(rowPolicyMethodActions[method], referenceId, sessionUser, transaction)
+	if err != nil {
+		log.Infof("[ObjectAccessPermissionChecker] row [%v] not allowed by row policy: %v", referenceId, err)
+		return false
+	}
+	return true
+}
+
 func BeginsWith(longerString string, smallerString string) bool {
 	if len(smallerString) > len(longerString) {
 		return false
@@ -105,10 +156,6 @@ func BeginsWith(longerString string, smallerString string) bool {
 
 func (pc *ObjectAccessPermissionChecker) InterceptBefore(dr *DbResource, req *api2go.Request, results []map[string]interface{}, transaction *sqlx.Tx) ([]map[string]interface{}, error) {
 
-	if req.PlainRequest.Method == "POST" {
-		return results, nil
-	}
-
 	//if OlricCache == nil {
 	//	OlricCache, _ = dr.OlricDb.NewDMap("default-OlricCache")
 	//}
@@ -123,6 +170,25 @@ func (pc *ObjectAccessPermissionChecker) InterceptBefore(dr *DbResource, req *ap
 		sessionUser = user.(*auth.SessionUser)
 	}
 
+	if req.PlainRequest.Method != "GET" {
+		if err := dr.checkImpersonatedWrite(sessionUser, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.PlainRequest.Method == "POST" {
+		if len(dr.tableInfo.ColumnPermissions) == 0 || IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+			return results, nil
+		}
+		for _, result := range results {
+			err := checkColumnCreatePermission(dr.tableInfo, result, sessionUser)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return results, nil
+	}
+
 	if IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
 		return results, nil
 	}
@@ -167,7 +233,8 @@ func (pc *ObjectAccessPermissionChecker) InterceptBefore(dr *DbResource, req *ap
 		//log.Printf("Row Permission for [%v] for [%v]", permission, result)
 
 		if req.PlainRequest.Method == "GET" {
-			if permission.CanPeek(sessionUser.UserReferenceId, sessionUser.Groups) {
+			if permission.CanPeek(sessionUser.UserReferenceId, sessionUser.Groups) &&
+				rowPolicyAllows(dr, req.PlainRequest.Method, result, sessionUser, transaction) {
 				returnMap = append(returnMap, result)
 				includedMapCache[referenceId] = true
 			} else {
@@ -176,7 +243,8 @@ func (pc *ObjectAccessPermissionChecker) InterceptBefore(dr *DbResource, req *ap
 
 			}
 		} else if req.PlainRequest.Method == "PUT" || req.PlainRequest.Method == "PATCH" {
-			if permission.CanUpdate(sessionUser.UserReferenceId, sessionUser.Groups) {
+			if permission.CanUpdate(sessionUser.UserReferenceId, sessionUser.Groups) &&
+				rowPolicyAllows(dr, req.PlainRequest.Method, result, sessionUser, transaction) {
 				returnMap = append(returnMap, result)
 				includedMapCache[referenceId] = true
 			} else {
@@ -184,7 +252,8 @@ func (pc *ObjectAccessPermissionChecker) InterceptBefore(dr *DbResource, req *ap
 				notIncludedMapCache[referenceId] = true
 			}
 		} else if req.PlainRequest.Method == "DELETE" {
-			if permission.CanDelete(sessionUser.UserReferenceId, sessionUser.Groups) {
+			if permission.CanDelete(sessionUser.UserReferenceId, sessionUser.Groups) &&
+				rowPolicyAllows(dr, req.PlainRequest.Method, result, sessionUser, transaction) {
 				returnMap = append(returnMap, result)
 				includedMapCache[referenceId] = true
 			} else {
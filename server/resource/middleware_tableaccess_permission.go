@@ -83,6 +83,10 @@ func (pc *TableAccessPermissionChecker) InterceptBefore(dr *DbResource, req *api
 		sessionUser = user.(*auth.SessionUser)
 	}
 
+	if !sessionUser.ApiKeyScope.AllowsTable(dr.tableInfo.TableName, req.PlainRequest.Method) {
+		return nil, api2go.NewHTTPError(fmt.Errorf(errorMsgFormat, "api key", dr.tableInfo.TableName, req.PlainRequest.Method, sessionUser.UserReferenceId), pc.String(), 403)
+	}
+
 	if IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
 		return results, nil
 	}
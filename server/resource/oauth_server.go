@@ -1 +1,578 @@
 package resource
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/jwt"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const OAUTH_CLIENT_TABLE_NAME = "oauth_client"
+const OAUTH_AUTHORIZATION_CODE_TABLE_NAME = "oauth_authorization_code"
+
+// authorization codes are exchanged by the client right after the redirect
+const authorizationCodeLifeTime = time.Minute
+
+var oidcScopes = []string{"openid", "profile", "email", "groups"}
+
+// OAuthServer is the OpenID Connect provider of daptin. Signed in users authorize the registered clients
+// with the authorization code flow, the clients get an id token and a session of the user.
+type OAuthServer struct {
+	cruds              map[string]*DbResource
+	sessionTokenIssuer *SessionTokenIssuer
+	issuer             string
+	loginUrl           string
+}
+
+// oauthClient is a client registered in the oauth_client table
+type oauthClient struct {
+	ClientId         string
+	ClientSecretHash string
+	RedirectUris     []string
+	IsPublic         bool
+}
+
+// oauthError is an error response of the token endpoint, https://tools.ietf.org/html/rfc6749#section-5.2
+type oauthError struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (e oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func NewOAuthServer(cruds map[string]*DbResource, configStore *ConfigStore) *OAuthServer {
+
+	// the issuer is the url of the server as the clients see it, the provider is disabled until it is set
+	issuer, _ := configStore.GetConfigValueFor("oidc.issuer", "backend")
+
+	loginUrl, err := configStore.GetConfigValueFor("oidc.login.url", "backend")
+	if err != nil || loginUrl == "" {
+		loginUrl = "/auth/signin"
+		err = configStore.SetConfigValueFor("oidc.login.url", loginUrl, "backend")
+		CheckErr(err, "Failed to store default oidc login url")
+	}
+
+	return &OAuthServer{
+		cruds:              cruds,
+		sessionTokenIssuer: NewSessionTokenIssuer(configStore),
+		issuer:             strings.TrimSuffix(issuer, "/"),
+		loginUrl:           loginUrl,
+	}
+}
+
+// Enabled is true when oidc.issuer is set. The issuer is not taken from the request, the host and forwarded
+// headers are chosen by the client and would let it name the issuer of the tokens.
+func (s *OAuthServer) Enabled() bool {
+	return s.issuer != ""
+}
+
+// DiscoveryHandler serves the provider metadata at /.well-known/openid-configuration
+func (s *OAuthServer) DiscoveryHandler(c *gin.Context) {
+	issuer := s.issuer
+
+	signingAlgorithms := make([]string, 0)
+	if key := jwtmiddleware.SigningKeys.Current(); key != nil {
+		signingAlgorithms = append(signingAlgorithms, key.Method.Alg())
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(200, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"scopes_supported":                      oidcScopes,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": signingAlgorithms,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "nonce", "email", "email_verified", "name", "groups"},
+	})
+}
+
+// getOAuthClient returns the registered client with the client id
+func getOAuthClient(clientId string, transaction *sqlx.Tx) (*oauthClient, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select("client_id", "client_secret_hash", "redirect_uris", "is_public").
+		From(OAUTH_CLIENT_TABLE_NAME).Where(goqu.Ex{"client_id": clientId}).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var client oauthClient
+	var clientSecretHash, redirectUris sql.NullString
+	err = transaction.QueryRowx(query, args...).Scan(&client.ClientId, &clientSecretHash, &redirectUris, &client.IsPublic)
+	if err != nil {
+		return nil, err
+	}
+	client.ClientSecretHash = clientSecretHash.String
+	if redirectUris.String != "" {
+		err = json.Unmarshal([]byte(redirectUris.String), &client.RedirectUris)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect uris of client [%v]: %v", clientId, err)
+		}
+	}
+	return &client, nil
+}
+
+// allowsRedirectUri checks the redirect uri against the registered ones, uris have to match exactly
+func (c *oauthClient) allowsRedirectUri(redirectUri string) bool {
+	for _, registered := range c.RedirectUris {
+		if registered == redirectUri {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate checks the secret of a confidential client, public clients have no secret and rely on pkce
+func (c *oauthClient) authenticate(clientSecret string) bool {
+	if c.IsPublic {
+		return clientSecret == ""
+	}
+	return c.ClientSecretHash != "" &&
+		subtle.ConstantTimeCompare([]byte(auth.HashApiKey(clientSecret)), []byte(c.ClientSecretHash)) == 1
+}
+
+// pkceChallenge is the S256 code challenge of the code verifier
+func pkceChallenge(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func newAuthorizationCode() (string, error) {
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// AuthorizeHandler is the authorization endpoint. Users who are not signed in are sent to the login url, and
+// come back to the same request after signing in. Registered clients are trusted, so signed in users are
+// redirected to the client with a code without a consent screen.
+func (s *OAuthServer) AuthorizeHandler(c *gin.Context) {
+
+	query := c.Request.URL.Query()
+	clientId := query.Get("client_id")
+	redirectUri := query.Get("redirect_uri")
+
+	transaction, err := s.cruds[OAUTH_CLIENT_TABLE_NAME].Connection.Beginx()
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+	defer RollbackTransaction(transaction)
+
+	// errors about the client or the redirect uri are not sent to the redirect uri
+	client, err := getOAuthClient(clientId, transaction)
+	if err != nil {
+		c.String(400, "unknown client")
+		return
+	}
+	if !client.allowsRedirectUri(redirectUri) {
+		c.String(400, "redirect_uri is not registered for the client")
+		return
+	}
+
+	redirectWith := func(params url.Values) {
+		if state := query.Get("state"); state != "" {
+			params.Set("state", state)
+		}
+		location, _ := url.Parse(redirectUri)
+		values := location.Query()
+		for key := range params {
+			values.Set(key, params.Get(key))
+		}
+		location.RawQuery = values.Encode()
+		c.Redirect(http.StatusFound, location.String())
+	}
+	redirectError := func(code string, description string) {
+		redirectWith(url.Values{"error": {code}, "error_description": {description}})
+	}
+
+	if query.Get("response_type") != "code" {
+		redirectError("unsupported_response_type", "only the code response type is supported")
+		return
+	}
+	scopes := strings.Fields(query.Get("scope"))
+	if !containsString(scopes, "openid") {
+		redirectError("invalid_scope", "the openid scope is required")
+		return
+	}
+	codeChallenge := query.Get("code_challenge")
+	if codeChallenge != "" && query.Get("code_challenge_method") != "S256" {
+		redirectError("invalid_request", "only the S256 code challenge method is supported")
+		return
+	}
+	if codeChallenge == "" && client.IsPublic {
+		redirectError("invalid_request", "public clients need a code challenge")
+		return
+	}
+
+	sessionUser, ok := c.Request.Context().Value("user").(*auth.SessionUser)
+	if !ok || sessionUser.UserId == 0 {
+		if query.Get("prompt") == "none" {
+			redirectError("login_required", "the user is not signed in")
+			return
+		}
+		c.Redirect(http.StatusFound, s.loginUrl+"?redirect="+url.QueryEscape(c.Request.URL.RequestURI()))
+		return
+	}
+	if sessionUser.Impersonator != nil || sessionUser.ApiKeyScope != nil {
+		redirectError("access_denied", "sign in as the user to authorize a client")
+		return
+	}
+
+	code, err := newAuthorizationCode()
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	httpReq := &http.Request{
+		Method: "POST",
+	}
+	httpReq = httpReq.WithContext(context.WithValue(context.Background(), "user", sessionUser))
+	_, err = s.cruds[OAUTH_AUTHORIZATION_CODE_TABLE_NAME].CreateWithoutFilter(api2go.NewApi2GoModelWithData(OAUTH_AUTHORIZATION_CODE_TABLE_NAME, nil, 0, nil, map[string]interface{}{
+		"code_hash":      auth.HashApiKey(code),
+		"client_id":      clientId,
+		"redirect_uri":   redirectUri,
+		"scope":          strings.Join(scopes, " "),
+		"nonce":          query.Get("nonce"),
+		"code_challenge": codeChallenge,
+		"expires_at":     time.Now().Add(authorizationCodeLifeTime),
+	}), api2go.Request{PlainRequest: httpReq}, transaction)
+	if err == nil {
+		err = CommitTransaction(transaction)
+	}
+	if err != nil {
+		log.Errorf("Failed to store authorization code for client [%v]: %v", clientId, err)
+		redirectError("server_error", "failed to issue a code")
+		return
+	}
+
+	redirectWith(url.Values{"code": {code}})
+}
+
+// clientCredentials reads the client id and secret from the basic authorization header or the form
+func clientCredentials(r *http.Request) (string, string) {
+	if clientId, clientSecret, ok := r.BasicAuth(); ok {
+		clientId, _ = url.QueryUnescape(clientId)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+		return clientId, clientSecret
+	}
+	return r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+}
+
+// TokenHandler is the token endpoint, it exchanges an authorization code for the tokens of a new session of
+// the user, and refresh tokens for new tokens of the session
+func (s *OAuthServer) TokenHandler(c *gin.Context) {
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	transaction, err := s.cruds[OAUTH_CLIENT_TABLE_NAME].Connection.Beginx()
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	response, err := s.token(c, transaction)
+	if err != nil {
+		rollbackErr := RollbackTransaction(transaction)
+		CheckErr(rollbackErr, "Failed to rollback")
+		var tokenError oauthError
+		if !errors.As(err, &tokenError) {
+			log.Errorf("Failed to issue oauth tokens: %v", err)
+			tokenError = oauthError{Status: 500, Code: "server_error", Description: "failed to issue tokens"}
+		}
+		if tokenError.Status == 401 {
+			c.Header("WWW-Authenticate", "Basic")
+		}
+		c.JSON(tokenError.Status, map[string]string{
+			"error":             tokenError.Code,
+			"error_description": tokenError.Description,
+		})
+		return
+	}
+
+	err = CommitTransaction(transaction)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+	c.JSON(200, response)
+}
+
+func (s *OAuthServer) token(c *gin.Context, transaction *sqlx.Tx) (map[string]interface{}, error) {
+
+	clientId, clientSecret := clientCredentials(c.Request)
+	client, err := getOAuthClient(clientId, transaction)
+	if err != nil || !client.authenticate(clientSecret) {
+		return nil, oauthError{Status: 401, Code: "invalid_client", Description: "client authentication failed"}
+	}
+
+	switch c.Request.PostFormValue("grant_type") {
+	case "authorization_code":
+		return s.exchangeAuthorizationCode(c, client, transaction)
+	case "refresh_token":
+		accessToken, refreshToken, err := s.sessionTokenIssuer.RefreshSession(c.Request.PostFormValue("refresh_token"), client.ClientId, transaction)
+		if err != nil {
+			return nil, oauthError{Status: 400, Code: "invalid_grant", Description: err.Error()}
+		}
+		return map[string]interface{}{
+			"access_token":  accessToken,
+			"refresh_token": refreshToken,
+			"token_type":    "Bearer",
+			"expires_in":    int(s.sessionTokenIssuer.accessTokenLifeTime.Seconds()),
+		}, nil
+	default:
+		return nil, oauthError{Status: 400, Code: "unsupported_grant_type", Description: "use authorization_code or refresh_token"}
+	}
+}
+
+// exchangeAuthorizationCode redeems a code once, for the client and redirect uri it was issued to
+func (s *OAuthServer) exchangeAuthorizationCode(c *gin.Context, client *oauthClient, transaction *sqlx.Tx) (map[string]interface{}, error) {
+
+	invalidGrant := oauthError{Status: 400, Code: "invalid_grant", Description: "invalid authorization code"}
+	codeHash := auth.HashApiKey(c.Request.PostFormValue("code"))
+
+	query, args, err := statementbuilder.Squirrel.Select("id", USER_ACCOUNT_ID_COLUMN, "client_id", "redirect_uri", "scope", "nonce", "code_challenge", "expires_at").
+		From(OAUTH_AUTHORIZATION_CODE_TABLE_NAME).Where(goqu.Ex{"code_hash": codeHash}, goqu.C("used_at").IsNull()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	var id, userId int64
+	var clientId, redirectUri, scope string
+	var nonce, codeChallenge sql.NullString
+	var expiresAt interface{}
+	err = transaction.QueryRowx(query, args...).Scan(&id, &userId, &clientId, &redirectUri, &scope, &nonce, &codeChallenge, &expiresAt)
+	if err != nil {
+		return nil, invalidGrant
+	}
+
+	updateQuery, updateArgs, err := statementbuilder.Squirrel.Update(OAUTH_AUTHORIZATION_CODE_TABLE_NAME).
+		Set(goqu.Record{"used_at": time.Now()}).Where(goqu.Ex{"id": id}, goqu.C("used_at").IsNull()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	result, err := transaction.Exec(updateQuery, updateArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		// another request redeemed the same code first
+		return nil, invalidGrant
+	}
+
+	if expiry, ok := auth.ParseStoredTime(expiresAt); !ok || time.Now().After(expiry) {
+		return nil, oauthError{Status: 400, Code: "invalid_grant", Description: "authorization code has expired"}
+	}
+	if clientId != client.ClientId || redirectUri != c.Request.PostFormValue("redirect_uri") {
+		return nil, invalidGrant
+	}
+	if codeChallenge.String != "" &&
+		subtle.ConstantTimeCompare([]byte(pkceChallenge(c.Request.PostFormValue("code_verifier"))), []byte(codeChallenge.String)) != 1 {
+		return nil, oauthError{Status: 400, Code: "invalid_grant", Description: "code verifier does not match the code challenge"}
+	}
+
+	userAccount, err := s.cruds[USER_ACCOUNT_TABLE_NAME].GetIdToObjectWithTransaction(USER_ACCOUNT_TABLE_NAME, userId, transaction)
+	if err != nil {
+		return nil, invalidGrant
+	}
+
+	accessToken, refreshToken, err := s.sessionTokenIssuer.StartClientSession(userAccount, &ActionClient{
+		IpAddress: ClientIP(c),
+		UserAgent: c.Request.UserAgent(),
+	}, client.ClientId, scope, s.cruds[USER_SESSION_TABLE_NAME], transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	idTokenClaims, err := s.userClaims(userId, userAccount, strings.Fields(scope), transaction)
+	if err != nil {
+		return nil, err
+	}
+	timeNow := time.Now()
+	idTokenClaims["iss"] = s.issuer
+	idTokenClaims["aud"] = client.ClientId
+	idTokenClaims["iat"] = timeNow.Unix()
+	idTokenClaims["exp"] = timeNow.Add(s.sessionTokenIssuer.accessTokenLifeTime).Unix()
+	if nonce.String != "" {
+		idTokenClaims["nonce"] = nonce.String
+	}
+	idToken, err := signIdToken(idTokenClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"id_token":      idToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(s.sessionTokenIssuer.accessTokenLifeTime.Seconds()),
+		"scope":         scope,
+	}, nil
+}
+
+// signIdToken signs with the current RS256 or ES256 key so that clients can verify the token with the jwks,
+// a token signed with the jwt secret could not be verified by them
+func signIdToken(claims jwt.MapClaims) (string, error) {
+	key := jwtmiddleware.SigningKeys.Current()
+	if key == nil {
+		return "", errors.New("id tokens need a RS256 or ES256 jwt signing key")
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.PrivateKey)
+}
+
+// userClaims are the claims about the user for the scopes, in the id token and from the userinfo endpoint
+func (s *OAuthServer) userClaims(userId int64, userAccount map[string]interface{}, scopes []string, transaction *sqlx.Tx) (jwt.MapClaims, error) {
+
+	claims := jwt.MapClaims{
+		"sub": userAccount["reference_id"],
+	}
+	if containsString(scopes, "email") {
+		claims["email"] = userAccount["email"]
+		claims["email_verified"] = queryValueBool(userAccount["confirmed"])
+	}
+	if containsString(scopes, "profile") {
+		claims["name"] = userAccount["name"]
+	}
+	if containsString(scopes, "groups") {
+		groups, err := userGroupNames(userId, transaction)
+		if err != nil {
+			return nil, err
+		}
+		claims["groups"] = groups
+	}
+	return claims, nil
+}
+
+// userGroupNames are the names of the usergroups of the user, including the ones inherited from parent groups
+func userGroupNames(userId int64, transaction *sqlx.Tx) ([]string, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select(goqu.I("g.reference_id"), goqu.I("g.name"), goqu.I("uug.id")).
+		From(goqu.T("usergroup").As("g")).
+		LeftJoin(goqu.T("user_account_user_account_id_has_usergroup_usergroup_id").As("uug"), goqu.On(goqu.Ex{
+			"uug.usergroup_id":    goqu.I("g.id"),
+			"uug.user_account_id": userId,
+		})).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := transaction.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	memberOf := make([]auth.GroupPermission, 0)
+	for rows.Next() {
+		var referenceId, name string
+		var membershipId sql.NullInt64
+		if err = rows.Scan(&referenceId, &name, &membershipId); err != nil {
+			return nil, err
+		}
+		names[referenceId] = name
+		if membershipId.Valid {
+			memberOf = append(memberOf, auth.GroupPermission{GroupReferenceId: referenceId})
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	parents, err := auth.LoadUserGroupParents(transaction)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]string, 0)
+	for _, group := range auth.ExpandUserGroups(memberOf, parents) {
+		groups = append(groups, names[group.GroupReferenceId])
+	}
+	return groups, nil
+}
+
+// UserInfoHandler returns the claims about the user of the access token, for the scope granted to the client
+// of its session. Sessions of users who signed in to daptin get every claim, tokens without a session only
+// get the subject.
+func (s *OAuthServer) UserInfoHandler(c *gin.Context) {
+
+	sessionUser, ok := c.Request.Context().Value("user").(*auth.SessionUser)
+	if !ok || sessionUser.UserId == 0 {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.AbortWithStatus(401)
+		return
+	}
+
+	transaction, err := s.cruds[USER_ACCOUNT_TABLE_NAME].Connection.Beginx()
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+	defer RollbackTransaction(transaction)
+
+	userAccount, err := s.cruds[USER_ACCOUNT_TABLE_NAME].GetIdToObjectWithTransaction(USER_ACCOUNT_TABLE_NAME, sessionUser.UserId, transaction)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+	scopes := []string{"openid"}
+	if sessionUser.SessionReferenceId != "" {
+		clientId, scope, err := SessionClient(sessionUser.SessionReferenceId, transaction)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatus(401)
+			return
+		}
+		scopes = oidcScopes
+		if clientId != "" {
+			scopes = strings.Fields(scope)
+		}
+	}
+	claims, err := s.userClaims(sessionUser.UserId, userAccount, scopes, transaction)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+	c.JSON(200, claims)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
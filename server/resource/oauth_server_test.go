@@ -0,0 +1,65 @@
+package resource
+
+import (
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestOAuthClient(t *testing.T) {
+
+	// the example of https://tools.ietf.org/html/rfc7636#appendix-B
+	if challenge := pkceChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); challenge != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("unexpected code challenge %v", challenge)
+	}
+
+	redirectUris, err := oauthRedirectUris("https://app.example.com/callback\nhttp://localhost:3000/cb")
+	if err != nil || len(redirectUris) != 2 {
+		t.Errorf("expected two redirect uris, got %v: %v", redirectUris, err)
+	}
+	for _, invalid := range []string{"", "/callback", "https://app.example.com/cb#fragment"} {
+		if _, err = oauthRedirectUris(invalid); err == nil {
+			t.Errorf("expected [%v] to be rejected", invalid)
+		}
+	}
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.MustExec("create table oauth_client (id integer primary key, client_id varchar(64), client_secret_hash varchar(64), redirect_uris text, is_public bool)")
+	db.MustExec("insert into oauth_client (client_id, client_secret_hash, redirect_uris, is_public) values (?, ?, ?, ?)",
+		"confidential", auth.HashApiKey("secret"), `["https://app.example.com/callback"]`, false)
+	db.MustExec("insert into oauth_client (client_id, redirect_uris, is_public) values (?, ?, ?)",
+		"public", `["http://localhost:3000/cb"]`, true)
+
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	confidential, err := getOAuthClient("confidential", transaction)
+	if err != nil {
+		t.Fatalf("failed to get client: %v", err)
+	}
+	if !confidential.allowsRedirectUri("https://app.example.com/callback") || confidential.allowsRedirectUri("https://app.example.com/callback/") {
+		t.Errorf("expected only the exact registered redirect uri, got %v", confidential.RedirectUris)
+	}
+	if !confidential.authenticate("secret") || confidential.authenticate("wrong") || confidential.authenticate("") {
+		t.Errorf("expected the confidential client to authenticate with its secret only")
+	}
+
+	public, err := getOAuthClient("public", transaction)
+	if err != nil {
+		t.Fatalf("failed to get client: %v", err)
+	}
+	if !public.authenticate("") || public.authenticate("secret") {
+		t.Errorf("expected the public client to authenticate without a secret")
+	}
+
+	if _, err = getOAuthClient("unknown", transaction); err == nil {
+		t.Errorf("expected unknown clients to be rejected")
+	}
+}
@@ -0,0 +1,240 @@
+package resource
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+// PasswordPolicy are the rules for the passwords users choose, on signup and when changing the password
+// of their account
+type PasswordPolicy struct {
+	MinLength int
+	// bcrypt only reads the first 72 bytes
+	MaxLength     int
+	CheckBreached bool
+	// the number of previous passwords which cannot be used again
+	HistoryCount int
+	breached     map[string]bool
+}
+
+const passwordHistoryColumn = "password_history"
+
+// configIntValue reads an int config and stores the default when it is not set or below min
+func configIntValue(configStore *ConfigStore, key string, defaultValue int, min int) int {
+	value, err := configStore.GetConfigIntValueFor(key, "backend")
+	if err != nil || value < min {
+		value = defaultValue
+		err = configStore.SetConfigIntValueFor(key, value, "backend")
+		CheckErr(err, "Failed to store default [%v]", key)
+	}
+	return value
+}
+
+// NewPasswordPolicy reads the policy from the config. The breached list is bundled, a file with one password
+// per line set as password.breached_list.path is checked as well.
+func NewPasswordPolicy(configStore *ConfigStore) *PasswordPolicy {
+
+	policy := &PasswordPolicy{
+		MinLength:    configIntValue(configStore, "password.length.min", 8, 1),
+		MaxLength:    configIntValue(configStore, "password.length.max", 72, 1),
+		HistoryCount: configIntValue(configStore, "password.history.count", 5, 0),
+		breached:     bundledBreachedPasswords,
+	}
+
+	checkBreached, err := configStore.GetConfigValueFor("password.breached_check", "backend")
+	if err != nil || checkBreached == "" {
+		checkBreached = "true"
+		err = configStore.SetConfigValueFor("password.breached_check", checkBreached, "backend")
+		CheckErr(err, "Failed to store default password breached check")
+	}
+	policy.CheckBreached = checkBreached != "false"
+
+	breachedListPath, _ := configStore.GetConfigValueFor("password.breached_list.path", "backend")
+	if policy.CheckBreached && breachedListPath != "" {
+		policy.breached, err = loadBreachedPasswords(breachedListPath)
+		if err != nil {
+			log.Errorf("Failed to read breached password list [%v]: %v", breachedListPath, err)
+			policy.breached = bundledBreachedPasswords
+		}
+	}
+
+	return policy
+}
+
+func loadBreachedPasswords(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	breached := make(map[string]bool, len(bundledBreachedPasswords))
+	for password := range bundledBreachedPasswords {
+		breached[password] = true
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			breached[strings.ToLower(password)] = true
+		}
+	}
+	return breached, scanner.Err()
+}
+
+// Check validates a new password of the user with the email
+func (p *PasswordPolicy) Check(password string, email string) error {
+	if len(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters", p.MaxLength)
+	}
+	if email != "" && strings.EqualFold(password, email) {
+		return fmt.Errorf("password cannot be the email")
+	}
+	if p.CheckBreached && p.breached[strings.ToLower(password)] {
+		return fmt.Errorf("password is too common, it is in lists of breached passwords")
+	}
+	return nil
+}
+
+// CheckReuse rejects a password matching one of the hashes of the current and previous passwords
+func (p *PasswordPolicy) CheckReuse(password string, hashes []string) error {
+	for i, hash := range hashes {
+		if i > p.HistoryCount {
+			break
+		}
+		if hash != "" && BcryptCheckStringHash(password, hash) {
+			return fmt.Errorf("password was used before, choose a new one")
+		}
+	}
+	return nil
+}
+
+// passwordHashes are the hash of the current password of the user account, followed by the previous ones
+func passwordHashes(referenceId string, transaction *sqlx.Tx) ([]string, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select("password", passwordHistoryColumn).From(USER_ACCOUNT_TABLE_NAME).
+		Where(goqu.Ex{"reference_id": referenceId}).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var current, history *string
+	err = transaction.QueryRowx(query, args...).Scan(&current, &history)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0)
+	if current != nil && *current != "" {
+		hashes = append(hashes, *current)
+	}
+	if history != nil && *history != "" {
+		var previous []string
+		err = json.Unmarshal([]byte(*history), &previous)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password history: %v", err)
+		}
+		hashes = append(hashes, previous...)
+	}
+	return hashes, nil
+}
+
+// checkPasswordPolicy validates a password changed through an update of a user account
+func (dbResource *DbResource) checkPasswordPolicy(data api2go.Api2GoModel, transaction *sqlx.Tx) error {
+	if dbResource.passwordPolicy == nil {
+		return nil
+	}
+	change, ok := data.GetChanges()["password"]
+	if !ok {
+		return nil
+	}
+	password, _ := change.NewValue.(string)
+
+	policy := dbResource.passwordPolicy
+	email, _ := data.GetAttributes()["email"].(string)
+	err := policy.Check(password, email)
+	if err == nil && policy.HistoryCount > 0 {
+		var hashes []string
+		hashes, err = passwordHashes(data.GetID(), transaction)
+		if err == nil {
+			err = policy.CheckReuse(password, hashes)
+		}
+	}
+	if err != nil {
+		return api2go.NewHTTPError(err, err.Error(), 400)
+	}
+	return nil
+}
+
+// checkNewPasswordPolicy validates the password of a user account created through the api, accounts
+// without a password cannot sign in with one
+func (dbResource *DbResource) checkNewPasswordPolicy(data api2go.Api2GoModel) error {
+	if dbResource.passwordPolicy == nil {
+		return nil
+	}
+	password, _ := data.GetAttributes()["password"].(string)
+	if password == "" {
+		return nil
+	}
+	email, _ := data.GetAttributes()["email"].(string)
+	if err := dbResource.passwordPolicy.Check(password, email); err != nil {
+		return api2go.NewHTTPError(err, err.Error(), 400)
+	}
+	return nil
+}
+
+// nextPasswordHistory is the password history after the password of the user account is changed, the
+// replaced hash goes first
+func (dbResource *DbResource) nextPasswordHistory(referenceId string, transaction *sqlx.Tx) (string, error) {
+	if dbResource.passwordPolicy == nil || dbResource.passwordPolicy.HistoryCount < 1 {
+		return "[]", nil
+	}
+	hashes, err := passwordHashes(referenceId, transaction)
+	if err != nil {
+		return "", err
+	}
+	if historyCount := dbResource.passwordPolicy.HistoryCount; len(hashes) > historyCount {
+		hashes = hashes[:historyCount]
+	}
+	history, err := json.Marshal(hashes)
+	return string(history), err
+}
+
+type passwordCheckPerformer struct {
+	policy *PasswordPolicy
+}
+
+func (d *passwordCheckPerformer) Name() string {
+	return "password.check"
+}
+
+// DoAction validates the password chosen on signup against the password policy
+func (d *passwordCheckPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+	password, _ := inFields["password"].(string)
+	email, _ := inFields["email"].(string)
+	if err := d.policy.Check(password, email); err != nil {
+		return nil, []ActionResponse{
+			NewActionResponse("client.notify", NewClientNotification("error", err.Error(), "Failed")),
+		}, []error{err}
+	}
+	return nil, nil, nil
+}
+
+func NewPasswordCheckPerformer(configStore *ConfigStore) (ActionPerformerInterface, error) {
+
+	handler := passwordCheckPerformer{
+		policy: NewPasswordPolicy(configStore),
+	}
+
+	return &handler, nil
+
+}
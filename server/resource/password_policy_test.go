@@ -0,0 +1,74 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+
+	policy := &PasswordPolicy{MinLength: 8, MaxLength: 72, CheckBreached: true, HistoryCount: 2, breached: bundledBreachedPasswords}
+
+	cases := map[string]bool{
+		"short":                   false,
+		"Password1":               false,
+		"QWERTY123":               false,
+		"user@example.com":        false,
+		"correct horse staple":    true,
+		string(make([]byte, 100)): false,
+	}
+	for password, valid := range cases {
+		if err := policy.Check(password, "user@example.com"); (err == nil) != valid {
+			t.Errorf("expected [%v] valid to be %v, got %v", password, valid, err)
+		}
+	}
+
+	policy.CheckBreached = false
+	if err := policy.Check("Password1", ""); err != nil {
+		t.Errorf("expected a common password to be allowed without the breached check: %v", err)
+	}
+
+	hashes := make([]string, 0)
+	for _, password := range []string{"current password", "previous password", "older password", "oldest password"} {
+		hash, err := BcryptHashString(password)
+		if err != nil {
+			t.Fatalf("failed to hash: %v", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	for password, reused := range map[string]bool{"current password": true, "older password": true, "oldest password": false, "new password": false} {
+		if err := policy.CheckReuse(password, hashes); (err != nil) != reused {
+			t.Errorf("expected [%v] reused to be %v, got %v", password, reused, err)
+		}
+	}
+}
+
+func TestCheckNewPasswordPolicy(t *testing.T) {
+
+	userAccount := &DbResource{
+		tableInfo:      &TableInfo{TableName: USER_ACCOUNT_TABLE_NAME},
+		passwordPolicy: &PasswordPolicy{MinLength: 8, MaxLength: 72, CheckBreached: true, breached: bundledBreachedPasswords},
+	}
+
+	cases := map[string]bool{
+		"":                     true,
+		"Password1":            false,
+		"short":                false,
+		"correct horse staple": true,
+	}
+	for password, valid := range cases {
+		data := api2go.NewApi2GoModelWithData(USER_ACCOUNT_TABLE_NAME, nil, 0, nil, map[string]interface{}{
+			"email":    "user@example.com",
+			"password": password,
+		})
+		if err := userAccount.checkNewPasswordPolicy(data); (err == nil) != valid {
+			t.Errorf("expected new account with [%v] valid to be %v, got %v", password, valid, err)
+		}
+	}
+
+	// other tables have no password policy
+	data := api2go.NewApi2GoModelWithData("note", nil, 0, nil, map[string]interface{}{"password": "short"})
+	if err := (&DbResource{tableInfo: &TableInfo{TableName: "note"}}).checkNewPasswordPolicy(data); err != nil {
+		t.Errorf("expected no policy outside of user accounts, got %v", err)
+	}
+}
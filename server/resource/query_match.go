@@ -0,0 +1,149 @@
+package resource
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MatchesQueries checks a row against the query filters in memory, with the same operators as
+// the query parameter of find all. Used for rows which do not come from the database (eg events)
+func MatchesQueries(row map[string]interface{}, queries []Query) bool {
+	for _, query := range queries {
+		if !matchesQuery(row[query.ColumnName], query) {
+			return false
+		}
+	}
+	return true
+}
+
+func queryValueString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if bytes, ok := value.([]byte); ok {
+		return string(bytes)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func queryValueList(value interface{}) []string {
+	switch values := value.(type) {
+	case []string:
+		return values
+	case []interface{}:
+		list := make([]string, 0, len(values))
+		for _, item := range values {
+			list = append(list, queryValueString(item))
+		}
+		return list
+	}
+	list := make([]string, 0)
+	for _, item := range strings.Split(queryValueString(value), ",") {
+		list = append(list, strings.TrimSpace(item))
+	}
+	return list
+}
+
+func queryValueBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case int:
+		return v != 0
+	case float64:
+		return v != 0
+	}
+	s := strings.ToLower(queryValueString(value))
+	return s == "true" || s == "1"
+}
+
+// compareQueryValues compares numerically when both values are numbers, else as strings
+func compareQueryValues(a interface{}, b interface{}) int {
+	aString := queryValueString(a)
+	bString := queryValueString(b)
+	aFloat, aErr := strconv.ParseFloat(aString, 64)
+	bFloat, bErr := strconv.ParseFloat(bString, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case aFloat < bFloat:
+			return -1
+		case aFloat > bFloat:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(aString, bString)
+}
+
+func likePatternMatches(pattern string, value string, caseInsensitive bool) bool {
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.ReplaceAll(expression, "%", ".*")
+	expression = strings.ReplaceAll(expression, "_", ".")
+	if caseInsensitive {
+		expression = "(?i)" + expression
+	}
+	matched, err := regexp.MatchString("^"+expression+"$", value)
+	return err == nil && matched
+}
+
+func listContainsValue(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesQuery(value interface{}, query Query) bool {
+	valueString := queryValueString(value)
+	queryString := queryValueString(query.Value)
+
+	switch query.Operator {
+	case "contains":
+		return strings.Contains(strings.ToLower(valueString), strings.ToLower(strings.Trim(queryString, "%")))
+	case "not contains":
+		return !strings.Contains(strings.ToLower(valueString), strings.ToLower(strings.Trim(queryString, "%")))
+	case "like":
+		return likePatternMatches(queryString, valueString, false)
+	case "not like":
+		return !likePatternMatches(queryString, valueString, false)
+	case "ilike":
+		return likePatternMatches(queryString, valueString, true)
+	case "not ilike":
+		return !likePatternMatches(queryString, valueString, true)
+	case "begins with":
+		return strings.HasPrefix(valueString, strings.TrimRight(queryString, "%"))
+	case "ends with":
+		return strings.HasSuffix(valueString, strings.TrimLeft(queryString, "%"))
+	case "is", "eq", "=":
+		return valueString == queryString
+	case "is not", "neq", "not":
+		return valueString != queryString
+	case "in", "any of":
+		return listContainsValue(queryValueList(query.Value), valueString)
+	case "none of":
+		return !listContainsValue(queryValueList(query.Value), valueString)
+	case "before", "less then", "lt":
+		return compareQueryValues(value, query.Value) < 0
+	case "after", "more then", "gt":
+		return compareQueryValues(value, query.Value) > 0
+	case "lte":
+		return compareQueryValues(value, query.Value) <= 0
+	case "gte":
+		return compareQueryValues(value, query.Value) >= 0
+	case "is empty", "is nil", "is null":
+		return value == nil || valueString == ""
+	case "not empty", "not nil", "not null":
+		return value != nil && valueString != ""
+	case "is true":
+		return queryValueBool(value)
+	case "is false":
+		return !queryValueBool(value)
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package resource
+
+import "testing"
+
+func TestMatchesQueries(t *testing.T) {
+
+	row := map[string]interface{}{
+		"title":     "Buy milk",
+		"priority":  int64(3),
+		"completed": false,
+		"status":    "open",
+		"notes":     nil,
+	}
+
+	cases := []struct {
+		query    Query
+		expected bool
+	}{
+		{Query{ColumnName: "title", Operator: "contains", Value: "milk"}, true},
+		{Query{ColumnName: "title", Operator: "begins with", Value: "Buy"}, true},
+		{Query{ColumnName: "title", Operator: "ilike", Value: "buy%"}, true},
+		{Query{ColumnName: "title", Operator: "like", Value: "buy%"}, false},
+		{Query{ColumnName: "status", Operator: "is", Value: "open"}, true},
+		{Query{ColumnName: "status", Operator: "is not", Value: "open"}, false},
+		{Query{ColumnName: "status", Operator: "any of", Value: "open,closed"}, true},
+		{Query{ColumnName: "status", Operator: "none of", Value: []interface{}{"open"}}, false},
+		{Query{ColumnName: "priority", Operator: "more then", Value: "2"}, true},
+		{Query{ColumnName: "priority", Operator: "less then", Value: "10"}, true},
+		{Query{ColumnName: "completed", Operator: "is false"}, true},
+		{Query{ColumnName: "notes", Operator: "is empty"}, true},
+		{Query{ColumnName: "title", Operator: "unknown", Value: "x"}, false},
+	}
+
+	for _, c := range cases {
+		if MatchesQueries(row, []Query{c.query}) != c.expected {
+			t.Errorf("expected [%v %v %v] to be %v", c.query.ColumnName, c.query.Operator, c.query.Value, c.expected)
+		}
+	}
+
+	if !MatchesQueries(row, nil) {
+		t.Errorf("expected empty query list to match")
+	}
+}
@@ -0,0 +1,485 @@
+package resource
+
+import (
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	fieldtypes "github.com/daptin/daptin/server/columntypes"
+	"github.com/daptin/daptin/server/database"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const relatedParentColumn = "__parent_reference_id"
+const relatedRowNumberColumn = "__row_number"
+
+// RelatedObjectsPage selects the related rows of every parent, newest first
+type RelatedObjectsPage struct {
+	Offset uint64
+	Limit  uint64
+}
+
+// GetRelatedObjects loads the rows related to many parent rows in a single query
+// dbResource is the resource of the related table, parentTable is the table on the other side of the relation
+// for belongs_to and has_one relations from the subject side the parent keys are the foreign key reference ids
+// held by the parent rows, in every other case they are the reference ids of the parent rows
+// the result is keyed by the parent key, foreign keys in the rows are converted to reference ids
+// only the rows the user can read are loaded, and the page is applied to the rows of each parent in the query
+func (dbResource *DbResource) GetRelatedObjects(relation api2go.TableRelation, parentTable string,
+	parentKeys []string, queries []Query, filter string, page RelatedObjectsPage, sessionUser *auth.SessionUser) (map[string][]map[string]interface{}, error) {
+
+	result := make(map[string][]map[string]interface{})
+	if len(parentKeys) == 0 {
+		return result, nil
+	}
+
+	relatedTable := dbResource.model.GetName()
+	isSubject := parentTable == relation.GetSubject()
+
+	if !sessionUser.ApiKeyScope.AllowsTable(relatedTable, "GET") {
+		return result, api2go.NewHTTPError(nil, fmt.Sprintf("api key is not allowed to read [%v]", relatedTable), 403)
+	}
+
+	var queryBuilder *goqu.SelectDataset
+	parentColumn := goqu.I("parent.reference_id")
+	switch relation.GetRelation() {
+	case "belongs_to", "has_one":
+		if isSubject {
+			parentColumn = goqu.I("related.reference_id")
+			queryBuilder = statementbuilder.Squirrel.
+				Select(goqu.I("related.*"), parentColumn.As(relatedParentColumn)).
+				From(goqu.T(relatedTable).As("related")).
+				Where(goqu.Ex{"related.reference_id": parentKeys})
+		} else {
+			queryBuilder = statementbuilder.Squirrel.
+				Select(goqu.I("related.*"), parentColumn.As(relatedParentColumn)).
+				From(goqu.T(relatedTable).As("related")).
+				Join(goqu.T(parentTable).As("parent"), goqu.On(goqu.Ex{
+					"related." + relation.GetObjectName(): goqu.I("parent.id"),
+				})).
+				Where(goqu.Ex{"parent.reference_id": parentKeys})
+		}
+	case "has_many", "has_many_and_belongs_to_many":
+		joinTable := relation.GetJoinTableName()
+		relatedColumn, parentJoinColumn := relation.GetSubjectName(), relation.GetObjectName()
+		if isSubject {
+			relatedColumn, parentJoinColumn = relation.GetObjectName(), relation.GetSubjectName()
+		}
+		queryBuilder = statementbuilder.Squirrel.
+			Select(goqu.I("related.*"), parentColumn.As(relatedParentColumn)).
+			From(goqu.T(relatedTable).As("related")).
+			Join(goqu.T(joinTable).As("relation"), goqu.On(goqu.Ex{
+				"relation." + relatedColumn: goqu.I("related.id"),
+			})).
+			Join(goqu.T(parentTable).As("parent"), goqu.On(goqu.Ex{
+				"relation." + parentJoinColumn: goqu.I("parent.id"),
+			})).
+			Where(goqu.Ex{"parent.reference_id": parentKeys})
+	default:
+		return result, fmt.Errorf("unknown relation [%v]", relation.GetRelation())
+	}
+
+	transaction, err := dbResource.Connection.Beginx()
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		err := RollbackTransaction(transaction)
+		CheckErr(err, "Failed to rollback related objects transaction")
+	}()
+
+	if len(filter) > 0 {
+		queryExpressions := make([]goqu.Expression, 0)
+		restrictedColumns := dbResource.tableInfo.readRestrictedColumns()
+		for _, col := range dbResource.model.GetColumns() {
+			if !restrictedColumns[col.ColumnName] && col.IsIndexed && (col.ColumnType == "name" || col.ColumnType == "label" || col.ColumnType == "email") {
+				queryExpressions = append(queryExpressions, goqu.Ex{
+					"related." + col.ColumnName: goqu.Op{"like": "%" + filter + "%"},
+				})
+			}
+		}
+		if len(queryExpressions) > 0 {
+			queryBuilder = queryBuilder.Where(goqu.Or(queryExpressions...))
+		}
+	}
+	queryBuilder, _ = dbResource.addFilters(queryBuilder, queryBuilder, queries, "related.", transaction)
+	if dbResource.tableInfo.IsSoftDeleteEnabled {
+		queryBuilder = queryBuilder.Where(goqu.I("related." + SoftDeleteColumn).IsNull())
+	}
+	if !IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+		if readCondition, ok := dbResource.readPermissionCondition("related", sessionUser, transaction); ok {
+			queryBuilder = queryBuilder.Where(readCondition)
+		}
+		if policyCondition, ok := dbResource.rowPolicyCondition("read", sessionUser, "related", transaction); ok {
+			queryBuilder = queryBuilder.Where(policyCondition)
+		}
+	}
+	if virtualColumns := virtualColumnSelects(dbResource.tableInfo, "related", nil, false); len(virtualColumns) > 0 {
+		queryBuilder = queryBuilder.SelectAppend(ColumnToInterfaceArray(virtualColumns)...)
+	}
+
+	var responseArray []map[string]interface{}
+	if windowFunctionsSupported(dbResource.Connection) {
+		// the rows of every parent are numbered so that the page is taken from each parent in the same query
+		queryBuilder = queryBuilder.SelectAppend(goqu.L("ROW_NUMBER() OVER (PARTITION BY ? ORDER BY ? DESC, ? DESC)",
+			parentColumn, goqu.I("related.created_at"), goqu.I("related.id")).As(relatedRowNumberColumn))
+		pageQuery := statementbuilder.Squirrel.From(queryBuilder.As("ranked")).
+			Where(goqu.C(relatedRowNumberColumn).Gt(page.Offset))
+		if page.Limit > 0 {
+			pageQuery = pageQuery.Where(goqu.C(relatedRowNumberColumn).Lte(page.Offset + page.Limit))
+		}
+		responseArray, err = queryRelatedRows(pageQuery.Order(goqu.C(relatedParentColumn).Asc(), goqu.C(relatedRowNumberColumn).Asc()), relatedTable, transaction)
+		if err != nil {
+			log.Errorf("Failed to load related [%v] of [%v]: %v", relatedTable, parentTable, err)
+			return result, err
+		}
+	} else {
+		// without window functions the page of each parent is loaded with its own query
+		for _, parentKey := range parentKeys {
+			pageQuery := queryBuilder.Where(parentColumn.Eq(parentKey)).
+				Order(goqu.I("related.created_at").Desc(), goqu.I("related.id").Desc()).
+				Offset(uint(page.Offset))
+			if page.Limit > 0 {
+				pageQuery = pageQuery.Limit(uint(page.Limit))
+			}
+			parentRows, err := queryRelatedRows(pageQuery, relatedTable, transaction)
+			if err != nil {
+				log.Errorf("Failed to load related [%v] of [%v]: %v", relatedTable, parentTable, err)
+				return result, err
+			}
+			responseArray = append(responseArray, parentRows...)
+		}
+	}
+
+	err = dbResource.convertForeignKeysToReferenceIds(responseArray, transaction)
+	if err != nil {
+		return result, err
+	}
+
+	for _, row := range responseArray {
+		parentKey := fmt.Sprintf("%v", row[relatedParentColumn])
+		delete(row, relatedParentColumn)
+		delete(row, relatedRowNumberColumn)
+		EvaluateVirtualColumns(dbResource.tableInfo, row)
+		result[parentKey] = append(result[parentKey], row)
+	}
+
+	return result, nil
+}
+
+func queryRelatedRows(query *goqu.SelectDataset, relatedTable string, transaction *sqlx.Tx) ([]map[string]interface{}, error) {
+	s, v, err := query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := transaction.Queryx(s, v...)
+	if err != nil {
+		return nil, err
+	}
+	responseArray, err := RowsToMap(rows, relatedTable)
+	CheckErr(rows.Close(), "Failed to close related objects rows")
+	return responseArray, err
+}
+
+var windowFunctions struct {
+	once      sync.Once
+	supported bool
+}
+
+// windowFunctionsSupported is false for MySQL before 8.0 and MariaDB before 10.2, they cannot number the rows
+// of each parent. The version is read once.
+func windowFunctionsSupported(db database.DatabaseConnection) bool {
+	windowFunctions.once.Do(func() {
+		windowFunctions.supported = true
+		if db.DriverName() != "mysql" {
+			return
+		}
+		var version string
+		err := db.QueryRowx("select version()").Scan(&version)
+		if err != nil {
+			log.Errorf("Failed to read the database version, related rows are paged without window functions: %v", err)
+			windowFunctions.supported = false
+			return
+		}
+		windowFunctions.supported = mysqlSupportsWindowFunctions(version)
+		if !windowFunctions.supported {
+			log.Warnf("Database version [%v] has no window functions, related rows of each parent are loaded with a query each", version)
+		}
+	})
+	return windowFunctions.supported
+}
+
+// mysqlSupportsWindowFunctions checks a MySQL or MariaDB version string, eg 5.7.33-log or 10.5.9-MariaDB
+func mysqlSupportsWindowFunctions(version string) bool {
+	isMariaDb := strings.Contains(strings.ToLower(version), "mariadb")
+	if isMariaDb {
+		// older clients get the version of MariaDB after a 5.5.5- prefix
+		version = strings.TrimPrefix(version, "5.5.5-")
+	}
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	minor, _ := strconv.Atoi(parts[1])
+	if isMariaDb {
+		return major > 10 || (major == 10 && minor >= 2)
+	}
+	return major >= 8
+}
+
+// readPermissionCondition is the condition for the rows of the table the user can read, the same check as
+// PermissionInstance.CanRead done in the query, so that pages only count readable rows
+func (dbResource *DbResource) readPermissionCondition(tableAlias string, sessionUser *auth.SessionUser, transaction *sqlx.Tx) (exp.Expression, bool) {
+
+	tableName := dbResource.tableInfo.TableName
+	if tableName == "usergroup" || strings.Index(tableName, "_has_") > -1 {
+		return nil, false
+	}
+
+	conditions := []exp.Expression{
+		goqu.L("(? & ?) = ?", goqu.I(tableAlias+".permission"), int64(auth.GuestRead), int64(auth.GuestRead)),
+	}
+	if sessionUser.UserId > 0 {
+		conditions = append(conditions, goqu.And(
+			goqu.I(tableAlias+"."+USER_ACCOUNT_ID_COLUMN).Eq(sessionUser.UserId),
+			goqu.L("(? & ?) = ?", goqu.I(tableAlias+".permission"), int64(auth.UserRead), int64(auth.UserRead)),
+		))
+	}
+
+	if len(sessionUser.Groups) > 0 && dbResource.model.HasMany("usergroup") {
+		groupReferenceIds := make([]string, 0, len(sessionUser.Groups))
+		for _, group := range sessionUser.Groups {
+			groupReferenceIds = append(groupReferenceIds, group.GroupReferenceId)
+		}
+		groupIds, err := GetReferenceIdListToIdListWithTransaction("usergroup", groupReferenceIds, transaction)
+		CheckErr(err, "Failed to fetch group ids")
+		ids := make([]int64, 0, len(groupIds))
+		for _, id := range groupIds {
+			ids = append(ids, id)
+		}
+		if len(ids) > 0 {
+			joinTable := fmt.Sprintf("%s_%s_id_has_usergroup_usergroup_id", tableName, tableName)
+			groupRows := statementbuilder.Squirrel.Select(goqu.L("1")).From(goqu.T(joinTable).As("row_group")).Where(
+				goqu.I("row_group."+tableName+"_id").Eq(goqu.I(tableAlias+".id")),
+				goqu.I("row_group.usergroup_id").In(ids),
+				goqu.L("(? & ?) = ?", goqu.I("row_group.permission"), int64(auth.GroupRead), int64(auth.GroupRead)),
+			)
+			conditions = append(conditions, goqu.L("EXISTS ?", groupRows))
+		}
+	}
+
+	return goqu.Or(conditions...), true
+}
+
+func foreignKeyIntId(value interface{}) (int64, bool) {
+	switch id := value.(type) {
+	case int64:
+		return id, true
+	case int:
+		return int64(id), true
+	case []byte:
+		parsed, err := strconv.ParseInt(string(id), 10, 64)
+		return parsed, err == nil
+	case string:
+		parsed, err := strconv.ParseInt(id, 10, 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+// convertForeignKeysToReferenceIds replaces the integer foreign keys by reference ids with one query per
+// foreign table and parses the datetime columns, like ResultToArrayOfMap does row by row
+func (dbResource *DbResource) convertForeignKeysToReferenceIds(rows []map[string]interface{}, transaction *sqlx.Tx) error {
+
+	idsByNamespace := make(map[string][]int64)
+	for _, column := range dbResource.model.GetColumns() {
+		for _, row := range rows {
+			val := row[column.ColumnName]
+			if val == nil {
+				continue
+			}
+
+			if column.ColumnType == "datetime" {
+				stringVal, ok := val.(string)
+				if ok {
+					parsedValue, _, err := fieldtypes.GetTime(stringVal)
+					if err != nil {
+						parsedValue, _, err = fieldtypes.GetDateTime(stringVal)
+					}
+					if err == nil {
+						row[column.ColumnName] = parsedValue
+					} else {
+						row[column.ColumnName] = nil
+					}
+				}
+			}
+
+			if !column.IsForeignKey || column.ForeignKeyData.DataSource != "self" {
+				continue
+			}
+			id, ok := foreignKeyIntId(val)
+			if !ok {
+				continue
+			}
+			idsByNamespace[column.ForeignKeyData.Namespace] = append(idsByNamespace[column.ForeignKeyData.Namespace], id)
+		}
+	}
+
+	referenceIds := make(map[string]map[int64]string)
+	for namespace, ids := range idsByNamespace {
+		s, v, err := statementbuilder.Squirrel.Select("id", "reference_id").From(namespace).
+			Where(goqu.Ex{"id": ids}).ToSQL()
+		if err != nil {
+			return err
+		}
+		idRows, err := transaction.Queryx(s, v...)
+		if err != nil {
+			return err
+		}
+		idMap := make(map[int64]string)
+		for idRows.Next() {
+			var id int64
+			var referenceId string
+			err = idRows.Scan(&id, &referenceId)
+			if err != nil {
+				break
+			}
+			idMap[id] = referenceId
+		}
+		CheckErr(idRows.Close(), "Failed to close reference id rows")
+		if err != nil {
+			return err
+		}
+		referenceIds[namespace] = idMap
+	}
+
+	for _, column := range dbResource.model.GetColumns() {
+		if !column.IsForeignKey || column.ForeignKeyData.DataSource != "self" {
+			continue
+		}
+		for _, row := range rows {
+			id, ok := foreignKeyIntId(row[column.ColumnName])
+			if !ok {
+				continue
+			}
+			row[column.ColumnName] = referenceIds[column.ForeignKeyData.Namespace][id]
+		}
+	}
+
+	return nil
+}
+
+// GetRowPermissions is GetRowPermission for many rows of one table, the usergroups of all rows
+// are loaded with a single query
+func (dbResource *DbResource) GetRowPermissions(typeName string, rows []map[string]interface{}) []PermissionInstance {
+
+	permissions := make([]PermissionInstance, len(rows))
+	if len(rows) == 0 {
+		return permissions
+	}
+
+	if typeName == "usergroup" || strings.Index(typeName, "_has_") > -1 {
+		for i, row := range rows {
+			permissions[i] = dbResource.GetRowPermission(row)
+		}
+		return permissions
+	}
+
+	groupsByReferenceId := make(map[string][]auth.GroupPermission)
+	if dbResource.Cruds[typeName].model.HasMany("usergroup") {
+		referenceIds := make([]string, 0, len(rows))
+		for _, row := range rows {
+			if referenceId, ok := row["reference_id"].(string); ok {
+				referenceIds = append(referenceIds, referenceId)
+			}
+		}
+		groupsByReferenceId = dbResource.getObjectUserGroupsByReferenceIds(typeName, referenceIds)
+	}
+
+	for i, row := range rows {
+		referenceId, _ := row["reference_id"].(string)
+		var perm PermissionInstance
+		perm.UserId, _ = row[USER_ACCOUNT_ID_COLUMN].(string)
+		perm.UserGroupId = groupsByReferenceId[referenceId]
+		if perm.UserGroupId == nil {
+			perm.UserGroupId = []auth.GroupPermission{}
+		}
+
+		switch rowPermission := row["permission"].(type) {
+		case int64:
+			perm.Permission = auth.AuthPermission(rowPermission)
+		case float64:
+			perm.Permission = auth.AuthPermission(int64(rowPermission))
+		case string:
+			i64, err := strconv.ParseInt(rowPermission, 10, 64)
+			CheckErr(err, "Invalid permission value [%v]", rowPermission)
+			perm.Permission = auth.AuthPermission(i64)
+		default:
+			perm.Permission = dbResource.GetObjectPermissionByReferenceId(typeName, referenceId).Permission
+		}
+		permissions[i] = perm
+	}
+
+	return permissions
+}
+
+func (dbResource *DbResource) getObjectUserGroupsByReferenceIds(objectType string, referenceIds []string) map[string][]auth.GroupPermission {
+
+	groups := make(map[string][]auth.GroupPermission)
+	if len(referenceIds) == 0 {
+		return groups
+	}
+
+	rel := api2go.NewTableRelation(objectType, "has_many_and_belongs_to_many", "usergroup")
+	joinTable := rel.GetJoinTableName()
+
+	s, v, err := statementbuilder.Squirrel.Select(
+		goqu.I(objectType+".reference_id").As("objectreferenceid"),
+		goqu.I("usergroup_id.reference_id").As("groupreferenceid"),
+		goqu.I(joinTable+".reference_id").As("relationreferenceid"),
+		goqu.I(joinTable+".permission").As("permission"),
+	).From(goqu.T(objectType)).
+		Join(goqu.T(joinTable).As(joinTable), goqu.On(goqu.Ex{
+			joinTable + "." + rel.GetSubjectName(): goqu.I(objectType + ".id"),
+		})).
+		Join(goqu.T("usergroup").As(rel.GetObjectName()), goqu.On(goqu.Ex{
+			joinTable + "." + rel.GetObjectName(): goqu.I(rel.GetObjectName() + ".id"),
+		})).
+		Where(goqu.Ex{objectType + ".reference_id": referenceIds}).ToSQL()
+	if err != nil {
+		log.Errorf("Failed to create permission select query: %v", err)
+		return groups
+	}
+
+	rows, err := dbResource.Connection.Queryx(s, v...)
+	if err != nil {
+		log.Errorf("Failed to get object groups of [%v]: %v", objectType, err)
+		return groups
+	}
+	defer func(rows *sqlx.Rows) {
+		err := rows.Close()
+		CheckErr(err, "Failed to close object groups rows")
+	}(rows)
+
+	for rows.Next() {
+		var g auth.GroupPermission
+		err = rows.StructScan(&g)
+		if err != nil {
+			log.Errorf("Failed to scan group permission: %v", err)
+			continue
+		}
+		groups[g.ObjectReferenceId] = append(groups[g.ObjectReferenceId], g)
+	}
+
+	return groups
+}
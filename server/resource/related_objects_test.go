@@ -0,0 +1,100 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestGetRelatedObjects(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.MustExec("create table user_account (id integer primary key, reference_id varchar(64))")
+	db.MustExec("create table usergroup (id integer primary key, reference_id varchar(64), name varchar(64))")
+	db.MustExec("create table user_account_user_account_id_has_usergroup_usergroup_id (id integer primary key, user_account_id int, usergroup_id int, created_at timestamp)")
+	db.MustExec("create table author (id integer primary key, reference_id varchar(64))")
+	db.MustExec("create table post (id integer primary key, reference_id varchar(64), title varchar(64), author_id int, user_account_id int, permission int, created_at timestamp)")
+	db.MustExec("insert into user_account (id, reference_id) values (1, 'u1')")
+	db.MustExec("insert into author (id, reference_id) values (1, 'a1'), (2, 'a2')")
+	// p2 can only be read by its owner u1
+	db.MustExec(`insert into post (reference_id, title, author_id, user_account_id, permission, created_at) values
+		('p1', 'one', 1, null, 2, '2020-01-01'), ('p2', 'two', 1, 1, 256, '2020-01-02'), ('p3', 'three', 1, null, 2, '2020-01-03'),
+		('p4', 'four', 2, null, 2, '2020-01-04'), ('p5', 'five', 2, null, 2, '2020-01-05')`)
+
+	post := &DbResource{
+		model:      api2go.NewApi2GoModel("post", []api2go.ColumnInfo{{ColumnName: "title", ColumnType: "label"}}, 0, nil),
+		tableInfo:  &TableInfo{TableName: "post"},
+		Connection: db,
+	}
+	relation := api2go.NewTableRelation("post", "belongs_to", "author")
+
+	referenceIds := func(rows []map[string]interface{}) []string {
+		ids := make([]string, 0)
+		for _, row := range rows {
+			ids = append(ids, row["reference_id"].(string))
+		}
+		return ids
+	}
+
+	guest := &auth.SessionUser{}
+	windowFunctions.once.Do(func() {})
+	defer func() {
+		windowFunctions.supported = true
+	}()
+	// MySQL before 8.0 pages the related rows of each parent with a query each
+	for _, supported := range []bool{true, false} {
+		windowFunctions.supported = supported
+
+		related, err := post.GetRelatedObjects(relation, "author", []string{"a1", "a2"}, nil, "", RelatedObjectsPage{Limit: 1}, guest)
+		if err != nil {
+			t.Fatalf("failed to get related objects: %v", err)
+		}
+		if ids := referenceIds(related["a1"]); len(ids) != 1 || ids[0] != "p3" {
+			t.Errorf("expected the newest post of a1 with window functions [%v], got %v", supported, ids)
+		}
+		if ids := referenceIds(related["a2"]); len(ids) != 1 || ids[0] != "p5" {
+			t.Errorf("expected the newest post of a2 with window functions [%v], got %v", supported, ids)
+		}
+		if _, ok := related["a1"][0][relatedRowNumberColumn]; ok {
+			t.Errorf("expected the row number not to be returned")
+		}
+
+		related, err = post.GetRelatedObjects(relation, "author", []string{"a1"}, nil, "", RelatedObjectsPage{Offset: 1, Limit: 10}, guest)
+		if err != nil {
+			t.Fatalf("failed to get related objects: %v", err)
+		}
+		if ids := referenceIds(related["a1"]); len(ids) != 1 || ids[0] != "p1" {
+			t.Errorf("expected the second page to skip the post guests cannot read with window functions [%v], got %v", supported, ids)
+		}
+	}
+	windowFunctions.supported = true
+
+	for version, supported := range map[string]bool{"5.7.33-log": false, "8.0.26": true, "10.1.48-MariaDB": false,
+		"10.5.9-MariaDB-1:10.5.9+maria~focal": true, "5.5.5-10.3.29-MariaDB": true} {
+		if mysqlSupportsWindowFunctions(version) != supported {
+			t.Errorf("expected window functions of [%v] to be %v", version, supported)
+		}
+	}
+
+	owner := &auth.SessionUser{UserId: 1, UserReferenceId: "u1"}
+	related, err := post.GetRelatedObjects(relation, "author", []string{"a1"}, nil, "", RelatedObjectsPage{Offset: 1, Limit: 1}, owner)
+	if err != nil {
+		t.Fatalf("failed to get related objects: %v", err)
+	}
+	if ids := referenceIds(related["a1"]); len(ids) != 1 || ids[0] != "p2" {
+		t.Errorf("expected the owner to read their post, got %v", ids)
+	}
+
+	scoped := &auth.SessionUser{ApiKeyScope: &auth.ApiKeyScope{Tables: []string{"author"}, Operations: []string{"read"}}}
+	if _, err = post.GetRelatedObjects(relation, "author", []string{"a1"}, nil, "", RelatedObjectsPage{Limit: 1}, scoped); err == nil {
+		t.Errorf("expected an api key without the post scope to be rejected")
+	}
+}
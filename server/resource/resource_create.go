@@ -75,6 +75,10 @@ func (dbResource *DbResource) CreateWithoutFilter(obj interface{}, req api2go.Re
 			continue
 		}
 
+		if col.ColumnName == SoftDeleteColumn && dbResource.tableInfo.IsSoftDeleteEnabled {
+			continue
+		}
+
 		if col.ColumnName == USER_ACCOUNT_ID_COLUMN && dbResource.model.GetName() != "user_account_user_account_id_has_usergroup_usergroup_id" {
 			continue
 		}
@@ -944,6 +948,10 @@ func (dbResource *DbResource) CreateWithTransaction(obj interface{}, req api2go.
 	if err != nil {
 		return NewResponse(nil, nil, 500, nil), err
 	}
+	err = dbResource.checkWrittenRowPolicy("create", createdResource, req, transaction)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, bf := range dbResource.ms.AfterCreate {
 		//log.Printf("Invoke AfterCreate [%v][%v] on Create Request", bf.String(), dbResource.model.GetName())
@@ -984,27 +992,40 @@ func (dbResource *DbResource) Create(obj interface{}, req api2go.Request) (api2g
 		responseData, err := bf.InterceptBefore(dbResource, &req, []map[string]interface{}{data.Data}, transaction)
 		if err != nil {
 			log.Warnf("Error from BeforeCreate[%v]: %v", bf.String(), err)
-			transaction.Rollback()
+			RollbackTransaction(transaction)
 			return nil, err
 		}
 		if responseData == nil {
-			transaction.Rollback()
+			RollbackTransaction(transaction)
 			return nil, errors.New(fmt.Sprintf("No object to act upon after %v", bf.String()))
 		}
 	}
 
+	// actions check the passwords they take, the signup action runs password.check
+	err = dbResource.checkNewPasswordPolicy(data)
+	if err != nil {
+		RollbackTransaction(transaction)
+		return nil, err
+	}
+
 	createdResource, err := dbResource.CreateWithoutFilter(obj, req, transaction)
 	if err != nil {
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "failed to rollback")
 		return NewResponse(nil, nil, 500, nil), err
 	}
+	err = dbResource.checkWrittenRowPolicy("create", createdResource, req, transaction)
+	if err != nil {
+		rollbackErr := RollbackTransaction(transaction)
+		CheckErr(rollbackErr, "failed to rollback")
+		return nil, err
+	}
 
 	for _, bf := range dbResource.ms.AfterCreate {
 		//log.Printf("Invoke AfterCreate [%v][%v] on Create Request", bf.String(), dbResource.model.GetName())
 		results, err := bf.InterceptAfter(dbResource, &req, []map[string]interface{}{createdResource}, transaction)
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			log.Errorf("Error from AfterCreate[%v] middleware: %v", bf.String(), err)
 			return nil, err
@@ -1015,7 +1036,7 @@ func (dbResource *DbResource) Create(obj interface{}, req api2go.Request) (api2g
 			createdResource = results[0]
 		}
 	}
-	commitErr := transaction.Commit()
+	commitErr := CommitTransaction(transaction)
 	if commitErr != nil {
 		return nil, commitErr
 	}
@@ -66,6 +66,11 @@ func (dbResource *DbResource) DeleteWithoutFilters(id string, req api2go.Request
 		}
 	}
 
+	// rows of soft delete tables are only marked, the relations and files are removed when the row is purged
+	if dbResource.tableInfo.IsSoftDeleteEnabled && !isPurgeRequest(req) {
+		return dbResource.SoftDeleteWithTransaction(id, transaction)
+	}
+
 	parentId := data["id"].(int64)
 	parentReferenceId := data["reference_id"].(string)
 
@@ -460,13 +465,13 @@ func (dbResource *DbResource) Delete(id string, req api2go.Request) (api2go.Resp
 			},
 		}, transaction)
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "Failed to rollback")
 			log.Errorf("Error from BeforeDelete[%v] middleware: %v", bf.String(), err)
 			return nil, err
 		}
 		if r == nil || len(r) == 0 {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "Failed to rollback")
 			return nil, fmt.Errorf("Cannot delete this object [%v][%v]", bf.String(), id)
 		}
@@ -474,7 +479,7 @@ func (dbResource *DbResource) Delete(id string, req api2go.Request) (api2go.Resp
 
 	err = dbResource.DeleteWithoutFilters(id, req, transaction)
 	if err != nil {
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "Failed to rollback")
 		return nil, err
 	}
@@ -487,14 +492,14 @@ func (dbResource *DbResource) Delete(id string, req api2go.Request) (api2go.Resp
 			},
 		}, transaction)
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "Failed to rollback")
 			log.Errorf("Error from AfterDelete middleware: %v", err)
 			return nil, err
 		}
 	}
 
-	commitErr := transaction.Commit()
+	commitErr := CommitTransaction(transaction)
 	CheckErr(commitErr, "Failed to commit")
 
 	return NewResponse(nil, nil, 200, nil), commitErr
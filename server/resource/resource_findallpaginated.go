@@ -300,6 +300,13 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 		sortOrder = []string{"-created_at"}
 	}
 
+	if !isAdmin {
+		err = dbResource.CheckQueryColumnPermissions(queries, req.QueryParams["sort"])
+		if err != nil {
+			return nil, nil, nil, false, err
+		}
+	}
+
 	var filters []string
 
 	if len(req.QueryParams["filter"]) > 0 && len(queries) == 0 {
@@ -421,6 +428,11 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 			sort = sort[1:]
 		}
 
+		if virtualColumn, ok := dbResource.tableInfo.GetVirtualColumnByName(sort); ok && virtualColumn.IsSql() {
+			idQueryCols = append(idQueryCols, virtualColumn.sqlExpression(dbResource.tableInfo, tableModel.GetTableName()).As(sort))
+			continue
+		}
+
 		if strings.Index(sort, "(") == -1 {
 			sort = prefix + sort
 		}
@@ -478,8 +490,12 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 	if len(filters) > 0 {
 
 		colsToAdd := make([]string, 0)
+		restrictedColumns := dbResource.tableInfo.readRestrictedColumns()
 
 		for _, col := range infos {
+			if !isAdmin && restrictedColumns[col.ColumnName] {
+				continue
+			}
 			if col.IsIndexed && (col.ColumnType == "name" || col.ColumnType == "label" || col.ColumnType == "email") {
 				colsToAdd = append(colsToAdd, col.ColumnName)
 			}
@@ -880,25 +896,31 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 			//ord := prefix + so[1:] + " desc"
 			// queryBuilder = queryBuilder.OrderBy(ord)
 			// countQueryBuilder = countQueryBuilder.OrderBy(ord)
-			orders = append(orders, goqu.I(prefix+so[1:]).Desc())
+			orders = append(orders, orderExpression(dbResource.tableInfo, prefix, so[1:]).Desc())
 		} else {
 			if so[0] == '+' {
 				//ord := prefix + so[1:] + " asc"
 				// queryBuilder = queryBuilder.OrderBy(ord)
 				// countQueryBuilder = countQueryBuilder.OrderBy(ord)
-				orders = append(orders, goqu.I(prefix+so[1:]).Asc())
+				orders = append(orders, orderExpression(dbResource.tableInfo, prefix, so[1:]).Asc())
 			} else {
-				ord := prefix + so
 				if strings.ToLower(so) == "rand()" || strings.ToLower(so) == "random()" {
-					ord = so
+					orders = append(orders, goqu.I(so).Asc())
+					continue
 				}
 				// queryBuilder = queryBuilder.OrderBy(ord)
 				// countQueryBuilder = countQueryBuilder.OrderBy(ord)
-				orders = append(orders, goqu.I(ord).Asc())
+				orders = append(orders, orderExpression(dbResource.tableInfo, prefix, so).Asc())
 			}
 		}
 	}
 
+	if dbResource.tableInfo.IsSoftDeleteEnabled && !includeDeleted(req, isAdmin) {
+		notDeleted := goqu.I(tableModel.GetTableName() + "." + SoftDeleteColumn).IsNull()
+		queryBuilder = queryBuilder.Where(notDeleted)
+		countQueryBuilder = countQueryBuilder.Where(notDeleted)
+	}
+
 	if !isAdmin && tableModel.GetTableName() != "usergroup" {
 
 		groupReferenceIds := make([]string, 0)
@@ -945,6 +967,10 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 			tableModel.GetTableName(), tableModel.GetTableName()),
 			queryArgs...))
 
+		if policyCondition, ok := dbResource.rowPolicyCondition("read", sessionUser, tableModel.GetTableName(), transaction); ok {
+			queryBuilder = queryBuilder.Where(policyCondition)
+			countQueryBuilder = countQueryBuilder.Where(policyCondition)
+		}
 	}
 
 	idsListQuery, args, err := queryBuilder.Order(orders...).ToSQL()
@@ -1005,6 +1031,8 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 			}
 		}
 
+		finalCols = append(finalCols, virtualColumnSelects(dbResource.tableInfo, tableModel.GetTableName(), reqFieldMap, hasRequestedFields)...)
+
 		queryBuilder = statementbuilder.Squirrel.Select(ColumnToInterfaceArray(finalCols)...).From(tableModel.GetTableName()).Where(goqu.Ex{
 			idColumn: ids,
 		}).Order(orders...)
@@ -1039,6 +1067,8 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 			}
 		}
 
+		finalCols = append(finalCols, virtualColumnSelects(dbResource.tableInfo, tableModel.GetTableName(), reqFieldMap, hasRequestedFields)...)
+
 		queryBuilder = statementbuilder.Squirrel.Select(ColumnToInterfaceArray(finalCols)...).
 			From(tableModel.GetTableName()).
 			LeftJoin(
@@ -1280,7 +1310,18 @@ func (dbResource *DbResource) addFilters(queryBuilder *goqu.SelectDataset, count
 		colInfo, ok := tableInfo.GetColumnByName(columnName)
 
 		if !ok {
-			log.Printf("warn: invalid column [%v] in query, skipping", columnName)
+			virtualColumn, isVirtual := tableInfo.GetVirtualColumnByName(columnName)
+			if !isVirtual || !virtualColumn.IsSql() {
+				log.Printf("warn: invalid column [%v] in query, skipping", columnName)
+				continue
+			}
+			condition, err := virtualColumnCondition(tableInfo, strings.TrimSuffix(prefix, "."), virtualColumn, filterQuery)
+			if err != nil {
+				log.Printf("warn: %v, skipping", err)
+				continue
+			}
+			queryBuilder = queryBuilder.Where(condition)
+			countQueryBuilder = countQueryBuilder.Where(condition)
 			continue
 		}
 
@@ -1443,7 +1484,7 @@ func (dbResource *DbResource) PaginatedFindAll(req api2go.Request) (totalCount u
 
 		if err != nil {
 			log.Printf("Error from BeforeFindAll middleware [%v]: %v", bf.String(), err)
-			transaction.Rollback()
+			RollbackTransaction(transaction)
 			return 0, NewResponse(nil, err, 400, nil), err
 		}
 	}
@@ -1452,7 +1493,7 @@ func (dbResource *DbResource) PaginatedFindAll(req api2go.Request) (totalCount u
 	start := time.Now()
 	results, includes, pagination, finalResponseIsSingleObject, err := dbResource.PaginatedFindAllWithoutFilters(req, transaction)
 	if err != nil {
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "failed to rollback")
 		return 0, nil, err
 	}
@@ -1469,7 +1510,7 @@ func (dbResource *DbResource) PaginatedFindAll(req api2go.Request) (totalCount u
 
 		if err != nil {
 			//log.Errorf("Error from findall paginated create middleware: %v", err)
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 
 			log.Errorf("Error from AfterFindAll[%v] middleware: %v", bf.String(), err)
@@ -1484,7 +1525,7 @@ func (dbResource *DbResource) PaginatedFindAll(req api2go.Request) (totalCount u
 		for _, include := range includes {
 			include, err = bf.InterceptAfter(dbResource, &req, include, transaction)
 			if err != nil {
-				rollbackErr := transaction.Rollback()
+				rollbackErr := RollbackTransaction(transaction)
 				CheckErr(rollbackErr, "failed to rollback")
 				log.Errorf("Error from AfterFindAll[includes][%v] middleware: %v", bf.String(), err)
 				return 0, nil, err
@@ -1493,7 +1534,7 @@ func (dbResource *DbResource) PaginatedFindAll(req api2go.Request) (totalCount u
 		}
 
 	}
-	commitErr := transaction.Commit()
+	commitErr := CommitTransaction(transaction)
 	if commitErr != nil {
 		CheckErr(commitErr, "Failed to commit")
 		return 0, nil, commitErr
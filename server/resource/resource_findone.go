@@ -48,13 +48,13 @@ func (dbResource *DbResource) FindOne(referenceId string, req api2go.Request) (a
 		log.Tracef("[TIMING] FindOne BeforeFilter[%v]: %v", bf.String(), duration)
 
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "Failed to rollback")
 			log.Errorf("Error from BeforeFindOne[%s][%s] middleware: %v", bf.String(), dbResource.model.GetName(), err)
 			return nil, err
 		}
 		if r == nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "Failed to rollback")
 			return nil, errors.New("Cannot find this object")
 		}
@@ -85,8 +85,11 @@ func (dbResource *DbResource) FindOne(referenceId string, req api2go.Request) (a
 
 	start := time.Now()
 	data, include, err := dbResource.GetSingleRowByReferenceIdWithTransaction(modelName, referenceId, includedRelations, transaction)
+	if err == nil {
+		err = dbResource.checkNotSoftDeleted(data, req, transaction)
+	}
 	if err != nil {
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "Failed to rollback")
 		return nil, err
 	}
@@ -112,7 +115,7 @@ func (dbResource *DbResource) FindOne(referenceId string, req api2go.Request) (a
 					translatedObj, err := dbResource.GetIdToObjectWithTransaction(modelName+"_i18n", data_i18n, transaction)
 					CheckErr(err, "Failed to fetch translated object for [%v][%v][%v]", modelName, lang, data["id"])
 					if err != nil {
-						rollbackErr := transaction.Rollback()
+						rollbackErr := RollbackTransaction(transaction)
 						CheckErr(rollbackErr, "Failed to rollback")
 						return nil, err
 					}
@@ -151,7 +154,7 @@ func (dbResource *DbResource) FindOne(referenceId string, req api2go.Request) (a
 			data = nil
 		}
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "Failed to rollback")
 			log.Errorf("Error from AfterFindOne middleware: %v", err)
 			return nil, err
@@ -159,14 +162,14 @@ func (dbResource *DbResource) FindOne(referenceId string, req api2go.Request) (a
 		include, err = bf.InterceptAfter(dbResource, &req, include, transaction)
 
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "Failed to rollback")
 			log.Errorf("Error from AfterFindOne middleware: %v", err)
 			return nil, err
 		}
 	}
 
-	commitErr := transaction.Commit()
+	commitErr := CommitTransaction(transaction)
 	CheckErr(commitErr, "failed to commit")
 
 	//delete(data, "id")
@@ -257,6 +260,9 @@ func (dbResource *DbResource) FindOneWithTransaction(referenceId string, req api
 
 	start := time.Now()
 	data, include, err := dbResource.GetSingleRowByReferenceIdWithTransaction(modelName, referenceId, includedRelations, transaction)
+	if err == nil {
+		err = dbResource.checkNotSoftDeleted(data, req, transaction)
+	}
 	if err != nil {
 		return nil, err
 	}
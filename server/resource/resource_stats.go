@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/artpar/api2go"
 	uuid "github.com/artpar/go.uuid"
+	"github.com/daptin/daptin/server/auth"
 	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
@@ -31,6 +32,8 @@ type AggregationRequest struct {
 	TimeSample    TimeStamp
 	TimeFrom      string
 	TimeTo        string
+	// user running the aggregation, read restricted columns are only available to administrators
+	User *auth.SessionUser
 }
 
 type AggregateRow struct {
@@ -111,6 +114,25 @@ func ColumnToInterfaceArray(s []column) []interface{} {
 
 func (dbResource *DbResource) DataStats(req AggregationRequest, transaction *sqlx.Tx) (*AggregateData, error) {
 
+	sessionUser := req.User
+	if sessionUser == nil {
+		sessionUser = &auth.SessionUser{}
+	}
+	if !sessionUser.ApiKeyScope.AllowsTable(req.RootEntity, "GET") {
+		return nil, api2go.NewHTTPError(nil, fmt.Sprintf("api key cannot read [%v]", req.RootEntity), 403)
+	}
+	for _, join := range req.Join {
+		if joinTable := strings.Split(join, "@")[0]; !sessionUser.ApiKeyScope.AllowsTable(joinTable, "GET") {
+			return nil, api2go.NewHTTPError(nil, fmt.Sprintf("api key cannot read [%v]", joinTable), 403)
+		}
+	}
+	isAdmin := IsAdminWithTransaction(sessionUser.UserReferenceId, transaction)
+	if !isAdmin {
+		if err := checkAggregateColumnPermissions(dbResource.Cruds, req); err != nil {
+			return nil, err
+		}
+	}
+
 	sort.Strings(req.GroupBy)
 	projections := req.ProjectColumn
 
@@ -191,6 +213,12 @@ func (dbResource *DbResource) DataStats(req AggregationRequest, transaction *sql
 
 		}
 	}
+	// the rows the read policies of the table hide from the user are not aggregated either
+	if rootResource := dbResource.Cruds[req.RootEntity]; !isAdmin && rootResource != nil {
+		if policyCondition, ok := rootResource.rowPolicyCondition("read", sessionUser, req.RootEntity, transaction); ok {
+			whereExpressions = append(whereExpressions, policyCondition)
+		}
+	}
 	builder = builder.Where(whereExpressions...)
 
 	havingExpressions := make([]goqu.Expression, 0)
@@ -304,6 +332,12 @@ func (dbResource *DbResource) DataStats(req AggregationRequest, transaction *sql
 			}
 
 		}
+		// joined rows hidden by the read policies of their table are left out of the join
+		if joinResource := dbResource.Cruds[joinTable]; !isAdmin && joinResource != nil {
+			if policyCondition, ok := joinResource.rowPolicyCondition("read", sessionUser, joinTable, transaction); ok {
+				joinWhereList = append(joinWhereList, policyCondition)
+			}
+		}
 		builder = builder.LeftJoin(goqu.T(joinTable), goqu.On(joinWhereList...))
 
 	}
@@ -106,6 +106,10 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 				continue
 			}
 
+			if col.ColumnName == SoftDeleteColumn && dbResource.tableInfo.IsSoftDeleteEnabled {
+				continue
+			}
+
 			change, ok := allChanges[col.ColumnName]
 			if !ok {
 				continue
@@ -398,6 +402,15 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 
 		}
 
+		if _, ok := allChanges["password"]; ok && dbResource.tableInfo.TableName == USER_ACCOUNT_TABLE_NAME {
+			passwordHistory, err := dbResource.nextPasswordHistory(updateObjectReferenceId, updateTransaction)
+			if err != nil {
+				return nil, err
+			}
+			colsList = append(colsList, passwordHistoryColumn)
+			valsList = append(valsList, passwordHistory)
+		}
+
 		colsList = append(colsList, "updated_at")
 		valsList = append(valsList, time.Now())
 
@@ -1028,6 +1041,18 @@ func (dbResource *DbResource) Update(obj interface{}, req api2go.Request) (api2g
 	if err != nil {
 		return nil, err
 	}
+	err = dbResource.checkColumnUpdatePermission(data, req, transaction)
+	if err == nil {
+		sessionUser, _ := req.PlainRequest.Context().Value("user").(*auth.SessionUser)
+		err = dbResource.checkImpersonatedWrite(sessionUser, data.GetChanges())
+	}
+	if err == nil {
+		err = dbResource.checkPasswordPolicy(data, transaction)
+	}
+	if err != nil {
+		RollbackTransaction(transaction)
+		return nil, err
+	}
 	data.Data["__type"] = dbResource.model.GetName()
 	for _, bf := range dbResource.ms.BeforeUpdate {
 		//log.Printf("Invoke BeforeUpdate [%v][%v] on FindAll Request", bf.String(), dbResource.model.GetName())
@@ -1041,7 +1066,7 @@ func (dbResource *DbResource) Update(obj interface{}, req api2go.Request) (api2g
 			data.GetAllAsAttributes(),
 		}, transaction)
 		if err != nil {
-			transaction.Rollback()
+			RollbackTransaction(transaction)
 			log.Errorf("Error From BeforeUpdate middleware: %v", err)
 			return nil, err
 		}
@@ -1054,10 +1079,16 @@ func (dbResource *DbResource) Update(obj interface{}, req api2go.Request) (api2g
 
 	updatedResource, err := dbResource.UpdateWithoutFilters(obj, req, transaction)
 	if err != nil {
-		rollbackErr := transaction.Rollback()
+		rollbackErr := RollbackTransaction(transaction)
 		CheckErr(rollbackErr, "Failed to rollback")
 		return NewResponse(nil, nil, 500, nil), err
 	}
+	err = dbResource.checkWrittenRowPolicy("update", updatedResource, req, transaction)
+	if err != nil {
+		rollbackErr := RollbackTransaction(transaction)
+		CheckErr(rollbackErr, "Failed to rollback")
+		return nil, err
+	}
 
 	for _, bf := range dbResource.ms.AfterUpdate {
 		//log.Printf("Invoke AfterUpdate [%v][%v] on FindAll Request", bf.String(), dbResource.model.GetName())
@@ -1076,13 +1107,13 @@ func (dbResource *DbResource) Update(obj interface{}, req api2go.Request) (api2g
 		}
 
 		if err != nil {
-			rollbackErr := transaction.Rollback()
+			rollbackErr := RollbackTransaction(transaction)
 			CheckErr(rollbackErr, "failed to rollback")
 			return nil, err
 			log.Errorf("Error from AfterUpdate middleware: %v", err)
 		}
 	}
-	commitErr := transaction.Commit()
+	commitErr := CommitTransaction(transaction)
 	CheckErr(commitErr, "failed to commit")
 	if commitErr != nil {
 		return nil, commitErr
@@ -1102,6 +1133,17 @@ func (dbResource *DbResource) UpdateWithTransaction(obj interface{}, req api2go.
 	}
 	updateRequest = updateRequest.WithContext(req.PlainRequest.Context())
 
+	err := dbResource.checkColumnUpdatePermission(data, req, transaction)
+	if err == nil {
+		sessionUser, _ := req.PlainRequest.Context().Value("user").(*auth.SessionUser)
+		err = dbResource.checkImpersonatedWrite(sessionUser, data.GetChanges())
+	}
+	if err == nil {
+		err = dbResource.checkPasswordPolicy(data, transaction)
+	}
+	if err != nil {
+		return nil, err
+	}
 	data.Data["__type"] = dbResource.model.GetName()
 	for _, bf := range dbResource.ms.BeforeUpdate {
 		//log.Printf("Invoke BeforeUpdate [%v][%v] on FindAll Request", bf.String(), dbResource.model.GetName())
@@ -1129,6 +1171,10 @@ func (dbResource *DbResource) UpdateWithTransaction(obj interface{}, req api2go.
 	if err != nil {
 		return NewResponse(nil, nil, 500, nil), err
 	}
+	err = dbResource.checkWrittenRowPolicy("update", updatedResource, req, transaction)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, bf := range dbResource.ms.AfterUpdate {
 		//log.Printf("Invoke AfterUpdate [%v][%v] on FindAll Request", bf.String(), dbResource.model.GetName())
@@ -0,0 +1,158 @@
+package resource
+
+import (
+	"fmt"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+const rollupRelatedAlias = "rollup_related"
+const rollupJoinAlias = "rollup_join"
+
+// Rollup aggregates the rows related to a row over a to-many relation, eg the number of comments on a post
+// or the total of the lines of an invoice. Relation is the name of the relation in the api, eg comment_id
+// for the comments belonging to a post. Aggregate is one of count, sum, min, max and avg of the Column of
+// the related rows, count without a column counts the rows. Filter limits the related rows which are
+// aggregated, with the same column/operator/value queries as the query parameter of the find all api.
+type Rollup struct {
+	Relation  string
+	Aggregate string
+	Column    string
+	Filter    []Query
+	// set at startup when the related table has soft delete enabled, the deleted rows are not aggregated
+	excludeDeleted bool
+}
+
+var rollupAggregates = map[string]bool{
+	"count": true,
+	"sum":   true,
+	"min":   true,
+	"max":   true,
+	"avg":   true,
+}
+
+func (r Rollup) validate() error {
+	if r.Relation == "" {
+		return fmt.Errorf("relation is required")
+	}
+	aggregate := strings.ToLower(r.Aggregate)
+	if !rollupAggregates[aggregate] {
+		return fmt.Errorf("unknown aggregate [%v]", r.Aggregate)
+	}
+	if aggregate != "count" && r.Column == "" {
+		return fmt.Errorf("aggregate [%v] needs a column", r.Aggregate)
+	}
+	return nil
+}
+
+func (r Rollup) aggregateExpression() exp.Expression {
+	column := goqu.I(rollupRelatedAlias + "." + r.Column)
+	switch strings.ToLower(r.Aggregate) {
+	case "sum":
+		return goqu.COALESCE(goqu.SUM(column), 0)
+	case "min":
+		return goqu.MIN(column)
+	case "max":
+		return goqu.MAX(column)
+	case "avg":
+		return goqu.AVG(column)
+	}
+	if r.Column == "" {
+		return goqu.L("count(*)")
+	}
+	return goqu.COUNT(column)
+}
+
+// relatedRows selects the related rows of the row of tableAlias, and returns the name of the related table.
+// The related rows are the subjects of a belongs_to relation to this table, or the other side of a has_many
+// relation through the join table.
+func (r Rollup) relatedRows(tableInfo *TableInfo, tableAlias string) (*goqu.SelectDataset, string) {
+
+	for _, relation := range tableInfo.Relations {
+
+		switch relation.GetRelation() {
+		case "belongs_to":
+			if relation.GetObject() != tableInfo.TableName || relation.GetSubjectName() != r.Relation {
+				continue
+			}
+			return statementbuilder.Squirrel.From(goqu.T(relation.GetSubject()).As(rollupRelatedAlias)).
+				Where(goqu.Ex{
+					rollupRelatedAlias + "." + relation.GetObjectName(): goqu.I(tableAlias + ".id"),
+				}), relation.GetSubject()
+		case "has_many", "has_many_and_belongs_to_many":
+			relatedTable, relatedColumn, parentColumn := "", "", ""
+			if relation.GetSubject() == tableInfo.TableName && relation.GetObjectName() == r.Relation {
+				relatedTable, relatedColumn, parentColumn = relation.GetObject(), relation.GetObjectName(), relation.GetSubjectName()
+			} else if relation.GetObject() == tableInfo.TableName && relation.GetSubjectName() == r.Relation {
+				relatedTable, relatedColumn, parentColumn = relation.GetSubject(), relation.GetSubjectName(), relation.GetObjectName()
+			} else {
+				continue
+			}
+			return statementbuilder.Squirrel.From(goqu.T(relatedTable).As(rollupRelatedAlias)).
+				Join(goqu.T(relation.GetJoinTableName()).As(rollupJoinAlias), goqu.On(goqu.Ex{
+					rollupJoinAlias + "." + relatedColumn: goqu.I(rollupRelatedAlias + ".id"),
+				})).
+				Where(goqu.Ex{
+					rollupJoinAlias + "." + parentColumn: goqu.I(tableAlias + ".id"),
+				}), relatedTable
+		}
+	}
+	return nil, ""
+}
+
+// subQuery is the sql of the sub query computing the rollup, correlated to the id of the row of tableAlias
+func (r Rollup) subQuery(tableInfo *TableInfo, tableAlias string) (string, error) {
+
+	query, _ := r.relatedRows(tableInfo, tableAlias)
+	if query == nil {
+		return "", fmt.Errorf("no to-many relation [%v] on [%v]", r.Relation, tableInfo.TableName)
+	}
+	if r.excludeDeleted {
+		query = query.Where(goqu.I(rollupRelatedAlias + "." + SoftDeleteColumn).IsNull())
+	}
+
+	for _, filterQuery := range r.Filter {
+		condition, err := queryCondition(goqu.I(rollupRelatedAlias+"."+filterQuery.ColumnName), filterQuery)
+		if err != nil {
+			return "", fmt.Errorf("%v in filter on [%v]", err, filterQuery.ColumnName)
+		}
+		query = query.Where(condition)
+	}
+
+	sql, _, err := query.Select(r.aggregateExpression()).Prepared(false).ToSQL()
+	return sql, err
+}
+
+// checkRollupRelations removes the rollup columns whose relation is not a to-many relation of the table,
+// once all the relations are known
+func checkRollupRelations(config *CmsConfig) {
+	softDeleteTables := make(map[string]bool)
+	for _, table := range config.Tables {
+		softDeleteTables[table.TableName] = table.IsSoftDeleteEnabled
+	}
+	for i, table := range config.Tables {
+		if len(table.VirtualColumns) == 0 {
+			continue
+		}
+		virtualColumns := make([]VirtualColumn, 0, len(table.VirtualColumns))
+		for _, vc := range table.VirtualColumns {
+			if vc.Rollup != nil {
+				query, relatedTable := vc.Rollup.relatedRows(&table, table.TableName)
+				if query == nil {
+					log.Errorf("Removing rollup column [%v][%v]: no to-many relation [%v]", table.TableName, vc.ColumnName, vc.Rollup.Relation)
+					continue
+				}
+				vc.Rollup.excludeDeleted = softDeleteTables[relatedTable]
+				if _, err := vc.Rollup.subQuery(&table, table.TableName); err != nil {
+					log.Errorf("Removing rollup column [%v][%v]: %v", table.TableName, vc.ColumnName, err)
+					continue
+				}
+			}
+			virtualColumns = append(virtualColumns, vc)
+		}
+		config.Tables[i].VirtualColumns = virtualColumns
+	}
+}
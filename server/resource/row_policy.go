@@ -0,0 +1,284 @@
+package resource
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// RowPolicy lets the users it applies to access the rows which match all of its filters. Once a table has a
+// policy for an action, users who are not administrators can only read, create, update or delete the rows
+// matched by one of the policies for that action, on top of the permission of the row.
+// eg users in group sales can read rows where region = user.region
+//
+//	RowPolicies:
+//	- Name: sales by region
+//	  Actions: [read]
+//	  UserGroups: [sales]
+//	  Filters:
+//	  - column: region
+//	    operator: eq
+//	    value: $user.region
+type RowPolicy struct {
+	Name string
+	// read, create, update and delete, all actions when empty
+	Actions []string
+	// names of the usergroups the policy applies to, every user when empty
+	UserGroups []string
+	// the filters of the policy, a value starting with $user. is the column of the user_account of the request
+	Filters []Query
+}
+
+const rowPolicyUserPrefix = "$user."
+
+var rowPolicyMethodActions = map[string]string{
+	"GET":    "read",
+	"POST":   "create",
+	"PUT":    "update",
+	"PATCH":  "update",
+	"DELETE": "delete",
+}
+
+func (rp RowPolicy) appliesTo(action string) bool {
+	if len(rp.Actions) == 0 {
+		return true
+	}
+	for _, policyAction := range rp.Actions {
+		if strings.ToLower(policyAction) == action {
+			return true
+		}
+	}
+	return false
+}
+
+// rowPoliciesFor are the policies of the table for the action
+func (ti *TableInfo) rowPoliciesFor(action string) []RowPolicy {
+	policies := make([]RowPolicy, 0)
+	for _, policy := range ti.RowPolicies {
+		if policy.appliesTo(action) {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// ValidateRowPolicies checks the actions and filters of the policies. A policy which cannot be compiled never
+// matches a row, so a broken policy takes access away instead of giving it.
+func (ti *TableInfo) ValidateRowPolicies() error {
+	for _, policy := range ti.RowPolicies {
+		for _, action := range policy.Actions {
+			switch strings.ToLower(action) {
+			case "read", "create", "update", "delete":
+			default:
+				return fmt.Errorf("row policy [%v] of [%v] has unknown action [%v]", policy.Name, ti.TableName, action)
+			}
+		}
+		if len(policy.Filters) == 0 {
+			return fmt.Errorf("row policy [%v] of [%v] has no filters", policy.Name, ti.TableName)
+		}
+		for _, filter := range policy.Filters {
+			if _, ok := ti.GetColumnByName(filter.ColumnName); ok {
+				continue
+			}
+			if vc, ok := ti.GetVirtualColumnByName(filter.ColumnName); ok && vc.IsSql() {
+				continue
+			}
+			return fmt.Errorf("row policy [%v] of [%v] filters on unknown column [%v]", policy.Name, ti.TableName, filter.ColumnName)
+		}
+	}
+	return nil
+}
+
+// rowPolicyUser resolves the $user.<column> values of the filters, the user_account row is only read when a
+// filter refers to a column other than id and reference_id
+type rowPolicyUser struct {
+	sessionUser *auth.SessionUser
+	account     map[string]interface{}
+}
+
+func (u *rowPolicyUser) value(dbResource *DbResource, columnName string, transaction *sqlx.Tx) interface{} {
+	if u.sessionUser.UserId < 1 {
+		return nil
+	}
+	switch columnName {
+	case "id":
+		return u.sessionUser.UserId
+	case "reference_id":
+		return u.sessionUser.UserReferenceId
+	}
+	if u.account == nil {
+		account, err := dbResource.GetIdToObjectWithTransaction(USER_ACCOUNT_TABLE_NAME, u.sessionUser.UserId, transaction)
+		if err != nil {
+			log.Errorf("Failed to get user account [%v] for row policies: %v", u.sessionUser.UserReferenceId, err)
+			account = make(map[string]interface{})
+		}
+		u.account = account
+	}
+	return u.account[columnName]
+}
+
+// rowPolicyGroups are the names of the groups of the policies which the user is a member of
+func rowPolicyGroups(policies []RowPolicy, sessionUser *auth.SessionUser, transaction *sqlx.Tx) map[string]bool {
+	memberOf := make(map[string]bool)
+	if len(sessionUser.Groups) == 0 {
+		return memberOf
+	}
+
+	names := make([]string, 0)
+	for _, policy := range policies {
+		names = append(names, policy.UserGroups...)
+	}
+	if len(names) == 0 {
+		return memberOf
+	}
+
+	userGroups := make(map[string]bool)
+	for _, group := range sessionUser.Groups {
+		userGroups[group.GroupReferenceId] = true
+	}
+
+	query, args, err := statementbuilder.Squirrel.Select("name", "reference_id").From("usergroup").
+		Where(goqu.Ex{"name": names}).ToSQL()
+	if err != nil {
+		log.Errorf("Failed to build usergroup query for row policies: %v", err)
+		return memberOf
+	}
+	rows, err := transaction.Queryx(query, args...)
+	if err != nil {
+		log.Errorf("Failed to query usergroups for row policies: %v", err)
+		return memberOf
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, referenceId string
+		if err = rows.Scan(&name, &referenceId); err != nil {
+			log.Errorf("Failed to scan usergroup for row policies: %v", err)
+			continue
+		}
+		if userGroups[referenceId] {
+			memberOf[name] = true
+		}
+	}
+	return memberOf
+}
+
+// rowPolicyFilterCondition is the where clause of one filter of a policy on the table named tableAlias
+func (dbResource *DbResource) rowPolicyFilterCondition(tableAlias string, filter Query, user *rowPolicyUser, transaction *sqlx.Tx) (exp.Expression, error) {
+
+	value := filter.Value
+	if name, ok := value.(string); ok && strings.HasPrefix(name, rowPolicyUserPrefix) {
+		value = user.value(dbResource, strings.TrimPrefix(name, rowPolicyUserPrefix), transaction)
+		if value == nil {
+			return nil, fmt.Errorf("user has no value for [%v]", name)
+		}
+	}
+
+	tableInfo := dbResource.tableInfo
+	if vc, ok := tableInfo.GetVirtualColumnByName(filter.ColumnName); ok && vc.IsSql() {
+		return queryCondition(vc.sqlExpression(tableInfo, tableAlias), Query{ColumnName: filter.ColumnName, Operator: filter.Operator, Value: value})
+	}
+
+	colInfo, ok := tableInfo.GetColumnByName(filter.ColumnName)
+	if !ok {
+		return nil, fmt.Errorf("unknown column [%v]", filter.ColumnName)
+	}
+	if referenceId, isString := value.(string); isString && colInfo.IsForeignKey && colInfo.ForeignKeyData.DataSource == "self" {
+		id, err := GetReferenceIdToIdWithTransaction(colInfo.ForeignKeyData.Namespace, referenceId, transaction)
+		if err != nil {
+			return nil, fmt.Errorf("unknown [%v] [%v]", colInfo.ForeignKeyData.Namespace, referenceId)
+		}
+		value = id
+	}
+
+	return queryCondition(goqu.I(tableAlias+"."+colInfo.ColumnName), Query{ColumnName: filter.ColumnName, Operator: filter.Operator, Value: value})
+}
+
+// rowPolicyCondition is the where clause limiting the table named tableAlias to the rows the policies for the
+// action let the user access, ok is false when the table has no policy for the action
+func (dbResource *DbResource) rowPolicyCondition(action string, sessionUser *auth.SessionUser, tableAlias string, transaction *sqlx.Tx) (exp.Expression, bool) {
+
+	policies := dbResource.tableInfo.rowPoliciesFor(action)
+	if len(policies) == 0 {
+		return nil, false
+	}
+
+	memberOf := rowPolicyGroups(policies, sessionUser, transaction)
+	user := &rowPolicyUser{sessionUser: sessionUser}
+
+	conditions := make([]exp.Expression, 0)
+	for _, policy := range policies {
+
+		applies := len(policy.UserGroups) == 0
+		for _, groupName := range policy.UserGroups {
+			applies = applies || memberOf[groupName]
+		}
+		if !applies {
+			continue
+		}
+
+		filterConditions := make([]exp.Expression, 0, len(policy.Filters))
+		for _, filter := range policy.Filters {
+			condition, err := dbResource.rowPolicyFilterCondition(tableAlias, filter, user, transaction)
+			if err != nil {
+				log.Debugf("Row policy [%v] of [%v] does not apply to [%v]: %v", policy.Name, dbResource.tableInfo.TableName, sessionUser.UserReferenceId, err)
+				filterConditions = nil
+				break
+			}
+			filterConditions = append(filterConditions, condition)
+		}
+		if len(filterConditions) > 0 {
+			conditions = append(conditions, goqu.And(filterConditions...))
+		}
+	}
+
+	if len(conditions) == 0 {
+		return goqu.L("1 = 0"), true
+	}
+	return goqu.Or(conditions...), true
+}
+
+// checkRowPolicy returns a 403 when the policies of the table for the action do not allow the user to access
+// the row, administrators are not limited by policies
+func (dbResource *DbResource) checkRowPolicy(action string, referenceId string, sessionUser *auth.SessionUser, transaction *sqlx.Tx) error {
+
+	if len(dbResource.tableInfo.rowPoliciesFor(action)) == 0 || IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+		return nil
+	}
+
+	tableName := dbResource.tableInfo.TableName
+	condition, _ := dbResource.rowPolicyCondition(action, sessionUser, tableName, transaction)
+	query, args, err := statementbuilder.Squirrel.Select(goqu.L("1")).From(tableName).
+		Where(goqu.Ex{tableName + ".reference_id": referenceId}, condition).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	var matched int
+	err = transaction.QueryRowx(query, args...).Scan(&matched)
+	if err == sql.ErrNoRows {
+		return api2go.NewHTTPError(fmt.Errorf(errorMsgFormat, "row", tableName, action, sessionUser.UserReferenceId),
+			"row policy", 403)
+	}
+	return err
+}
+
+// checkWrittenRowPolicy checks that the row created or updated by the request is one the policies for the action
+// let the user write, so a user cannot move a row out of the rows they can access
+func (dbResource *DbResource) checkWrittenRowPolicy(action string, row map[string]interface{}, req api2go.Request, transaction *sqlx.Tx) error {
+	if len(dbResource.tableInfo.rowPoliciesFor(action)) == 0 {
+		return nil
+	}
+	sessionUser := &auth.SessionUser{}
+	if user := req.PlainRequest.Context().Value("user"); user != nil {
+		sessionUser = user.(*auth.SessionUser)
+	}
+	referenceId, _ := row["reference_id"].(string)
+	return dbResource.checkRowPolicy(action, referenceId, sessionUser, transaction)
+}
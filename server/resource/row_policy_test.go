@@ -0,0 +1,167 @@
+package resource
+
+import (
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRowPolicies(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.MustExec("create table usergroup (id integer primary key, reference_id varchar(36), name varchar(100))")
+	db.MustExec("create table deal (id integer primary key, reference_id varchar(36), region varchar(10), owner_id integer)")
+	db.MustExec("insert into usergroup (reference_id, name) values ('g-sales', 'sales'), ('g-support', 'support')")
+	db.MustExec("insert into deal (reference_id, region, owner_id) values ('d1', 'eu', 1), ('d2', 'us', 2), ('d3', 'eu', 2)")
+
+	deal := &TableInfo{
+		TableName: "deal",
+		Columns: []api2go.ColumnInfo{
+			{ColumnName: "region"},
+			{ColumnName: "owner_id"},
+		},
+		RowPolicies: []RowPolicy{
+			{
+				Name:       "sales in europe",
+				Actions:    []string{"read"},
+				UserGroups: []string{"sales"},
+				Filters:    []Query{{ColumnName: "region", Operator: "eq", Value: "eu"}},
+			},
+			{
+				Name:    "own deals",
+				Filters: []Query{{ColumnName: "owner_id", Operator: "eq", Value: "$user.id"}},
+			},
+		},
+	}
+	if err = deal.ValidateRowPolicies(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	invalid := &TableInfo{TableName: "deal", RowPolicies: []RowPolicy{{Name: "x", Actions: []string{"list"}, Filters: deal.RowPolicies[0].Filters}}}
+	if err = invalid.ValidateRowPolicies(); err == nil {
+		t.Errorf("expected an error for an unknown action")
+	}
+	dbResource := &DbResource{tableInfo: deal}
+
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	visible := func(action string, sessionUser *auth.SessionUser) []string {
+		condition, ok := dbResource.rowPolicyCondition(action, sessionUser, "deal", transaction)
+		if !ok {
+			t.Fatalf("expected a policy for [%v]", action)
+		}
+		query, args, err := statementbuilder.Squirrel.Select("reference_id").From("deal").Where(condition).Order(goqu.I("id").Asc()).ToSQL()
+		if err != nil {
+			t.Fatalf("failed to build query: %v", err)
+		}
+		referenceIds := make([]string, 0)
+		if err = transaction.Select(&referenceIds, query, args...); err != nil {
+			t.Fatalf("failed to query [%v]: %v", query, err)
+		}
+		return referenceIds
+	}
+
+	salesUser := &auth.SessionUser{UserId: 2, UserReferenceId: "u2", Groups: []auth.GroupPermission{{GroupReferenceId: "g-sales"}}}
+	// d2 and d3 are their own deals, d1 and d3 are in europe
+	if rows := visible("read", salesUser); len(rows) != 3 {
+		t.Errorf("expected the sales user to read their own and the european deals, got %v", rows)
+	}
+	if rows := visible("update", salesUser); len(rows) != 2 || rows[0] != "d2" || rows[1] != "d3" {
+		t.Errorf("expected the sales user to update only their own deals, got %v", rows)
+	}
+	supportUser := &auth.SessionUser{UserId: 1, UserReferenceId: "u1", Groups: []auth.GroupPermission{{GroupReferenceId: "g-support"}}}
+	if rows := visible("read", supportUser); len(rows) != 1 || rows[0] != "d1" {
+		t.Errorf("expected the support user to read only their own deal, got %v", rows)
+	}
+	if rows := visible("read", &auth.SessionUser{}); len(rows) != 0 {
+		t.Errorf("expected guests to read no deals, got %v", rows)
+	}
+	if _, ok := (&DbResource{tableInfo: &TableInfo{TableName: "deal"}}).rowPolicyCondition("read", salesUser, "deal", transaction); ok {
+		t.Errorf("expected a table without policies not to be limited")
+	}
+
+	if err = dbResource.checkRowPolicy("delete", "d1", supportUser, transaction); err != nil {
+		t.Errorf("expected the support user to delete their own deal: %v", err)
+	}
+	if err = dbResource.checkRowPolicy("delete", "d3", supportUser, transaction); err == nil {
+		t.Errorf("expected the support user not to delete the deal of another user")
+	}
+}
+
+func TestDataStatsRowPolicies(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	directory, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(directory)
+	// the aggregation reads outside of the transaction, the connections share a file
+	db, err := sqlx.Open("sqlite3", filepath.Join(directory, "stats.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.MustExec("create table deal (id integer primary key, reference_id varchar(36), region varchar(10), owner_id integer)")
+	db.MustExec("create table deal_note (id integer primary key, deal_id integer, region varchar(10))")
+	db.MustExec("insert into deal (reference_id, region, owner_id) values ('d1', 'eu', 1), ('d2', 'us', 2), ('d3', 'eu', 2)")
+	db.MustExec("insert into deal_note (deal_id, region) values (1, 'eu'), (2, 'us'), (3, 'us')")
+
+	ownDeals := []RowPolicy{{Name: "own deals", Filters: []Query{{ColumnName: "owner_id", Operator: "eq", Value: "$user.id"}}}}
+	europeanNotes := []RowPolicy{{Name: "european notes", Filters: []Query{{ColumnName: "region", Operator: "eq", Value: "eu"}}}}
+	cruds := map[string]*DbResource{}
+	cruds["deal"] = &DbResource{
+		tableInfo:  &TableInfo{TableName: "deal", Columns: []api2go.ColumnInfo{{ColumnName: "owner_id"}}, RowPolicies: ownDeals},
+		Connection: db,
+		Cruds:      cruds,
+	}
+	cruds["deal_note"] = &DbResource{
+		tableInfo:  &TableInfo{TableName: "deal_note", Columns: []api2go.ColumnInfo{{ColumnName: "region"}}, RowPolicies: europeanNotes},
+		Connection: db,
+		Cruds:      cruds,
+	}
+
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	stats, err := cruds["deal"].DataStats(AggregationRequest{
+		RootEntity:    "deal",
+		ProjectColumn: []string{"count(deal_note.id) as notes"},
+		Join:          []string{"deal_note@eq(deal_note.deal_id,deal.id)"},
+		User:          &auth.SessionUser{UserId: 2, UserReferenceId: "u2"},
+	}, transaction)
+	if err != nil {
+		t.Fatalf("failed to aggregate: %v", err)
+	}
+	// d2 and d3 are their own deals, only the note of d1 is in europe
+	if len(stats.Data) != 1 || fmt.Sprintf("%v", stats.Data[0].Attributes["notes"]) != "0" {
+		t.Errorf("expected no own deals with a european note, got %v", stats.Data)
+	}
+
+	stats, err = cruds["deal"].DataStats(AggregationRequest{
+		RootEntity:    "deal",
+		ProjectColumn: []string{"count"},
+		User:          &auth.SessionUser{UserId: 2, UserReferenceId: "u2"},
+	}, transaction)
+	if err != nil {
+		t.Fatalf("failed to aggregate: %v", err)
+	}
+	if len(stats.Data) != 1 || fmt.Sprintf("%v", stats.Data[0].Attributes["count"]) != "2" {
+		t.Errorf("expected the user to count their own deals, got %v", stats.Data)
+	}
+}
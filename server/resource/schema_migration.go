@@ -0,0 +1,590 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"github.com/alexeyco/simpletable"
+	"github.com/artpar/api2go"
+	uuid "github.com/artpar/go.uuid"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const SchemaMigrationTableName = "schema_migration"
+
+// step types of a migration plan
+const (
+	MigrationCreateTable  = "create_table"
+	MigrationAddColumn    = "add_column"
+	MigrationAlterColumn  = "alter_column"
+	MigrationRenameColumn = "rename_column"
+	MigrationDropColumn   = "drop_column"
+	MigrationCopyTable    = "copy_table"
+	MigrationCreateIndex  = "create_index"
+	MigrationDropIndex    = "drop_index"
+)
+
+// indexes created by CreateIndexes and CreateUniqueConstraints, other indexes are never dropped by a plan
+var generatedIndexName = regexp.MustCompile(`^[iu][0-9a-f]{32}$`)
+
+// SchemaMigrationStep is one change to the database, destructive steps can lose data and
+// are only applied when confirmed
+type SchemaMigrationStep struct {
+	TableName   string   `json:"table_name"`
+	StepType    string   `json:"step_type"`
+	ColumnName  string   `json:"column_name"`
+	Description string   `json:"description"`
+	Statements  []string `json:"statements"`
+	Destructive bool     `json:"destructive"`
+}
+
+type SchemaMigrationPlan struct {
+	Steps []SchemaMigrationStep `json:"steps"`
+}
+
+type liveColumn struct {
+	Name     string
+	DataType string
+	Nullable bool
+}
+
+func (plan SchemaMigrationPlan) IsEmpty() bool {
+	return len(plan.Steps) == 0
+}
+
+func (plan SchemaMigrationPlan) DestructiveSteps() []SchemaMigrationStep {
+	steps := make([]SchemaMigrationStep, 0)
+	for _, step := range plan.Steps {
+		if step.Destructive {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+func (plan SchemaMigrationPlan) String() string {
+
+	if plan.IsEmpty() {
+		return "No schema changes"
+	}
+
+	table := simpletable.New()
+	table.Header = &simpletable.Header{
+		Cells: []*simpletable.Cell{
+			{Text: "Table"},
+			{Text: "Step"},
+			{Text: "Description"},
+			{Text: "Destructive"},
+		},
+	}
+	body := simpletable.Body{
+		Cells: make([][]*simpletable.Cell, 0),
+	}
+	for _, step := range plan.Steps {
+		destructive := ""
+		if step.Destructive {
+			destructive = "yes"
+		}
+		body.Cells = append(body.Cells, []*simpletable.Cell{
+			{Text: step.TableName},
+			{Text: step.StepType},
+			{Text: step.Description},
+			{Text: destructive},
+		})
+	}
+	table.Body = &body
+	return table.String()
+}
+
+// PlanSchemaMigration diffs the tables against the live database, the plan is not applied
+func PlanSchemaMigration(tables []TableInfo, tx *sqlx.Tx) (SchemaMigrationPlan, error) {
+	plan := SchemaMigrationPlan{
+		Steps: make([]SchemaMigrationStep, 0),
+	}
+	for _, table := range tables {
+		if len(table.TableName) < 2 {
+			continue
+		}
+		steps, err := PlanTableMigration(table, tx)
+		if err != nil {
+			return plan, err
+		}
+		plan.Steps = append(plan.Steps, steps...)
+	}
+	return plan, nil
+}
+
+// PlanTableMigration returns the steps which change the live table into the table described by tableInfo
+// columns listed in RenamedColumns are renamed instead of being dropped and added again
+// sqlite cannot alter or drop columns, the table is copied into a new table instead
+func PlanTableMigration(tableInfo TableInfo, tx *sqlx.Tx) ([]SchemaMigrationStep, error) {
+
+	driverName := tx.DriverName()
+	tableName := tableInfo.TableName
+	steps := make([]SchemaMigrationStep, 0)
+
+	desiredColumns := desiredMigrationColumns(tableInfo)
+	desiredIndexes := desiredMigrationIndexes(tableInfo)
+
+	liveColumns, err := getLiveColumns(tableName, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(liveColumns) == 0 {
+		createTable := tableInfo
+		createTable.Columns = desiredColumns
+		steps = append(steps, SchemaMigrationStep{
+			TableName:   tableName,
+			StepType:    MigrationCreateTable,
+			Description: fmt.Sprintf("create table %v with %d columns", tableName, len(desiredColumns)),
+			Statements:  []string{MakeCreateTableQuery(&createTable, driverName)},
+		})
+		return append(steps, indexSteps(tableName, desiredIndexes, map[string]bool{}, driverName)...), nil
+	}
+
+	liveColumnMap := make(map[string]liveColumn)
+	for _, col := range liveColumns {
+		liveColumnMap[col.Name] = col
+	}
+	desiredColumnMap := make(map[string]api2go.ColumnInfo)
+	for _, col := range desiredColumns {
+		desiredColumnMap[col.ColumnName] = col
+	}
+
+	oldNames := make([]string, 0)
+	for oldName := range tableInfo.RenamedColumns {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
+	for _, oldName := range oldNames {
+		newName := tableInfo.RenamedColumns[oldName]
+		live, isLive := liveColumnMap[oldName]
+		_, newIsLive := liveColumnMap[newName]
+		_, isDesired := desiredColumnMap[newName]
+		if !isLive || newIsLive || !isDesired {
+			continue
+		}
+		steps = append(steps, SchemaMigrationStep{
+			TableName:   tableName,
+			StepType:    MigrationRenameColumn,
+			ColumnName:  newName,
+			Description: fmt.Sprintf("rename column %v to %v", oldName, newName),
+			Statements:  []string{fmt.Sprintf("alter table %v rename column %v to %v", tableName, oldName, newName)},
+		})
+		delete(liveColumnMap, oldName)
+		live.Name = newName
+		liveColumnMap[newName] = live
+	}
+
+	addSteps := make([]SchemaMigrationStep, 0)
+	changeSteps := make([]SchemaMigrationStep, 0)
+	copiedColumns := make([]string, 0)
+	for _, col := range desiredColumns {
+		live, isLive := liveColumnMap[col.ColumnName]
+		if !isLive {
+			addSteps = append(addSteps, SchemaMigrationStep{
+				TableName:   tableName,
+				StepType:    MigrationAddColumn,
+				ColumnName:  col.ColumnName,
+				Description: fmt.Sprintf("add column %v %v", col.ColumnName, columnDataType(col)),
+				Statements:  []string{alterTableAddColumn(tableName, &col, driverName)},
+			})
+			continue
+		}
+		copiedColumns = append(copiedColumns, col.ColumnName)
+
+		if col.IsAutoIncrement || col.IsPrimaryKey {
+			continue
+		}
+		wantType := normalizeDataType(columnDataType(col))
+		liveType := normalizeDataType(live.DataType)
+		if wantType == liveType && columnIsNullable(col) == live.Nullable {
+			continue
+		}
+		changeSteps = append(changeSteps, SchemaMigrationStep{
+			TableName:   tableName,
+			StepType:    MigrationAlterColumn,
+			ColumnName:  col.ColumnName,
+			Description: fmt.Sprintf("alter column %v from %v to %v", col.ColumnName, describeLiveColumn(live), getColumnLine(&col, driverName)),
+			Statements:  alterColumnStatements(tableName, col, driverName),
+			Destructive: true,
+		})
+	}
+
+	liveNames := make([]string, 0)
+	for name := range liveColumnMap {
+		liveNames = append(liveNames, name)
+	}
+	sort.Strings(liveNames)
+	for _, name := range liveNames {
+		if _, ok := desiredColumnMap[name]; ok {
+			continue
+		}
+		changeSteps = append(changeSteps, SchemaMigrationStep{
+			TableName:   tableName,
+			StepType:    MigrationDropColumn,
+			ColumnName:  name,
+			Description: fmt.Sprintf("drop column %v, the data in the column is lost", name),
+			Statements:  []string{fmt.Sprintf("alter table %v drop column %v", tableName, name)},
+			Destructive: true,
+		})
+	}
+
+	liveIndexes, err := getLiveIndexes(tableName, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if driverName == "sqlite3" && len(changeSteps) > 0 {
+		// the copied table has all the columns, and the indexes are dropped along with the old table
+		descriptions := make([]string, 0)
+		for _, step := range append(addSteps, changeSteps...) {
+			descriptions = append(descriptions, step.Description)
+		}
+		copyTable := tableInfo
+		copyTable.TableName = tableName + "__migration"
+		copyTable.Columns = desiredColumns
+		columnList := strings.Join(copiedColumns, ", ")
+		steps = append(steps, SchemaMigrationStep{
+			TableName:   tableName,
+			StepType:    MigrationCopyTable,
+			Description: fmt.Sprintf("copy table %v: %v", tableName, strings.Join(descriptions, "; ")),
+			Statements: []string{
+				MakeCreateTableQuery(&copyTable, driverName),
+				fmt.Sprintf("insert into %v (%v) select %v from %v", copyTable.TableName, columnList, columnList, tableName),
+				fmt.Sprintf("drop table %v", tableName),
+				fmt.Sprintf("alter table %v rename to %v", copyTable.TableName, tableName),
+			},
+			Destructive: true,
+		})
+		return append(steps, indexSteps(tableName, desiredIndexes, map[string]bool{}, driverName)...), nil
+	}
+
+	steps = append(steps, addSteps...)
+	steps = append(steps, changeSteps...)
+	steps = append(steps, indexSteps(tableName, desiredIndexes, liveIndexes, driverName)...)
+
+	liveIndexNames := make([]string, 0)
+	for name := range liveIndexes {
+		liveIndexNames = append(liveIndexNames, name)
+	}
+	sort.Strings(liveIndexNames)
+	for _, name := range liveIndexNames {
+		if _, ok := desiredIndexes[name]; ok || !generatedIndexName.MatchString(name) {
+			continue
+		}
+		dropIndex := "drop index " + name
+		if driverName == "mysql" {
+			dropIndex = dropIndex + " on " + tableName
+		}
+		steps = append(steps, SchemaMigrationStep{
+			TableName:   tableName,
+			StepType:    MigrationDropIndex,
+			Description: fmt.Sprintf("drop index %v", name),
+			Statements:  []string{dropIndex},
+		})
+	}
+
+	return steps, nil
+}
+
+// desiredMigrationColumns are the standard columns and the columns of the table, once each
+func desiredMigrationColumns(tableInfo TableInfo) []api2go.ColumnInfo {
+	columns := make([]api2go.ColumnInfo, 0)
+	done := make(map[string]bool)
+	for _, col := range append(append([]api2go.ColumnInfo{}, StandardColumns...), tableInfo.Columns...) {
+		if col.ColumnName == "" {
+			col.ColumnName = SmallSnakeCaseText(col.Name)
+		}
+		if strings.TrimSpace(col.ColumnName) == "" || done[col.ColumnName] {
+			continue
+		}
+		if col.ColumnType == "truefalse" && col.DataType == "" {
+			col.DataType = "bool"
+		}
+		done[col.ColumnName] = true
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+// desiredMigrationIndexes maps the index names to the create index statement, the names are the
+// same as the ones used in CreateIndexes and CreateUniqueConstraints
+func desiredMigrationIndexes(tableInfo TableInfo) map[string]string {
+	indexes := make(map[string]string)
+	foreignKeys := make([]string, 0)
+	for _, col := range desiredMigrationColumns(tableInfo) {
+		if col.IsForeignKey {
+			foreignKeys = append(foreignKeys, col.ColumnName)
+		}
+		if col.IsUnique {
+			indexName := "u" + GetMD5HashString("index_"+tableInfo.TableName+"_"+col.ColumnName+"_unique")
+			indexes[indexName] = "create unique index " + indexName + " on " + tableInfo.TableName + " (" + col.ColumnName + ")"
+		} else if col.IsIndexed {
+			indexName := "i" + GetMD5HashString("index_"+tableInfo.TableName+"_"+col.ColumnName+"_index")
+			indexes[indexName] = "create index " + indexName + " on " + tableInfo.TableName + " (" + col.ColumnName + ")"
+		}
+	}
+	for _, compositeKeyCols := range tableInfo.CompositeKeys {
+		indexName := "i" + GetMD5HashString("index_cl_"+strings.Join(compositeKeyCols, ",")+"_unique")
+		indexes[indexName] = "create unique index " + indexName + " on " + tableInfo.TableName + "(" + strings.Join(compositeKeyCols, ",") + ")"
+	}
+	if strings.Index(tableInfo.TableName, "_has_") > -1 && len(foreignKeys) > 0 {
+		indexName := "i" + GetMD5HashString("index_join_"+tableInfo.TableName+"_"+"_unique")
+		indexes[indexName] = "create unique index " + indexName + " on " + tableInfo.TableName + "(" + strings.Join(foreignKeys, ", ") + ")"
+	}
+	return indexes
+}
+
+func indexSteps(tableName string, desiredIndexes map[string]string, liveIndexes map[string]bool, driverName string) []SchemaMigrationStep {
+	names := make([]string, 0)
+	for name := range desiredIndexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	steps := make([]SchemaMigrationStep, 0)
+	for _, name := range names {
+		if liveIndexes[name] {
+			continue
+		}
+		steps = append(steps, SchemaMigrationStep{
+			TableName:   tableName,
+			StepType:    MigrationCreateIndex,
+			Description: fmt.Sprintf("create index %v", name),
+			Statements:  []string{desiredIndexes[name]},
+		})
+	}
+	return steps
+}
+
+func alterColumnStatements(tableName string, col api2go.ColumnInfo, driverName string) []string {
+	switch driverName {
+	case "postgres":
+		columnLine := strings.Fields(getColumnLine(&col, driverName))
+		statements := []string{
+			fmt.Sprintf("alter table %v alter column %v type %v using %v::%v", tableName, col.ColumnName, columnLine[1], col.ColumnName, columnLine[1]),
+		}
+		if columnIsNullable(col) {
+			statements = append(statements, fmt.Sprintf("alter table %v alter column %v drop not null", tableName, col.ColumnName))
+		} else {
+			statements = append(statements, fmt.Sprintf("alter table %v alter column %v set not null", tableName, col.ColumnName))
+		}
+		return statements
+	default:
+		return []string{fmt.Sprintf("alter table %v modify column %v", tableName, getColumnLine(&col, driverName))}
+	}
+}
+
+func columnDataType(col api2go.ColumnInfo) string {
+	if col.DataType == "" {
+		return "varchar(100)"
+	}
+	return col.DataType
+}
+
+// columnIsNullable is the nullability getColumnLine creates the column with
+func columnIsNullable(col api2go.ColumnInfo) bool {
+	return col.IsNullable || (columnDataType(col) == "timestamp" && col.DefaultValue == "")
+}
+
+func describeLiveColumn(col liveColumn) string {
+	if col.Nullable {
+		return col.DataType + " null"
+	}
+	return col.DataType + " not null"
+}
+
+// normalizeDataType maps the data types of the three databases to a common name so that a column
+// is only altered when the type really changed, varchar keeps the size
+func normalizeDataType(dataType string) string {
+
+	dataType = strings.ToLower(strings.TrimSpace(dataType))
+	base := dataType
+	size := ""
+	if i := strings.Index(dataType, "("); i > -1 {
+		base = strings.TrimSpace(dataType[:i])
+		size = dataType[i+1:]
+		if j := strings.Index(size, ")"); j > -1 {
+			size = size[:j]
+		}
+	}
+
+	switch {
+	case base == "bool" || base == "boolean" || (base == "tinyint" && size == "1"):
+		return "bool"
+	case base == "int" || base == "integer" || base == "bigint" || base == "smallint" || base == "mediumint" ||
+		base == "tinyint" || base == "serial" || base == "bigserial" || base == "int4" || base == "int8" ||
+		strings.HasPrefix(base, "int "):
+		return "int"
+	case base == "float" || base == "double" || base == "real" || base == "decimal" || base == "numeric" ||
+		strings.HasPrefix(base, "double "):
+		return "float"
+	case base == "varchar" || base == "character varying" || base == "char" || base == "character" || base == "nvarchar":
+		if size == "" {
+			return "text"
+		}
+		return "varchar(" + size + ")"
+	case strings.HasSuffix(base, "text") || base == "clob":
+		return "text"
+	case base == "datetime" || strings.HasPrefix(base, "timestamp"):
+		return "timestamp"
+	case strings.HasSuffix(base, "blob") || base == "bytea" || base == "varbinary" || base == "binary" || base == "bit":
+		return "blob"
+	case base == "jsonb":
+		return "json"
+	}
+	return base
+}
+
+// getLiveColumns returns the columns of the table in the database, no columns when the table does not exist
+func getLiveColumns(tableName string, tx *sqlx.Tx) ([]liveColumn, error) {
+
+	columns := make([]liveColumn, 0)
+
+	switch tx.DriverName() {
+	case "sqlite3":
+		rows, err := tx.Queryx(fmt.Sprintf("pragma table_info(%v)", tableName))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var cid, notNull, pk int
+			var name, dataType string
+			var defaultValue interface{}
+			err = rows.Scan(&cid, &name, &dataType, &notNull, &defaultValue, &pk)
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, liveColumn{Name: name, DataType: dataType, Nullable: notNull == 0})
+		}
+		return columns, rows.Err()
+	case "mysql":
+		rows, err := tx.Queryx(`select column_name, column_type, is_nullable from information_schema.columns
+			where table_schema = database() and table_name = ? order by ordinal_position`, tableName)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name, dataType, nullable string
+			err = rows.Scan(&name, &dataType, &nullable)
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, liveColumn{Name: name, DataType: dataType, Nullable: nullable == "YES"})
+		}
+		return columns, rows.Err()
+	case "postgres":
+		rows, err := tx.Queryx(`select column_name, data_type, character_maximum_length, is_nullable from information_schema.columns
+			where table_schema = current_schema() and table_name = $1 order by ordinal_position`, tableName)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name, dataType, nullable string
+			var length *int64
+			err = rows.Scan(&name, &dataType, &length, &nullable)
+			if err != nil {
+				return nil, err
+			}
+			if length != nil {
+				dataType = fmt.Sprintf("%v(%d)", dataType, *length)
+			}
+			columns = append(columns, liveColumn{Name: name, DataType: dataType, Nullable: nullable == "YES"})
+		}
+		return columns, rows.Err()
+	}
+	return nil, fmt.Errorf("schema migrations are not supported on [%v]", tx.DriverName())
+}
+
+// getLiveIndexes returns the names of the indexes on the table
+func getLiveIndexes(tableName string, tx *sqlx.Tx) (map[string]bool, error) {
+
+	var query string
+	switch tx.DriverName() {
+	case "sqlite3":
+		// automatic indexes for unique constraints have no sql
+		query = "select name from sqlite_master where type = 'index' and tbl_name = ? and sql is not null"
+	case "mysql":
+		query = "select distinct index_name from information_schema.statistics where table_schema = database() and table_name = ?"
+	case "postgres":
+		query = "select indexname from pg_indexes where schemaname = current_schema() and tablename = $1"
+	default:
+		return nil, fmt.Errorf("schema migrations are not supported on [%v]", tx.DriverName())
+	}
+
+	indexes := make(map[string]bool)
+	rows, err := tx.Queryx(query, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		indexes[name] = true
+	}
+	return indexes, rows.Err()
+}
+
+// ApplySchemaMigrationPlan executes the steps in order and records each step in the schema_migration table
+// nothing is applied if the plan has destructive steps and confirmDestructive is false
+// mysql commits ddl statements implicitly, so a failed plan can be partially applied there
+func ApplySchemaMigrationPlan(plan SchemaMigrationPlan, confirmDestructive bool, user *auth.SessionUser, tx *sqlx.Tx) error {
+
+	destructiveSteps := plan.DestructiveSteps()
+	if len(destructiveSteps) > 0 && !confirmDestructive {
+		descriptions := make([]string, 0)
+		for _, step := range destructiveSteps {
+			descriptions = append(descriptions, step.TableName+": "+step.Description)
+		}
+		return errors.New("plan has destructive steps which need to be confirmed: " + strings.Join(descriptions, ", "))
+	}
+
+	var userReferenceId interface{}
+	if user != nil && user.UserReferenceId != "" {
+		userReferenceId = user.UserReferenceId
+	}
+
+	for _, step := range plan.Steps {
+		for _, statement := range step.Statements {
+			log.Printf("Migration [%v][%v]: %v", step.TableName, step.StepType, statement)
+			_, err := tx.Exec(statement)
+			if err != nil {
+				return fmt.Errorf("failed to %v: %v", step.Description, err)
+			}
+		}
+
+		statements, err := json.Marshal(step.Statements)
+		if err != nil {
+			return err
+		}
+		newReferenceId, _ := uuid.NewV4()
+		s, v, err := statementbuilder.Squirrel.Insert(SchemaMigrationTableName).
+			Cols("table_name", "step_type", "column_name", "description", "statements", "destructive",
+				"user_reference_id", "reference_id", "permission").
+			Vals([]interface{}{step.TableName, step.StepType, step.ColumnName, step.Description, string(statements),
+				step.Destructive, userReferenceId, newReferenceId.String(), auth.DEFAULT_PERMISSION}).
+			ToSQL()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s, v...)
+		if err != nil {
+			return fmt.Errorf("failed to record migration step [%v]: %v", step.Description, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestNormalizeDataType(t *testing.T) {
+
+	cases := map[string]string{
+		"varchar(100)":                "varchar(100)",
+		"character varying(100)":      "varchar(100)",
+		"int(11)":                     "int",
+		"INTEGER":                     "int",
+		"tinyint(1)":                  "bool",
+		"boolean":                     "bool",
+		"datetime":                    "timestamp",
+		"timestamp without time zone": "timestamp",
+		"longtext":                    "text",
+		"bytea":                       "blob",
+	}
+	for dataType, expected := range cases {
+		if normalized := normalizeDataType(dataType); normalized != expected {
+			t.Errorf("expected [%v] for [%v], got [%v]", expected, dataType, normalized)
+		}
+	}
+}
+
+func TestPlanTableMigration(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	todo := TableInfo{
+		TableName: "todo",
+		Columns: []api2go.ColumnInfo{
+			{ColumnName: "title", DataType: "varchar(100)", ColumnType: "label"},
+			{ColumnName: "notes", DataType: "text", ColumnType: "content", IsNullable: true},
+			{ColumnName: "priority", DataType: "int(4)", ColumnType: "measurement", IsNullable: true},
+		},
+	}
+	var history TableInfo
+	for _, table := range StandardTables {
+		if table.TableName == SchemaMigrationTableName {
+			history = table
+		}
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	plan, err := PlanSchemaMigration([]TableInfo{history, todo}, tx)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	if plan.Steps[0].StepType != MigrationCreateTable || len(plan.DestructiveSteps()) != 0 {
+		t.Errorf("expected the tables to be created: %v", plan.String())
+	}
+	if err = ApplySchemaMigrationPlan(plan, false, nil, tx); err != nil {
+		t.Fatalf("failed to create the tables: %v", err)
+	}
+	_, err = tx.Exec("insert into todo (title, notes, priority, reference_id, permission) values ('one', 'first', 1, 'r1', 0)")
+	if err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	// rename notes, change the size of title and drop priority
+	todo.RenamedColumns = map[string]string{"notes": "description"}
+	todo.Columns = []api2go.ColumnInfo{
+		{ColumnName: "title", DataType: "varchar(200)", ColumnType: "label"},
+		{ColumnName: "description", DataType: "text", ColumnType: "content", IsNullable: true},
+	}
+	plan, err = PlanSchemaMigration([]TableInfo{todo}, tx)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	if len(plan.Steps) < 2 || plan.Steps[0].StepType != MigrationRenameColumn || plan.Steps[1].StepType != MigrationCopyTable {
+		t.Fatalf("expected a rename and a table copy: %v", plan.String())
+	}
+
+	if ApplySchemaMigrationPlan(plan, false, nil, tx) == nil {
+		t.Errorf("expected destructive steps to need confirmation")
+	}
+	if err = ApplySchemaMigrationPlan(plan, true, nil, tx); err != nil {
+		t.Fatalf("failed to apply the plan: %v", err)
+	}
+
+	var description string
+	err = tx.QueryRowx("select description from todo where reference_id = 'r1'").Scan(&description)
+	if err != nil || description != "first" {
+		t.Errorf("expected the renamed column to keep the data, got [%v] %v", description, err)
+	}
+
+	plan, err = PlanSchemaMigration([]TableInfo{todo}, tx)
+	if err != nil || !plan.IsEmpty() {
+		t.Errorf("expected no changes after the migration: %v %v", plan.String(), err)
+	}
+
+	var count int
+	err = tx.QueryRowx("select count(*) from schema_migration where table_name = 'todo'").Scan(&count)
+	if err != nil || count < 3 {
+		t.Errorf("expected the applied steps in the history, got %d %v", count, err)
+	}
+	_ = tx.Rollback()
+}
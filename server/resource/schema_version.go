@@ -0,0 +1,409 @@
+package resource
+
+import (
+	"database/sql"
+	"fmt"
+	uuid "github.com/artpar/go.uuid"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const SchemaVersionTableName = "schema_version"
+
+// the user and the reason of the next restart, the version recorded on startup is attributed to them
+const schemaPendingChangeConfigKey = "schema.pending_change"
+
+// change types of a schema diff
+const (
+	SchemaTableAdded    = "table_added"
+	SchemaTableRemoved  = "table_removed"
+	SchemaTableChanged  = "table_changed"
+	SchemaColumnAdded   = "column_added"
+	SchemaColumnRemoved = "column_removed"
+	SchemaColumnChanged = "column_changed"
+)
+
+// SchemaChange is one difference between two schema versions, before and after are the
+// table or column definitions
+type SchemaChange struct {
+	ChangeType string      `json:"change_type"`
+	TableName  string      `json:"table_name"`
+	ColumnName string      `json:"column_name,omitempty"`
+	Before     interface{} `json:"before,omitempty"`
+	After      interface{} `json:"after,omitempty"`
+}
+
+// SchemaVersion is a snapshot of the user defined tables in the world table
+type SchemaVersion struct {
+	Version         int            `json:"version"`
+	Source          string         `json:"source"`
+	UserReferenceId string         `json:"user_reference_id"`
+	Tables          []TableInfo    `json:"tables"`
+	Diff            []SchemaChange `json:"diff"`
+	CreatedAt       interface{}    `json:"created_at"`
+}
+
+type schemaPendingChange struct {
+	Source          string `json:"source"`
+	UserReferenceId string `json:"user_reference_id"`
+}
+
+// isVersionedTable is false for the system tables, the join tables and the audit tables, they
+// follow the user tables and the daptin version
+func isVersionedTable(tableName string) bool {
+	if strings.Index(tableName, "_has_") > -1 || strings.HasSuffix(tableName, "_audit") ||
+		strings.HasSuffix(tableName, "_i18n") {
+		return false
+	}
+	for _, table := range StandardTables {
+		if table.TableName == tableName {
+			return false
+		}
+	}
+	return true
+}
+
+// GetCurrentSchemaSnapshot returns the user defined tables from the world table, ordered by name
+func GetCurrentSchemaSnapshot(tx sqlx.Queryer) ([]TableInfo, error) {
+
+	s, v, err := statementbuilder.Squirrel.Select("table_name", "world_schema_json").From("world").
+		Order(goqu.C("table_name").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Queryx(s, v...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]TableInfo, 0)
+	for rows.Next() {
+		var tableName, schemaJson string
+		err = rows.Scan(&tableName, &schemaJson)
+		if err != nil {
+			return nil, err
+		}
+		if !isVersionedTable(tableName) {
+			continue
+		}
+		var table TableInfo
+		err = json.Unmarshal([]byte(schemaJson), &table)
+		if err != nil {
+			log.Errorf("Failed to read schema of [%v] for schema version: %v", tableName, err)
+			continue
+		}
+		// the id is assigned by the world table and is not part of the schema
+		table.TableId = 0
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// GetSchemaVersion returns the version, or the latest version when version is 0
+// sql.ErrNoRows is returned when there is no such version
+func GetSchemaVersion(version int, tx sqlx.Queryer) (*SchemaVersion, error) {
+
+	query := statementbuilder.Squirrel.
+		Select("version", "source", "user_reference_id", "schema_json", "diff", "created_at").
+		From(SchemaVersionTableName)
+	if version > 0 {
+		query = query.Where(goqu.Ex{"version": version})
+	} else {
+		query = query.Order(goqu.C("version").Desc()).Limit(1)
+	}
+	s, v, err := query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var schemaVersion SchemaVersion
+	var userReferenceId, diff sql.NullString
+	var schemaJson string
+	err = tx.QueryRowx(s, v...).Scan(&schemaVersion.Version, &schemaVersion.Source, &userReferenceId,
+		&schemaJson, &diff, &schemaVersion.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	schemaVersion.UserReferenceId = userReferenceId.String
+
+	err = json.Unmarshal([]byte(schemaJson), &schemaVersion.Tables)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Valid && diff.String != "" {
+		err = json.Unmarshal([]byte(diff.String), &schemaVersion.Diff)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &schemaVersion, nil
+}
+
+// DiffSchemaSnapshots lists the changes which turn the before tables into the after tables
+func DiffSchemaSnapshots(before []TableInfo, after []TableInfo) []SchemaChange {
+
+	changes := make([]SchemaChange, 0)
+
+	beforeMap := make(map[string]TableInfo)
+	for _, table := range before {
+		beforeMap[table.TableName] = table
+	}
+	afterMap := make(map[string]TableInfo)
+	names := make([]string, 0)
+	for _, table := range after {
+		afterMap[table.TableName] = table
+		names = append(names, table.TableName)
+	}
+	for _, table := range before {
+		if _, ok := afterMap[table.TableName]; !ok {
+			names = append(names, table.TableName)
+		}
+	}
+	sort.Strings(names)
+
+	for _, tableName := range names {
+		beforeTable, wasPresent := beforeMap[tableName]
+		afterTable, isPresent := afterMap[tableName]
+
+		if !wasPresent {
+			changes = append(changes, SchemaChange{ChangeType: SchemaTableAdded, TableName: tableName, After: afterTable})
+			continue
+		}
+		if !isPresent {
+			changes = append(changes, SchemaChange{ChangeType: SchemaTableRemoved, TableName: tableName, Before: beforeTable})
+			continue
+		}
+
+		beforeProperties, afterProperties := beforeTable, afterTable
+		beforeProperties.Columns, afterProperties.Columns = nil, nil
+		if !sameJson(beforeProperties, afterProperties) {
+			changes = append(changes, SchemaChange{ChangeType: SchemaTableChanged, TableName: tableName,
+				Before: beforeProperties, After: afterProperties})
+		}
+
+		beforeColumns := make(map[string]interface{})
+		for _, col := range beforeTable.Columns {
+			beforeColumns[col.ColumnName] = col
+		}
+		afterColumns := make(map[string]bool)
+		for _, col := range afterTable.Columns {
+			afterColumns[col.ColumnName] = true
+			beforeColumn, ok := beforeColumns[col.ColumnName]
+			if !ok {
+				changes = append(changes, SchemaChange{ChangeType: SchemaColumnAdded, TableName: tableName,
+					ColumnName: col.ColumnName, After: col})
+			} else if !sameJson(beforeColumn, col) {
+				changes = append(changes, SchemaChange{ChangeType: SchemaColumnChanged, TableName: tableName,
+					ColumnName: col.ColumnName, Before: beforeColumn, After: col})
+			}
+		}
+		for _, col := range beforeTable.Columns {
+			if !afterColumns[col.ColumnName] {
+				changes = append(changes, SchemaChange{ChangeType: SchemaColumnRemoved, TableName: tableName,
+					ColumnName: col.ColumnName, Before: col})
+			}
+		}
+	}
+	return changes
+}
+
+func sameJson(a interface{}, b interface{}) bool {
+	aJson, err1 := json.Marshal(a)
+	bJson, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(aJson) == string(bJson)
+}
+
+// SetSchemaPendingChange marks the next schema version to be from the source and the user
+func SetSchemaPendingChange(configStore *ConfigStore, source string, user *auth.SessionUser, transaction *sqlx.Tx) error {
+	pending := schemaPendingChange{Source: source}
+	if user != nil {
+		pending.UserReferenceId = user.UserReferenceId
+	}
+	value, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return configStore.SetConfigValueForWithTransaction(schemaPendingChangeConfigKey, string(value), "backend", transaction)
+}
+
+// RecordSchemaVersion stores the current schema as a new version when it is different from the latest version
+// it is called on startup after the world table is updated with the schema files
+func RecordSchemaVersion(db database.DatabaseConnection, configStore *ConfigStore) error {
+
+	pending := schemaPendingChange{Source: "startup"}
+	pendingValue, err := configStore.GetConfigValueFor(schemaPendingChangeConfigKey, "backend")
+	if err == nil {
+		if json.Unmarshal([]byte(pendingValue), &pending) != nil {
+			pending.Source = "startup"
+		}
+		_ = configStore.DeleteConfigValueFor(schemaPendingChangeConfigKey, "backend")
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	tables, err := GetCurrentSchemaSnapshot(tx)
+	if err != nil {
+		RollbackTransaction(tx)
+		return err
+	}
+
+	previousTables := make([]TableInfo, 0)
+	version := 1
+	latest, err := GetSchemaVersion(0, tx)
+	if err == nil {
+		previousTables = latest.Tables
+		version = latest.Version + 1
+	} else if err != sql.ErrNoRows {
+		RollbackTransaction(tx)
+		return err
+	}
+
+	diff := DiffSchemaSnapshots(previousTables, tables)
+	if latest != nil && len(diff) == 0 {
+		return RollbackTransaction(tx)
+	}
+
+	schemaJson, err := json.Marshal(tables)
+	if err != nil {
+		RollbackTransaction(tx)
+		return err
+	}
+	diffJson, err := json.Marshal(diff)
+	if err != nil {
+		RollbackTransaction(tx)
+		return err
+	}
+
+	var userReferenceId interface{}
+	if pending.UserReferenceId != "" {
+		userReferenceId = pending.UserReferenceId
+	}
+
+	newReferenceId, _ := uuid.NewV4()
+	s, v, err := statementbuilder.Squirrel.Insert(SchemaVersionTableName).
+		Cols("version", "source", "user_reference_id", "schema_json", "diff", "reference_id", "permission").
+		Vals([]interface{}{version, pending.Source, userReferenceId, string(schemaJson), string(diffJson),
+			newReferenceId.String(), auth.DEFAULT_PERMISSION}).
+		ToSQL()
+	if err != nil {
+		RollbackTransaction(tx)
+		return err
+	}
+	_, err = tx.Exec(s, v...)
+	if err != nil {
+		RollbackTransaction(tx)
+		return err
+	}
+	log.Printf("Recorded schema version %d from %v with %d changes", version, pending.Source, len(diff))
+	return CommitTransaction(tx)
+}
+
+// PlanSchemaRollback returns the migration which changes the database back to the tables of the version
+// tables created after the version are not dropped, they are returned so that they can be removed separately
+func PlanSchemaRollback(version *SchemaVersion, tx *sqlx.Tx) (SchemaMigrationPlan, []string, error) {
+
+	currentTables, err := GetCurrentSchemaSnapshot(tx)
+	if err != nil {
+		return SchemaMigrationPlan{}, nil, err
+	}
+
+	versionTables := make(map[string]bool)
+	for _, table := range version.Tables {
+		versionTables[table.TableName] = true
+	}
+	currentTableMap := make(map[string]TableInfo)
+	newTables := make([]string, 0)
+	for _, table := range currentTables {
+		currentTableMap[table.TableName] = table
+		if !versionTables[table.TableName] {
+			newTables = append(newTables, table.TableName)
+		}
+	}
+
+	// columns renamed after the version are renamed back instead of being dropped
+	rollbackTables := make([]TableInfo, 0)
+	for _, table := range version.Tables {
+		current, ok := currentTableMap[table.TableName]
+		if ok && len(current.RenamedColumns) > 0 {
+			renames := make(map[string]string)
+			for oldName, newName := range current.RenamedColumns {
+				_, hasOld := table.GetColumnByName(oldName)
+				_, hasNew := table.GetColumnByName(newName)
+				if hasOld && !hasNew {
+					renames[newName] = oldName
+				}
+			}
+			table.RenamedColumns = renames
+		}
+		rollbackTables = append(rollbackTables, table)
+	}
+
+	plan, err := PlanSchemaMigration(rollbackTables, tx)
+	return plan, newTables, err
+}
+
+// RestoreSchemaVersion writes the table definitions of the version back to the world table
+func RestoreSchemaVersion(version *SchemaVersion, tx *sqlx.Tx) error {
+	for _, table := range version.Tables {
+		schemaJson, err := json.Marshal(table)
+		if err != nil {
+			return err
+		}
+		s, v, err := statementbuilder.Squirrel.Update("world").
+			Set(goqu.Record{
+				"world_schema_json": string(schemaJson),
+				"is_top_level":      table.IsTopLevel,
+				"is_hidden":         table.IsHidden,
+				"icon":              table.Icon,
+				"default_order":     table.DefaultOrder,
+			}).Where(goqu.Ex{"table_name": table.TableName}).ToSQL()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s, v...)
+		if err != nil {
+			return fmt.Errorf("failed to restore schema of [%v]: %v", table.TableName, err)
+		}
+	}
+	return nil
+}
+
+// ArchiveUploadedSchemaFiles renames the uploaded schema files so that they are not merged into the
+// world table again on the next start, schema files added by hand are left as they are
+func ArchiveUploadedSchemaFiles() ([]string, error) {
+
+	patterns := []string{"schema_uploaded_*"}
+	if schemaPath, ok := os.LookupEnv("DAPTIN_SCHEMA_FOLDER"); ok && schemaPath != "" {
+		patterns = append(patterns, filepath.Join(schemaPath, "schema_uploaded_*"))
+	}
+
+	archived := make([]string, 0)
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return archived, err
+		}
+		for _, file := range files {
+			archivedName := filepath.Join(filepath.Dir(file), "rolled_back_"+filepath.Base(file))
+			err = os.Rename(file, archivedName)
+			if err != nil {
+				return archived, err
+			}
+			archived = append(archived, file)
+		}
+	}
+	return archived, nil
+}
@@ -0,0 +1,55 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"testing"
+)
+
+func TestDiffSchemaSnapshots(t *testing.T) {
+
+	before := []TableInfo{
+		{
+			TableName: "todo",
+			Columns: []api2go.ColumnInfo{
+				{ColumnName: "title", DataType: "varchar(100)"},
+				{ColumnName: "notes", DataType: "text"},
+			},
+		},
+		{TableName: "project"},
+	}
+	after := []TableInfo{
+		{
+			TableName: "todo",
+			IsHidden:  true,
+			Columns: []api2go.ColumnInfo{
+				{ColumnName: "title", DataType: "varchar(200)"},
+				{ColumnName: "priority", DataType: "int(4)"},
+			},
+		},
+		{TableName: "tag"},
+	}
+
+	expected := []SchemaChange{
+		{ChangeType: SchemaTableRemoved, TableName: "project"},
+		{ChangeType: SchemaTableAdded, TableName: "tag"},
+		{ChangeType: SchemaTableChanged, TableName: "todo"},
+		{ChangeType: SchemaColumnChanged, TableName: "todo", ColumnName: "title"},
+		{ChangeType: SchemaColumnAdded, TableName: "todo", ColumnName: "priority"},
+		{ChangeType: SchemaColumnRemoved, TableName: "todo", ColumnName: "notes"},
+	}
+
+	changes := DiffSchemaSnapshots(before, after)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.ChangeType != expected[i].ChangeType || change.TableName != expected[i].TableName ||
+			change.ColumnName != expected[i].ColumnName {
+			t.Errorf("expected %v at %d, got %v", expected[i], i, change)
+		}
+	}
+
+	if len(DiffSchemaSnapshots(after, after)) != 0 {
+		t.Errorf("expected no changes between the same snapshots")
+	}
+}
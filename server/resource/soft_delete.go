@@ -0,0 +1,160 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// SoftDeleteColumn holds the time a row of a table with soft delete enabled was deleted at, rows with a
+// value are hidden from reads until they are restored or purged
+const SoftDeleteColumn = "deleted_at"
+
+// DefaultSoftDeleteRetentionDays is how long deleted rows are kept when soft_delete.retention_days is not set
+const DefaultSoftDeleteRetentionDays = 30
+
+// number of expired rows purged from a table in one run of the retention task
+const softDeletePurgeBatchSize = 500
+
+var softDeleteColumnInfo = api2go.ColumnInfo{
+	Name:       SoftDeleteColumn,
+	ColumnName: SoftDeleteColumn,
+	DataType:   "timestamp",
+	ColumnType: "datetime",
+	IsIndexed:  true,
+	IsNullable: true,
+}
+
+// checkSoftDeleteColumns adds the deleted_at column to the tables with soft delete enabled
+func checkSoftDeleteColumns(config *CmsConfig) {
+	for i, table := range config.Tables {
+		if !table.IsSoftDeleteEnabled {
+			continue
+		}
+		if _, ok := table.GetColumnByName(SoftDeleteColumn); ok {
+			continue
+		}
+		config.Tables[i].Columns = append(config.Tables[i].Columns, softDeleteColumnInfo)
+	}
+}
+
+// WithPurge marks the request so that the delete removes the row from a soft delete table instead of
+// marking it as deleted, the mark is passed on to the deletes of the related rows
+func WithPurge(req api2go.Request) api2go.Request {
+	purgeRequest := req
+	purgeRequest.PlainRequest = req.PlainRequest.WithContext(context.WithValue(req.PlainRequest.Context(), "purge", true))
+	return purgeRequest
+}
+
+func isPurgeRequest(req api2go.Request) bool {
+	if req.PlainRequest == nil {
+		return false
+	}
+	purge, _ := req.PlainRequest.Context().Value("purge").(bool)
+	return purge
+}
+
+// includeDeleted is true when the soft deleted rows are part of the result of a read, for administrators
+// asking for them with include_deleted=true and for the deletes of a purge
+func includeDeleted(req api2go.Request, isAdmin bool) bool {
+	if isPurgeRequest(req) {
+		return true
+	}
+	values := req.QueryParams["include_deleted"]
+	return isAdmin && len(values) > 0 && queryValueBool(values[0])
+}
+
+func isSoftDeleted(row map[string]interface{}) bool {
+	return row != nil && row[SoftDeleteColumn] != nil
+}
+
+// SoftDeleteWithTransaction marks the row as deleted, the relations and files of the row are kept so
+// that it can be restored
+func (dbResource *DbResource) SoftDeleteWithTransaction(referenceId string, transaction *sqlx.Tx) error {
+
+	query, args, err := statementbuilder.Squirrel.Update(dbResource.tableInfo.TableName).
+		Set(goqu.Record{SoftDeleteColumn: time.Now()}).
+		Where(goqu.Ex{"reference_id": referenceId}).
+		Where(goqu.C(SoftDeleteColumn).IsNull()).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Soft delete sql: %v", query)
+	_, err = transaction.Exec(query, args...)
+	return err
+}
+
+// RestoreWithTransaction clears the deleted mark of a soft deleted row
+func (dbResource *DbResource) RestoreWithTransaction(referenceId string, transaction *sqlx.Tx) error {
+
+	query, args, err := statementbuilder.Squirrel.Update(dbResource.tableInfo.TableName).
+		Set(goqu.Record{SoftDeleteColumn: nil}).
+		Where(goqu.Ex{"reference_id": referenceId}).
+		Where(goqu.C(SoftDeleteColumn).IsNotNull()).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	result, err := transaction.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return fmt.Errorf("no deleted row [%v][%v]", dbResource.tableInfo.TableName, referenceId)
+	}
+	return err
+}
+
+// GetExpiredSoftDeletedReferenceIds returns the reference ids of the rows deleted before the cutoff
+func (dbResource *DbResource) GetExpiredSoftDeletedReferenceIds(cutoff time.Time, limit uint, transaction *sqlx.Tx) ([]string, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select("reference_id").From(dbResource.tableInfo.TableName).
+		Where(goqu.C(SoftDeleteColumn).Lt(cutoff)).
+		Order(goqu.C(SoftDeleteColumn).Asc()).
+		Limit(limit).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := transaction.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		CheckErr(rows.Close(), "Failed to close expired rows")
+	}()
+
+	referenceIds := make([]string, 0)
+	for rows.Next() {
+		var referenceId string
+		err = rows.Scan(&referenceId)
+		if err != nil {
+			return nil, err
+		}
+		referenceIds = append(referenceIds, referenceId)
+	}
+	return referenceIds, rows.Err()
+}
+
+// checkNotSoftDeleted fails for a soft deleted row unless the request includes the deleted rows
+func (dbResource *DbResource) checkNotSoftDeleted(row map[string]interface{}, req api2go.Request, transaction *sqlx.Tx) error {
+	if !dbResource.tableInfo.IsSoftDeleteEnabled || !isSoftDeleted(row) {
+		return nil
+	}
+	sessionUser := &auth.SessionUser{}
+	if user, ok := req.PlainRequest.Context().Value("user").(*auth.SessionUser); ok {
+		sessionUser = user
+	}
+	if includeDeleted(req, IsAdminWithTransaction(sessionUser.UserReferenceId, transaction)) {
+		return nil
+	}
+	return fmt.Errorf("no such entity [%v][%v]", dbResource.tableInfo.TableName, row["reference_id"])
+}
@@ -0,0 +1,175 @@
+package resource
+
+import (
+	"context"
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckSoftDeleteColumns(t *testing.T) {
+
+	config := &CmsConfig{
+		Tables: []TableInfo{
+			{TableName: "document", IsSoftDeleteEnabled: true},
+			{TableName: "note"},
+		},
+	}
+	checkSoftDeleteColumns(config)
+	checkSoftDeleteColumns(config)
+
+	if len(config.Tables[0].Columns) != 1 || config.Tables[0].Columns[0].ColumnName != SoftDeleteColumn {
+		t.Errorf("expected one deleted_at column on document, got %v", config.Tables[0].Columns)
+	}
+	if len(config.Tables[1].Columns) != 0 {
+		t.Errorf("expected no column on note, got %v", config.Tables[1].Columns)
+	}
+}
+
+func TestIncludeDeleted(t *testing.T) {
+
+	httpReq, _ := http.NewRequestWithContext(context.Background(), "GET", "/api/document", nil)
+	req := api2go.Request{
+		PlainRequest: httpReq,
+		QueryParams:  map[string][]string{"include_deleted": {"true"}},
+	}
+
+	if !includeDeleted(req, true) {
+		t.Errorf("expected deleted rows for an administrator asking for them")
+	}
+	if includeDeleted(req, false) {
+		t.Errorf("expected no deleted rows for a user who is not an administrator")
+	}
+	req.QueryParams = map[string][]string{}
+	if includeDeleted(req, true) {
+		t.Errorf("expected no deleted rows without include_deleted")
+	}
+	if !includeDeleted(WithPurge(req), false) {
+		t.Errorf("expected deleted rows for a purge")
+	}
+	if isPurgeRequest(req) {
+		t.Errorf("expected WithPurge to leave the original request unchanged")
+	}
+}
+
+func TestSoftDeleteRestore(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.MustExec("create table document (id integer primary key, reference_id varchar(36), deleted_at timestamp null)")
+	db.MustExec("insert into document (reference_id) values ('a'), ('b')")
+
+	dbResource := &DbResource{tableInfo: &TableInfo{TableName: "document", IsSoftDeleteEnabled: true}}
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	if err = dbResource.SoftDeleteWithTransaction("a", transaction); err != nil {
+		t.Fatalf("failed to soft delete: %v", err)
+	}
+
+	expired, err := dbResource.GetExpiredSoftDeletedReferenceIds(time.Now().Add(time.Minute), softDeletePurgeBatchSize, transaction)
+	if err != nil {
+		t.Fatalf("failed to get expired rows: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != "a" {
+		t.Errorf("expected row a to be expired, got %v", expired)
+	}
+	expired, err = dbResource.GetExpiredSoftDeletedReferenceIds(time.Now().Add(-time.Hour), softDeletePurgeBatchSize, transaction)
+	if err != nil || len(expired) != 0 {
+		t.Errorf("expected no row deleted an hour ago, got %v %v", expired, err)
+	}
+
+	if err = dbResource.RestoreWithTransaction("a", transaction); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	if err = dbResource.RestoreWithTransaction("b", transaction); err == nil {
+		t.Errorf("expected an error restoring a row which is not deleted")
+	}
+
+	var deleted int
+	if err = transaction.Get(&deleted, "select count(*) from document where deleted_at is not null"); err != nil || deleted != 0 {
+		t.Errorf("expected no deleted rows after restore, got %v %v", deleted, err)
+	}
+}
+
+func TestRollupExcludesSoftDeletedRows(t *testing.T) {
+
+	config := &CmsConfig{
+		Tables: []TableInfo{
+			{
+				TableName: "post",
+				Relations: []api2go.TableRelation{api2go.NewTableRelation("comment", "belongs_to", "post")},
+				VirtualColumns: []VirtualColumn{
+					{ColumnName: "comment_count", Rollup: &Rollup{Relation: "comment_id", Aggregate: "count"}},
+				},
+			},
+			{TableName: "comment", IsSoftDeleteEnabled: true},
+		},
+	}
+	checkRollupRelations(config)
+
+	rollup := config.Tables[0].VirtualColumns[0].Rollup
+	if !rollup.excludeDeleted {
+		t.Fatalf("expected the rollup on a soft delete table to exclude the deleted rows")
+	}
+	query, err := rollup.subQuery(&config.Tables[0], "post")
+	if err != nil {
+		t.Fatalf("failed to build the rollup: %v", err)
+	}
+	if !strings.Contains(query, "deleted_at` IS NULL") && !strings.Contains(query, "deleted_at\" IS NULL") {
+		t.Errorf("expected the rollup to filter the deleted rows, got %v", query)
+	}
+}
+
+func TestCheckSoftDeletePermission(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.MustExec("create table document (id integer primary key, reference_id varchar(36), user_account_id int, permission int, deleted_at timestamp null)")
+	// guests can update a but not delete it, b is read only
+	db.MustExec("insert into document (reference_id, permission, deleted_at) values ('a', ?, '2020-01-01'), ('b', ?, '2020-01-01')",
+		int64(auth.GuestRead|auth.GuestUpdate), int64(auth.GuestRead))
+
+	cruds := map[string]*DbResource{}
+	cruds["document"] = &DbResource{
+		model:     api2go.NewApi2GoModel("document", nil, 0, nil),
+		tableInfo: &TableInfo{TableName: "document", IsSoftDeleteEnabled: true},
+		Cruds:     cruds,
+	}
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	guest := Outcome{Attributes: map[string]interface{}{"user": &auth.SessionUser{}}}
+	if err = checkSoftDeletePermission(cruds["document"], "a", guest, "PATCH", transaction); err != nil {
+		t.Errorf("expected a guest to restore a row they can update: %v", err)
+	}
+	if err = checkSoftDeletePermission(cruds["document"], "a", guest, "DELETE", transaction); err == nil {
+		t.Errorf("expected a guest not to purge a row they cannot delete")
+	}
+	if err = checkSoftDeletePermission(cruds["document"], "b", guest, "PATCH", transaction); err == nil {
+		t.Errorf("expected a guest not to restore a read only row")
+	}
+
+	readOnlyKey := Outcome{Attributes: map[string]interface{}{"user": &auth.SessionUser{
+		ApiKeyScope: &auth.ApiKeyScope{Tables: []string{"document"}, Operations: []string{"read"}},
+	}}}
+	if err = checkSoftDeletePermission(cruds["document"], "a", readOnlyKey, "PATCH", transaction); err == nil {
+		t.Errorf("expected a read only api key not to restore rows")
+	}
+}
@@ -91,7 +91,9 @@ func (ati *ActiveTaskInstance) Run() {
 			usergroups := ati.DbResource.GetObjectUserGroupsByWhere(USER_ACCOUNT_TABLE_NAME, "reference_id", refId.(string))
 			sessionUser.UserReferenceId = permission["reference_id"].(string)
 			sessionUser.UserId = permission["id"].(int64)
-			sessionUser.Groups = usergroups
+			parents, err := auth.LoadUserGroupParents(ati.DbResource.Connection)
+			CheckErr(err, "Failed to load usergroup parents")
+			sessionUser.Groups = auth.ExpandUserGroups(usergroups, parents)
 		}
 	}
 
@@ -0,0 +1,66 @@
+package resource
+
+import (
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// transactionState is kept for a transaction from the middlewares until it commits or rolls back
+type transactionState struct {
+	lock         sync.Mutex
+	afterCommit  []func()
+	beforeImages map[string]map[string]interface{}
+}
+
+var transactionStates sync.Map
+
+func stateOfTransaction(transaction *sqlx.Tx) *transactionState {
+	state, _ := transactionStates.LoadOrStore(transaction, &transactionState{})
+	return state.(*transactionState)
+}
+
+// AfterCommit runs the hook once the transaction is committed, the hook is dropped if the transaction rolls
+// back. Use it for side effects which should only happen for committed changes, like webhooks and caches.
+func AfterCommit(transaction *sqlx.Tx, hook func()) {
+	if transaction == nil {
+		hook()
+		return
+	}
+	state := stateOfTransaction(transaction)
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	state.afterCommit = append(state.afterCommit, hook)
+}
+
+// CommitTransaction commits the transaction and runs its after commit hooks
+func CommitTransaction(transaction *sqlx.Tx) error {
+	err := transaction.Commit()
+	state, ok := transactionStates.Load(transaction)
+	if !ok {
+		return err
+	}
+	transactionStates.Delete(transaction)
+	if err != nil {
+		return err
+	}
+	for _, hook := range state.(*transactionState).afterCommit {
+		runAfterCommitHook(hook)
+	}
+	return nil
+}
+
+// RollbackTransaction rolls back the transaction and drops its after commit hooks
+func RollbackTransaction(transaction *sqlx.Tx) error {
+	transactionStates.Delete(transaction)
+	return transaction.Rollback()
+}
+
+func runAfterCommitHook(hook func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("After commit hook failed: %v", r)
+		}
+	}()
+	hook()
+}
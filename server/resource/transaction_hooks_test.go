@@ -0,0 +1,35 @@
+package resource
+
+import (
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestAfterCommit(t *testing.T) {
+
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ran := 0
+	rolledBack := db.MustBegin()
+	AfterCommit(rolledBack, func() { ran++ })
+	if err = RollbackTransaction(rolledBack); err != nil {
+		t.Fatalf("failed to rollback: %v", err)
+	}
+	if _, ok := transactionStates.Load(rolledBack); ok || ran != 0 {
+		t.Errorf("expected the hooks of a rolled back transaction to be dropped")
+	}
+
+	committed := db.MustBegin()
+	AfterCommit(committed, func() { ran++ })
+	if err = CommitTransaction(committed); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if _, ok := transactionStates.Load(committed); ok || ran != 1 {
+		t.Errorf("expected the hook to run once after commit, ran %v times", ran)
+	}
+}
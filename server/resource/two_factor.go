@@ -0,0 +1,218 @@
+package resource
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"fmt"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"strings"
+	"time"
+)
+
+// totp codes follow the defaults of authenticator apps, six digits every thirty seconds
+const totpPeriod = 30
+const totpSkew = 1
+const recoveryCodeCount = 10
+
+var totpOpts = hotp.ValidateOpts{
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// userTotp is the authenticator app enrolled by a user, it guards signin once enabled
+type userTotp struct {
+	Id            int64
+	Secret        string
+	Enabled       bool
+	LastUsedStep  int64
+	RecoveryCodes []string
+}
+
+// getUserTotp returns the totp enrollment of the user, or nil when the user has none
+func getUserTotp(userId int64, encryptionSecret []byte, transaction *sqlx.Tx) (*userTotp, error) {
+
+	query, args, err := statementbuilder.Squirrel.
+		Select("id", "totp_secret", "enabled", "last_used_step", "recovery_codes").
+		From(USER_TOTP_TABLE_NAME).Where(goqu.Ex{USER_ACCOUNT_ID_COLUMN: userId}).Limit(1).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var userTotpRow userTotp
+	var encryptedSecret string
+	var lastUsedStep sql.NullInt64
+	var recoveryCodes sql.NullString
+	err = transaction.QueryRowx(query, args...).Scan(&userTotpRow.Id, &encryptedSecret, &userTotpRow.Enabled, &lastUsedStep, &recoveryCodes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	userTotpRow.Secret, err = Decrypt(encryptionSecret, encryptedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt totp secret: %v", err)
+	}
+	userTotpRow.LastUsedStep = lastUsedStep.Int64
+	if recoveryCodes.String != "" {
+		err = json.Unmarshal([]byte(recoveryCodes.String), &userTotpRow.RecoveryCodes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recovery codes: %v", err)
+		}
+	}
+	return &userTotpRow, nil
+}
+
+// codeStep returns the time step of the totp code, a step at or before the last used one is rejected so a
+// code cannot be replayed
+func (t *userTotp) codeStep(code string, now time.Time) (int64, bool) {
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= t.LastUsedStep {
+			continue
+		}
+		expected, err := hotp.GenerateCodeCustom(t.Secret, uint64(step), totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// useRecoveryCode removes the recovery code from the remaining ones
+func (t *userTotp) useRecoveryCode(code string) bool {
+	hash := auth.HashApiKey(normaliseRecoveryCode(code))
+	for i, recoveryCodeHash := range t.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCodeHash), []byte(hash)) == 1 {
+			t.RecoveryCodes = append(t.RecoveryCodes[:i:i], t.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// verifyCode checks a code from the authenticator app or a recovery code, and stores the used step or the
+// remaining recovery codes
+func (t *userTotp) verifyCode(code string, transaction *sqlx.Tx) (bool, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, nil
+	}
+
+	updates := goqu.Record{}
+	if step, ok := t.codeStep(code, time.Now()); ok {
+		t.LastUsedStep = step
+		updates["last_used_step"] = step
+	} else if t.Enabled && t.useRecoveryCode(code) {
+		recoveryCodes, _ := json.Marshal(t.RecoveryCodes)
+		updates["recovery_codes"] = string(recoveryCodes)
+	} else {
+		return false, nil
+	}
+
+	return true, t.update(updates, transaction)
+}
+
+func (t *userTotp) update(updates goqu.Record, transaction *sqlx.Tx) error {
+	query, args, err := statementbuilder.Squirrel.Update(USER_TOTP_TABLE_NAME).
+		Set(updates).Where(goqu.Ex{"id": t.Id}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Exec(query, args...)
+	return err
+}
+
+// checkSecondFactor returns the responses asking for a code, and the reason of the login event, when the user
+// enabled two factor authentication and the request has no valid code. It returns nil when signin can go ahead.
+// Every signin which starts a session with a password or a one time password goes through it.
+func checkSecondFactor(userAccount map[string]interface{}, code interface{}, encryptionSecret []byte, transaction *sqlx.Tx) ([]ActionResponse, string, error) {
+
+	userId, _ := userAccount["id"].(int64)
+	userTotpRow, err := getUserTotp(userId, encryptionSecret, transaction)
+	if err != nil {
+		return nil, "", err
+	}
+	if userTotpRow == nil || !userTotpRow.Enabled {
+		return nil, "", nil
+	}
+
+	codeString := queryValueString(code)
+	if codeString == "" {
+		return []ActionResponse{
+			NewActionResponse("totp.required", map[string]interface{}{
+				"email": userAccount["email"],
+			}),
+			NewActionResponse("client.notify", NewClientNotification("message", "Enter the code from your authenticator app", "Two factor authentication")),
+		}, loginEventTwoFactorRequired, nil
+	}
+
+	ok, err := userTotpRow.verifyCode(codeString, transaction)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return []ActionResponse{
+			NewActionResponse("client.notify", NewClientNotification("error", "Invalid two factor code", "Failed")),
+		}, loginEventInvalidTwoFactor, nil
+	}
+	return nil, "", nil
+}
+
+// IsTwoFactorEnabled is true when the user with the email has an enabled authenticator app. Basic auth has no
+// way to send a code, so it is refused for these users.
+func (dbResource *DbResource) IsTwoFactorEnabled(email string) (bool, error) {
+	query, args, err := statementbuilder.Squirrel.Select(goqu.COUNT("*")).
+		From(goqu.T(USER_TOTP_TABLE_NAME).As("t")).
+		Join(goqu.T(USER_ACCOUNT_TABLE_NAME).As("u"), goqu.On(goqu.Ex{"t." + USER_ACCOUNT_ID_COLUMN: goqu.I("u.id")})).
+		Where(goqu.Ex{"u.email": email, "t.enabled": true}).ToSQL()
+	if err != nil {
+		return false, err
+	}
+	var count int
+	err = dbResource.Connection.Get(&count, query, args...)
+	return count > 0, err
+}
+
+// deleteUserTotp removes the totp enrollment of the user, signin then only needs the password
+func deleteUserTotp(userId int64, transaction *sqlx.Tx) error {
+	query, args, err := statementbuilder.Squirrel.Delete(USER_TOTP_TABLE_NAME).
+		Where(goqu.Ex{USER_ACCOUNT_ID_COLUMN: userId}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Exec(query, args...)
+	return err
+}
+
+// generateRecoveryCodes returns the recovery codes to show to the user once, and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		bytes := make([]byte, 10)
+		_, err := rand.Read(bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(bytes))
+		code = code[:8] + "-" + code[8:]
+		codes = append(codes, code)
+		hashes = append(hashes, auth.HashApiKey(normaliseRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+func normaliseRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
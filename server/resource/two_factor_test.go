@@ -0,0 +1,90 @@
+package resource
+
+import (
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pquerna/otp/hotp"
+	"testing"
+	"time"
+)
+
+func TestUserTotp(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.MustExec("create table user_totp (id integer primary key, totp_secret varchar(200), enabled bool, last_used_step int, recovery_codes text, user_account_id integer)")
+
+	encryptionSecret := []byte("0123456789abcdef0123456789abcdef")
+	secret := "JBSWY3DPEHPK3PXP"
+	encryptedSecret, err := Encrypt(encryptionSecret, secret)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("failed to generate recovery codes: %v", err)
+	}
+	recoveryCodes, _ := json.Marshal(hashes)
+	db.MustExec("insert into user_totp (totp_secret, enabled, recovery_codes, user_account_id) values (?, 1, ?, 7)", encryptedSecret, string(recoveryCodes))
+
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	if userTotpRow, err := getUserTotp(8, encryptionSecret, transaction); err != nil || userTotpRow != nil {
+		t.Fatalf("expected no enrollment for another user, got %v, %v", userTotpRow, err)
+	}
+	userTotpRow, err := getUserTotp(7, encryptionSecret, transaction)
+	if err != nil || userTotpRow == nil {
+		t.Fatalf("failed to get enrollment: %v", err)
+	}
+	if userTotpRow.Secret != secret || !userTotpRow.Enabled || len(userTotpRow.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("unexpected enrollment %v", userTotpRow)
+	}
+
+	code, _ := hotp.GenerateCodeCustom(secret, uint64(time.Now().Unix()/totpPeriod), totpOpts)
+	if ok, err := userTotpRow.verifyCode("000000"+code, transaction); ok || err != nil {
+		t.Errorf("expected an invalid code to be rejected")
+	}
+	if ok, err := userTotpRow.verifyCode(code, transaction); !ok || err != nil {
+		t.Fatalf("expected the current code to be accepted: %v", err)
+	}
+	if ok, _ := userTotpRow.verifyCode(code, transaction); ok {
+		t.Errorf("expected a used code not to be accepted again")
+	}
+
+	// recovery codes are accepted once, with or without the dash
+	if ok, err := userTotpRow.verifyCode(normaliseRecoveryCode(codes[3]), transaction); !ok || err != nil {
+		t.Fatalf("expected a recovery code to be accepted: %v", err)
+	}
+	if ok, _ := userTotpRow.verifyCode(codes[3], transaction); ok {
+		t.Errorf("expected a used recovery code not to be accepted again")
+	}
+
+	stored, err := getUserTotp(7, encryptionSecret, transaction)
+	if err != nil {
+		t.Fatalf("failed to get enrollment: %v", err)
+	}
+	if stored.LastUsedStep == 0 || len(stored.RecoveryCodes) != recoveryCodeCount-1 {
+		t.Errorf("expected the used step and recovery code to be stored, got %v", stored)
+	}
+
+	responses, reason, err := checkSecondFactor(map[string]interface{}{"id": int64(7)}, nil, encryptionSecret, transaction)
+	if err != nil || responses == nil || reason != loginEventTwoFactorRequired {
+		t.Errorf("expected a session without a code to ask for it, got %v, %v", reason, err)
+	}
+	if responses, _, err = checkSecondFactor(map[string]interface{}{"id": int64(8)}, nil, encryptionSecret, transaction); err != nil || responses != nil {
+		t.Errorf("expected users without two factor authentication to go ahead, got %v, %v", responses, err)
+	}
+
+	if err = deleteUserTotp(7, transaction); err != nil {
+		t.Fatalf("failed to delete enrollment: %v", err)
+	}
+	if stored, _ = getUserTotp(7, encryptionSecret, transaction); stored != nil {
+		t.Errorf("expected the enrollment to be deleted")
+	}
+}
@@ -0,0 +1,370 @@
+package resource
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/artpar/go.uuid"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/jwt"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultAccessTokenLifeTimeMinutes is used when jwt.access_token.life.minutes is not set
+const DefaultAccessTokenLifeTimeMinutes = 15
+
+// ActionClient is the client which sent an action request, recorded on the sessions it starts
+type ActionClient struct {
+	IpAddress string
+	UserAgent string
+}
+
+// ErrRefreshTokenReused is returned for a refresh token which was already exchanged, the session is revoked
+// since either the client or someone who copied the token is replaying it
+var ErrRefreshTokenReused = errors.New("refresh token was already used, the session has been revoked")
+
+// SessionTokenIssuer signs the access tokens and rotates the refresh tokens of user sessions. Access tokens
+// live for jwt.access_token.life.minutes and sessions for jwt.token.life.hours.
+type SessionTokenIssuer struct {
+	secret               []byte
+	algorithm            string
+	issuer               string
+	accessTokenLifeTime  time.Duration
+	refreshTokenLifeTime time.Duration
+}
+
+// MigrateAccessTokenLifeTime keeps the access token life time of installations from before sessions, where
+// jwt.token.life.hours was the life time of the token. Clients which only keep the token would otherwise be
+// signed out after a few minutes. New installations get the short default.
+func MigrateAccessTokenLifeTime(configStore *ConfigStore) {
+
+	if _, err := configStore.GetConfigIntValueFor("jwt.access_token.life.minutes", "backend"); err == nil {
+		return
+	}
+	tokenLifeTimeHours, err := configStore.GetConfigIntValueFor("jwt.token.life.hours", "backend")
+	if err != nil || tokenLifeTimeHours < 1 {
+		return
+	}
+
+	err = configStore.SetConfigIntValueFor("jwt.access_token.life.minutes", tokenLifeTimeHours*60, "backend")
+	CheckErr(err, "Failed to store access token life time")
+	log.Warnf("Access tokens live for jwt.token.life.hours (%d hours) as before sessions, lower "+
+		"jwt.access_token.life.minutes once the clients use refresh tokens", tokenLifeTimeHours)
+}
+
+func NewSessionTokenIssuer(configStore *ConfigStore) *SessionTokenIssuer {
+
+	secret, _ := configStore.GetConfigValueFor("jwt.secret", "backend")
+
+	tokenLifeTimeHours, err := configStore.GetConfigIntValueFor("jwt.token.life.hours", "backend")
+	CheckErr(err, "No default jwt token life time set in configuration")
+	if err != nil {
+		err = configStore.SetConfigIntValueFor("jwt.token.life.hours", 24*3, "backend")
+		CheckErr(err, "Failed to store default jwt token life time")
+		tokenLifeTimeHours = 24 * 3 // 3 days
+	}
+
+	accessTokenLifeTimeMinutes, err := configStore.GetConfigIntValueFor("jwt.access_token.life.minutes", "backend")
+	if err != nil {
+		err = configStore.SetConfigIntValueFor("jwt.access_token.life.minutes", DefaultAccessTokenLifeTimeMinutes, "backend")
+		CheckErr(err, "Failed to store default access token life time")
+		accessTokenLifeTimeMinutes = DefaultAccessTokenLifeTimeMinutes
+	}
+
+	algorithm, err := configStore.GetConfigValueFor("jwt.signing.algorithm", "backend")
+	if err != nil || algorithm == "" {
+		algorithm = DefaultJwtSigningAlgorithm
+	}
+
+	jwtTokenIssuer, err := configStore.GetConfigValueFor("jwt.token.issuer", "backend")
+	CheckErr(err, "No default jwt token issuer set")
+	if err != nil {
+		uid, _ := uuid.NewV4()
+		jwtTokenIssuer = "daptin-" + uid.String()[0:6]
+		err = configStore.SetConfigValueFor("jwt.token.issuer", jwtTokenIssuer, "backend")
+		CheckErr(err, "Failed to store jwt token issuer")
+	}
+
+	return &SessionTokenIssuer{
+		secret:               []byte(secret),
+		algorithm:            strings.ToUpper(algorithm),
+		issuer:               jwtTokenIssuer,
+		accessTokenLifeTime:  time.Duration(accessTokenLifeTimeMinutes) * time.Minute,
+		refreshTokenLifeTime: time.Duration(tokenLifeTimeHours) * time.Hour,
+	}
+}
+
+// AccessToken signs a short lived token for the user, sid ties it to the session so that it can be revoked
+func (s *SessionTokenIssuer) AccessToken(email string, name string, userReferenceId string, sessionReferenceId string) (string, error) {
+	u, _ := uuid.NewV4()
+	timeNow := time.Now()
+	return s.Sign(jwt.MapClaims{
+		"email": email,
+		"sub":   userReferenceId,
+		"name":  name,
+		"sid":   sessionReferenceId,
+		"nbf":   timeNow.Unix(),
+		"exp":   timeNow.Add(s.accessTokenLifeTime).Unix(),
+		"iss":   s.issuer,
+		"iat":   timeNow.Unix(),
+		"jti":   u.String(),
+	})
+}
+
+// Sign signs the claims with the current signing key, the kid header names the key in the jwks. Tokens are
+// signed with jwt.secret when the algorithm is HS256, or when there is no signing key yet while tokens signed
+// with the secret are still accepted.
+func (s *SessionTokenIssuer) Sign(claims jwt.MapClaims) (string, error) {
+	if s.algorithm != jwt.SigningMethodHS256.Alg() {
+		if key := jwtmiddleware.SigningKeys.Current(); key != nil && key.Method.Alg() == s.algorithm {
+			token := jwt.NewWithClaims(key.Method, claims)
+			token.Header["kid"] = key.Kid
+			return token.SignedString(key.PrivateKey)
+		}
+		if !jwtmiddleware.SigningKeys.AcceptsSecret() {
+			return "", fmt.Errorf("no %v jwt signing key", s.algorithm)
+		}
+		log.Warnf("No %v jwt signing key, signing with the jwt secret", s.algorithm)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+func newRefreshToken() (string, error) {
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
+
+// StartSession creates the session of a user who signed in and returns its access and refresh token
+func (s *SessionTokenIssuer) StartSession(userAccount map[string]interface{}, client *ActionClient,
+	sessionResource *DbResource, transaction *sqlx.Tx) (string, string, error) {
+	return s.startSession(userAccount, client, map[string]interface{}{}, sessionResource, transaction)
+}
+
+// StartClientSession creates the session of a user for an oauth client, only the client can refresh it and
+// the userinfo of the session is limited to the granted scope
+func (s *SessionTokenIssuer) StartClientSession(userAccount map[string]interface{}, client *ActionClient,
+	clientId string, scope string, sessionResource *DbResource, transaction *sqlx.Tx) (string, string, error) {
+	return s.startSession(userAccount, client, map[string]interface{}{
+		"client_id": clientId,
+		"scope":     scope,
+	}, sessionResource, transaction)
+}
+
+func (s *SessionTokenIssuer) startSession(userAccount map[string]interface{}, client *ActionClient,
+	session map[string]interface{}, sessionResource *DbResource, transaction *sqlx.Tx) (string, string, error) {
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	session["refresh_token_hash"] = hashRefreshToken(refreshToken)
+	session["expires_at"] = time.Now().Add(s.refreshTokenLifeTime)
+	if client != nil {
+		session["ip_address"] = client.IpAddress
+		session["user_agent"] = client.UserAgent
+	}
+
+	userId, _ := userAccount["id"].(int64)
+	userReferenceId, _ := userAccount["reference_id"].(string)
+	httpReq := &http.Request{
+		Method: "POST",
+	}
+	httpReq = httpReq.WithContext(context.WithValue(context.Background(), "user", &auth.SessionUser{
+		UserId:          userId,
+		UserReferenceId: userReferenceId,
+	}))
+	created, err := sessionResource.CreateWithoutFilter(api2go.NewApi2GoModelWithData(USER_SESSION_TABLE_NAME, nil, 0, nil, session),
+		api2go.Request{PlainRequest: httpReq}, transaction)
+	if err != nil {
+		return "", "", err
+	}
+
+	email, _ := userAccount["email"].(string)
+	name, _ := userAccount["name"].(string)
+	accessToken, err := s.AccessToken(email, name, userReferenceId, created["reference_id"].(string))
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// RefreshSession exchanges a refresh token for a new access token and a new refresh token, the old refresh
+// token stops working. Presenting an exchanged refresh token again revokes the session. The client id is the
+// oauth client refreshing the tokens, empty for the sessions of users who signed in to daptin.
+func (s *SessionTokenIssuer) RefreshSession(refreshToken string, clientId string, transaction *sqlx.Tx) (string, string, error) {
+
+	tokenHash := hashRefreshToken(refreshToken)
+	query, args, err := statementbuilder.Squirrel.Select(
+		goqu.I("s.reference_id"), goqu.I("s.refresh_token_hash"), goqu.I("s.expires_at"), goqu.I("s.client_id"),
+		goqu.I("u.email"), goqu.I("u.name"), goqu.I("u.reference_id")).
+		From(goqu.T(USER_SESSION_TABLE_NAME).As("s")).
+		Join(goqu.T(USER_ACCOUNT_TABLE_NAME).As("u"), goqu.On(goqu.Ex{
+			"u.id": goqu.I("s.user_account_id"),
+		})).
+		Where(goqu.Or(
+			goqu.Ex{"s.refresh_token_hash": tokenHash},
+			goqu.Ex{"s.previous_refresh_token_hash": tokenHash},
+		)).ToSQL()
+	if err != nil {
+		return "", "", err
+	}
+
+	var sessionReferenceId, currentHash, email, name, userReferenceId string
+	var sessionClientId sql.NullString
+	var expiresAt interface{}
+	err = transaction.QueryRowx(query, args...).Scan(&sessionReferenceId, &currentHash, &expiresAt, &sessionClientId, &email, &name, &userReferenceId)
+	if err != nil {
+		return "", "", errors.New("invalid refresh token")
+	}
+	// the refresh token of another client is rejected without touching its session
+	if sessionClientId.String != clientId {
+		log.Warnf("Refresh token of session [%v] of [%v] presented by another client [%v]", sessionReferenceId, email, clientId)
+		return "", "", errors.New("invalid refresh token")
+	}
+
+	if currentHash != tokenHash {
+		log.Warnf("Refresh token of session [%v] of [%v] was reused", sessionReferenceId, email)
+		err = s.RevokeSession(sessionReferenceId, transaction)
+		if err != nil {
+			return "", "", err
+		}
+		return "", "", ErrRefreshTokenReused
+	}
+
+	if expiry, ok := auth.ParseStoredTime(expiresAt); !ok || time.Now().After(expiry) {
+		return "", "", errors.New("session has expired")
+	}
+
+	newToken, err := newRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	updateQuery, updateArgs, err := statementbuilder.Squirrel.Update(USER_SESSION_TABLE_NAME).
+		Set(goqu.Record{
+			"refresh_token_hash":          hashRefreshToken(newToken),
+			"previous_refresh_token_hash": tokenHash,
+			"last_used_at":                time.Now(),
+		}).
+		Where(goqu.Ex{"reference_id": sessionReferenceId, "refresh_token_hash": tokenHash}).ToSQL()
+	if err != nil {
+		return "", "", err
+	}
+	result, err := transaction.Exec(updateQuery, updateArgs...)
+	if err != nil {
+		return "", "", err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		// another request exchanged the same token first
+		return "", "", ErrRefreshTokenReused
+	}
+
+	accessToken, err := s.AccessToken(email, name, userReferenceId, sessionReferenceId)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, newToken, nil
+}
+
+// SessionClient returns the oauth client of the session and the scope granted to it, both are empty for the
+// sessions of users who signed in to daptin
+func SessionClient(sessionReferenceId string, transaction *sqlx.Tx) (string, string, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select("client_id", "scope").From(USER_SESSION_TABLE_NAME).
+		Where(goqu.Ex{"reference_id": sessionReferenceId}).ToSQL()
+	if err != nil {
+		return "", "", err
+	}
+	var clientId, scope sql.NullString
+	err = transaction.QueryRowx(query, args...).Scan(&clientId, &scope)
+	if err != nil {
+		return "", "", err
+	}
+	return clientId.String, scope.String, nil
+}
+
+// RevokeSession ends a session, its refresh tokens stop working and its access tokens are rejected on all nodes
+func (s *SessionTokenIssuer) RevokeSession(sessionReferenceId string, transaction *sqlx.Tx) error {
+
+	query, args, err := statementbuilder.Squirrel.Update(USER_SESSION_TABLE_NAME).
+		Set(goqu.Record{
+			"refresh_token_hash":          nil,
+			"previous_refresh_token_hash": nil,
+			"revoked_at":                  time.Now(),
+		}).
+		Where(goqu.Ex{"reference_id": sessionReferenceId}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	return jwtmiddleware.RevokeSession(sessionReferenceId, s.accessTokenLifeTime)
+}
+
+// RevokeUserSessions ends all sessions of the user, tokens issued to the user before now are rejected,
+// including the ones issued without a session
+func (s *SessionTokenIssuer) RevokeUserSessions(userId int64, email string, transaction *sqlx.Tx) (int, error) {
+
+	query, args, err := statementbuilder.Squirrel.Select("reference_id").From(USER_SESSION_TABLE_NAME).
+		Where(goqu.Ex{USER_ACCOUNT_ID_COLUMN: userId}).
+		Where(goqu.C("revoked_at").IsNull()).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+	var sessionReferenceIds []string
+	err = transaction.Select(&sessionReferenceIds, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, sessionReferenceId := range sessionReferenceIds {
+		err = s.RevokeSession(sessionReferenceId, transaction)
+		if err != nil {
+			return 0, fmt.Errorf("failed to revoke session [%v]: %v", sessionReferenceId, err)
+		}
+	}
+
+	return len(sessionReferenceIds), jwtmiddleware.RevokeUserTokens(email, time.Now(), s.refreshTokenLifeTime)
+}
+
+// SessionResponses stores the tokens of a session on the client
+func SessionResponses(accessToken string, refreshToken string) []ActionResponse {
+	return []ActionResponse{
+		NewActionResponse("client.store.set", map[string]interface{}{
+			"key":   "token",
+			"value": accessToken,
+		}),
+		NewActionResponse("client.store.set", map[string]interface{}{
+			"key":   "refresh_token",
+			"value": refreshToken,
+		}),
+		NewActionResponse("client.cookie.set", map[string]interface{}{
+			"key":   "token",
+			"value": accessToken + "; SameSite=Strict",
+		}),
+	}
+}
@@ -0,0 +1,124 @@
+package resource
+
+import (
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+	"time"
+)
+
+func TestRefreshSession(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.MustExec("create table user_account (id integer primary key, reference_id varchar(36), email varchar(100), name varchar(100))")
+	db.MustExec("create table user_session (id integer primary key, reference_id varchar(36), user_account_id integer, " +
+		"refresh_token_hash varchar(64) null, previous_refresh_token_hash varchar(64) null, expires_at timestamp, " +
+		"last_used_at timestamp null, revoked_at timestamp null, client_id varchar(64) null, scope varchar(200) null)")
+	db.MustExec("insert into user_account (id, reference_id, email, name) values (1, 'u1', 'user@example.com', 'user')")
+	db.MustExec("insert into user_session (reference_id, user_account_id, refresh_token_hash, expires_at) values ('s1', 1, ?, ?), ('s2', 1, ?, ?)",
+		hashRefreshToken("first"), time.Now().Add(time.Hour), hashRefreshToken("expired"), time.Now().Add(-time.Hour))
+	db.MustExec("insert into user_session (reference_id, user_account_id, refresh_token_hash, expires_at, client_id, scope) values ('s3', 1, ?, ?, 'wiki', 'openid email')",
+		hashRefreshToken("client"), time.Now().Add(time.Hour))
+
+	issuer := &SessionTokenIssuer{
+		secret:               []byte("secret"),
+		algorithm:            "HS256",
+		issuer:               "daptin-test",
+		accessTokenLifeTime:  time.Minute,
+		refreshTokenLifeTime: time.Hour,
+	}
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	accessToken, second, err := issuer.RefreshSession("first", "", transaction)
+	if err != nil {
+		t.Fatalf("failed to refresh session: %v", err)
+	}
+	parsed, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
+		return []byte("secret"), nil
+	})
+	if err != nil {
+		t.Fatalf("failed to parse access token: %v", err)
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+	if claims["sid"] != "s1" || claims["email"] != "user@example.com" || claims["sub"] != "u1" {
+		t.Errorf("unexpected access token claims: %v", claims)
+	}
+
+	if _, _, err = issuer.RefreshSession("expired", "", transaction); err == nil {
+		t.Errorf("expected an error refreshing an expired session")
+	}
+
+	if _, _, err = issuer.RefreshSession("first", "", transaction); err != ErrRefreshTokenReused {
+		t.Fatalf("expected reuse of the first refresh token to be detected, got %v", err)
+	}
+	var revoked int
+	if err = transaction.Get(&revoked, "select count(*) from user_session where reference_id = 's1' and revoked_at is not null and refresh_token_hash is null"); err != nil || revoked != 1 {
+		t.Errorf("expected the session to be revoked after reuse, got %v %v", revoked, err)
+	}
+	if _, _, err = issuer.RefreshSession(second, "", transaction); err == nil {
+		t.Errorf("expected the refresh token of a revoked session to be rejected")
+	}
+
+	// the session of an oauth client is only refreshed by the client
+	if _, _, err = issuer.RefreshSession("client", "", transaction); err == nil {
+		t.Errorf("expected the refresh token of a client session to be rejected without the client")
+	}
+	if _, _, err = issuer.RefreshSession("client", "other", transaction); err == nil {
+		t.Errorf("expected the refresh token of a client session to be rejected for another client")
+	}
+	if _, _, err = issuer.RefreshSession("client", "wiki", transaction); err != nil {
+		t.Errorf("expected the client to refresh its session: %v", err)
+	}
+	if clientId, scope, err := SessionClient("s3", transaction); err != nil || clientId != "wiki" || scope != "openid email" {
+		t.Errorf("unexpected client of the session %v %v %v", clientId, scope, err)
+	}
+
+	count, err := issuer.RevokeUserSessions(1, "user@example.com", transaction)
+	if err != nil || count != 2 {
+		t.Errorf("expected the two remaining sessions to be revoked, got %v %v", count, err)
+	}
+}
+
+func TestMigrateAccessTokenLifeTime(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	newConfigStore := func() (*ConfigStore, func()) {
+		db, err := sqlx.Open("sqlite3", ":memory:")
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		// every connection to :memory: is another database
+		db.SetMaxOpenConns(1)
+		configStore, err := NewConfigStore(db)
+		if err != nil {
+			t.Fatalf("failed to create config store: %v", err)
+		}
+		return configStore, func() { db.Close() }
+	}
+
+	upgraded, closeUpgraded := newConfigStore()
+	defer closeUpgraded()
+	if err := upgraded.SetConfigIntValueFor("jwt.token.life.hours", 24, "backend"); err != nil {
+		t.Fatalf("failed to set config: %v", err)
+	}
+	MigrateAccessTokenLifeTime(upgraded)
+	if minutes, err := upgraded.GetConfigIntValueFor("jwt.access_token.life.minutes", "backend"); err != nil || minutes != 24*60 {
+		t.Errorf("expected an upgraded installation to keep day long access tokens, got %v %v", minutes, err)
+	}
+
+	fresh, closeFresh := newConfigStore()
+	defer closeFresh()
+	MigrateAccessTokenLifeTime(fresh)
+	if issuer := NewSessionTokenIssuer(fresh); issuer.accessTokenLifeTime != DefaultAccessTokenLifeTimeMinutes*time.Minute {
+		t.Errorf("expected a new installation to get short access tokens, got %v", issuer.accessTokenLifeTime)
+	}
+}
@@ -0,0 +1,236 @@
+package resource
+
+import (
+	"fmt"
+	"github.com/artpar/api2go"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// VirtualColumn is a read only column which is not stored in the table. The value is either computed
+// by the database from the Expression over the row, eg "price * quantity", or from the Rollup over the
+// rows of a relation, or by evaluating the javascript Script after the row is fetched with the values
+// of the row as variables.
+// Only the sql backed columns can be used in filters and sort.
+type VirtualColumn struct {
+	ColumnName        string
+	ColumnType        string
+	ColumnDescription string
+	Expression        string
+	Script            string
+	Rollup            *Rollup
+}
+
+func (vc VirtualColumn) IsSql() bool {
+	return vc.Expression != "" || vc.Rollup != nil
+}
+
+// ColumnInfo describes the virtual column as a nullable column for the api schemas
+func (vc VirtualColumn) ColumnInfo() api2go.ColumnInfo {
+	columnType := vc.ColumnType
+	if columnType == "" && vc.Rollup != nil {
+		columnType = "measurement"
+	} else if columnType == "" {
+		columnType = "label"
+	}
+	return api2go.ColumnInfo{
+		Name:              vc.ColumnName,
+		ColumnName:        vc.ColumnName,
+		ColumnType:        columnType,
+		ColumnDescription: vc.ColumnDescription,
+		IsNullable:        true,
+	}
+}
+
+// sqlExpression is the expression of the column in a query on the table, tableAlias is the name of the
+// table in the query which the rollup subqueries are correlated to
+func (vc VirtualColumn) sqlExpression(tableInfo *TableInfo, tableAlias string) exp.LiteralExpression {
+	if vc.Rollup == nil {
+		return goqu.L("(" + vc.Expression + ")")
+	}
+	subQuery, err := vc.Rollup.subQuery(tableInfo, tableAlias)
+	if err != nil {
+		log.Errorf("Failed to build rollup [%v][%v]: %v", tableInfo.TableName, vc.ColumnName, err)
+		return goqu.L("(null)")
+	}
+	return goqu.L("(" + subQuery + ")")
+}
+
+func (ti *TableInfo) GetVirtualColumnByName(name string) (*VirtualColumn, bool) {
+
+	for _, col := range ti.VirtualColumns {
+		if col.ColumnName == name {
+			return &col, true
+		}
+	}
+
+	return nil, false
+}
+
+// ValidateVirtualColumns checks that each virtual column has a name which is not used by a column of the table
+// and exactly one of expression and script
+func (ti *TableInfo) ValidateVirtualColumns() error {
+
+	seen := make(map[string]bool)
+	for _, col := range ti.VirtualColumns {
+		if col.ColumnName == "" {
+			return fmt.Errorf("virtual column in [%v] has no name", ti.TableName)
+		}
+		if _, ok := ti.GetColumnByName(col.ColumnName); ok || seen[col.ColumnName] {
+			return fmt.Errorf("virtual column [%v] in [%v] is already defined", col.ColumnName, ti.TableName)
+		}
+		definitions := 0
+		for _, defined := range []bool{col.Expression != "", col.Script != "", col.Rollup != nil} {
+			if defined {
+				definitions++
+			}
+		}
+		if definitions != 1 {
+			return fmt.Errorf("virtual column [%v] in [%v] needs one of expression, script or rollup", col.ColumnName, ti.TableName)
+		}
+		if col.Rollup != nil {
+			if err := col.Rollup.validate(); err != nil {
+				return fmt.Errorf("rollup column [%v] in [%v]: %v", col.ColumnName, ti.TableName, err)
+			}
+		}
+		seen[col.ColumnName] = true
+	}
+	return nil
+}
+
+// virtualColumnSelects are the sql backed virtual columns to add to a select query on the table named tableAlias
+func virtualColumnSelects(tableInfo *TableInfo, tableAlias string, reqFieldMap map[string]bool, hasRequestedFields bool) []column {
+	cols := make([]column, 0)
+	if tableInfo == nil {
+		return cols
+	}
+	for _, vc := range tableInfo.VirtualColumns {
+		if !vc.IsSql() || (hasRequestedFields && !reqFieldMap[vc.ColumnName]) {
+			continue
+		}
+		cols = append(cols, column{
+			originalvalue: vc.sqlExpression(tableInfo, tableAlias).As(vc.ColumnName),
+			reference:     vc.ColumnName,
+		})
+	}
+	return cols
+}
+
+// orderExpression is the expression to sort on for a column name, virtual columns are sorted by their expression
+func orderExpression(tableInfo *TableInfo, prefix string, name string) exp.Orderable {
+	if tableInfo != nil {
+		if vc, ok := tableInfo.GetVirtualColumnByName(name); ok && vc.IsSql() {
+			return vc.sqlExpression(tableInfo, strings.TrimSuffix(prefix, "."))
+		}
+	}
+	return goqu.I(prefix + name)
+}
+
+// conditionOperand is a column or an expression which can be compared in a where clause
+type conditionOperand interface {
+	exp.Comparable
+	exp.Isable
+	exp.Inable
+	exp.Likeable
+}
+
+// virtualColumnCondition builds the where clause for a filter on a sql backed virtual column
+func virtualColumnCondition(tableInfo *TableInfo, tableAlias string, vc *VirtualColumn, filterQuery Query) (exp.Expression, error) {
+	condition, err := queryCondition(vc.sqlExpression(tableInfo, tableAlias), filterQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%v on virtual column [%v]", err, vc.ColumnName)
+	}
+	return condition, nil
+}
+
+// queryCondition builds the where clause of a query on an operand, the operators are the ones of OperatorMap
+func queryCondition(lhs conditionOperand, filterQuery Query) (exp.Expression, error) {
+
+	opValue, ok := OperatorMap[filterQuery.Operator]
+	if !ok {
+		opValue = filterQuery.Operator
+	}
+	value := filterQuery.Value
+
+	switch strings.ToLower(opValue) {
+	case "is true":
+		return lhs.IsTrue(), nil
+	case "is false":
+		return lhs.IsFalse(), nil
+	case "is nil", "is null", "is empty":
+		return lhs.IsNull(), nil
+	case "not true":
+		return lhs.IsNotTrue(), nil
+	case "not false":
+		return lhs.IsNotFalse(), nil
+	case "not nil", "not null", "not empty":
+		return lhs.IsNotNull(), nil
+	case "is", "=", "eq":
+		return lhs.Eq(value), nil
+	case "not", "neq":
+		return lhs.Neq(value), nil
+	case "isnot":
+		return lhs.IsNot(value), nil
+	case "gt":
+		return lhs.Gt(value), nil
+	case "gte":
+		return lhs.Gte(value), nil
+	case "lt":
+		return lhs.Lt(value), nil
+	case "lte":
+		return lhs.Lte(value), nil
+	case "in":
+		return lhs.In(value), nil
+	case "notin":
+		return lhs.NotIn(value), nil
+	case "like":
+		return lhs.Like(value), nil
+	case "notlike":
+		return lhs.NotLike(value), nil
+	case "ilike":
+		return lhs.ILike(value), nil
+	case "notilike":
+		return lhs.NotILike(value), nil
+	}
+	return nil, fmt.Errorf("operator [%v] is not supported", filterQuery.Operator)
+}
+
+// EvaluateVirtualColumns sets the value of the script backed virtual columns on the row, the values of
+// the row are available by name and as "row"
+func EvaluateVirtualColumns(tableInfo *TableInfo, row map[string]interface{}) {
+	if tableInfo == nil || len(tableInfo.VirtualColumns) == 0 {
+		return
+	}
+
+	var contextMap map[string]interface{}
+	for _, vc := range tableInfo.VirtualColumns {
+		if vc.IsSql() {
+			continue
+		}
+		if contextMap == nil {
+			contextMap = make(map[string]interface{}, len(row)+1)
+			for key, val := range row {
+				contextMap[key] = val
+			}
+			contextMap["row"] = row
+		}
+		value, err := runUnsafeJavascript(vc.Script, contextMap)
+		if err != nil {
+			log.Errorf("Failed to evaluate virtual column [%v][%v]: %v", tableInfo.TableName, vc.ColumnName, err)
+			value = nil
+		}
+		row[vc.ColumnName] = value
+	}
+}
+
+// RemoveVirtualColumns drops the values of virtual columns from an object being written, they are read only
+func RemoveVirtualColumns(tableInfo *TableInfo, obj map[string]interface{}) {
+	if tableInfo == nil {
+		return
+	}
+	for _, vc := range tableInfo.VirtualColumns {
+		delete(obj, vc.ColumnName)
+	}
+}
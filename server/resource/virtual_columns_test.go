@@ -0,0 +1,171 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestValidateVirtualColumns(t *testing.T) {
+
+	table := TableInfo{
+		TableName: "line_item",
+		Columns: []api2go.ColumnInfo{
+			{ColumnName: "price", ColumnType: "measurement"},
+		},
+	}
+
+	cases := map[string][]VirtualColumn{
+		"":                {{ColumnName: "total", Expression: "price * quantity"}, {ColumnName: "label", Script: "'#' + price"}},
+		"name":            {{Expression: "price"}},
+		"clash":           {{ColumnName: "price", Expression: "price"}},
+		"duplicate":       {{ColumnName: "total", Expression: "price"}, {ColumnName: "total", Script: "price"}},
+		"no definition":   {{ColumnName: "total"}},
+		"both definition": {{ColumnName: "total", Expression: "price", Script: "price"}},
+	}
+	for name, virtualColumns := range cases {
+		table.VirtualColumns = virtualColumns
+		err := table.ValidateVirtualColumns()
+		if (err == nil) != (name == "") {
+			t.Errorf("unexpected validation result for [%v]: %v", name, err)
+		}
+	}
+}
+
+func TestVirtualColumnQueries(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.MustExec("create table line_item (id integer primary key, price int, quantity int)")
+	db.MustExec("insert into line_item (price, quantity) values (10, 1), (3, 5), (4, 2)")
+
+	table := &TableInfo{
+		TableName: "line_item",
+		VirtualColumns: []VirtualColumn{
+			{ColumnName: "total", ColumnType: "measurement", Expression: "price * quantity"},
+			{ColumnName: "label", Script: "'total ' + row.total"},
+		},
+	}
+
+	condition, err := virtualColumnCondition(table, "line_item", &table.VirtualColumns[0], Query{ColumnName: "total", Operator: "more then", Value: 8})
+	if err != nil {
+		t.Fatalf("failed to build the condition: %v", err)
+	}
+	if _, err = virtualColumnCondition(table, "line_item", &table.VirtualColumns[0], Query{ColumnName: "total", Operator: "any of", Value: 8}); err == nil {
+		t.Errorf("expected an error for an unsupported operator")
+	}
+
+	query, args, err := statementbuilder.Squirrel.
+		Select(append([]interface{}{goqu.I("line_item.id")}, ColumnToInterfaceArray(virtualColumnSelects(table, "line_item", nil, false))...)...).
+		From("line_item").Where(condition).
+		Order(orderExpression(table, "line_item.", "total").Desc()).ToSQL()
+	if err != nil {
+		t.Fatalf("failed to build the query: %v", err)
+	}
+
+	rows, err := db.Queryx(query, args...)
+	if err != nil {
+		t.Fatalf("failed to run [%v]: %v", query, err)
+	}
+	result, err := RowsToMap(rows, "line_item")
+	rows.Close()
+	if err != nil {
+		t.Fatalf("failed to read rows: %v", err)
+	}
+
+	if len(result) != 2 || result[0]["total"] != int64(15) || result[1]["total"] != int64(10) {
+		t.Fatalf("expected totals 15 and 10, got %v", result)
+	}
+
+	EvaluateVirtualColumns(table, result[0])
+	if result[0]["label"] != "total 15" {
+		t.Errorf("expected the script column to be evaluated, got %v", result[0]["label"])
+	}
+
+	obj := map[string]interface{}{"price": 1, "total": 100, "label": "x"}
+	RemoveVirtualColumns(table, obj)
+	if len(obj) != 1 {
+		t.Errorf("expected the virtual columns to be removed, got %v", obj)
+	}
+}
+
+func TestRollupColumns(t *testing.T) {
+
+	statementbuilder.InitialiseStatementBuilder("sqlite3")
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	db.MustExec("create table post (id integer primary key, title varchar(100))")
+	db.MustExec("create table comment (id integer primary key, post_id int, votes int, approved bool)")
+	db.MustExec("create table tag (id integer primary key)")
+	db.MustExec("create table post_post_id_has_tag_tag_id (id integer primary key, post_id int, tag_id int)")
+	db.MustExec("insert into post (title) values ('one'), ('two'), ('three')")
+	db.MustExec("insert into comment (post_id, votes, approved) values (1, 3, 1), (1, 4, 0), (2, 10, 1)")
+	db.MustExec("insert into tag (id) values (1), (2)")
+	db.MustExec("insert into post_post_id_has_tag_tag_id (post_id, tag_id) values (3, 1), (3, 2), (1, 1)")
+
+	post := &TableInfo{
+		TableName: "post",
+		Relations: []api2go.TableRelation{
+			api2go.NewTableRelation("comment", "belongs_to", "post"),
+			api2go.NewTableRelation("post", "has_many", "tag"),
+		},
+		VirtualColumns: []VirtualColumn{
+			{ColumnName: "comment_count", Rollup: &Rollup{Relation: "comment_id", Aggregate: "count"}},
+			{ColumnName: "approved_votes", Rollup: &Rollup{Relation: "comment_id", Aggregate: "sum", Column: "votes",
+				Filter: []Query{{ColumnName: "approved", Operator: "is true"}}}},
+			{ColumnName: "tag_count", Rollup: &Rollup{Relation: "tag_id", Aggregate: "count"}},
+			{ColumnName: "missing", Rollup: &Rollup{Relation: "author_id", Aggregate: "count"}},
+		},
+	}
+	if err = post.ValidateVirtualColumns(); err != nil {
+		t.Fatalf("expected valid rollups: %v", err)
+	}
+	if err = (Rollup{Relation: "comment_id", Aggregate: "sum"}).validate(); err == nil {
+		t.Errorf("expected an error for a sum without a column")
+	}
+
+	condition, err := virtualColumnCondition(post, "post", &post.VirtualColumns[0], Query{ColumnName: "comment_count", Operator: "lt", Value: 2})
+	if err != nil {
+		t.Fatalf("failed to build the condition: %v", err)
+	}
+	query, args, err := statementbuilder.Squirrel.
+		Select(append([]interface{}{goqu.I("post.id")}, ColumnToInterfaceArray(virtualColumnSelects(post, "post", nil, false))...)...).
+		From("post").Where(condition).
+		Order(orderExpression(post, "post.", "tag_count").Desc()).ToSQL()
+	if err != nil {
+		t.Fatalf("failed to build the query: %v", err)
+	}
+
+	rows, err := db.Queryx(query, args...)
+	if err != nil {
+		t.Fatalf("failed to run [%v]: %v", query, err)
+	}
+	result, err := RowsToMap(rows, "post")
+	rows.Close()
+	if err != nil {
+		t.Fatalf("failed to read rows: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("expected posts two and three, got %v", result)
+	}
+	three, two := result[0], result[1]
+	if three["id"] != int64(3) || three["tag_count"] != int64(2) || three["comment_count"] != int64(0) || three["approved_votes"] != int64(0) {
+		t.Errorf("unexpected rollups of post three: %v", three)
+	}
+	if two["id"] != int64(2) || two["comment_count"] != int64(1) || two["approved_votes"] != int64(10) || two["missing"] != nil {
+		t.Errorf("unexpected rollups of post two: %v", two)
+	}
+}
@@ -0,0 +1,404 @@
+package resource
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	uuid "github.com/artpar/go.uuid"
+	"github.com/artpar/resty"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+const WebhookTableName = "webhook"
+const WebhookDeliveryTableName = "webhook_delivery"
+
+// webhooks are reloaded from the database after this duration, or right away when
+// the webhook table is changed on this node
+const webhookCacheDuration = time.Minute
+const webhookQueueSize = 1000
+const webhookWorkerCount = 4
+const webhookMaxResponseLength = 1000
+const webhookMaxBackoff = 5 * time.Minute
+
+// Webhook is an enabled row of the webhook table
+type Webhook struct {
+	Id          int64
+	ReferenceId string
+	Name        string
+	Url         string
+	TableNames  []string
+	EventTypes  []string
+	Filter      string
+	Secret      string
+	MaxRetries  int
+}
+
+type webhookRow struct {
+	Id          int64   `db:"id"`
+	ReferenceId string  `db:"reference_id"`
+	Name        string  `db:"webhook_name"`
+	Url         string  `db:"url"`
+	TableNames  *string `db:"table_names"`
+	EventTypes  *string `db:"event_types"`
+	Filter      *string `db:"event_filter"`
+	Secret      *string `db:"secret"`
+	MaxRetries  *int    `db:"max_retries"`
+}
+
+// WebhookPayload is the json body posted to the webhook url
+type WebhookPayload struct {
+	DeliveryId string                 `json:"delivery_id"`
+	Event      string                 `json:"event"`
+	Table      string                 `json:"table"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Data       map[string]interface{} `json:"data"`
+	Before     map[string]interface{} `json:"before,omitempty"`
+}
+
+type webhookDelivery struct {
+	webhook Webhook
+	payload WebhookPayload
+	attempt int
+}
+
+// WebhookDispatcher posts the create/update/delete events generated by the event middleware
+// to the matching webhooks and records every attempt in the webhook_delivery table
+type WebhookDispatcher struct {
+	cruds    *map[string]*DbResource
+	lock     sync.RWMutex
+	webhooks []Webhook
+	loadedAt time.Time
+	queue    chan webhookDelivery
+	client   *resty.Client
+}
+
+var webhookDispatcher *WebhookDispatcher
+var webhookDispatcherLock sync.Mutex
+
+// GetWebhookDispatcher returns the dispatcher of the process. Its workers are started once and keep running
+// when the server restarts, the dispatcher then uses the tables of the restarted server.
+func GetWebhookDispatcher(cruds *map[string]*DbResource) *WebhookDispatcher {
+	webhookDispatcherLock.Lock()
+	defer webhookDispatcherLock.Unlock()
+
+	if webhookDispatcher == nil {
+		webhookDispatcher = &WebhookDispatcher{
+			cruds:  cruds,
+			queue:  make(chan webhookDelivery, webhookQueueSize),
+			client: resty.New().SetTimeout(10 * time.Second),
+		}
+		for i := 0; i < webhookWorkerCount; i++ {
+			go webhookDispatcher.work()
+		}
+		return webhookDispatcher
+	}
+
+	webhookDispatcher.lock.Lock()
+	defer webhookDispatcher.lock.Unlock()
+	webhookDispatcher.cruds = cruds
+	webhookDispatcher.webhooks = nil
+	return webhookDispatcher
+}
+
+func (wd *WebhookDispatcher) resource(tableName string) (*DbResource, bool) {
+	wd.lock.RLock()
+	defer wd.lock.RUnlock()
+	dbResource, ok := (*wd.cruds)[tableName]
+	return dbResource, ok
+}
+
+// Invalidate drops the loaded webhooks, they are loaded again on the next event
+func (wd *WebhookDispatcher) Invalidate() {
+	wd.lock.Lock()
+	defer wd.lock.Unlock()
+	wd.webhooks = nil
+}
+
+func (wd *WebhookDispatcher) getWebhooks() []Webhook {
+	wd.lock.RLock()
+	if wd.webhooks != nil && time.Since(wd.loadedAt) < webhookCacheDuration {
+		defer wd.lock.RUnlock()
+		return wd.webhooks
+	}
+	wd.lock.RUnlock()
+
+	webhooks, err := wd.loadWebhooks()
+	if err != nil {
+		log.Errorf("Failed to load webhooks: %v", err)
+		return []Webhook{}
+	}
+
+	wd.lock.Lock()
+	defer wd.lock.Unlock()
+	wd.webhooks = webhooks
+	wd.loadedAt = time.Now()
+	return webhooks
+}
+
+func splitWebhookList(value *string) []string {
+	list := make([]string, 0)
+	if value == nil {
+		return list
+	}
+	for _, item := range strings.Split(*value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && item != "*" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (wd *WebhookDispatcher) loadWebhooks() ([]Webhook, error) {
+	webhooks := make([]Webhook, 0)
+
+	dbResource, ok := wd.resource(WebhookTableName)
+	if !ok {
+		return webhooks, nil
+	}
+
+	s, v, err := statementbuilder.Squirrel.Select("id", "reference_id", "webhook_name", "url", "table_names",
+		"event_types", "event_filter", "secret", "max_retries").
+		From(WebhookTableName).Where(goqu.Ex{"enabled": true}).ToSQL()
+	if err != nil {
+		return webhooks, err
+	}
+
+	rows, err := dbResource.db.Queryx(s, v...)
+	if err != nil {
+		return webhooks, err
+	}
+	defer func(rows *sqlx.Rows) {
+		err := rows.Close()
+		CheckErr(err, "Failed to close webhook rows")
+	}(rows)
+
+	encryptionSecret, err := dbResource.configStore.GetConfigValueFor("encryption.secret", "backend")
+	if err != nil {
+		return webhooks, err
+	}
+
+	for rows.Next() {
+		var row webhookRow
+		err = rows.StructScan(&row)
+		if err != nil {
+			return webhooks, err
+		}
+
+		webhook := Webhook{
+			Id:          row.Id,
+			ReferenceId: row.ReferenceId,
+			Name:        row.Name,
+			Url:         row.Url,
+			TableNames:  splitWebhookList(row.TableNames),
+			EventTypes:  splitWebhookList(row.EventTypes),
+			MaxRetries:  5,
+		}
+		if row.Filter != nil {
+			webhook.Filter = strings.TrimSpace(*row.Filter)
+		}
+		if row.MaxRetries != nil {
+			webhook.MaxRetries = *row.MaxRetries
+		}
+		if row.Secret != nil && *row.Secret != "" {
+			webhook.Secret, err = Decrypt([]byte(encryptionSecret), *row.Secret)
+			if err != nil {
+				log.Errorf("Failed to decrypt secret of webhook [%v], skipping it: %v", webhook.Name, err)
+				continue
+			}
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func listContains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches checks the table, event type and the filter of the webhook against an event
+// the filter is a condition like the action outcome conditions, eg "!subject.status == 'done'"
+func (webhook Webhook) Matches(eventType string, tableName string, row map[string]interface{}) bool {
+	if !listContains(webhook.TableNames, tableName) || !listContains(webhook.EventTypes, eventType) {
+		return false
+	}
+	if webhook.Filter == "" {
+		return true
+	}
+
+	result, err := evaluateString(webhook.Filter, map[string]interface{}{
+		"subject": row,
+		"event":   eventType,
+		"table":   tableName,
+	})
+	if err != nil {
+		log.Errorf("Failed to evaluate filter of webhook [%v], skipping event: %v", webhook.Name, err)
+		return false
+	}
+	switch value := result.(type) {
+	case bool:
+		return value
+	case string:
+		return value == "1" || strings.ToLower(strings.TrimSpace(value)) == "true"
+	}
+	return false
+}
+
+// Dispatch queues the event for every matching webhook
+// for deletes the before image is sent as data, it is called once the change is committed
+func (wd *WebhookDispatcher) Dispatch(eventType string, tableName string, before map[string]interface{}, after map[string]interface{}) {
+
+	if tableName == WebhookTableName {
+		wd.Invalidate()
+	}
+	if tableName == WebhookDeliveryTableName || tableName == ChangeLogTableName {
+		return
+	}
+
+	row := after
+	if row == nil {
+		row = before
+	}
+	if row == nil {
+		return
+	}
+
+	for _, webhook := range wd.getWebhooks() {
+		if !webhook.Matches(eventType, tableName, row) {
+			continue
+		}
+
+		deliveryId, _ := uuid.NewV4()
+		payload := WebhookPayload{
+			DeliveryId: deliveryId.String(),
+			Event:      eventType,
+			Table:      tableName,
+			Timestamp:  time.Now(),
+			Data:       row,
+		}
+		if eventType == "update" {
+			payload.Before = before
+		}
+
+		wd.enqueue(webhookDelivery{webhook: webhook, payload: payload, attempt: 1})
+	}
+}
+
+func (wd *WebhookDispatcher) enqueue(delivery webhookDelivery) {
+	select {
+	case wd.queue <- delivery:
+	default:
+		log.Errorf("Webhook queue is full, dropping delivery [%v] for webhook [%v]",
+			delivery.payload.DeliveryId, delivery.webhook.Name)
+	}
+}
+
+func (wd *WebhookDispatcher) work() {
+	for delivery := range wd.queue {
+		wd.deliver(delivery)
+	}
+}
+
+// SignWebhookPayload returns the value of the X-Daptin-Signature header, the hex hmac-sha256 of the body
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts the payload once, a failed attempt is queued again after an exponential backoff
+// until the max retries of the webhook are used up
+func (wd *WebhookDispatcher) deliver(delivery webhookDelivery) {
+
+	body, err := json.Marshal(delivery.payload)
+	if err != nil {
+		log.Errorf("Failed to marshal webhook payload for [%v]: %v", delivery.webhook.Name, err)
+		return
+	}
+
+	headers := map[string]string{
+		"Content-Type":       "application/json",
+		"User-Agent":         "daptin-webhook",
+		"X-Daptin-Event":     delivery.payload.Event,
+		"X-Daptin-Table":     delivery.payload.Table,
+		"X-Daptin-Delivery":  delivery.payload.DeliveryId,
+		"X-Daptin-Signature": SignWebhookPayload(delivery.webhook.Secret, body),
+	}
+
+	start := time.Now()
+	response, err := wd.client.R().SetHeaders(headers).SetBody(body).Post(delivery.webhook.Url)
+	duration := time.Since(start)
+
+	statusCode := 0
+	responseBody := ""
+	errorMessage := ""
+	if err != nil {
+		errorMessage = err.Error()
+	}
+	if response != nil {
+		statusCode = response.StatusCode()
+		responseBody = response.String()
+		if len(responseBody) > webhookMaxResponseLength {
+			responseBody = responseBody[:webhookMaxResponseLength]
+		}
+	}
+	success := err == nil && statusCode >= 200 && statusCode < 300
+
+	wd.logDelivery(delivery, statusCode, success, responseBody, errorMessage, duration)
+
+	if success || delivery.attempt > delivery.webhook.MaxRetries {
+		return
+	}
+	log.Printf("Webhook [%v] delivery [%v] attempt %d failed: %v %v", delivery.webhook.Name,
+		delivery.payload.DeliveryId, delivery.attempt, statusCode, errorMessage)
+
+	backoff := time.Duration(1<<uint(delivery.attempt-1)) * time.Second
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	delivery.attempt += 1
+	time.AfterFunc(backoff, func() {
+		wd.enqueue(delivery)
+	})
+}
+
+func (wd *WebhookDispatcher) logDelivery(delivery webhookDelivery, statusCode int, success bool,
+	responseBody string, errorMessage string, duration time.Duration) {
+
+	dbResource, ok := wd.resource(WebhookDeliveryTableName)
+	if !ok {
+		return
+	}
+
+	referenceId, _ := uuid.NewV4()
+	s, v, err := statementbuilder.Squirrel.Insert(WebhookDeliveryTableName).
+		Cols("webhook_id", "delivery_id", "event_type", "table_name", "object_reference_id", "attempt",
+			"status_code", "success", "response", "error", "duration_ms", "reference_id", "permission").
+		Vals([]interface{}{delivery.webhook.Id, delivery.payload.DeliveryId, delivery.payload.Event,
+			delivery.payload.Table, delivery.payload.Data["reference_id"], delivery.attempt, statusCode, success,
+			responseBody, errorMessage, duration.Milliseconds(), referenceId.String(), auth.DEFAULT_PERMISSION}).
+		ToSQL()
+	if err != nil {
+		log.Errorf("Failed to create webhook delivery log query: %v", err)
+		return
+	}
+
+	_, err = dbResource.db.Exec(s, v...)
+	CheckErr(err, "Failed to write webhook delivery log for [%v]", delivery.webhook.Name)
+}
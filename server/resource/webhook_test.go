@@ -0,0 +1,58 @@
+package resource
+
+import (
+	"testing"
+)
+
+func TestWebhookMatches(t *testing.T) {
+
+	webhook := Webhook{
+		Name:       "todo-done",
+		TableNames: []string{"todo"},
+		EventTypes: []string{"update"},
+		Filter:     "!subject.completed == true",
+	}
+
+	if !webhook.Matches("update", "todo", map[string]interface{}{"completed": true}) {
+		t.Errorf("expected webhook to match completed todo")
+	}
+	if webhook.Matches("update", "todo", map[string]interface{}{"completed": false}) {
+		t.Errorf("expected filter to skip incomplete todo")
+	}
+	if webhook.Matches("create", "todo", map[string]interface{}{"completed": true}) {
+		t.Errorf("expected webhook to skip create events")
+	}
+	if webhook.Matches("update", "user_account", map[string]interface{}{"completed": true}) {
+		t.Errorf("expected webhook to skip other tables")
+	}
+
+	allEvents := Webhook{Name: "all"}
+	if !allEvents.Matches("delete", "user_account", map[string]interface{}{}) {
+		t.Errorf("expected webhook without tables and event types to match everything")
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	signature := SignWebhookPayload("secret", []byte(`{"event":"create"}`))
+	if signature != SignWebhookPayload("secret", []byte(`{"event":"create"}`)) {
+		t.Errorf("expected signature to be stable")
+	}
+	if signature == SignWebhookPayload("other", []byte(`{"event":"create"}`)) {
+		t.Errorf("expected signature to depend on the secret")
+	}
+	if len(signature) != len("sha256=")+64 {
+		t.Errorf("unexpected signature length: %v", signature)
+	}
+}
+
+func TestGetWebhookDispatcher(t *testing.T) {
+	first := map[string]*DbResource{}
+	second := map[string]*DbResource{}
+	dispatcher := GetWebhookDispatcher(&first)
+	if GetWebhookDispatcher(&second) != dispatcher {
+		t.Errorf("expected the dispatcher to be created once")
+	}
+	if _, ok := dispatcher.resource(WebhookTableName); ok || dispatcher.cruds != &second {
+		t.Errorf("expected the dispatcher to use the tables of the latest server")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/aviddiviner/gin-limit"
 	"github.com/daptin/daptin/server/auth"
 	"github.com/daptin/daptin/server/database"
+	"github.com/daptin/daptin/server/jwt"
 	"github.com/daptin/daptin/server/resource"
 	"github.com/daptin/daptin/server/websockets"
 	server2 "github.com/fclairamb/ftpserver/server"
@@ -115,6 +116,12 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 	configStore, err := resource.NewConfigStore(db)
 	resource.CheckErr(err, "Failed to get config store")
 
+	err = resource.RecordSchemaVersion(db, configStore)
+	resource.CheckErr(err, "Failed to record schema version")
+
+	// before anything stores the default life times
+	resource.MigrateAccessTokenLifeTime(configStore)
+
 	hostname, err := configStore.GetConfigValueFor("hostname", "backend")
 	if err != nil {
 		name, e := os.Hostname()
@@ -130,6 +137,25 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 
 	defaultRouter := gin.Default()
 
+	// the client ip, used by the sign in lockout and the rate limits, is taken from the X-Forwarded-For
+	// header only when the request comes from one of these proxies
+	trustedProxies, err := configStore.GetConfigValueFor("http.trusted.proxies", "backend")
+	if err != nil {
+		trustedProxies = ""
+		err = configStore.SetConfigValueFor("http.trusted.proxies", trustedProxies, "backend")
+		resource.CheckErr(err, "Failed to store http.trusted.proxies in _config")
+	}
+	proxies := make([]string, 0)
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	err = resource.SetTrustedProxies(proxies)
+	resource.CheckErr(err, "Failed to set http.trusted.proxies")
+	// gin takes the leftmost X-Forwarded-For entry, which the client writes, resource.ClientIP is used instead
+	defaultRouter.ForwardedByClientIP = false
+
 	enableGzip, err := configStore.GetConfigValueFor("gzip.enable", "backend")
 	if err != nil {
 		enableGzip = "true"
@@ -140,7 +166,7 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 	if enableGzip == "true" {
 		defaultRouter.Use(gzip.Gzip(gzip.DefaultCompression,
 			gzip.WithExcludedExtensions([]string{".pdf", ".mp4", ".jpg", ".png", ".wav", ".gif", ".mp3"}),
-			gzip.WithExcludedPaths([]string{"/asset/"})),
+			gzip.WithExcludedPaths([]string{"/asset/", "/events"})),
 		)
 	}
 
@@ -229,7 +255,7 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 
 	defaultRouter.Use(rateLimit.NewRateLimiter(func(c *gin.Context) string {
 		requestPath := strings.Split(c.Request.RequestURI, "?")[0]
-		return c.ClientIP() + requestPath // limit rate by client ip + url
+		return resource.ClientIP(c) + requestPath // limit rate by client ip + url
 	}, func(c *gin.Context) (*rate.Limiter, time.Duration) {
 		requestPath := strings.Split(c.Request.RequestURI, "?")[0]
 		ratePerSecond, ok := rateConfig.limits[requestPath]
@@ -354,6 +380,15 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 		}
 	}
 
+	eventBufferSize, err := configStore.GetConfigIntValueFor("sse.buffer.size", "backend")
+	if err != nil || eventBufferSize < 1 {
+		// the buffer drops its oldest event to make room, it holds at least one
+		eventBufferSize = 1000
+		err = configStore.SetConfigIntValueFor("sse.buffer.size", eventBufferSize, "backend")
+		resource.CheckErr(err, "Failed to store default value for sse.buffer.size")
+	}
+	eventStreamBuffer := NewEventStreamBuffer(eventBufferSize, &dtopicMap)
+
 	rcloneRetries, err := configStore.GetConfigIntValueFor("rclone.retries", "backend")
 	if err != nil {
 		rcloneRetries = 5
@@ -458,6 +493,9 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 	TaskScheduler = resource.NewTaskScheduler(&initConfig, cruds, configStore)
 
 	hostSwitch, subsiteCacheFolders := CreateSubSites(&initConfig, db, cruds, authMiddleware, rateConfig, maxConnections)
+	for _, hostRouter := range hostSwitch.handlerMap {
+		hostRouter.ForwardedByClientIP = false
+	}
 
 	if enableCaldav == "true" {
 
@@ -540,6 +578,36 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 		Schedule:    "@every 1h",
 	})
 
+	_, err = configStore.GetConfigIntValueFor("soft_delete.retention_days", "backend")
+	if err != nil {
+		err = configStore.SetConfigIntValueFor("soft_delete.retention_days", resource.DefaultSoftDeleteRetentionDays, "backend")
+		resource.CheckErr(err, "Failed to store default value for soft_delete.retention_days")
+	}
+
+	err = TaskScheduler.AddTask(resource.Task{
+		EntityName:  "world",
+		ActionName:  "purge_expired_deleted_rows",
+		Attributes:  map[string]interface{}{},
+		AsUserEmail: cruds[resource.USER_ACCOUNT_TABLE_NAME].GetAdminEmailId(),
+		Schedule:    "@every 1h",
+	})
+	resource.CheckErr(err, "Failed to register the soft delete retention task")
+
+	_, err = certificateManager.RotateJwtSigningKey(false)
+	resource.CheckErr(err, "Failed to generate the jwt signing key")
+	err = jwtmiddleware.SigningKeys.SetLoader(certificateManager.LoadJwtSigningKeys)
+	resource.CheckErr(err, "Failed to load the jwt signing keys")
+	jwtmiddleware.SigningKeys.AcceptSecret(certificateManager.JwtSecretWindow())
+
+	err = TaskScheduler.AddTask(resource.Task{
+		EntityName:  "certificate",
+		ActionName:  "rotate_jwt_signing_key",
+		Attributes:  map[string]interface{}{},
+		AsUserEmail: cruds[resource.USER_ACCOUNT_TABLE_NAME].GetAdminEmailId(),
+		Schedule:    "@every 1h",
+	})
+	resource.CheckErr(err, "Failed to register the jwt signing key rotation task")
+
 	TaskScheduler.StartTasks()
 
 	assetColumnFolders := CreateAssetColumnSync(cruds)
@@ -580,6 +648,22 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 		}()
 	}
 
+	defaultRouter.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, jwtmiddleware.SigningKeys.JWKS())
+	})
+
+	oauthServer := resource.NewOAuthServer(cruds, configStore)
+	if oauthServer.Enabled() {
+		defaultRouter.GET("/.well-known/openid-configuration", oauthServer.DiscoveryHandler)
+		defaultRouter.GET("/oauth/authorize", oauthServer.AuthorizeHandler)
+		defaultRouter.POST("/oauth/token", oauthServer.TokenHandler)
+		defaultRouter.GET("/oauth/userinfo", oauthServer.UserInfoHandler)
+		defaultRouter.POST("/oauth/userinfo", oauthServer.UserInfoHandler)
+	} else {
+		log.Infof("OpenID Connect provider is disabled, set oidc.issuer to enable it")
+	}
+
 	defaultRouter.GET("/ping", func(c *gin.Context) {
 		transaction, err := cruds["world"].Connection.Beginx()
 		//_, err := cruds["world"].GetObjectByWhereClause("world", "table_name", "world")
@@ -587,13 +671,13 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 			c.AbortWithError(500, err)
 			return
 		}
-		_ = transaction.Rollback()
+		_ = resource.RollbackTransaction(transaction)
 		c.String(200, "pong")
 	})
 
 	handler := CreateJsModelHandler(&initConfig, cruds)
 	metaHandler := CreateMetaHandler(&initConfig)
-	blueprintHandler := CreateApiBlueprintHandler(&initConfig, cruds)
+	blueprintHandler := CreateApiBlueprintHandler(&initConfig, cruds, configStore)
 	statsHandler := CreateStatsHandler(&initConfig, cruds)
 	resource.InitialiseColumnManager()
 
@@ -624,32 +708,72 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 			GraphiQL:   true,
 		})
 
-		// serve HTTP
+		graphqlMaxDepth, err := configStore.GetConfigIntValueFor("graphql.max_depth", "backend")
+		if err != nil {
+			graphqlMaxDepth = 10
+			err = configStore.SetConfigIntValueFor("graphql.max_depth", graphqlMaxDepth, "backend")
+			resource.CheckErr(err, "Failed to set a default value for graphql.max_depth")
+		}
+		graphqlMaxCost, err := configStore.GetConfigIntValueFor("graphql.max_cost", "backend")
+		if err != nil {
+			graphqlMaxCost = 5000
+			err = configStore.SetConfigIntValueFor("graphql.max_cost", graphqlMaxCost, "backend")
+			resource.CheckErr(err, "Failed to set a default value for graphql.max_cost")
+		}
+		persistedQueriesOnly, err := configStore.GetConfigValueFor("graphql.persisted_queries_only", "backend")
+		if err != nil {
+			persistedQueriesOnly = "false"
+			err = configStore.SetConfigValueFor("graphql.persisted_queries_only", persistedQueriesOnly, "backend")
+			resource.CheckErr(err, "Failed to set a default value for graphql.persisted_queries_only")
+		}
+		graphqlLimits := GraphqlQueryLimits{
+			MaxDepth: graphqlMaxDepth,
+			MaxCost:  graphqlMaxCost,
+		}
+		graphqlGuard := NewGraphqlGuard(graphqlSchema, graphqlLimits, persistedQueriesOnly == "true", cruds)
+
+		graphqlSubscriptionHandler := CreateGraphqlSubscriptionHandler(graphqlSchema, &dtopicMap, cruds, graphqlLimits)
+
+		// each request gets its own relation loader to batch the lookups of related objects
+		serveGraphql := func(c *gin.Context) {
+			if isGraphqlMultipartRequest(c.Request) {
+				err := rewriteGraphqlMultipartRequest(c.Request)
+				if err != nil {
+					c.AbortWithStatusJSON(400, gin.H{"errors": []gin.H{{"message": err.Error()}}})
+					return
+				}
+			}
+			status, err := graphqlGuard.Check(c.Request)
+			if err != nil {
+				c.AbortWithStatusJSON(status, gin.H{"errors": []gin.H{{"message": err.Error()}}})
+				return
+			}
+			graphqlHttpHandler.ContextHandler(WithGraphqlRelationLoader(c.Request.Context()), c.Writer, c.Request)
+		}
+
+		// serve HTTP, subscriptions are served over websocket on the same path
 		defaultRouter.Handle("GET", "/graphql", func(c *gin.Context) {
-			graphqlHttpHandler.ServeHTTP(c.Writer, c.Request)
+			if isWebsocketUpgrade(c.Request) {
+				graphqlSubscriptionHandler(c)
+				return
+			}
+			serveGraphql(c)
 		})
 		// serve HTTP
-		defaultRouter.Handle("POST", "/graphql", func(c *gin.Context) {
-			graphqlHttpHandler.ServeHTTP(c.Writer, c.Request)
-		})
+		defaultRouter.Handle("POST", "/graphql", serveGraphql)
 		// serve HTTP
-		defaultRouter.Handle("PUT", "/graphql", func(c *gin.Context) {
-			graphqlHttpHandler.ServeHTTP(c.Writer, c.Request)
-		})
+		defaultRouter.Handle("PUT", "/graphql", serveGraphql)
 		// serve HTTP
-		defaultRouter.Handle("PATCH", "/graphql", func(c *gin.Context) {
-			graphqlHttpHandler.ServeHTTP(c.Writer, c.Request)
-		})
+		defaultRouter.Handle("PATCH", "/graphql", serveGraphql)
 		// serve HTTP
-		defaultRouter.Handle("DELETE", "/graphql", func(c *gin.Context) {
-			graphqlHttpHandler.ServeHTTP(c.Writer, c.Request)
-		})
+		defaultRouter.Handle("DELETE", "/graphql", serveGraphql)
 	}
 
 	defaultRouter.GET("/jsmodel/:typename", handler)
 	defaultRouter.GET("/aggregate/:typename", statsHandler)
 	defaultRouter.GET("/meta", metaHandler)
 	defaultRouter.GET("/openapi.yaml", blueprintHandler)
+	defaultRouter.GET("/codegen/:filename", CreateCodegenHandler(&initConfig))
 	defaultRouter.OPTIONS("/jsmodel/:typename", handler)
 	defaultRouter.OPTIONS("/openapi.yaml", blueprintHandler)
 
@@ -668,6 +792,8 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 	//TODO: make websockets functional at /live
 
 	websocketServer := websockets.NewServer("/live", &dtopicMap, cruds)
+	defaultRouter.GET("/events", CreateEventStreamHandler(eventStreamBuffer, cruds))
+	defaultRouter.GET("/changes", CreateChangeLogHandler(cruds))
 
 	if enableYjs == "true" {
 		var ydbInstance = ydb.InitYdb(documentProvider)
@@ -914,7 +1040,7 @@ func initialiseResources(initConfig *resource.CmsConfig, db database.DatabaseCon
 	resource.CheckErr(errb, "Failed to begin transaction")
 	if tx != nil {
 		resource.CreateUniqueConstraints(initConfig, tx)
-		errc = tx.Commit()
+		errc = resource.CommitTransaction(tx)
 		resource.CheckErr(errc, "Failed to commit transaction after creating unique constrains")
 	}
 
@@ -922,7 +1048,7 @@ func initialiseResources(initConfig *resource.CmsConfig, db database.DatabaseCon
 	resource.CheckErr(errb, "Failed to begin transaction for creating indexes")
 	if tx != nil {
 		resource.CreateIndexes(initConfig, db)
-		errc = tx.Commit()
+		errc = resource.CommitTransaction(tx)
 		resource.CheckErr(errc, "Failed to commit transaction after creating indexes")
 	}
 
@@ -932,7 +1058,7 @@ func initialiseResources(initConfig *resource.CmsConfig, db database.DatabaseCon
 	if tx != nil {
 		errb = resource.UpdateWorldTable(initConfig, tx)
 		resource.CheckErr(errb, "Failed to update world tables")
-		errc := tx.Commit()
+		errc := resource.CommitTransaction(tx)
 		resource.CheckErr(errc, "Failed to commit transaction after updating world tables")
 	}
 
@@ -1040,6 +1166,10 @@ func MergeTables(existingTables []resource.TableInfo, initConfigTables []resourc
 			existableTable.Conformations = tableBeingModified.Conformations
 			existableTable.Validations = tableBeingModified.Validations
 			existableTable.CompositeKeys = tableBeingModified.CompositeKeys
+			existableTable.VirtualColumns = tableBeingModified.VirtualColumns
+			existableTable.ColumnPermissions = tableBeingModified.ColumnPermissions
+			existableTable.RowPolicies = tableBeingModified.RowPolicies
+			existableTable.IsSoftDeleteEnabled = tableBeingModified.IsSoftDeleteEnabled
 			existableTable.Icon = tableBeingModified.Icon
 			existingTables[j] = existableTable
 		} else {
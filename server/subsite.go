@@ -229,7 +229,7 @@ func CreateSubSites(cmsConfig *resource.CmsConfig, db database.DatabaseConnectio
 		hostRouter.Use(limit.MaxAllowed(max_connections))
 		hostRouter.Use(limit2.NewRateLimiter(func(c *gin.Context) string {
 			requestPath := c.Request.Host + "/" + strings.Split(c.Request.RequestURI, "?")[0]
-			return c.ClientIP() + requestPath // limit rate by client ip
+			return resource.ClientIP(c) + requestPath // limit rate by client ip
 		}, func(c *gin.Context) (*rate.Limiter, time.Duration) {
 			requestPath := c.Request.Host + "/" + strings.Split(c.Request.RequestURI, "?")[0]
 			limitValue, ok := rateConfig.limits[requestPath]
@@ -264,7 +264,7 @@ func CreateSubSites(cmsConfig *resource.CmsConfig, db database.DatabaseConnectio
 			c.File(faviconPath)
 		})
 		hostRouter.NoRoute(func(c *gin.Context) {
-			log.Printf("Found no route for [%v] [%v] [%v]", c.ClientIP(), c.Request.Header.Get("User-Agent"), c.Request.URL)
+			log.Printf("Found no route for [%v] [%v] [%v]", resource.ClientIP(c), c.Request.Header.Get("User-Agent"), c.Request.URL)
 			c.File(tempDirectoryPath + "/index.html")
 			c.AbortWithStatus(404)
 		})
@@ -194,9 +194,10 @@ func BuildMiddlewareSet(cmsConfig *resource.CmsConfig,
 	objectPermissionChecker := &resource.ObjectAccessPermissionChecker{}
 	dataValidationMiddleware := resource.NewDataValidationMiddleware(cmsConfig, cruds)
 
-	createEventHandler := resource.NewCreateEventHandler(cruds, dtopicMap)
-	updateEventHandler := resource.NewUpdateEventHandler(cruds, dtopicMap)
-	deleteEventHandler := resource.NewDeleteEventHandler(cruds, dtopicMap)
+	webhookDispatcher := resource.GetWebhookDispatcher(cruds)
+	createEventHandler := resource.NewCreateEventHandler(cruds, dtopicMap, webhookDispatcher)
+	updateEventHandler := resource.NewUpdateEventHandler(cruds, dtopicMap, webhookDispatcher)
+	deleteEventHandler := resource.NewDeleteEventHandler(cruds, dtopicMap, webhookDispatcher)
 
 	var yhsHandler resource.DatabaseRequestInterceptor
 	yhsHandler = nil
@@ -234,6 +235,7 @@ func BuildMiddlewareSet(cmsConfig *resource.CmsConfig,
 	ms.BeforeDelete = []resource.DatabaseRequestInterceptor{
 		tablePermissionChecker,
 		objectPermissionChecker,
+		dataValidationMiddleware,
 		deleteEventHandler,
 		exchangeMiddleware,
 	}
@@ -0,0 +1,67 @@
+package websockets
+
+import (
+	"encoding/gob"
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/resource"
+)
+
+// TopicMeta is the ownership record of a user created topic, stored in the olric
+// topic-meta dmap so that every node in the cluster applies the same permission checks
+type TopicMeta struct {
+	Name            string
+	UserReferenceId string
+	Groups          []auth.GroupPermission
+	Permission      auth.AuthPermission
+}
+
+func init() {
+	// the ownership record is read by every node of the cluster
+	gob.Register(TopicMeta{})
+}
+
+// PermissionInstance converts the topic ownership into the row permission used everywhere else
+func (tm TopicMeta) PermissionInstance() resource.PermissionInstance {
+	return resource.PermissionInstance{
+		UserId:      tm.UserReferenceId,
+		UserGroupId: tm.Groups,
+		Permission:  tm.Permission,
+	}
+}
+
+// NewTopicMeta creates the ownership record for a topic created by the session user
+// the user groups of the creator get the group bits of the topic permission
+func NewTopicMeta(topicName string, user *auth.SessionUser, permission auth.AuthPermission) TopicMeta {
+
+	groups := make([]auth.GroupPermission, 0)
+	for _, group := range user.Groups {
+		groups = append(groups, auth.GroupPermission{
+			GroupReferenceId:    group.GroupReferenceId,
+			ObjectReferenceId:   topicName,
+			RelationReferenceId: group.RelationReferenceId,
+			Permission:          permission,
+		})
+	}
+
+	return TopicMeta{
+		Name:            topicName,
+		UserReferenceId: user.UserReferenceId,
+		Groups:          groups,
+		Permission:      permission,
+	}
+}
+
+// GetTopicMeta returns the ownership record of a user created topic
+// ok is false for topics which were not created by a user (eg table topics)
+func GetTopicMeta(topicMetaMap *olric.DMap, topicName string) (TopicMeta, bool) {
+	if topicMetaMap == nil {
+		return TopicMeta{}, false
+	}
+	value, err := topicMetaMap.Get(topicName)
+	if err != nil || value == nil {
+		return TopicMeta{}, false
+	}
+	topicMeta, ok := value.(TopicMeta)
+	return topicMeta, ok
+}
@@ -0,0 +1,103 @@
+package websockets
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/buraksezer/olric"
+	"github.com/buraksezer/olric/query"
+	"github.com/daptin/daptin/server/resource"
+	"regexp"
+	"time"
+)
+
+// PresenceTimeout is how long a member stays in the presence list of a topic without a heartbeat
+const PresenceTimeout = 90 * time.Second
+
+// PresenceEntry is one connected client on a topic, stored in the olric topic-presence dmap
+// with an expiry of PresenceTimeout, every heartbeat from the client refreshes the expiry
+type PresenceEntry struct {
+	Topic           string
+	UserReferenceId string
+	ConnectionId    string
+	JoinedAt        time.Time
+	LastSeen        time.Time
+	State           map[string]interface{}
+}
+
+func init() {
+	// entries are stored and read by every node of the cluster, the state holds any json value
+	gob.Register(PresenceEntry{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+func (pe PresenceEntry) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"user_reference_id": pe.UserReferenceId,
+		"connection_id":     pe.ConnectionId,
+		"joined_at":         pe.JoinedAt,
+		"last_seen":         pe.LastSeen,
+		"state":             pe.State,
+	}
+}
+
+func presenceKey(topicName string, connectionId string) string {
+	return fmt.Sprintf("%s/%s", topicName, connectionId)
+}
+
+// PutPresence adds or refreshes the presence of a connection on a topic
+func PutPresence(presenceMap *olric.DMap, entry PresenceEntry) error {
+	entry.LastSeen = time.Now()
+	return presenceMap.PutEx(presenceKey(entry.Topic, entry.ConnectionId), entry, PresenceTimeout)
+}
+
+// GetPresence returns the presence of a connection on a topic
+func GetPresence(presenceMap *olric.DMap, topicName string, connectionId string) (PresenceEntry, bool) {
+	value, err := presenceMap.Get(presenceKey(topicName, connectionId))
+	if err != nil || value == nil {
+		return PresenceEntry{}, false
+	}
+	entry, ok := value.(PresenceEntry)
+	return entry, ok
+}
+
+// RemovePresence removes a connection from the presence list of a topic
+func RemovePresence(presenceMap *olric.DMap, topicName string, connectionId string) error {
+	return presenceMap.Delete(presenceKey(topicName, connectionId))
+}
+
+// ListPresence returns all the connections currently present on a topic across the cluster
+func ListPresence(presenceMap *olric.DMap, topicName string) ([]PresenceEntry, error) {
+	members := make([]PresenceEntry, 0)
+
+	cursor, err := presenceMap.Query(query.M{
+		"$onKey": query.M{
+			"$regexMatch": "^" + regexp.QuoteMeta(topicName+"/"),
+		},
+	})
+	if err != nil {
+		return members, err
+	}
+	defer cursor.Close()
+
+	staleBefore := time.Now().Add(-PresenceTimeout)
+	err = cursor.Range(func(key string, value interface{}) bool {
+		entry, ok := value.(PresenceEntry)
+		if ok && entry.LastSeen.After(staleBefore) {
+			members = append(members, entry)
+		}
+		return true
+	})
+
+	return members, err
+}
+
+// presenceEvent is published on the topic itself so that all subscribers see joins and leaves
+func presenceEvent(eventType string, entry PresenceEntry) resource.EventMessage {
+	return resource.EventMessage{
+		MessageSource: entry.UserReferenceId,
+		EventType:     eventType,
+		ObjectType:    entry.Topic,
+		EventData:     entry.AsMap(),
+	}
+}
@@ -6,14 +6,68 @@ import (
 	"github.com/daptin/daptin/server/resource"
 	log "github.com/sirupsen/logrus"
 	"strings"
+	"sync"
+	"time"
 )
 
+// topicMapLock guards the shared topic map, user topics are created and destroyed from client goroutines
+var topicMapLock = sync.RWMutex{}
+
 // WebSocketConnectionHandlerImpl : Each websocket connection has its own handler
 type WebSocketConnectionHandlerImpl struct {
 	DtopicMap        *map[string]*olric.DTopic
 	subscribedTopics map[string]uint64
+	joinedTopics     map[string]bool
 	olricDb          *olric.Olric
 	cruds            map[string]*resource.DbResource
+	topicMetaMap     *olric.DMap
+	presenceMap      *olric.DMap
+}
+
+func (wsch *WebSocketConnectionHandlerImpl) getTopic(topicName string) (*olric.DTopic, bool) {
+	topicMapLock.RLock()
+	topic, ok := (*wsch.DtopicMap)[topicName]
+	topicMapLock.RUnlock()
+	if ok {
+		return topic, true
+	}
+
+	// the topic might have been created by a client connected to another node of the cluster
+	_, isUserTopic := GetTopicMeta(wsch.topicMetaMap, topicName)
+	if !isUserTopic {
+		return nil, false
+	}
+
+	topic, err := wsch.olricDb.NewDTopic(topicName, 4, 1)
+	if err != nil {
+		resource.CheckErr(err, "Failed to open user topic [%v]", topicName)
+		return nil, false
+	}
+	topicMapLock.Lock()
+	(*wsch.DtopicMap)[topicName] = topic
+	topicMapLock.Unlock()
+	return topic, true
+}
+
+// topicPermission returns the permission of a user created topic, table topics are checked per row
+func (wsch *WebSocketConnectionHandlerImpl) topicPermission(topicName string) (resource.PermissionInstance, bool) {
+	topicMeta, ok := GetTopicMeta(wsch.topicMetaMap, topicName)
+	if !ok {
+		return resource.PermissionInstance{}, false
+	}
+	return topicMeta.PermissionInstance(), true
+}
+
+func (wsch *WebSocketConnectionHandlerImpl) sendError(client *Client, topicName string, message string) {
+	client.ch <- resource.EventMessage{
+		EventData: map[string]interface{}{
+			"topic":   topicName,
+			"message": message,
+		},
+		MessageSource: "system",
+		EventType:     "error",
+		ObjectType:    topicName,
+	}
 }
 
 func (wsch *WebSocketConnectionHandlerImpl) MessageFromClient(message WebSocketPayload, client *Client) {
@@ -37,6 +91,23 @@ func (wsch *WebSocketConnectionHandlerImpl) MessageFromClient(message WebSocketP
 		for _, topic := range topicsList {
 			_, ok := wsch.subscribedTopics[topic]
 			if !ok {
+
+				dtopic, ok := wsch.getTopic(topic)
+				if !ok {
+					log.Printf("topic does not exist: %v", topic)
+					continue
+				}
+
+				topicPermission, isUserTopic := wsch.topicPermission(topic)
+				if isUserTopic && !topicPermission.CanRead(client.user.UserReferenceId, client.user.Groups) {
+					wsch.sendError(client, topic, "unauthorized")
+					continue
+				}
+				if _, isTable := wsch.cruds[topic]; isTable && !client.user.ApiKeyScope.AllowsTable(topic, "GET") {
+					wsch.sendError(client, topic, "unauthorized")
+					continue
+				}
+
 				var err error
 				eventType, ok := filtersMap["EventType"]
 				eventTypeString := ""
@@ -44,7 +115,7 @@ func (wsch *WebSocketConnectionHandlerImpl) MessageFromClient(message WebSocketP
 					eventTypeString = eventType.(string)
 					delete(filtersMap, "EventType")
 				}
-				wsch.subscribedTopics[topic], err = (*wsch.DtopicMap)[topic].AddListener(func(eventType string, filtersMap map[string]interface{}) func(olric.DTopicMessage) {
+				wsch.subscribedTopics[topic], err = dtopic.AddListener(func(eventType string, filtersMap map[string]interface{}) func(olric.DTopicMessage) {
 					return func(message olric.DTopicMessage) {
 						eventMessage := message.Message.(resource.EventMessage)
 
@@ -54,13 +125,11 @@ func (wsch *WebSocketConnectionHandlerImpl) MessageFromClient(message WebSocketP
 							_, tableExists = wsch.cruds[typeName.(string)]
 						}
 
-						permission := resource.PermissionInstance{Permission: auth.ALLOW_ALL_PERMISSIONS}
-
+						canRead := true
 						if tableExists {
-							permission = wsch.cruds["world"].GetRowPermission(eventMessage.EventData)
-
+							eventMessage.EventData, canRead = wsch.cruds[typeName.(string)].ReadableRow(eventMessage.EventData, client.user)
 						}
-						if permission.CanRead(client.user.UserReferenceId, client.user.Groups) {
+						if canRead {
 
 							sendMessage := true
 							if filtersMap != nil {
@@ -97,22 +166,41 @@ func (wsch *WebSocketConnectionHandlerImpl) MessageFromClient(message WebSocketP
 			return
 		}
 
+		topicMapLock.RLock()
 		_, exists := (*wsch.DtopicMap)[topic]
-		if exists {
+		topicMapLock.RUnlock()
+		_, existsInCluster := GetTopicMeta(wsch.topicMetaMap, topic)
+		if exists || existsInCluster {
 			log.Printf("topic already exists: %v", topic)
 			return
 		}
 
+		permission := auth.DEFAULT_PERMISSION
+		permissionValue, ok := message.Payload["permission"].(float64)
+		if ok {
+			permission = auth.AuthPermission(int64(permissionValue))
+		}
+
+		err := wsch.topicMetaMap.PutIf(topic, NewTopicMeta(topic, client.user, permission), olric.IfNotFound)
+		if err != nil {
+			resource.CheckErr(err, "Failed to store owner of new topic [%v]", topic)
+			return
+		}
+
 		newTopic, err := wsch.olricDb.NewDTopic(topic, 4, 1)
 		resource.CheckErr(err, "Failed to create new topic on client request [%v]", topic)
 
+		topicMapLock.Lock()
 		(*wsch.DtopicMap)[topic] = newTopic
+		topicMapLock.Unlock()
 
 	case "list-topic":
 		topics := make([]string, 0)
+		topicMapLock.RLock()
 		for t, _ := range *wsch.DtopicMap {
 			topics = append(topics, t)
 		}
+		topicMapLock.RUnlock()
 
 		client.ch <- resource.EventMessage{
 			EventData: map[string]interface{}{
@@ -136,32 +224,173 @@ func (wsch *WebSocketConnectionHandlerImpl) MessageFromClient(message WebSocketP
 			return
 		}
 
-		err := (*wsch.DtopicMap)[topic].Destroy()
+		topicPermission, isUserTopic := wsch.topicPermission(topic)
+		if isUserTopic && !topicPermission.CanDelete(client.user.UserReferenceId, client.user.Groups) {
+			wsch.sendError(client, topic, "unauthorized")
+			return
+		}
+
+		dtopic, ok := wsch.getTopic(topic)
+		if !ok {
+			log.Printf("topic does not exist: %v", topic)
+			return
+		}
+
+		err := dtopic.Destroy()
 		resource.CheckErr(err, "failed to destroy topic")
+		err = wsch.topicMetaMap.Delete(topic)
+		resource.CheckErr(err, "failed to remove owner of topic [%v]", topic)
+		topicMapLock.Lock()
 		delete(*wsch.DtopicMap, topic)
+		topicMapLock.Unlock()
 
-	case "new-message":
+	case "publish", "new-message":
 		var err error
-		var topic *olric.DTopic
 		topicName, ok := message.Payload["topic"].(string)
-		message, ok := message.Payload["message"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		eventData, _ := message.Payload["message"].(map[string]interface{})
 
-		topic, ok = (*wsch.DtopicMap)[topicName]
+		_, isSystemTopic := wsch.cruds[topicName]
+		if isSystemTopic {
+			wsch.sendError(client, topicName, "clients can publish only on user created topics")
+			return
+		}
 
+		topic, ok := wsch.getTopic(topicName)
 		if !ok {
 			log.Printf("topic does not exist: %v", topicName)
 			return
 		}
 
+		topicPermission, isUserTopic := wsch.topicPermission(topicName)
+		if isUserTopic && !topicPermission.CanCreate(client.user.UserReferenceId, client.user.Groups) {
+			wsch.sendError(client, topicName, "unauthorized")
+			return
+		}
+
 		err = topic.Publish(resource.EventMessage{
 			MessageSource: client.user.UserReferenceId,
 			EventType:     "new-message",
 			ObjectType:    topicName,
-			EventData:     message,
+			EventData:     eventData,
 		})
 
 		resource.CheckErr(err, "Failed to publish message on topic")
 
+	case "join":
+		topicName, ok := message.Payload["topic"].(string)
+		if !ok {
+			return
+		}
+		state, _ := message.Payload["state"].(map[string]interface{})
+
+		topic, ok := wsch.getTopic(topicName)
+		if !ok {
+			log.Printf("topic does not exist: %v", topicName)
+			return
+		}
+
+		topicPermission, isUserTopic := wsch.topicPermission(topicName)
+		if !isUserTopic {
+			wsch.sendError(client, topicName, "presence is available only on user created topics")
+			return
+		}
+		if !topicPermission.CanRead(client.user.UserReferenceId, client.user.Groups) {
+			wsch.sendError(client, topicName, "unauthorized")
+			return
+		}
+
+		now := time.Now()
+		entry := PresenceEntry{
+			Topic:           topicName,
+			UserReferenceId: client.user.UserReferenceId,
+			ConnectionId:    client.connectionId,
+			JoinedAt:        now,
+			LastSeen:        now,
+			State:           state,
+		}
+		err := PutPresence(wsch.presenceMap, entry)
+		if err != nil {
+			resource.CheckErr(err, "Failed to store presence on topic [%v]", topicName)
+			return
+		}
+		wsch.joinedTopics[topicName] = true
+
+		err = topic.Publish(presenceEvent("presence-join", entry))
+		resource.CheckErr(err, "Failed to publish presence join on topic [%v]", topicName)
+
+	case "heartbeat":
+		topicNames := make([]string, 0)
+		topics, ok := message.Payload["topic"].(string)
+		if ok && len(topics) > 0 {
+			topicNames = strings.Split(topics, ",")
+		} else {
+			for topicName := range wsch.joinedTopics {
+				topicNames = append(topicNames, topicName)
+			}
+		}
+		state, hasState := message.Payload["state"].(map[string]interface{})
+
+		for _, topicName := range topicNames {
+			if !wsch.joinedTopics[topicName] {
+				continue
+			}
+			entry, ok := GetPresence(wsch.presenceMap, topicName, client.connectionId)
+			if !ok {
+				// expired in between, join again with the same connection
+				entry = PresenceEntry{
+					Topic:           topicName,
+					UserReferenceId: client.user.UserReferenceId,
+					ConnectionId:    client.connectionId,
+					JoinedAt:        time.Now(),
+				}
+			}
+			if hasState {
+				entry.State = state
+			}
+			err := PutPresence(wsch.presenceMap, entry)
+			resource.CheckErr(err, "Failed to refresh presence on topic [%v]", topicName)
+		}
+
+	case "leave":
+		topicName, ok := message.Payload["topic"].(string)
+		if !ok {
+			return
+		}
+		wsch.leaveTopic(topicName, client)
+
+	case "presence":
+		topicName, ok := message.Payload["topic"].(string)
+		if !ok {
+			return
+		}
+
+		topicPermission, isUserTopic := wsch.topicPermission(topicName)
+		if !isUserTopic || !topicPermission.CanRead(client.user.UserReferenceId, client.user.Groups) {
+			wsch.sendError(client, topicName, "unauthorized")
+			return
+		}
+
+		members, err := ListPresence(wsch.presenceMap, topicName)
+		resource.CheckErr(err, "Failed to list presence on topic [%v]", topicName)
+
+		memberList := make([]map[string]interface{}, 0)
+		for _, member := range members {
+			memberList = append(memberList, member.AsMap())
+		}
+
+		client.ch <- resource.EventMessage{
+			EventData: map[string]interface{}{
+				"topic":   topicName,
+				"members": memberList,
+			},
+			MessageSource: "system",
+			EventType:     "response",
+			ObjectType:    "presence-list",
+		}
+
 	case "unsubscribe":
 		topics := message.Payload["topic"].(string)
 		if len(topics) < 1 {
@@ -169,14 +398,59 @@ func (wsch *WebSocketConnectionHandlerImpl) MessageFromClient(message WebSocketP
 		}
 		topicsList := strings.Split(topics, ",")
 		for _, topic := range topicsList {
-			subscriptionId, ok := wsch.subscribedTopics[topic]
-			if ok {
-				err := (*wsch.DtopicMap)[topic].RemoveListener(subscriptionId)
-				delete(wsch.subscribedTopics, topic)
-				if err != nil {
-					log.Printf("Failed to remove listener from topic: %v", err)
-				}
-			}
+			wsch.unsubscribe(topic)
+		}
+	}
+}
+
+func (wsch *WebSocketConnectionHandlerImpl) unsubscribe(topicName string) {
+	subscriptionId, ok := wsch.subscribedTopics[topicName]
+	if !ok {
+		return
+	}
+	delete(wsch.subscribedTopics, topicName)
+
+	topic, ok := wsch.getTopic(topicName)
+	if !ok {
+		return
+	}
+	err := topic.RemoveListener(subscriptionId)
+	if err != nil {
+		log.Printf("Failed to remove listener from topic: %v", err)
+	}
+}
+
+func (wsch *WebSocketConnectionHandlerImpl) leaveTopic(topicName string, client *Client) {
+	if !wsch.joinedTopics[topicName] {
+		return
+	}
+	delete(wsch.joinedTopics, topicName)
+
+	entry, ok := GetPresence(wsch.presenceMap, topicName, client.connectionId)
+	if !ok {
+		entry = PresenceEntry{
+			Topic:           topicName,
+			UserReferenceId: client.user.UserReferenceId,
+			ConnectionId:    client.connectionId,
 		}
 	}
+	err := RemovePresence(wsch.presenceMap, topicName, client.connectionId)
+	resource.CheckErr(err, "Failed to remove presence from topic [%v]", topicName)
+
+	topic, ok := wsch.getTopic(topicName)
+	if !ok {
+		return
+	}
+	err = topic.Publish(presenceEvent("presence-leave", entry))
+	resource.CheckErr(err, "Failed to publish presence leave on topic [%v]", topicName)
+}
+
+// ClientDisconnected removes the listeners and presence of a closed connection
+func (wsch *WebSocketConnectionHandlerImpl) ClientDisconnected(client *Client) {
+	for topicName := range wsch.joinedTopics {
+		wsch.leaveTopic(topicName, client)
+	}
+	for topicName := range wsch.subscribedTopics {
+		wsch.unsubscribe(topicName)
+	}
 }
@@ -0,0 +1,244 @@
+package websockets
+
+import (
+	"context"
+	"github.com/buraksezer/olric"
+	olricConfig "github.com/buraksezer/olric/config"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/resource"
+	"golang.org/x/net/websocket"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+var testOlricDb *olric.Olric
+
+func freePort() int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestMain(m *testing.M) {
+
+	config := olricConfig.New("local")
+	config.BindAddr = "127.0.0.1"
+	config.BindPort = freePort()
+	config.MemberlistConfig.BindAddr = "127.0.0.1"
+	config.MemberlistConfig.BindPort = freePort()
+	config.LogOutput = ioutil.Discard
+	started, cancel := context.WithCancel(context.Background())
+	config.Started = cancel
+
+	var err error
+	testOlricDb, err = olric.New(config)
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		err := testOlricDb.Start()
+		resource.CheckErr(err, "Failed to start olric")
+	}()
+	<-started.Done()
+
+	code := m.Run()
+	_ = testOlricDb.Shutdown(context.Background())
+	os.Exit(code)
+}
+
+// newTestClient is a connection of the user which shares the topics and maps of the other test clients
+func newTestClient(t *testing.T, dtopicMap *map[string]*olric.DTopic, userReferenceId string) *Client {
+	topicMetaMap, err := testOlricDb.NewDMap("topic-meta")
+	if err != nil {
+		t.Fatalf("failed to create topic-meta map: %v", err)
+	}
+	presenceMap, err := testOlricDb.NewDMap("topic-presence")
+	if err != nil {
+		t.Fatalf("failed to create topic-presence map: %v", err)
+	}
+	return &Client{
+		connectionId: "connection-" + userReferenceId,
+		ch:           make(chan resource.EventMessage, channelBufSize),
+		doneCh:       make(chan struct{}),
+		user:         &auth.SessionUser{UserReferenceId: userReferenceId},
+		webSocketConnectionHandler: WebSocketConnectionHandlerImpl{
+			DtopicMap:        dtopicMap,
+			subscribedTopics: make(map[string]uint64),
+			joinedTopics:     make(map[string]bool),
+			olricDb:          testOlricDb,
+			cruds:            map[string]*resource.DbResource{},
+			topicMetaMap:     topicMetaMap,
+			presenceMap:      presenceMap,
+		},
+	}
+}
+
+// send hands a message of the client to its handler as if it was read from the connection
+func send(c *Client, method string, payload Message) {
+	c.webSocketConnectionHandler.MessageFromClient(WebSocketPayload{Method: method, Payload: payload}, c)
+}
+
+// nextEvent waits for the next event sent to the client, nil when there is none
+func nextEvent(client *Client) *resource.EventMessage {
+	select {
+	case event := <-client.ch:
+		return &event
+	case <-time.After(2 * time.Second):
+		return nil
+	}
+}
+
+func TestTopicPermissions(t *testing.T) {
+
+	dtopicMap := make(map[string]*olric.DTopic)
+	owner := newTestClient(t, &dtopicMap, "owner")
+	other := newTestClient(t, &dtopicMap, "other")
+
+	send(owner, "create-topic", Message{"name": "private-chat", "permission": float64(auth.UserCRUD)})
+	if _, ok := GetTopicMeta(owner.webSocketConnectionHandler.topicMetaMap, "private-chat"); !ok {
+		t.Fatalf("expected the owner of the new topic to be stored")
+	}
+
+	send(other, "subscribe", Message{"topic": "private-chat"})
+	if event := nextEvent(other); event == nil || event.EventType != "error" {
+		t.Errorf("expected other users not to subscribe, got %v", event)
+	}
+	if len(other.webSocketConnectionHandler.subscribedTopics) != 0 {
+		t.Errorf("expected no listener for the denied subscription")
+	}
+	send(other, "publish", Message{"topic": "private-chat", "message": map[string]interface{}{"text": "hi"}})
+	if event := nextEvent(other); event == nil || event.EventType != "error" {
+		t.Errorf("expected other users not to publish, got %v", event)
+	}
+	send(other, "destroy-topic", Message{"name": "private-chat"})
+	if event := nextEvent(other); event == nil || event.EventType != "error" {
+		t.Errorf("expected other users not to destroy the topic, got %v", event)
+	}
+	if _, ok := dtopicMap["private-chat"]; !ok {
+		t.Fatalf("expected the topic to be left in place")
+	}
+
+	send(owner, "subscribe", Message{"topic": "private-chat"})
+	send(owner, "publish", Message{"topic": "private-chat", "message": map[string]interface{}{"text": "hello"}})
+	if event := nextEvent(owner); event == nil || event.EventType != "new-message" || event.EventData["text"] != "hello" {
+		t.Errorf("expected the owner to receive their message, got %v", event)
+	}
+
+	owner.webSocketConnectionHandler.ClientDisconnected(owner)
+	if len(owner.webSocketConnectionHandler.subscribedTopics) != 0 {
+		t.Errorf("expected the listeners to be removed on disconnect")
+	}
+
+	send(owner, "destroy-topic", Message{"name": "private-chat"})
+	if _, ok := dtopicMap["private-chat"]; ok {
+		t.Errorf("expected the owner to destroy the topic")
+	}
+	if _, ok := GetTopicMeta(owner.webSocketConnectionHandler.topicMetaMap, "private-chat"); ok {
+		t.Errorf("expected the owner record to be removed with the topic")
+	}
+}
+
+func TestTopicPresence(t *testing.T) {
+
+	dtopicMap := make(map[string]*olric.DTopic)
+	owner := newTestClient(t, &dtopicMap, "owner")
+	other := newTestClient(t, &dtopicMap, "other")
+	presenceMap := owner.webSocketConnectionHandler.presenceMap
+
+	send(owner, "create-topic", Message{"name": "lobby", "permission": float64(auth.UserCRUD | auth.GuestRead)})
+	send(owner, "subscribe", Message{"topic": "lobby"})
+
+	send(other, "join", Message{"topic": "lobby", "state": map[string]interface{}{"status": "online"}})
+	if event := nextEvent(owner); event == nil || event.EventType != "presence-join" {
+		t.Errorf("expected subscribers to see the join, got %v", event)
+	}
+	entry, ok := GetPresence(presenceMap, "lobby", other.connectionId)
+	if !ok || entry.UserReferenceId != "other" || entry.State["status"] != "online" {
+		t.Fatalf("expected the presence of the joined user, got %v", entry)
+	}
+
+	send(other, "heartbeat", Message{"state": map[string]interface{}{"status": "away"}})
+	refreshed, ok := GetPresence(presenceMap, "lobby", other.connectionId)
+	if !ok || refreshed.State["status"] != "away" || refreshed.LastSeen.Before(entry.LastSeen) {
+		t.Errorf("expected the heartbeat to refresh the presence, got %v", refreshed)
+	}
+
+	members, err := ListPresence(presenceMap, "lobby")
+	if err != nil || len(members) != 1 {
+		t.Errorf("expected one member in the lobby, got %v: %v", members, err)
+	}
+
+	send(other, "leave", Message{"topic": "lobby"})
+	if event := nextEvent(owner); event == nil || event.EventType != "presence-leave" {
+		t.Errorf("expected subscribers to see the leave, got %v", event)
+	}
+	if _, ok := GetPresence(presenceMap, "lobby", other.connectionId); ok {
+		t.Errorf("expected the presence to be removed on leave")
+	}
+
+	send(other, "join", Message{"topic": "lobby"})
+	other.webSocketConnectionHandler.ClientDisconnected(other)
+	if _, ok := GetPresence(presenceMap, "lobby", other.connectionId); ok {
+		t.Errorf("expected the presence to be removed on disconnect")
+	}
+
+	owner.webSocketConnectionHandler.ClientDisconnected(owner)
+	send(owner, "destroy-topic", Message{"name": "lobby"})
+}
+
+func TestClientListenCleansUpOnClose(t *testing.T) {
+
+	dtopicMap := make(map[string]*olric.DTopic)
+	owner := newTestClient(t, &dtopicMap, "owner")
+	send(owner, "create-topic", Message{"name": "room", "permission": float64(auth.UserCRUD)})
+	defer send(owner, "destroy-topic", Message{"name": "room"})
+
+	client := newTestClient(t, &dtopicMap, "owner")
+	client.connectionId = "closing-connection"
+	client.server = &Server{delCh: make(chan *Client, 1), errCh: make(chan error, 10)}
+	closed := make(chan bool)
+	httpServer := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		client.ws = ws
+		client.Listen()
+		closed <- true
+	}))
+	defer httpServer.Close()
+
+	ws, err := websocket.Dial("ws"+httpServer.URL[len("http"):], "", httpServer.URL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if err = websocket.JSON.Send(ws, WebSocketPayload{Method: "join", Payload: Message{"topic": "room"}}); err != nil {
+		t.Fatalf("failed to join: %v", err)
+	}
+	if err = websocket.JSON.Send(ws, WebSocketPayload{Method: "subscribe", Payload: Message{"topic": "room"}}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := GetPresence(client.webSocketConnectionHandler.presenceMap, "room", client.connectionId); !ok {
+		t.Fatalf("expected the connection to join the topic")
+	}
+	_ = ws.Close()
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the client to stop listening once the connection is closed")
+	}
+	if _, ok := GetPresence(client.webSocketConnectionHandler.presenceMap, "room", client.connectionId); ok {
+		t.Errorf("expected the presence to be removed once the connection is closed")
+	}
+	if len(client.webSocketConnectionHandler.subscribedTopics) != 0 {
+		t.Errorf("expected the listeners to be removed once the connection is closed")
+	}
+	if deleted := <-client.server.delCh; deleted != client {
+		t.Errorf("expected the client to be removed from the server")
+	}
+}
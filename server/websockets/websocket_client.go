@@ -3,11 +3,13 @@ package websockets
 import (
 	"errors"
 	"fmt"
+	"github.com/artpar/go.uuid"
 	"github.com/daptin/daptin/server/auth"
 	"github.com/daptin/daptin/server/resource"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/websocket"
 	"io"
+	"sync"
 )
 
 const channelBufSize = 100
@@ -16,10 +18,12 @@ var maxId = 0
 
 type Client struct {
 	id                         int
+	connectionId               string
 	ws                         *websocket.Conn
 	server                     *Server
 	ch                         chan resource.EventMessage
-	doneCh                     chan bool
+	doneCh                     chan struct{}
+	doneOnce                   sync.Once
 	user                       *auth.SessionUser
 	webSocketConnectionHandler WebSocketConnectionHandlerImpl
 }
@@ -38,21 +42,26 @@ func NewClient(ws *websocket.Conn, server *Server) (*Client, error) {
 	webSocketConnectionHandler := WebSocketConnectionHandlerImpl{
 		DtopicMap:        server.dtopicMap,
 		subscribedTopics: make(map[string]uint64),
+		joinedTopics:     make(map[string]bool),
 		olricDb:          server.olricDb,
 		cruds:            server.cruds,
+		topicMetaMap:     server.topicMetaMap,
+		presenceMap:      server.presenceMap,
 	}
 
 	maxId++
 	ch := make(chan resource.EventMessage, channelBufSize)
-	doneCh := make(chan bool)
+	doneCh := make(chan struct{})
 
 	u := ws.Request().Context().Value("user")
 	if u == nil {
 		return nil, errors.New("unauthorized")
 	}
 	user := u.(*auth.SessionUser)
+	connectionId, _ := uuid.NewV4()
 	return &Client{
 		id:                         maxId,
+		connectionId:               connectionId.String(),
 		ws:                         ws,
 		server:                     server,
 		ch:                         ch,
@@ -76,8 +85,11 @@ func (c *Client) Write(msg resource.EventMessage) {
 	}
 }
 
+// Done stops both loops of the client, it can be called more than once
 func (c *Client) Done() {
-	c.doneCh <- true
+	c.doneOnce.Do(func() {
+		close(c.doneCh)
+	})
 }
 
 // Listen Write and Read request via chanel
@@ -101,8 +113,6 @@ func (c *Client) listenWrite() {
 
 			// receive done request
 		case <-c.doneCh:
-			c.server.Del(c)
-			c.doneCh <- true // for listenRead method
 			return
 		}
 	}
@@ -111,13 +121,18 @@ func (c *Client) listenWrite() {
 // Listen read request via chanel
 func (c *Client) listenRead() {
 	log.Println("Listening read from client")
+	// the topics of the connection are only changed by its messages, they are cleaned up here once no more
+	// messages are read
+	defer func() {
+		c.Done()
+		c.server.Del(c)
+		c.webSocketConnectionHandler.ClientDisconnected(c)
+	}()
 	for {
 		select {
 
 		// receive done request
 		case <-c.doneCh:
-			c.server.Del(c)
-			c.doneCh <- true // for listenWrite method
 			return
 
 			// read data from websocket connection
@@ -125,7 +140,7 @@ func (c *Client) listenRead() {
 			var msg WebSocketPayload
 			err := websocket.JSON.Receive(c.ws, &msg)
 			if err == io.EOF {
-				c.doneCh <- true
+				return
 			} else if err != nil {
 				c.server.Err(err)
 			} else {
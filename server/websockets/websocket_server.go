@@ -17,15 +17,17 @@ type Message map[string]interface{}
 
 // Chat server.
 type Server struct {
-	pattern   string
-	clients   map[int]*Client
-	addCh     chan *Client
-	delCh     chan *Client
-	doneCh    chan bool
-	errCh     chan error
-	dtopicMap *map[string]*olric.DTopic
-	olricDb   *olric.Olric
-	cruds     map[string]*resource.DbResource
+	pattern      string
+	clients      map[int]*Client
+	addCh        chan *Client
+	delCh        chan *Client
+	doneCh       chan bool
+	errCh        chan error
+	dtopicMap    *map[string]*olric.DTopic
+	olricDb      *olric.Olric
+	cruds        map[string]*resource.DbResource
+	topicMetaMap *olric.DMap
+	presenceMap  *olric.DMap
 }
 
 // Create new chat server.
@@ -36,16 +38,24 @@ func NewServer(pattern string, dtopicMap *map[string]*olric.DTopic, cruds map[st
 	doneCh := make(chan bool)
 	errCh := make(chan error)
 
+	olricDb := cruds["world"].OlricDb
+	topicMetaMap, err := olricDb.NewDMap("topic-meta")
+	resource.CheckErr(err, "Failed to create topic-meta map")
+	presenceMap, err := olricDb.NewDMap("topic-presence")
+	resource.CheckErr(err, "Failed to create topic-presence map")
+
 	return &Server{
-		pattern:   pattern,
-		clients:   clients,
-		addCh:     addCh,
-		delCh:     delCh,
-		doneCh:    doneCh,
-		errCh:     errCh,
-		dtopicMap: dtopicMap,
-		olricDb:   cruds["world"].OlricDb,
-		cruds:     cruds,
+		pattern:      pattern,
+		clients:      clients,
+		addCh:        addCh,
+		delCh:        delCh,
+		doneCh:       doneCh,
+		errCh:        errCh,
+		dtopicMap:    dtopicMap,
+		olricDb:      olricDb,
+		cruds:        cruds,
+		topicMetaMap: topicMetaMap,
+		presenceMap:  presenceMap,
 	}
 }
 